@@ -49,6 +49,10 @@ type UploadConfig struct {
 
 type PlatformConfig struct {
 	FeePercent float64
+	// Ongkos kirim tetap per pesanan (per petani) yang ditagihkan ke pembeli saat checkout
+	// e-commerce, dari ECOMMERCE_SHIPPING_FEE. Default 0: ongkir tidak ditagihkan dan
+	// voucher gratis ongkir tidak bisa dibuat.
+	EcommerceShippingFee float64
 }
 
 var AppConfig_ *Config
@@ -84,7 +88,8 @@ func LoadConfig() *Config {
 			MaxFileSize: int64(getEnvAsInt("MAX_FILE_SIZE", 5242880)), // 5MB default
 		},
 		Platform: PlatformConfig{
-			FeePercent: getEnvAsFloat("PLATFORM_FEE_PERCENT", 5.0),
+			FeePercent:           getEnvAsFloat("PLATFORM_FEE_PERCENT", 5.0),
+			EcommerceShippingFee: getEnvAsFloat("ECOMMERCE_SHIPPING_FEE", 0),
 		},
 	}
 
@@ -8,8 +8,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/whsasmita/AgroLink_API/models"
 	"github.com/whsasmita/AgroLink_API/seeders"
+	"github.com/whsasmita/AgroLink_API/utils"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -24,16 +26,25 @@ var migrationModels = []interface{}{
 	&models.AIChatPremiumSubscription{},
 	&models.AIChatTurn{},
 	&models.Payout{}, // Payout di sini
+	&models.Address{},
 	// &models.SystemSetting{},
 
 	// 2. Model profil yang bergantung pada User
 	&models.Farmer{},
 	&models.Worker{},
 	&models.Driver{},
+	&models.DriverRoute{},
 
 	// 3. Model utama yang bergantung pada profil
 	&models.Project{},
 	&models.Delivery{},
+	&models.DeliveryStop{},
+	&models.DeliveryEvent{},
+	&models.DeliveryProof{},
+	&models.DeliveryDispute{},
+	&models.DeliveryFailure{},
+	&models.ColdChainDevice{},
+	&models.DeliveryConditionReading{},
 	&models.FarmLocation{},
 
 	// 4. Model transaksi & perjanjian yang bergantung pada Project/Delivery
@@ -51,12 +62,25 @@ var migrationModels = []interface{}{
 	&models.WebhookLog{},
 
 	// 6. Model tambahan dari ERD e-commerce
+	&models.Category{},
 	&models.Product{},
+	&models.ProductVariant{},
+	&models.BuyerGroup{},
+	&models.BuyerGroupMember{},
+	&models.PriceTier{},
+	&models.RestockSchedule{},
+	&models.BackInStockSubscription{},
 	&models.UserVerification{}, // Pastikan ini diaktifkan jika Anda menggunakannya
 	&models.Cart{},
 	&models.Order{},
 	&models.OrderItem{},
+	&models.StockReservation{},
+	&models.InventoryMovement{},
+	&models.PreOrderCampaign{},
+	&models.PreOrder{},
 	&models.ECommercePayment{},
+	&models.Voucher{},
+	&models.VoucherRedemption{},
 	&models.PlatformProfit{},
 }
 
@@ -83,6 +107,8 @@ func RunMigrationWithReset(db *gorm.DB) {
 // AutoMigrate hanya membuat atau memperbarui tabel tanpa menghapus data.
 func AutoMigrate(db *gorm.DB) {
 	log.Println("🔄 Running database migrations...")
+	backfillDeliveryProofTargets(db)
+	backfillCartHolds(db)
 	for _, model := range migrationModels {
 		if err := db.AutoMigrate(model); err != nil {
 			log.Fatalf("Failed to migrate %T: %v", model, err)
@@ -90,6 +116,247 @@ func AutoMigrate(db *gorm.DB) {
 	}
 	log.Println("✅ Database migrations completed successfully")
 	CreateIndexes(db)
+	dropLegacyDeliveryProofIndex(db)
+	backfillDriverRouteWeekdays(db)
+	backfillProductCategories(db)
+	backfillProductVariants(db)
+	dropLegacyCartIndex(db)
+	backfillStockReservations(db)
+	backfillInventoryOpeningBalances(db)
+	backfillOrderSubTotals(db)
+	backfillPreOrderRefundedAmounts(db)
+}
+
+// dropLegacyDeliveryProofIndex menghapus unique index lama pada delivery_proofs: index
+// delivery_id yang mencegah bukti per titik antar, dan index (delivery_id, stop_id) yang
+// sudah digantikan idx_delivery_proof_handover.
+func dropLegacyDeliveryProofIndex(db *gorm.DB) {
+	for _, legacyIndex := range []string{"idx_delivery_proofs_delivery_id", "idx_delivery_proof_target"} {
+		if !db.Migrator().HasIndex(&models.DeliveryProof{}, legacyIndex) {
+			continue
+		}
+		if err := db.Migrator().DropIndex(&models.DeliveryProof{}, legacyIndex); err != nil {
+			log.Printf("Warning: Failed to drop legacy index %s: %v", legacyIndex, err)
+		}
+	}
+}
+
+// backfillDeliveryProofTargets menambahkan kolom handover_target pada tabel lama dan
+// mengisinya sebelum AutoMigrate membuat unique index (delivery_id, handover_target).
+func backfillDeliveryProofTargets(db *gorm.DB) {
+	if !db.Migrator().HasTable(&models.DeliveryProof{}) || db.Migrator().HasColumn(&models.DeliveryProof{}, "HandoverTarget") {
+		return
+	}
+	if err := db.Migrator().AddColumn(&models.DeliveryProof{}, "HandoverTarget"); err != nil {
+		log.Printf("Warning: Failed to add delivery_proofs.handover_target: %v", err)
+		return
+	}
+	if err := db.Exec("UPDATE delivery_proofs SET handover_target = COALESCE(stop_id, 'final')").Error; err != nil {
+		log.Printf("Warning: Failed to backfill delivery_proofs.handover_target: %v", err)
+	}
+}
+
+// backfillCartHolds menambahkan kolom tahanan stok pada tabel keranjang lama. Item
+// keranjang lama sudah menahan seluruh kuantitasnya, jadi tahanannya dicatat dan diberi
+// batas waktu agar dilepas oleh job bila tidak di-checkout.
+func backfillCartHolds(db *gorm.DB) {
+	if !db.Migrator().HasTable(&models.Cart{}) || db.Migrator().HasColumn(&models.Cart{}, "HeldQuantity") {
+		return
+	}
+	for _, column := range []string{"HoldExpiresAt", "HeldQuantity"} { // held_quantity terakhir sebagai penanda
+		if db.Migrator().HasColumn(&models.Cart{}, column) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&models.Cart{}, column); err != nil {
+			log.Printf("Warning: Failed to add carts.%s: %v", column, err)
+			return
+		}
+	}
+	if err := db.Model(&models.Cart{}).Where("1 = 1").Updates(map[string]interface{}{
+		"held_quantity":   gorm.Expr("quantity"),
+		"hold_expires_at": time.Now().Add(models.CartHoldTTL),
+	}).Error; err != nil {
+		log.Printf("Warning: Failed to backfill cart stock holds: %v", err)
+	}
+}
+
+// backfillDriverRouteWeekdays mengisi kolom terstruktur Weekdays dari teks bebas
+// DaysAvailable untuk rute driver yang dibuat sebelum kolom tersebut ada.
+func backfillDriverRouteWeekdays(db *gorm.DB) {
+	var routes []models.DriverRoute
+	if err := db.Where("(weekdays IS NULL OR weekdays = '') AND days_available <> ''").Find(&routes).Error; err != nil {
+		log.Printf("Warning: Failed to load driver routes for weekday backfill: %v", err)
+		return
+	}
+	for _, route := range routes {
+		weekdays := models.FormatWeekdays(models.ParseWeekdays(route.DaysAvailable))
+		if weekdays == "" {
+			log.Printf("Warning: Could not parse days_available %q for driver route %s", route.DaysAvailable, route.ID)
+			continue
+		}
+		if err := db.Model(&models.DriverRoute{}).Where("id = ?", route.ID).Update("weekdays", weekdays).Error; err != nil {
+			log.Printf("Warning: Failed to backfill weekdays for driver route %s: %v", route.ID, err)
+		}
+	}
+}
+
+// backfillProductCategories memindahkan kategori teks bebas lama (kolom products.category)
+// ke pohon kategori. Teks yang belum punya padanan slug dibuat sebagai kategori utama
+// agar admin bisa menata ulang hierarkinya nanti.
+func backfillProductCategories(db *gorm.DB) {
+	var legacyNames []string
+	if err := db.Model(&models.Product{}).
+		Where("category_id IS NULL AND category IS NOT NULL AND category <> ''").
+		Distinct().Pluck("category", &legacyNames).Error; err != nil {
+		log.Printf("Warning: Failed to load legacy product categories: %v", err)
+		return
+	}
+	for _, name := range legacyNames {
+		slug := utils.Slugify(name)
+		if slug == "" {
+			log.Printf("Warning: Could not derive slug for legacy category %q", name)
+			continue
+		}
+		var category models.Category
+		if err := db.Where("slug = ?", slug).First(&category).Error; err != nil {
+			category = models.Category{Name: strings.TrimSpace(name), Slug: slug}
+			if err := db.Create(&category).Error; err != nil {
+				log.Printf("Warning: Failed to create category for %q: %v", name, err)
+				continue
+			}
+		}
+		if err := db.Model(&models.Product{}).
+			Where("category_id IS NULL AND category = ?", name).
+			Update("category_id", category.ID).Error; err != nil {
+			log.Printf("Warning: Failed to backfill category %q: %v", name, err)
+		}
+	}
+}
+
+// backfillProductVariants membuat satu varian default untuk produk lama yang belum
+// memiliki varian, memindahkan harga & stok dari kolom products, lalu menautkan
+// item keranjang dan item pesanan lama ke varian tersebut.
+func backfillProductVariants(db *gorm.DB) {
+	if !db.Migrator().HasColumn("products", "stock") {
+		return
+	}
+	var legacy []struct {
+		ID            uuid.UUID
+		Price         float64
+		Stock         float64
+		ReservedStock float64
+	}
+	if err := db.Table("products").
+		Select("id, price, stock, reserved_stock").
+		Where("NOT EXISTS (SELECT 1 FROM product_variants pv WHERE pv.product_id = products.id)").
+		Scan(&legacy).Error; err != nil {
+		log.Printf("Warning: Failed to load products for variant backfill: %v", err)
+		return
+	}
+	for _, p := range legacy {
+		variant := models.ProductVariant{
+			ProductID:     p.ID,
+			Name:          "Satuan",
+			Unit:          "pcs",
+			Price:         p.Price,
+			Stock:         p.Stock,
+			ReservedStock: p.ReservedStock,
+			MinOrderQty:   1,
+			QuantityStep:  1,
+		}
+		if err := db.Create(&variant).Error; err != nil {
+			log.Printf("Warning: Failed to create default variant for product %s: %v", p.ID, err)
+			continue
+		}
+		if err := db.Model(&models.Cart{}).Where("product_id = ? AND (variant_id IS NULL OR variant_id = '')", p.ID).
+			Update("variant_id", variant.ID).Error; err != nil {
+			log.Printf("Warning: Failed to link cart items of product %s: %v", p.ID, err)
+		}
+		if err := db.Model(&models.OrderItem{}).Where("product_id = ? AND variant_id IS NULL", p.ID).
+			Updates(map[string]interface{}{"variant_id": variant.ID, "variant_name": variant.Name, "unit": variant.Unit}).Error; err != nil {
+			log.Printf("Warning: Failed to link order items of product %s: %v", p.ID, err)
+		}
+	}
+}
+
+// backfillStockReservations membuat catatan reservasi untuk item pesanan pending yang
+// dibuat sebelum reservasi dilacak per pesanan, agar stok yang ditahannya bisa dilepas
+// oleh job kedaluwarsa alih-alih tertahan selamanya.
+func backfillStockReservations(db *gorm.DB) {
+	var items []struct {
+		ID             uuid.UUID
+		OrderID        uuid.UUID
+		ProductID      uuid.UUID
+		VariantID      uuid.UUID
+		Quantity       float64
+		OrderCreatedAt time.Time
+	}
+	if err := db.Table("order_items").
+		Select("order_items.id, order_items.order_id, order_items.product_id, order_items.variant_id, order_items.quantity, orders.created_at AS order_created_at").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.status = ? AND order_items.variant_id IS NOT NULL", models.OrderStatusPending).
+		Where("NOT EXISTS (SELECT 1 FROM stock_reservations sr WHERE sr.order_item_id = order_items.id)").
+		// Pesanan pre-order tidak menahan stok sampai panen, jadi tidak punya reservasi
+		Where("NOT EXISTS (SELECT 1 FROM pre_orders po WHERE po.order_id = orders.id)").
+		Scan(&items).Error; err != nil {
+		log.Printf("Warning: Failed to load pending order items for reservation backfill: %v", err)
+		return
+	}
+	for _, item := range items {
+		reservation := models.StockReservation{
+			OrderID:     item.OrderID,
+			OrderItemID: item.ID,
+			ProductID:   item.ProductID,
+			VariantID:   item.VariantID,
+			Quantity:    item.Quantity,
+			Status:      models.ReservationStatusActive,
+			ExpiresAt:   item.OrderCreatedAt.Add(models.ReservationTTL),
+		}
+		if err := db.Create(&reservation).Error; err != nil {
+			log.Printf("Warning: Failed to backfill reservation for order item %s: %v", item.ID, err)
+		}
+	}
+}
+
+// backfillInventoryOpeningBalances mencatat saldo awal ledger untuk varian yang belum
+// punya pergerakan stok (data lama atau hasil seeder), agar penjumlahan ledger sama
+// dengan stok tersimpan.
+func backfillInventoryOpeningBalances(db *gorm.DB) {
+	var variants []models.ProductVariant
+	if err := db.Where("stock <> 0 OR reserved_stock <> 0").
+		Where("NOT EXISTS (SELECT 1 FROM inventory_movements im WHERE im.variant_id = product_variants.id)").
+		Find(&variants).Error; err != nil {
+		log.Printf("Warning: Failed to load variants for inventory backfill: %v", err)
+		return
+	}
+	note := "Saldo awal ledger"
+	for _, v := range variants {
+		movement := models.InventoryMovement{
+			ProductID:     v.ProductID,
+			VariantID:     v.ID,
+			Type:          models.MovementAdjustment,
+			StockDelta:    v.Stock,
+			ReservedDelta: v.ReservedStock,
+			StockAfter:    v.Stock,
+			ReservedAfter: v.ReservedStock,
+			Note:          &note,
+		}
+		if err := db.Create(&movement).Error; err != nil {
+			log.Printf("Warning: Failed to record opening balance for variant %s: %v", v.ID, err)
+		}
+	}
+}
+
+// dropLegacyCartIndex menghapus unique index lama (user_id, product_id) pada carts
+// agar satu produk bisa masuk keranjang dengan beberapa varian berbeda.
+func dropLegacyCartIndex(db *gorm.DB) {
+	const legacyIndex = "idx_cart_user_product"
+	if !db.Migrator().HasIndex(&models.Cart{}, legacyIndex) {
+		return
+	}
+	if err := db.Migrator().DropIndex(&models.Cart{}, legacyIndex); err != nil {
+		log.Printf("Warning: Failed to drop legacy index %s: %v", legacyIndex, err)
+	}
 }
 
 func dropAllTables(db *gorm.DB) error {
@@ -123,6 +390,7 @@ func dropAllTables(db *gorm.DB) error {
 func SeedDefaultData(db *gorm.DB) {
 	log.Println("🌱 Seeding default data...")
 	seedUsers(db)
+	seeders.SeedCategories(db)
 	seeders.SeedTransactionsAndInvoices(db)
 	seeders.SeedEcommerceTransactions(db)
 	seeders.SeedProducts(db)
@@ -339,3 +607,23 @@ func randomBetween(start, end time.Time) time.Time {
 	sec := rand.Int63n(delta)
 	return time.Unix(start.Unix()+sec, 0)
 }
+
+// backfillOrderSubTotals mengisi rincian subtotal pesanan lama yang dibuat sebelum ada
+// ongkir dan diskon voucher; total pesanan tersebut seluruhnya adalah harga barang.
+func backfillOrderSubTotals(db *gorm.DB) {
+	if err := db.Model(&models.Order{}).
+		Where("sub_total = 0 AND total_amount > 0 AND shipping_fee = 0 AND discount_amount = 0").
+		Update("sub_total", gorm.Expr("total_amount")).Error; err != nil {
+		log.Printf("Warning: Failed to backfill order subtotals: %v", err)
+	}
+}
+
+// backfillPreOrderRefundedAmounts menandai pengembalian dana pre-order yang sudah selesai
+// sebelum kolom refunded_amount ada sebagai sudah ditransfer seluruhnya.
+func backfillPreOrderRefundedAmounts(db *gorm.DB) {
+	if err := db.Model(&models.PreOrder{}).
+		Where("refund_status = ? AND refunded_amount = 0 AND refund_amount > 0", models.PreOrderRefundCompleted).
+		Update("refunded_amount", gorm.Expr("refund_amount")).Error; err != nil {
+		log.Printf("Warning: Failed to backfill pre-order refunded amounts: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package dto
+
+import "github.com/google/uuid"
+
+// AddressRequest dipakai untuk menambah maupun mengubah alamat di buku alamat.
+type AddressRequest struct {
+	Label         string   `json:"label" binding:"required,max=50"`
+	RecipientName string   `json:"recipient_name" binding:"required,max=100"`
+	PhoneNumber   string   `json:"phone_number" binding:"required,max=20"`
+	Street        string   `json:"street" binding:"required"`
+	Province      string   `json:"province" binding:"required,max=100"`
+	City          string   `json:"city" binding:"required,max=100"`
+	District      string   `json:"district" binding:"required,max=100"`
+	PostalCode    string   `json:"postal_code" binding:"required,max=10"`
+	Latitude      *float64 `json:"latitude" binding:"omitempty,latitude"`
+	Longitude     *float64 `json:"longitude" binding:"omitempty,longitude"`
+	IsDefault     bool     `json:"is_default"`
+}
+
+// CheckoutRequest memilih alamat pengiriman dan voucher (opsional) untuk checkout keranjang.
+// Jika address_id kosong, alamat utama pembeli yang dipakai.
+type CheckoutRequest struct {
+	AddressID   *uuid.UUID `json:"address_id"`
+	VoucherCode *string    `json:"voucher_code"`
+}
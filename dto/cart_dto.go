@@ -3,21 +3,26 @@ package dto
 import "github.com/google/uuid"
 
 type AddToCartInput struct {
-	ProductID uuid.UUID `json:"product_id" binding:"required"`
-	Quantity  int       `json:"quantity" binding:"required,gt=0"`
+	ProductID uuid.UUID  `json:"product_id" binding:"required"`
+	VariantID *uuid.UUID `json:"variant_id"` // Wajib jika produk memiliki lebih dari satu varian
+	Quantity  float64    `json:"quantity" binding:"required,gt=0"`
 }
 
 type UpdateCartInput struct {
-	Quantity int `json:"quantity" binding:"required,gte=0"` // gte=0 untuk mengizinkan penghapusan
+	Quantity float64 `json:"quantity" binding:"gte=0"` // gte=0 untuk mengizinkan penghapusan
 }
 
 // Untuk menampilkan satu item di dalam keranjang
 type CartItemResponse struct {
 	ProductID   uuid.UUID `json:"product_id"`
+	VariantID   uuid.UUID `json:"variant_id"`
 	Title       string    `json:"title"`
-	Price       float64   `json:"price"`
-	ImageURL    string    `json:"image_url"` // Hanya gambar pertama
-	Quantity    int       `json:"quantity"`
+	VariantName string    `json:"variant_name"`
+	Unit        string    `json:"unit"`
+	Price       float64   `json:"price"`      // Harga satuan setelah tier grosir/grup pembeli
+	BasePrice   float64   `json:"base_price"` // Harga satuan normal varian
+	ImageURL    string    `json:"image_url"`  // Hanya gambar pertama
+	Quantity    float64   `json:"quantity"`
 	Subtotal    float64   `json:"subtotal"`
 }
 
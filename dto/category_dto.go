@@ -0,0 +1,39 @@
+package dto
+
+import "github.com/google/uuid"
+
+// CategoryRequest dipakai admin untuk menambah maupun mengubah kategori.
+// Slug dibuat dari nama jika dikosongkan.
+type CategoryRequest struct {
+	ParentID  *uuid.UUID `json:"parent_id"`
+	Name      string     `json:"name" binding:"required,max=100"`
+	Slug      string     `json:"slug" binding:"omitempty,max=120"`
+	IconURL   *string    `json:"icon_url" binding:"omitempty,max=255"`
+	SortOrder int        `json:"sort_order"`
+}
+
+// CategoryResponse adalah node pohon kategori beserta jumlah produknya.
+// ProductCount sudah termasuk produk pada seluruh subkategori.
+type CategoryResponse struct {
+	ID           uuid.UUID          `json:"id"`
+	ParentID     *uuid.UUID         `json:"parent_id"`
+	Name         string             `json:"name"`
+	Slug         string             `json:"slug"`
+	IconURL      *string            `json:"icon_url"`
+	SortOrder    int                `json:"sort_order"`
+	ProductCount int64              `json:"product_count"`
+	Children     []CategoryResponse `json:"children"`
+}
+
+// CategoryDetailResponse menampilkan satu kategori beserta jalur induknya (breadcrumb).
+type CategoryDetailResponse struct {
+	CategoryResponse
+	Breadcrumb []CategorySummary `json:"breadcrumb"`
+}
+
+// CategorySummary adalah ringkasan kategori untuk breadcrumb dan respons produk.
+type CategorySummary struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Slug string    `json:"slug"`
+}
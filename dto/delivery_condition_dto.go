@@ -0,0 +1,65 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/whsasmita/AgroLink_API/models"
+)
+
+// SetConditionThresholdsRequest menetapkan ambang batas cold-chain sebuah pengiriman.
+// Field yang kosong berarti tidak dipantau.
+type SetConditionThresholdsRequest struct {
+	MinTemperatureC *float64 `json:"min_temperature_c" binding:"omitempty,gte=-50,lte=60"`
+	MaxTemperatureC *float64 `json:"max_temperature_c" binding:"omitempty,gte=-50,lte=60"`
+	MaxHumidityPct  *float64 `json:"max_humidity_pct" binding:"omitempty,gte=0,lte=100"`
+}
+
+// ConditionReadingInput adalah satu pembacaan kondisi dari driver (multipart form)
+// atau perangkat IoT (JSON).
+type ConditionReadingInput struct {
+	TemperatureC *float64   `json:"temperature_c" binding:"omitempty,gte=-50,lte=80"`
+	HumidityPct  *float64   `json:"humidity_pct" binding:"omitempty,gte=0,lte=100"`
+	Lat          *float64   `json:"lat"`
+	Lng          *float64   `json:"lng"`
+	RecordedAt   *time.Time `json:"recorded_at"` // Default: waktu server
+	PhotoURL     *string    `json:"-"`
+	Notes        *string    `json:"-"`
+}
+
+// DeviceReadingsRequest adalah kiriman batch dari perangkat IoT.
+type DeviceReadingsRequest struct {
+	Readings []ConditionReadingInput `json:"readings" binding:"required,min=1,max=500,dive"`
+}
+
+// RegisterColdChainDeviceRequest mendaftarkan sensor IoT milik petani.
+type RegisterColdChainDeviceRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// RegisterColdChainDeviceResponse menampilkan API key perangkat. Key hanya ditampilkan sekali.
+type RegisterColdChainDeviceResponse struct {
+	Device *models.ColdChainDevice `json:"device"`
+	APIKey string                  `json:"api_key"`
+}
+
+// ConditionStats merangkum satu besaran (suhu atau kelembapan) selama pengiriman.
+type ConditionStats struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+// DeliveryConditionReport adalah laporan kondisi muatan untuk petani, sengketa, dan PDF pengiriman.
+type DeliveryConditionReport struct {
+	MinTemperatureC *float64                          `json:"min_temperature_c"`
+	MaxTemperatureC *float64                          `json:"max_temperature_c"`
+	MaxHumidityPct  *float64                          `json:"max_humidity_pct"`
+	ReadingCount    int                               `json:"reading_count"`
+	AlertCount      int                               `json:"alert_count"`
+	Temperature     *ConditionStats                   `json:"temperature"` // nil jika tidak ada data suhu
+	Humidity        *ConditionStats                   `json:"humidity"`
+	FirstReadingAt  *time.Time                        `json:"first_reading_at"`
+	LastReadingAt   *time.Time                        `json:"last_reading_at"`
+	Photos          []models.DeliveryConditionReading `json:"photos"` // pembacaan yang memiliki foto kondisi
+	Readings        []models.DeliveryConditionReading `json:"readings"`
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
 )
 
 // CreateDeliveryRequest adalah DTO untuk membuat permintaan pengiriman baru.
@@ -12,17 +13,74 @@ type CreateDeliveryRequest struct {
 	PickupLat          float64 `json:"pickup_lat" binding:"required"`
 	PickupLng          float64 `json:"pickup_lng" binding:"required"`
 	DestinationAddress string  `json:"destination_address" binding:"required"`
-	ItemDescription    string  `json:"item_description" binding:"required"`
-	ItemWeight         float64 `json:"item_weight" binding:"required"`
+	// Koordinat tujuan opsional agar klien lama tetap didukung; tanpa koordinat, rute tidak
+	// dihitung dan driver belum bisa dipilih karena tarif per-km hanya dihitung dari rute server.
+	DestinationLat  *float64 `json:"destination_lat" binding:"omitempty,latitude"`
+	DestinationLng  *float64 `json:"destination_lng" binding:"omitempty,longitude"`
+	ItemDescription string   `json:"item_description" binding:"required"`
+	ItemWeight      float64  `json:"item_weight" binding:"required"`
+	PickupDate      string   `json:"pickup_date"` // Opsional, format "YYYY-MM-DD"
+	RecipientName   *string  `json:"recipient_name"`
+	RecipientPhone  *string  `json:"recipient_phone"`
+	RecipientEmail  *string  `json:"recipient_email" binding:"omitempty,email"` // Tujuan OTP serah terima
+	// Titik antar tambahan (opsional), dikunjungi berurutan sebelum tujuan akhir.
+	Stops []DeliveryStopInput `json:"stops" binding:"omitempty,dive"`
+}
+
+// DeliveryStopInput adalah satu titik antar tambahan pada permintaan pengiriman.
+type DeliveryStopInput struct {
+	Address        string  `json:"address" binding:"required"`
+	Lat            float64 `json:"lat" binding:"required"`
+	Lng            float64 `json:"lng" binding:"required"`
+	RecipientName  *string `json:"recipient_name"`
+	RecipientPhone *string `json:"recipient_phone"`
+	Notes          *string `json:"notes"`
 }
 
 // DriverRecommendationResponse adalah DTO untuk menampilkan driver yang direkomendasikan.
 type DriverRecommendationResponse struct {
-	DriverID      uuid.UUID `json:"driver_id"`
-	DriverName    string    `json:"driver_name"`
-	VehicleTypes  string    `json:"vehicle_types"`
-	Rating        float64   `json:"rating"`
-	Distance      float64   `json:"distance_km"` // Jarak dari lokasi pickup
+	DriverID     uuid.UUID      `json:"driver_id"`
+	DriverName   string         `json:"driver_name"`
+	VehicleTypes string         `json:"vehicle_types"`
+	Rating       float64        `json:"rating"`
+	Distance     float64        `json:"distance_km"` // Jarak dari lokasi pickup
+	Quote        *DeliveryQuote `json:"quote"`       // nil jika skema harga driver tidak valid
+
+	HasActiveDelivery bool          `json:"has_active_delivery"`
+	Score             float64       `json:"score"` // 0-100, makin tinggi makin cocok
+	ScoreBreakdown    []MatchFactor `json:"score_breakdown"`
+}
+
+// MatchFactor menjelaskan kontribusi satu faktor terhadap skor pencocokan driver.
+type MatchFactor struct {
+	Factor string  `json:"factor"`
+	Points float64 `json:"points"`
+	Note   string  `json:"note"`
+}
+
+// DriverPricingScheme adalah bentuk terstruktur dari kolom Driver.PricingScheme.
+type DriverPricingScheme struct {
+	BaseFare          float64            `json:"base_fare"`
+	PerKm             float64            `json:"per_km"`
+	PerKg             float64            `json:"per_kg"`
+	MinimumFare       float64            `json:"minimum_fare"`
+	VehicleSurcharges map[string]float64 `json:"vehicle_surcharges,omitempty"` // key = jenis kendaraan
+}
+
+// DeliveryQuote adalah rincian harga pengiriman yang ditawarkan seorang driver.
+// QuoteID adalah sidik jari penawaran; petani mengirimkannya kembali saat memilih
+// driver agar harga yang dikunci sama dengan yang ia lihat.
+type DeliveryQuote struct {
+	QuoteID          string  `json:"quote_id"`
+	VehicleType      string  `json:"vehicle_type,omitempty"`
+	DistanceKm       float64 `json:"distance_km"`
+	WeightKg         float64 `json:"weight_kg"`
+	BaseFare         float64 `json:"base_fare"`
+	DistanceFare     float64 `json:"distance_fare"`
+	WeightFare       float64 `json:"weight_fare"`
+	VehicleSurcharge float64 `json:"vehicle_surcharge"`
+	MinimumFare      float64 `json:"minimum_fare"`
+	TotalPrice       float64 `json:"total_price"`
 }
 
 type MyDeliveryResponse struct {
@@ -31,4 +89,117 @@ type MyDeliveryResponse struct {
 	DestinationAddress string    `json:"destination_address"`
 	Status             string    `json:"status"`
 	CreatedAt          time.Time `json:"created_at"`
-}
\ No newline at end of file
+}
+
+// SelectDriverRequest memuat penawaran driver yang disetujui petani.
+type SelectDriverRequest struct {
+	QuoteID string `json:"quote_id" binding:"required"`
+}
+
+// DeliveryStatusUpdateRequest adalah aksi lapangan yang dikirim driver.
+// Penyelesaian pengiriman dilakukan lewat bukti serah terima dan kegagalan lewat
+// laporan kegagalan, bukan aksi ini. Aksi "return" menandai muatan sudah kembali ke petani.
+type DeliveryStatusUpdateRequest struct {
+	Action string   `json:"action" binding:"required,oneof=pickup depart arrive return"`
+	Notes  *string  `json:"notes"`
+	Lat    *float64 `json:"lat"`
+	Lng    *float64 `json:"lng"`
+}
+
+// DeliveryDetailResponse menampilkan detail pengiriman beserta riwayat statusnya.
+type DeliveryDetailResponse struct {
+	Delivery     *models.Delivery       `json:"delivery"`
+	Timeline     []models.DeliveryEvent `json:"timeline"`
+	NextStatuses []string               `json:"next_statuses"`
+}
+
+// OTPRequestResponse memberi tahu driver ke mana OTP serah terima dikirim.
+type OTPRequestResponse struct {
+	SentTo    string    `json:"sent_to"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SubmitDeliveryProofInput adalah bukti serah terima yang dikirim driver (multipart form).
+type SubmitDeliveryProofInput struct {
+	RecipientName string
+	OTP           string
+	PhotoURL      string
+	SignatureURL  string
+	Lat           *float64
+	Lng           *float64
+}
+
+// OpenDeliveryDisputeRequest adalah keberatan petani atas sebuah pengiriman.
+type OpenDeliveryDisputeRequest struct {
+	Type   string `json:"type" binding:"required,oneof=payment_issue delivery_problem contract_breach other"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ResolveDeliveryDisputeRequest adalah keputusan admin atas sebuah sengketa.
+type ResolveDeliveryDisputeRequest struct {
+	Status     string `json:"status" binding:"required,oneof=under_review resolved closed"`
+	Resolution string `json:"resolution" binding:"required"`
+}
+
+// DeliveryDisputeDetailResponse menampilkan sengketa beserta bukti serah terima dan timeline.
+type DeliveryDisputeDetailResponse struct {
+	Dispute   *models.DeliveryDispute  `json:"dispute"`
+	Proof     *models.DeliveryProof    `json:"proof"` // nil jika driver belum mengirim bukti
+	Timeline  []models.DeliveryEvent   `json:"timeline"`
+	Condition *DeliveryConditionReport `json:"condition"` // laporan cold-chain selama pengiriman
+}
+
+// CreateConsolidatedDeliveryRequest menggabungkan beberapa pesanan dan/atau muatan
+// hasil panen ke dalam satu pengiriman dengan banyak titik antar.
+type CreateConsolidatedDeliveryRequest struct {
+	PickupAddress string                  `json:"pickup_address" binding:"required"`
+	PickupLat     float64                 `json:"pickup_lat" binding:"required"`
+	PickupLng     float64                 `json:"pickup_lng" binding:"required"`
+	PickupDate    string                  `json:"pickup_date"`    // Opsional, format "YYYY-MM-DD"
+	OptimizeRoute *bool                   `json:"optimize_route"` // Default true; false = urutan sesuai input
+	Stops         []ConsolidatedStopInput `json:"stops" binding:"required,min=2,max=25,dive"`
+}
+
+// ConsolidatedStopInput adalah satu titik antar pada pengiriman gabungan.
+// Isi OrderID untuk pesanan e-commerce; kosongkan untuk muatan hasil panen biasa.
+type ConsolidatedStopInput struct {
+	OrderID         *uuid.UUID `json:"order_id"`
+	Address         string     `json:"address"` // Default: alamat pengiriman pesanan
+	Lat             *float64   `json:"lat"`     // Default: koordinat alamat pengiriman pesanan
+	Lng             *float64   `json:"lng"`
+	RecipientName   *string    `json:"recipient_name"` // Default: data pembeli pesanan
+	RecipientPhone  *string    `json:"recipient_phone"`
+	RecipientEmail  *string    `json:"recipient_email" binding:"omitempty,email"`
+	ItemDescription string     `json:"item_description"` // Wajib untuk muatan non-pesanan
+	ItemWeight      float64    `json:"item_weight" binding:"required,gt=0"`
+	Notes           *string    `json:"notes"`
+}
+
+// ConsolidatedDeliveryResponse menampilkan pengiriman gabungan beserta hasil optimasi rute.
+type ConsolidatedDeliveryResponse struct {
+	Delivery            *models.Delivery `json:"delivery"`
+	InputDistanceKm     float64          `json:"input_distance_km"`     // jarak garis lurus sesuai urutan input
+	OptimizedDistanceKm float64          `json:"optimized_distance_km"` // jarak garis lurus setelah diurutkan
+}
+
+// ReportDeliveryFailureInput adalah laporan kegagalan dari driver (multipart form).
+type ReportDeliveryFailureInput struct {
+	Reason   string
+	Notes    string
+	PhotoURL string
+	Lat      *float64
+	Lng      *float64
+}
+
+// ResolveDeliveryFailureRequest adalah keputusan petani atas pengiriman yang gagal.
+type ResolveDeliveryFailureRequest struct {
+	Resolution string  `json:"resolution" binding:"required,oneof=redeliver return_to_origin cancel"`
+	Notes      *string `json:"notes"`
+}
+
+// DeliveryFailureResolutionResponse menampilkan keputusan beserta tagihan tambahan (jika ada).
+type DeliveryFailureResolutionResponse struct {
+	Failure    *models.DeliveryFailure `json:"failure"`
+	Delivery   *models.Delivery        `json:"delivery"`
+	FeeInvoice *models.Invoice         `json:"fee_invoice"` // nil jika tidak ada biaya tambahan
+}
@@ -9,15 +9,33 @@ import (
 
 // DriverResponse merepresentasikan format respons API untuk data driver/ekspedisi
 type DriverResponse struct {
-	UserID          uuid.UUID `json:"user_id"`
-	Name            string    `json:"name"`
-	ProfilePicture  *string   `json:"profile_picture"`
-	PhoneNumber     *string   `json:"phone_number"`
-	CompanyAddress  *string   `json:"company_address"`
-	PricingScheme   string    `json:"pricing_scheme"`
-	VehicleTypes    string    `json:"vehicle_types"`
-	Rating          float64   `json:"rating"`
-	TotalDeliveries int       `json:"total_deliveries"`
-	CreatedAt       time.Time `json:"created_at"`
+	UserID          uuid.UUID  `json:"user_id"`
+	Name            string     `json:"name"`
+	ProfilePicture  *string    `json:"profile_picture"`
+	PhoneNumber     *string    `json:"phone_number"`
+	CompanyAddress  *string    `json:"company_address"`
+	PricingScheme   string     `json:"pricing_scheme"`
+	VehicleTypes    string     `json:"vehicle_types"`
+	Rating          float64    `json:"rating"`
+	TotalDeliveries int        `json:"total_deliveries"`
+	CreatedAt       time.Time  `json:"created_at"`
+	IsOnline        bool       `json:"is_online"`
+	LastSeenAt      *time.Time `json:"last_seen_at"`
+}
+
+// DriverPresenceRequest dikirim saat driver online atau mengirim heartbeat.
+type DriverPresenceRequest struct {
+	Lat *float64 `json:"latitude" binding:"required"`
+	Lng *float64 `json:"longitude" binding:"required"`
+}
 
+// DriverPresenceResponse adalah status ketersediaan driver saat ini.
+type DriverPresenceResponse struct {
+	IsOnline   bool       `json:"is_online"`
+	IsStale    bool       `json:"is_stale"` // online tetapi heartbeat terlambat
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	CurrentLat *float64   `json:"current_lat"`
+	CurrentLng *float64   `json:"current_lng"`
+	// HeartbeatIntervalSeconds adalah saran interval heartbeat untuk aplikasi driver.
+	HeartbeatIntervalSeconds int `json:"heartbeat_interval_seconds"`
 }
@@ -0,0 +1,36 @@
+package dto
+
+import "github.com/google/uuid"
+
+// DriverRouteInput adalah DTO untuk membuat atau memperbarui rute reguler driver.
+type DriverRouteInput struct {
+	Origin      string  `json:"origin" binding:"required"`
+	Destination string  `json:"destination" binding:"required"`
+	Weekdays    []int   `json:"weekdays" binding:"required,min=1,dive,min=0,max=6"` // 0 = Minggu ... 6 = Sabtu
+	StartTime   string  `json:"start_time"`                                         // Opsional, format "HH:MM"
+	EndTime     string  `json:"end_time"`                                           // Opsional, format "HH:MM"
+	Notes       *string `json:"notes"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+// DriverRouteResponse adalah DTO untuk menampilkan satu rute reguler.
+type DriverRouteResponse struct {
+	ID            uuid.UUID `json:"id"`
+	Origin        string    `json:"origin"`
+	Destination   string    `json:"destination"`
+	Weekdays      []int     `json:"weekdays"`
+	DaysAvailable string    `json:"days_available"`
+	StartTime     string    `json:"start_time"`
+	EndTime       string    `json:"end_time"`
+	Notes         *string   `json:"notes"`
+	IsActive      bool      `json:"is_active"`
+}
+
+// DriverRouteSearchResult mengelompokkan rute yang cocok per driver.
+type DriverRouteSearchResult struct {
+	DriverID     uuid.UUID             `json:"driver_id"`
+	DriverName   string                `json:"driver_name"`
+	VehicleTypes string                `json:"vehicle_types"`
+	Rating       float64               `json:"rating"`
+	Routes       []DriverRouteResponse `json:"routes"`
+}
@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StockMovementQuery memfilter riwayat pergerakan stok sebuah produk.
+type StockMovementQuery struct {
+	VariantID string `form:"variant_id" binding:"omitempty,uuid"`
+	Type      string `form:"type" binding:"omitempty,oneof=restock reservation release sale adjustment return"`
+	Page      int    `form:"page,default=1" binding:"gte=1"`
+	Limit     int    `form:"limit,default=20" binding:"gte=1,lte=100"`
+}
+
+// StockMovementRequest dipakai petani untuk mencatat stok masuk, koreksi, atau retur.
+// Quantity untuk restock dan return harus positif; untuk adjustment boleh negatif.
+type StockMovementRequest struct {
+	Type     string     `json:"type" binding:"required,oneof=restock adjustment return"`
+	Quantity float64    `json:"quantity" binding:"required"`
+	OrderID  *uuid.UUID `json:"order_id"` // Wajib untuk return
+	Note     string     `json:"note" binding:"max=255"`
+}
+
+type StockMovementResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	VariantID     uuid.UUID  `json:"variant_id"`
+	VariantName   string     `json:"variant_name"`
+	Unit          string     `json:"unit"`
+	Type          string     `json:"type"`
+	StockDelta    float64    `json:"stock_delta"`
+	ReservedDelta float64    `json:"reserved_delta"`
+	StockAfter    float64    `json:"stock_after"`
+	ReservedAfter float64    `json:"reserved_after"`
+	OrderID       *uuid.UUID `json:"order_id,omitempty"`
+	ActorID       *uuid.UUID `json:"actor_id,omitempty"`
+	Note          *string    `json:"note,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// VariantStockBalance membandingkan stok tersimpan dengan stok hasil penjumlahan ledger.
+type VariantStockBalance struct {
+	VariantID           uuid.UUID `json:"variant_id"`
+	VariantName         string    `json:"variant_name"`
+	Unit                string    `json:"unit"`
+	Stock               float64   `json:"stock"`
+	ReservedStock       float64   `json:"reserved_stock"`
+	LedgerStock         float64   `json:"ledger_stock"`
+	LedgerReservedStock float64   `json:"ledger_reserved_stock"`
+	InSync              bool      `json:"in_sync"`
+}
+
+// ProductStockHistoryResponse adalah riwayat pergerakan stok satu produk beserta saldonya.
+type ProductStockHistoryResponse struct {
+	Balances  []VariantStockBalance `json:"balances"`
+	Movements PaginationResponse    `json:"movements"`
+}
@@ -0,0 +1,31 @@
+package dto
+
+import "github.com/whsasmita/AgroLink_API/models"
+
+// ShipOrderRequest adalah cara petani memenuhi pesanan yang sudah dibayar.
+// Field lokasi hanya wajib untuk metode "courier" (diantar driver lewat modul Delivery).
+type ShipOrderRequest struct {
+	Method             string   `json:"method" binding:"required,oneof=courier self_delivery pickup"`
+	PickupAddress      string   `json:"pickup_address"`
+	PickupLat          *float64 `json:"pickup_lat"`
+	PickupLng          *float64 `json:"pickup_lng"`
+	DestinationAddress string   `json:"destination_address"` // Default: alamat pengiriman pesanan
+	DestinationLat     *float64 `json:"destination_lat"`     // Default: koordinat alamat pengiriman pesanan
+	DestinationLng     *float64 `json:"destination_lng"`
+	ItemWeight         float64  `json:"item_weight" binding:"omitempty,gt=0"` // dalam kg
+	PickupDate         string   `json:"pickup_date"`                          // Opsional, format "YYYY-MM-DD"
+}
+
+// IncomingOrderResponse adalah pesanan masuk untuk petani beserta nilai yang diterimanya.
+type IncomingOrderResponse struct {
+	models.Order
+	SellerAmount float64 `json:"seller_amount"` // TotalAmount + diskon yang ditanggung platform
+}
+
+// OrderDetailResponse menampilkan pesanan beserta timeline pengirimannya (jika diantar kurir).
+// SellerAmount hanya diisi untuk petani penjual dan admin.
+type OrderDetailResponse struct {
+	Order            *models.Order          `json:"order"`
+	SellerAmount     *float64               `json:"seller_amount,omitempty"`
+	DeliveryTimeline []models.DeliveryEvent `json:"delivery_timeline"`
+}
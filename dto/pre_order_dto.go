@@ -0,0 +1,88 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PreOrderCampaignRequest dipakai petani untuk membuka pre-order atas panen yang akan datang.
+type PreOrderCampaignRequest struct {
+	VariantID      uuid.UUID `json:"variant_id" binding:"required"`
+	Price          *float64  `json:"price" binding:"omitempty,gt=0"` // Default: harga varian saat ini
+	Quota          float64   `json:"quota" binding:"required,gt=0"`
+	DepositPercent int       `json:"deposit_percent" binding:"min=0,max=100"`
+	HarvestStart   string    `json:"harvest_start" binding:"required,datetime=2006-01-02"`
+	HarvestEnd     string    `json:"harvest_end" binding:"required,datetime=2006-01-02"`
+	Notes          *string   `json:"notes" binding:"omitempty,max=255"`
+}
+
+type PreOrderCampaignResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	ProductID      uuid.UUID  `json:"product_id"`
+	VariantID      uuid.UUID  `json:"variant_id"`
+	VariantName    string     `json:"variant_name"`
+	Unit           string     `json:"unit"`
+	Price          float64    `json:"price"`
+	Quota          float64    `json:"quota"`
+	ReservedQuota  float64    `json:"reserved_quota"`
+	RemainingQuota float64    `json:"remaining_quota"`
+	DepositPercent int        `json:"deposit_percent"`
+	HarvestStart   string     `json:"harvest_start"`
+	HarvestEnd     string     `json:"harvest_end"`
+	Status         string     `json:"status"`
+	ActualQuantity *float64   `json:"actual_quantity"`
+	HarvestedAt    *time.Time `json:"harvested_at"`
+	Notes          *string    `json:"notes"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// CreatePreOrderRequest dipakai pembeli untuk memesan dari kampanye pre-order.
+type CreatePreOrderRequest struct {
+	CampaignID uuid.UUID  `json:"campaign_id" binding:"required"`
+	Quantity   float64    `json:"quantity" binding:"required,gt=0"`
+	AddressID  *uuid.UUID `json:"address_id"` // Default: alamat utama pembeli
+}
+
+type PreOrderResponse struct {
+	ID                 uuid.UUID  `json:"id"`
+	CampaignID         uuid.UUID  `json:"campaign_id"`
+	OrderID            uuid.UUID  `json:"order_id"`
+	ProductID          uuid.UUID  `json:"product_id,omitempty"`
+	ProductTitle       string     `json:"product_title,omitempty"`
+	VariantName        string     `json:"variant_name,omitempty"`
+	Unit               string     `json:"unit,omitempty"`
+	BuyerName          string     `json:"buyer_name,omitempty"`
+	Quantity           float64    `json:"quantity"`
+	AllocatedQuantity  *float64   `json:"allocated_quantity"`
+	UnitPrice          float64    `json:"unit_price"`
+	TotalAmount        float64    `json:"total_amount"`
+	DepositAmount      float64    `json:"deposit_amount"`
+	PaidAmount         float64    `json:"paid_amount"`
+	OutstandingBalance float64    `json:"outstanding_balance"`
+	Status             string     `json:"status"`
+	HarvestStart       string     `json:"harvest_start,omitempty"`
+	HarvestEnd         string     `json:"harvest_end,omitempty"`
+	BalanceDueAt       *time.Time `json:"balance_due_at"`
+	RefundAmount       float64    `json:"refund_amount"`
+	RefundedAmount     float64    `json:"refunded_amount"`
+	PendingRefund      float64    `json:"pending_refund"`
+	RefundStatus       *string    `json:"refund_status"`
+	RefundReason       *string    `json:"refund_reason"`
+	RefundedAt         *time.Time `json:"refunded_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+
+	// Diisi saat pembayaran uang muka/pelunasan baru dibuat
+	Payment *PaymentInitiationResponse `json:"payment,omitempty"`
+}
+
+// PreOrderHarvestResponse merangkum alokasi hasil panen ke pre-order pembeli.
+type PreOrderHarvestResponse struct {
+	Campaign          PreOrderCampaignResponse `json:"campaign"`
+	HarvestedQuantity float64                  `json:"harvested_quantity"`
+	AllocatedQuantity float64                  `json:"allocated_quantity"`
+	SurplusQuantity   float64                  `json:"surplus_quantity"` // Sisa panen yang masuk stok jual biasa
+	ShortfallQuantity float64                  `json:"shortfall_quantity"`
+	RefundTotal       float64                  `json:"refund_total"`
+	PreOrders         []PreOrderResponse       `json:"pre_orders"`
+}
@@ -0,0 +1,55 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BuyerGroupRequest dipakai admin untuk membuat atau mengubah grup pembeli.
+type BuyerGroupRequest struct {
+	Name        string  `json:"name" binding:"required,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=255"`
+	IsActive    *bool   `json:"is_active"` // Default: aktif
+}
+
+type BuyerGroupMemberRequest struct {
+	UserID uuid.UUID `json:"user_id" binding:"required"`
+}
+
+type BuyerGroupResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	IsActive    bool      `json:"is_active"`
+	MemberCount int64     `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type BuyerGroupMemberResponse struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// PriceTierInput adalah satu baris harga: mulai MinQuantity (dalam Unit varian) harga
+// per Unit menjadi Price. Tanpa buyer_group_id tier berlaku untuk semua pembeli.
+type PriceTierInput struct {
+	BuyerGroupID *uuid.UUID `json:"buyer_group_id"`
+	MinQuantity  float64    `json:"min_quantity" binding:"required,gt=0"`
+	Price        float64    `json:"price" binding:"required,gt=0"`
+}
+
+// PriceTierSetRequest mengganti seluruh tier satu varian; daftar kosong menghapus semua tier.
+type PriceTierSetRequest struct {
+	Tiers []PriceTierInput `json:"tiers" binding:"max=20,dive"`
+}
+
+type PriceTierResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	BuyerGroupID   *uuid.UUID `json:"buyer_group_id,omitempty"`
+	BuyerGroupName string     `json:"buyer_group_name,omitempty"`
+	MinQuantity    float64    `json:"min_quantity"`
+	Price          float64    `json:"price"`
+}
@@ -3,37 +3,116 @@ package dto
 import "github.com/google/uuid"
 
 type CreateProductInput struct {
-	Title       string   `json:"title" binding:"required"`
-	Description string   `json:"description" binding:"required"`
-	Price       float64  `json:"price" binding:"required,gt=0"`
-	Stock       int      `json:"stock" binding:"required,gte=0"`
-	Category    string   `json:"category"`
-	Location    string   `json:"location"`
-	ImageURLs   []string `json:"image_urls"`
+	Title       string    `json:"title" binding:"required"`
+	Description string    `json:"description" binding:"required"`
+	CategoryID  uuid.UUID `json:"category_id" binding:"required"`
+	Location    string    `json:"location"`
+	Latitude    *float64  `json:"latitude" binding:"omitempty,latitude"`
+	Longitude   *float64  `json:"longitude" binding:"omitempty,longitude"`
+	ImageURLs   []string  `json:"image_urls"`
+
+	Variants []ProductVariantInput `json:"variants" binding:"required,min=1,dive"`
+}
+
+// ProductVariantInput adalah satuan jual produk, mis. "1 kg" atau "Karung 10 kg".
+// Harga & stok dinyatakan per Unit; kuantitas desimal diizinkan lewat quantity_step.
+type ProductVariantInput struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	SKU          *string  `json:"sku" binding:"omitempty,max=64"`
+	Unit         string   `json:"unit" binding:"required,max=20"`
+	Price        float64  `json:"price" binding:"required,gt=0"`
+	Stock        float64  `json:"stock" binding:"gte=0"`
+	MinOrderQty  *float64 `json:"min_order_qty" binding:"omitempty,gt=0"` // Default 1
+	QuantityStep *float64 `json:"quantity_step" binding:"omitempty,gt=0"` // Default 1; 0.5 untuk kelipatan 0,5
+	SortOrder    int      `json:"sort_order"`
+	// Ambang stok menipis (dalam Unit); kosong berarti tidak dipantau
+	LowStockThreshold *float64 `json:"low_stock_threshold" binding:"omitempty,gte=0"`
 }
 
 type UpdateProductInput struct {
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	Price       float64  `json:"price,omitempty"`
-	Stock       int      `json:"stock,omitempty"`
-	Category    string   `json:"category"`
-	Location    string   `json:"location"`
-	ImageURLs   []string `json:"image_urls"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	CategoryID  *uuid.UUID `json:"category_id"`
+	Location    string     `json:"location"`
+	Latitude    *float64   `json:"latitude" binding:"omitempty,latitude"`
+	Longitude   *float64   `json:"longitude" binding:"omitempty,longitude"`
+	ImageURLs   []string   `json:"image_urls"`
 }
 
 type ProductResponse struct {
+	ID          uuid.UUID        `json:"id"`
+	FarmerID    uuid.UUID        `json:"farmer_id"`
+	FarmerName  string           `json:"farmer_name"`
+	Title       string           `json:"title"`
+	Description string           `json:"description"`
+	Rating      *float64         `json:"rating"`
+	Price       float64          `json:"price"` // Harga varian termurah
+	CategoryID  *uuid.UUID       `json:"category_id"`
+	Category    *CategorySummary `json:"category"`
+	Location    *string          `json:"location"`
+	Latitude    *float64         `json:"latitude"`
+	Longitude   *float64         `json:"longitude"`
+	DistanceKm  *float64         `json:"distance_km,omitempty"` // Hanya pada pencarian dengan lat & lng
+	ImageURLs   []string         `json:"image_urls"`
+
+	Variants []ProductVariantResponse `json:"variants"`
+
+	// in_stock, coming_soon (habis tetapi ada jadwal panen), atau out_of_stock
+	Availability     string                   `json:"availability"`
+	UpcomingHarvests []RestockScheduleSummary `json:"upcoming_harvests"`
+}
+
+type ProductVariantResponse struct {
 	ID             uuid.UUID `json:"id"`
-	FarmerID       uuid.UUID `json:"farmer_id"`
-	FarmerName     string    `json:"farmer_name"`
-	Title          string    `json:"title"`
-	Description    string    `json:"description"`
-	Rating         *float64  `json:"rating"`
+	Name           string    `json:"name"`
+	SKU            *string   `json:"sku"`
+	Unit           string    `json:"unit"`
 	Price          float64   `json:"price"`
-	AvailableStock *int      `json:"available_stock,omitempty"` // Untuk pembeli
-	Stock          *int      `json:"stock,omitempty"`           // Stok total untuk petani
-	ReservedStock  *int      `json:"reserved_stock,omitempty"`  // Stok direservasi, hanya untuk petani
-	Category       *string   `json:"category"`
-	Location       *string   `json:"location"`
-	ImageURLs      []string  `json:"image_urls"`
+	AvailableStock float64   `json:"available_stock"`
+	Stock          *float64  `json:"stock,omitempty"`          // Stok total, hanya untuk petani
+	ReservedStock  *float64  `json:"reserved_stock,omitempty"` // Stok direservasi, hanya untuk petani
+	MinOrderQty    float64   `json:"min_order_qty"`
+	QuantityStep   float64   `json:"quantity_step"`
+
+	LowStockThreshold *float64 `json:"low_stock_threshold,omitempty"` // Hanya untuk petani
+
+	// Harga grosir bertingkat; pembeli umum hanya melihat tier tanpa grup
+	PriceTiers []PriceTierResponse `json:"price_tiers"`
+}
+
+// ProductSearchRequest adalah parameter query katalog publik (/public/products).
+type ProductSearchRequest struct {
+	Query    string   `form:"q"`
+	Category string   `form:"category"` // Slug kategori; termasuk seluruh subkategorinya
+	MinPrice *float64 `form:"min_price" binding:"omitempty,gte=0"`
+	MaxPrice *float64 `form:"max_price" binding:"omitempty,gte=0"`
+	Lat      *float64 `form:"lat" binding:"omitempty,latitude"`
+	Lng      *float64 `form:"lng" binding:"omitempty,longitude"`
+	RadiusKm *float64 `form:"radius_km" binding:"omitempty,gt=0"` // Butuh lat & lng
+	InStock  bool     `form:"in_stock"`
+	FarmerID string   `form:"farmer_id" binding:"omitempty,uuid"`
+	Sort     string   `form:"sort,default=newest" binding:"omitempty,oneof=newest price_asc price_desc rating distance"`
+	Page     int      `form:"page,default=1" binding:"gte=1"`
+	Limit    int      `form:"limit,default=20" binding:"gte=1,lte=100"`
+
+	CategoryIDs []uuid.UUID `form:"-"` // Diisi service dari slug kategori
+}
+
+// HasLocation bernilai true jika pencarian menyertakan titik asal pembeli.
+func (r ProductSearchRequest) HasLocation() bool {
+	return r.Lat != nil && r.Lng != nil
+}
+
+// CategoryFacet adalah jumlah produk per kategori pada hasil pencarian.
+type CategoryFacet struct {
+	CategoryID uuid.UUID `json:"category_id"`
+	Name       string    `json:"name"`
+	Slug       string    `json:"slug"`
+	Count      int64     `json:"count"`
+}
+
+// ProductSearchResponse adalah hasil pencarian berhalaman beserta facet kategori.
+type ProductSearchResponse struct {
+	PaginationResponse
+	Facets []CategoryFacet `json:"facets"`
 }
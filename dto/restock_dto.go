@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status ketersediaan produk pada halaman produk.
+const (
+	AvailabilityInStock    = "in_stock"
+	AvailabilityComingSoon = "coming_soon"
+	AvailabilityOutOfStock = "out_of_stock"
+)
+
+// RestockScheduleRequest dipakai petani untuk mengumumkan perkiraan panen sebuah varian.
+type RestockScheduleRequest struct {
+	VariantID        uuid.UUID `json:"variant_id" binding:"required"`
+	ExpectedQuantity float64   `json:"expected_quantity" binding:"required,gt=0"`
+	ExpectedDate     string    `json:"expected_date" binding:"required,datetime=2006-01-02"`
+	Notes            *string   `json:"notes" binding:"omitempty,max=255"`
+}
+
+// HarvestRequest mencatat hasil panen aktual; jumlahnya langsung masuk ke stok.
+type HarvestRequest struct {
+	ActualQuantity float64 `json:"actual_quantity" binding:"required,gt=0"`
+}
+
+type RestockScheduleResponse struct {
+	ID               uuid.UUID  `json:"id"`
+	ProductID        uuid.UUID  `json:"product_id"`
+	VariantID        uuid.UUID  `json:"variant_id"`
+	VariantName      string     `json:"variant_name"`
+	Unit             string     `json:"unit"`
+	ExpectedQuantity float64    `json:"expected_quantity"`
+	ExpectedDate     string     `json:"expected_date"`
+	Status           string     `json:"status"`
+	ActualQuantity   *float64   `json:"actual_quantity"`
+	HarvestedAt      *time.Time `json:"harvested_at"`
+	Notes            *string    `json:"notes"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// RestockScheduleSummary adalah jadwal panen mendatang yang ditampilkan ke pembeli.
+type RestockScheduleSummary struct {
+	VariantID        uuid.UUID `json:"variant_id"`
+	VariantName      string    `json:"variant_name"`
+	Unit             string    `json:"unit"`
+	ExpectedQuantity float64   `json:"expected_quantity"`
+	ExpectedDate     string    `json:"expected_date"`
+}
+
+type RestockSubscriptionResponse struct {
+	ProductID    uuid.UUID `json:"product_id"`
+	SubscribedAt time.Time `json:"subscribed_at"`
+}
@@ -0,0 +1,55 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+)
+
+// RouteStop adalah periode driver berhenti di satu tempat selama perjalanan.
+type RouteStop struct {
+	Lat             float64   `json:"lat"`
+	Lng             float64   `json:"lng"`
+	ArrivedAt       time.Time `json:"arrived_at"`
+	DepartedAt      time.Time `json:"departed_at"`
+	DurationMinutes float64   `json:"duration_minutes"`
+}
+
+// RouteStats adalah ringkasan perjalanan yang dihitung dari riwayat titik GPS.
+type RouteStats struct {
+	PointCount        int         `json:"point_count"`
+	TotalDistanceKm   float64     `json:"total_distance_km"`
+	PlannedDistanceKm *float64    `json:"planned_distance_km"` // estimasi saat delivery dibuat
+	StartedAt         *time.Time  `json:"started_at"`
+	EndedAt           *time.Time  `json:"ended_at"`
+	DurationMinutes   float64     `json:"duration_minutes"`
+	MovingMinutes     float64     `json:"moving_minutes"`
+	MaxSpeedKmh       float64     `json:"max_speed_kmh"`
+	AvgMovingSpeedKmh float64     `json:"avg_moving_speed_kmh"`
+	Stops             []RouteStop `json:"stops"`
+}
+
+// RouteHistory adalah jejak lengkap sebuah pengiriman beserta statistiknya.
+type RouteHistory struct {
+	DeliveryID uuid.UUID              `json:"delivery_id"`
+	Stats      RouteStats             `json:"stats"`
+	Track      []models.LocationTrack `json:"-"`
+}
+
+// GeoJSONFeatureCollection mengikuti RFC 7946.
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+type GeoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   GeoJSONGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+type GeoJSONGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
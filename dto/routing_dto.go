@@ -0,0 +1,14 @@
+package dto
+
+// GeoPoint adalah satu koordinat (lintang/bujur).
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// RouteEstimate adalah hasil perhitungan rute dari sebuah RoutingProvider.
+type RouteEstimate struct {
+	DistanceKm      float64 `json:"distance_km"`
+	DurationMinutes int     `json:"duration_minutes"`
+	Source          string  `json:"source"` // "osrm" atau "haversine"
+}
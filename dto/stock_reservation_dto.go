@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Alasan sebuah reservasi dianggap macet pada laporan admin.
+const (
+	StuckReasonExpired         = "expired"           // lewat batas waktu tetapi belum dilepas job
+	StuckReasonOrderNotPending = "order_not_pending" // order sudah dibayar/dibatalkan tetapi stok masih ditahan
+)
+
+// StuckReservationResponse adalah satu baris laporan reservasi stok yang macet.
+type StuckReservationResponse struct {
+	ID             uuid.UUID `json:"id"`
+	OrderID        uuid.UUID `json:"order_id"`
+	InvoiceNumber  string    `json:"invoice_number"`
+	OrderStatus    string    `json:"order_status"`
+	ProductID      uuid.UUID `json:"product_id"`
+	ProductTitle   string    `json:"product_title"`
+	VariantID      uuid.UUID `json:"variant_id"`
+	VariantName    string    `json:"variant_name"`
+	Unit           string    `json:"unit"`
+	Quantity       float64   `json:"quantity"`
+	Reason         string    `json:"reason"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	OverdueMinutes int       `json:"overdue_minutes"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// StuckReservationReport merangkum reservasi macet beserta total kuantitas per varian.
+type StuckReservationReport struct {
+	TotalReservations int                        `json:"total_reservations"`
+	TotalByVariant    map[string]float64         `json:"total_quantity_by_variant"`
+	Reservations      []StuckReservationResponse `json:"reservations"`
+}
@@ -1,6 +1,50 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/whsasmita/AgroLink_API/models"
+)
+
 type UpdateLocationRequest struct {
 	Lat float64 `json:"latitude" binding:"required"`
 	Lng float64 `json:"longitude" binding:"required"`
-}
\ No newline at end of file
+}
+
+// DeliveryETA adalah perkiraan sisa perjalanan dari posisi terakhir driver.
+type DeliveryETA struct {
+	RemainingKm float64   `json:"remaining_km"`
+	EtaMinutes  int       `json:"eta_minutes"`
+	EtaAt       time.Time `json:"eta_at"`
+	Source      string    `json:"source"`
+}
+
+// TrackingSnapshot adalah keadaan awal yang dikirim saat pemantau mulai berlangganan.
+type TrackingSnapshot struct {
+	Status         string                `json:"status"`
+	LatestLocation *models.LocationTrack `json:"latest_location"`
+	ETA            *DeliveryETA          `json:"eta"`
+}
+
+// LocationPointInput adalah satu titik GPS yang direkam perangkat driver.
+type LocationPointInput struct {
+	Lat       float64   `json:"latitude" binding:"required"`
+	Lng       float64   `json:"longitude" binding:"required"`
+	Timestamp time.Time `json:"timestamp" binding:"required"` // RFC3339, waktu perekaman di perangkat
+	Accuracy  *float64  `json:"accuracy"`
+}
+
+// BatchLocationRequest adalah unggahan sekumpulan titik (mis. setelah offline).
+type BatchLocationRequest struct {
+	Points []LocationPointInput `json:"points" binding:"required,min=1,max=500,dive"`
+}
+
+// BatchLocationResult merangkum nasib setiap titik dalam unggahan batch.
+type BatchLocationResult struct {
+	Received    int `json:"received"`
+	Accepted    int `json:"accepted"`
+	Duplicates  int `json:"duplicates"`
+	Outliers    int `json:"outliers"`    // kecepatan tidak masuk akal
+	Downsampled int `json:"downsampled"` // terlalu rapat dengan titik sebelumnya
+	InvalidTime int `json:"invalid_time"`
+}
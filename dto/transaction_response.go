@@ -10,6 +10,7 @@ type PaymentInitiationResponse struct {
 	SnapToken   string  `json:"snap_token"`
 	OrderID     string  `json:"order_id"`
 	Amount      float64 `json:"amount"`
+	Discount    float64 `json:"discount,omitempty"` // Potongan voucher yang sudah dikurangkan dari Amount
 	RedirectURL string  `json:"redirect_url"`
 }
 
@@ -29,4 +30,17 @@ type TransactionHistoryResponse struct {
 	Status          string    `json:"status"`
 	TransactionDate time.Time `json:"transaction_date"`
 	ReleasedAt      *time.Time `json:"released_at,omitempty"`
-}
\ No newline at end of file
+}
+// ECommerceRefundResponse adalah pengembalian dana pembayaran e-commerce yang
+// harus dicairkan admin.
+type ECommerceRefundResponse struct {
+	PaymentID    uuid.UUID  `json:"payment_id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	BuyerName    string     `json:"buyer_name"`
+	GrandTotal   float64    `json:"grand_total"`
+	RefundAmount float64    `json:"refund_amount"`
+	RefundStatus *string    `json:"refund_status"`
+	RefundReason *string    `json:"refund_reason"`
+	RefundedAt   *time.Time `json:"refunded_at,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
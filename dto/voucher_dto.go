@@ -0,0 +1,70 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VoucherRequest dipakai admin (promo platform) dan petani (voucher toko) untuk membuat
+// atau mengubah voucher.
+type VoucherRequest struct {
+	Code         string     `json:"code" binding:"required,min=3,max=32,alphanum"`
+	Name         string     `json:"name" binding:"required,max=100"`
+	Description  *string    `json:"description" binding:"omitempty,max=255"`
+	DiscountType string     `json:"discount_type" binding:"required,oneof=percentage fixed free_shipping"`
+	Value        float64    `json:"value" binding:"gte=0"` // Persen (1-100), nominal, atau batas ongkir (0 = gratis penuh)
+	MaxDiscount  *float64   `json:"max_discount" binding:"omitempty,gt=0"`
+	MinSpend     float64    `json:"min_spend" binding:"gte=0"`
+	FundedBy     string     `json:"funded_by" binding:"omitempty,oneof=platform farmer"` // Hanya admin; voucher petani selalu ditanggung petani
+	FarmerID     *uuid.UUID `json:"farmer_id"`                                           // Hanya admin; wajib untuk promo yang ditanggung petani
+	UsageLimit   *int       `json:"usage_limit" binding:"omitempty,gt=0"`
+	PerUserLimit *int       `json:"per_user_limit" binding:"omitempty,gte=0"` // Default 1, 0 = tanpa batas
+	StartsAt     time.Time  `json:"starts_at" binding:"required"`
+	EndsAt       time.Time  `json:"ends_at" binding:"required"`
+	IsActive     *bool      `json:"is_active"`
+}
+
+type VoucherResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	Code         string     `json:"code"`
+	Name         string     `json:"name"`
+	Description  *string    `json:"description"`
+	DiscountType string     `json:"discount_type"`
+	Value        float64    `json:"value"`
+	MaxDiscount  *float64   `json:"max_discount"`
+	MinSpend     float64    `json:"min_spend"`
+	FundedBy     string     `json:"funded_by"`
+	FarmerID     *uuid.UUID `json:"farmer_id"`
+	UsageLimit   *int       `json:"usage_limit"`
+	PerUserLimit int        `json:"per_user_limit"`
+	UsedCount    int        `json:"used_count"`
+	StartsAt     time.Time  `json:"starts_at"`
+	EndsAt       time.Time  `json:"ends_at"`
+	IsActive     bool       `json:"is_active"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// CheckoutPreviewRequest menghitung ringkasan checkout keranjang sebelum membayar.
+type CheckoutPreviewRequest struct {
+	VoucherCode *string `json:"voucher_code"`
+}
+
+// CheckoutOrderSummary adalah rincian pesanan untuk satu petani.
+type CheckoutOrderSummary struct {
+	FarmerID         uuid.UUID `json:"farmer_id"`
+	SubTotal         float64   `json:"sub_total"`
+	ShippingFee      float64   `json:"shipping_fee"`
+	DiscountAmount   float64   `json:"discount_amount"`
+	PlatformDiscount float64   `json:"platform_discount"` // Bagian diskon yang ditanggung platform
+	TotalAmount      float64   `json:"total_amount"`
+}
+
+type CheckoutPreviewResponse struct {
+	VoucherCode    *string                `json:"voucher_code"`
+	SubTotal       float64                `json:"sub_total"`
+	ShippingFee    float64                `json:"shipping_fee"`
+	DiscountAmount float64                `json:"discount_amount"`
+	GrandTotal     float64                `json:"grand_total"`
+	Orders         []CheckoutOrderSummary `json:"orders"`
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type AddressHandler struct {
+	addressService services.AddressService
+}
+
+func NewAddressHandler(service services.AddressService) *AddressHandler {
+	return &AddressHandler{addressService: service}
+}
+
+// CreateAddress menambah alamat ke buku alamat pengguna yang sedang login.
+func (h *AddressHandler) CreateAddress(c *gin.Context) {
+	var input dto.AddressRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+	currentUser := c.MustGet("user").(*models.User)
+
+	address, err := h.addressService.CreateAddress(currentUser.ID, input)
+	if err != nil {
+		respondAddressError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Address created successfully", address)
+}
+
+func (h *AddressHandler) GetMyAddresses(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+
+	addresses, err := h.addressService.GetMyAddresses(currentUser.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve addresses", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Addresses retrieved successfully", addresses)
+}
+
+func (h *AddressHandler) GetAddress(c *gin.Context) {
+	addressID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid address ID format", err)
+		return
+	}
+	currentUser := c.MustGet("user").(*models.User)
+
+	address, err := h.addressService.GetAddress(addressID, currentUser.ID)
+	if err != nil {
+		respondAddressError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Address retrieved successfully", address)
+}
+
+func (h *AddressHandler) UpdateAddress(c *gin.Context) {
+	addressID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid address ID format", err)
+		return
+	}
+	var input dto.AddressRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+	currentUser := c.MustGet("user").(*models.User)
+
+	address, err := h.addressService.UpdateAddress(addressID, currentUser.ID, input)
+	if err != nil {
+		respondAddressError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Address updated successfully", address)
+}
+
+func (h *AddressHandler) DeleteAddress(c *gin.Context) {
+	addressID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid address ID format", err)
+		return
+	}
+	currentUser := c.MustGet("user").(*models.User)
+
+	if err := h.addressService.DeleteAddress(addressID, currentUser.ID); err != nil {
+		respondAddressError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Address deleted successfully", nil)
+}
+
+// SetDefaultAddress menjadikan alamat sebagai alamat utama untuk checkout.
+func (h *AddressHandler) SetDefaultAddress(c *gin.Context) {
+	addressID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid address ID format", err)
+		return
+	}
+	currentUser := c.MustGet("user").(*models.User)
+
+	address, err := h.addressService.SetDefaultAddress(addressID, currentUser.ID)
+	if err != nil {
+		respondAddressError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Default address updated", address)
+}
+
+func respondAddressError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "forbidden"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error(), nil)
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process address", err)
+	}
+}
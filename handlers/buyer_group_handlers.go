@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type BuyerGroupHandler struct {
+	buyerGroupService services.BuyerGroupService
+}
+
+func NewBuyerGroupHandler(service services.BuyerGroupService) *BuyerGroupHandler {
+	return &BuyerGroupHandler{buyerGroupService: service}
+}
+
+// GetGroups menampilkan semua grup pembeli beserta jumlah anggotanya (admin).
+func (h *BuyerGroupHandler) GetGroups(c *gin.Context) {
+	groups, err := h.buyerGroupService.GetGroups()
+	if err != nil {
+		respondBuyerGroupError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Buyer groups retrieved successfully", groups)
+}
+
+func (h *BuyerGroupHandler) CreateGroup(c *gin.Context) {
+	var input dto.BuyerGroupRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	group, err := h.buyerGroupService.CreateGroup(input)
+	if err != nil {
+		respondBuyerGroupError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Buyer group created successfully", group)
+}
+
+func (h *BuyerGroupHandler) UpdateGroup(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid buyer group ID format", err)
+		return
+	}
+	var input dto.BuyerGroupRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	group, err := h.buyerGroupService.UpdateGroup(groupID, input)
+	if err != nil {
+		respondBuyerGroupError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Buyer group updated successfully", group)
+}
+
+func (h *BuyerGroupHandler) GetMembers(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid buyer group ID format", err)
+		return
+	}
+	members, err := h.buyerGroupService.GetMembers(groupID)
+	if err != nil {
+		respondBuyerGroupError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Buyer group members retrieved successfully", members)
+}
+
+// AddMember memasukkan pembeli ke grup; pembeli yang sudah di grup lain dipindahkan.
+func (h *BuyerGroupHandler) AddMember(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid buyer group ID format", err)
+		return
+	}
+	var input dto.BuyerGroupMemberRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	if err := h.buyerGroupService.AddMember(groupID, input.UserID); err != nil {
+		respondBuyerGroupError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Buyer added to group successfully", nil)
+}
+
+func (h *BuyerGroupHandler) RemoveMember(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid buyer group ID format", err)
+		return
+	}
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID format", err)
+		return
+	}
+
+	if err := h.buyerGroupService.RemoveMember(groupID, userID); err != nil {
+		respondBuyerGroupError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Buyer removed from group successfully", nil)
+}
+
+func respondBuyerGroupError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+	case strings.Contains(err.Error(), "invalid"):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process buyer group request", err)
+	}
+}
@@ -45,9 +45,9 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 }
 
 func (h *CartHandler) UpdateCartItem(c *gin.Context) {
-	productID, err := uuid.Parse(c.Param("productId"))
+	variantID, err := uuid.Parse(c.Param("variantId"))
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID", err)
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid variant ID", err)
 		return
 	}
 	var input dto.UpdateCartInput
@@ -56,7 +56,7 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 		return
 	}
 	currentUser := c.MustGet("user").(*models.User)
-	_, err = h.cartService.UpdateCartItem(currentUser.ID, productID, input)
+	_, err = h.cartService.UpdateCartItem(currentUser.ID, variantID, input)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
 		return
@@ -65,13 +65,13 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 }
 
 func (h *CartHandler) RemoveFromCart(c *gin.Context) {
-	productID, err := uuid.Parse(c.Param("productId"))
+	variantID, err := uuid.Parse(c.Param("variantId"))
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID", err)
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid variant ID", err)
 		return
 	}
 	currentUser := c.MustGet("user").(*models.User)
-	if err := h.cartService.RemoveFromCart(currentUser.ID, productID); err != nil {
+	if err := h.cartService.RemoveFromCart(currentUser.ID, variantID); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove item from cart", err)
 		return
 	}
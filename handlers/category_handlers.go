@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type CategoryHandler struct {
+	categoryService services.CategoryService
+}
+
+func NewCategoryHandler(service services.CategoryService) *CategoryHandler {
+	return &CategoryHandler{categoryService: service}
+}
+
+// GetCategoryTree menampilkan pohon kategori beserta jumlah produk (publik).
+func (h *CategoryHandler) GetCategoryTree(c *gin.Context) {
+	tree, err := h.categoryService.GetCategoryTree()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve categories", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Categories retrieved successfully", tree)
+}
+
+// GetCategoryBySlug menampilkan satu kategori, subkategori, dan breadcrumb-nya (publik).
+func (h *CategoryHandler) GetCategoryBySlug(c *gin.Context) {
+	category, err := h.categoryService.GetCategoryBySlug(c.Param("slug"))
+	if err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Category retrieved successfully", category)
+}
+
+// CreateCategory dipakai admin untuk menambah kategori atau subkategori.
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var input dto.CategoryRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	category, err := h.categoryService.CreateCategory(input)
+	if err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Category created successfully", category)
+}
+
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid category ID format", err)
+		return
+	}
+	var input dto.CategoryRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	category, err := h.categoryService.UpdateCategory(categoryID, input)
+	if err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Category updated successfully", category)
+}
+
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid category ID format", err)
+		return
+	}
+
+	if err := h.categoryService.DeleteCategory(categoryID); err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Category deleted successfully", nil)
+}
+
+func respondCategoryError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+	case strings.Contains(err.Error(), "invalid"):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process category", err)
+	}
+}
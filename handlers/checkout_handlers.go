@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/whsasmita/AgroLink_API/dto"
 	"github.com/whsasmita/AgroLink_API/models"
 	"github.com/whsasmita/AgroLink_API/services"
 	"github.com/whsasmita/AgroLink_API/utils"
@@ -28,9 +30,18 @@ func (h *CheckoutHandler) CreateOrders(c *gin.Context) {
 	}
 	user := currentUser.(*models.User)
 
+	// Body opsional: tanpa address_id, alamat utama pembeli yang dipakai
+	var input dto.CheckoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+			return
+		}
+	}
+
 	// 2. Panggil CheckoutService untuk memproses keranjang
 	// Service ini akan mengembalikan DTO respons pembayaran
-	paymentResponse, err := h.checkoutService.CreateOrdersFromCart(user.ID)
+	paymentResponse, err := h.checkoutService.CreateOrdersFromCart(user.ID, input)
 	if err != nil {
 		// Tangani error, seperti "cart is empty" atau "insufficient stock"
 		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
@@ -54,4 +65,29 @@ func (h *CheckoutHandler) DirectCheckout(c *gin.Context) {
 		return
 	}
 	utils.SuccessResponse(c, http.StatusCreated, "Direct checkout successful, please proceed to payment", paymentResponse)
-}
\ No newline at end of file
+}
+
+// PreviewCheckout menampilkan ringkasan checkout keranjang beserta potongan voucher.
+func (h *CheckoutHandler) PreviewCheckout(c *gin.Context) {
+	var input dto.CheckoutPreviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+			return
+		}
+	}
+	currentUser := c.MustGet("user").(*models.User)
+	preview, err := h.checkoutService.PreviewCheckout(currentUser.ID, input)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+		case strings.Contains(err.Error(), "invalid"), strings.Contains(err.Error(), "cart is empty"):
+			utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to calculate checkout summary", err)
+		}
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Checkout summary calculated successfully", preview)
+}
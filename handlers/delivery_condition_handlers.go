@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type DeliveryConditionHandler struct {
+	conditionService services.DeliveryConditionService
+}
+
+func NewDeliveryConditionHandler(service services.DeliveryConditionService) *DeliveryConditionHandler {
+	return &DeliveryConditionHandler{conditionService: service}
+}
+
+// SetThresholds dipakai petani untuk menetapkan ambang suhu/kelembapan pengiriman.
+func (h *DeliveryConditionHandler) SetThresholds(c *gin.Context) {
+	var input dto.SetConditionThresholdsRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only farmers can set condition thresholds", nil)
+		return
+	}
+
+	delivery, err := h.conditionService.SetThresholds(c.Param("id"), currentUser.Farmer.UserID, input)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Condition thresholds updated", delivery)
+}
+
+// RecordReading menerima pembacaan kondisi dari driver (multipart/form-data):
+// temperature_c, humidity_pct, notes, lat, lng, photo (file, opsional).
+func (h *DeliveryConditionHandler) RecordReading(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can record delivery condition", nil)
+		return
+	}
+
+	input := dto.ConditionReadingInput{
+		TemperatureC: parseOptionalFloat(c.PostForm("temperature_c")),
+		HumidityPct:  parseOptionalFloat(c.PostForm("humidity_pct")),
+		Lat:          parseOptionalFloat(c.PostForm("lat")),
+		Lng:          parseOptionalFloat(c.PostForm("lng")),
+	}
+	if notes := strings.TrimSpace(c.PostForm("notes")); notes != "" {
+		input.Notes = &notes
+	}
+	var photoPath string
+	if _, err := c.FormFile("photo"); err == nil {
+		path, url, err := saveDeliveryImage(c, "photo", currentUser.ID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		photoPath = path
+		input.PhotoURL = &url
+	}
+
+	reading, err := h.conditionService.RecordDriverReading(c.Param("id"), currentUser.Driver.UserID, input)
+	if err != nil {
+		if photoPath != "" {
+			os.Remove(photoPath)
+		}
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Condition reading recorded", reading)
+}
+
+// RecordDeviceReadings menerima batch pembacaan dari sensor IoT (header X-Device-Key).
+func (h *DeliveryConditionHandler) RecordDeviceReadings(c *gin.Context) {
+	var input dto.DeviceReadingsRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	device := c.MustGet("device").(*models.ColdChainDevice)
+	readings, err := h.conditionService.RecordDeviceReadings(c.Param("id"), device, input.Readings)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Condition readings recorded", readings)
+}
+
+// GetConditionReport menampilkan ringkasan dan rincian kondisi muatan.
+func (h *DeliveryConditionHandler) GetConditionReport(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+
+	report, err := h.conditionService.GetConditionReport(c.Param("id"), currentUser)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Condition report retrieved successfully", report)
+}
+
+// DownloadDeliveryReport mengunduh dokumen pengiriman (PDF) termasuk laporan kondisi.
+func (h *DeliveryConditionHandler) DownloadDeliveryReport(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+
+	pdfBuffer, err := h.conditionService.GenerateDeliveryReportPDF(c.Param("id"), currentUser)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+
+	fileName := fmt.Sprintf("pengiriman_%s.pdf", c.Param("id"))
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	c.Data(http.StatusOK, "application/pdf", pdfBuffer.Bytes())
+}
+
+// RegisterDevice mendaftarkan sensor IoT milik petani. API key hanya ditampilkan sekali.
+func (h *DeliveryConditionHandler) RegisterDevice(c *gin.Context) {
+	var input dto.RegisterColdChainDeviceRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only farmers can register devices", nil)
+		return
+	}
+
+	result, err := h.conditionService.RegisterDevice(currentUser.Farmer.UserID, input)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to register device", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Device registered, store the api key securely", result)
+}
+
+func (h *DeliveryConditionHandler) GetMyDevices(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: User is not a farmer", nil)
+		return
+	}
+
+	devices, err := h.conditionService.GetMyDevices(currentUser.Farmer.UserID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve devices", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Devices retrieved successfully", devices)
+}
+
+// RevokeDevice mencabut API key sebuah perangkat.
+func (h *DeliveryConditionHandler) RevokeDevice(c *gin.Context) {
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid device ID format", err)
+		return
+	}
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: User is not a farmer", nil)
+		return
+	}
+
+	device, err := h.conditionService.RevokeDevice(deviceID, currentUser.Farmer.UserID)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Device revoked", device)
+}
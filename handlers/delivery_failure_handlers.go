@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type DeliveryFailureHandler struct {
+	failureService services.DeliveryFailureService
+}
+
+func NewDeliveryFailureHandler(service services.DeliveryFailureService) *DeliveryFailureHandler {
+	return &DeliveryFailureHandler{failureService: service}
+}
+
+var validFailureReasons = map[string]bool{
+	models.FailureReasonRecipientAbsent:  true,
+	models.FailureReasonGoodsRejected:    true,
+	models.FailureReasonAddressNotFound:  true,
+	models.FailureReasonVehicleBreakdown: true,
+	models.FailureReasonOther:            true,
+}
+
+// ReportFailure menerima laporan kegagalan dari driver (multipart/form-data):
+// reason, notes, photo (file), lat, lng.
+func (h *DeliveryFailureHandler) ReportFailure(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can report delivery failures", nil)
+		return
+	}
+
+	reason := strings.TrimSpace(c.PostForm("reason"))
+	notes := strings.TrimSpace(c.PostForm("notes"))
+	if !validFailureReasons[reason] {
+		utils.ErrorResponse(c, http.StatusBadRequest, "reason must be one of recipient_absent, goods_rejected, address_not_found, vehicle_breakdown, other", nil)
+		return
+	}
+	if notes == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "notes are required", nil)
+		return
+	}
+	photoPath, photoURL, err := saveDeliveryImage(c, "photo", currentUser.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	failure, err := h.failureService.ReportFailure(c.Param("id"), currentUser.Driver.UserID, dto.ReportDeliveryFailureInput{
+		Reason:   reason,
+		Notes:    notes,
+		PhotoURL: photoURL,
+		Lat:      parseOptionalFloat(c.PostForm("lat")),
+		Lng:      parseOptionalFloat(c.PostForm("lng")),
+	})
+	if err != nil {
+		os.Remove(photoPath)
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Delivery failure reported", failure)
+}
+
+// ResolveFailure dipakai petani untuk memilih kirim ulang, retur, atau pembatalan.
+func (h *DeliveryFailureHandler) ResolveFailure(c *gin.Context) {
+	var input dto.ResolveDeliveryFailureRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only farmers can resolve delivery failures", nil)
+		return
+	}
+
+	result, err := h.failureService.ResolveFailure(c.Param("id"), currentUser.Farmer.UserID, input)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Delivery failure resolved", result)
+}
+
+// GetFailures menampilkan riwayat laporan kegagalan sebuah pengiriman.
+func (h *DeliveryFailureHandler) GetFailures(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+
+	failures, err := h.failureService.GetFailures(c.Param("id"), currentUser)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Delivery failures retrieved successfully", failures)
+}
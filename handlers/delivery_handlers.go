@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -43,6 +44,28 @@ func (h *DeliveryHandler) CreateDelivery(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusCreated, "Delivery request created successfully", delivery)
 }
 
+// CreateConsolidatedDelivery menggabungkan beberapa pesanan/muatan dalam satu pengiriman multi-titik.
+func (h *DeliveryHandler) CreateConsolidatedDelivery(c *gin.Context) {
+	var input dto.CreateConsolidatedDeliveryRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only farmers can create delivery requests", nil)
+		return
+	}
+
+	result, err := h.deliveryService.CreateConsolidatedDelivery(input, currentUser.Farmer.UserID)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Consolidated delivery created successfully", result)
+}
+
 // FindDrivers mencari driver yang cocok untuk sebuah pengiriman.
 func (h *DeliveryHandler) FindDrivers(c *gin.Context) {
 	deliveryID := c.Param("id")
@@ -75,9 +98,15 @@ func (h *DeliveryHandler) SelectDriver(c *gin.Context) {
 		return
 	}
 
-	contract, err := h.deliveryService.SelectDriver(deliveryID, driverID, currentUser.Farmer.UserID.String())
+	var input dto.SelectDriverRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input: quote_id is required", err)
+		return
+	}
+
+	contract, err := h.deliveryService.SelectDriver(deliveryID, driverID, currentUser.Farmer.UserID.String(), input.QuoteID)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error(), nil)
+		respondDeliveryError(c, err)
 		return
 	}
 
@@ -104,3 +133,50 @@ func (h *DeliveryHandler) GetMyDeliveries(c *gin.Context) {
 	}
 	utils.SuccessResponse(c, http.StatusOK, "Deliveries retrieved successfully", deliveries)
 }
+
+// GetDeliveryDetail menampilkan detail pengiriman beserta timeline statusnya.
+func (h *DeliveryHandler) GetDeliveryDetail(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+
+	detail, err := h.deliveryService.GetDeliveryDetail(c.Param("id"), currentUser.ID)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Delivery retrieved successfully", detail)
+}
+
+// UpdateStatus dipakai driver untuk melaporkan pickup, keberangkatan, tiba atau gagal.
+func (h *DeliveryHandler) UpdateStatus(c *gin.Context) {
+	var input dto.DeliveryStatusUpdateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can update delivery status", nil)
+		return
+	}
+
+	delivery, err := h.deliveryService.UpdateStatusByDriver(c.Param("id"), currentUser.Driver.UserID, input)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Delivery status updated successfully", delivery)
+}
+
+func respondDeliveryError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "forbidden"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error(), nil)
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+	case strings.Contains(err.Error(), "invalid"):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process delivery", err)
+	}
+}
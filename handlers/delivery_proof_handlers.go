@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/config"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type DeliveryProofHandler struct {
+	proofService   services.DeliveryProofService
+	disputeService services.DeliveryDisputeService
+}
+
+func NewDeliveryProofHandler(proofService services.DeliveryProofService, disputeService services.DeliveryDisputeService) *DeliveryProofHandler {
+	return &DeliveryProofHandler{proofService: proofService, disputeService: disputeService}
+}
+
+// RequestOTP mengirim kode serah terima ke penerima barang.
+func (h *DeliveryProofHandler) RequestOTP(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can request handover codes", nil)
+		return
+	}
+
+	result, err := h.proofService.RequestOTP(c.Param("id"), currentUser.Driver.UserID)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Handover code sent", result)
+}
+
+// SubmitProof menerima bukti serah terima (multipart/form-data):
+// photo, signature (file), recipient_name, otp, lat, lng.
+func (h *DeliveryProofHandler) SubmitProof(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can submit proof of delivery", nil)
+		return
+	}
+
+	input, cleanup, ok := parseProofForm(c, currentUser.ID)
+	if !ok {
+		return
+	}
+	proof, err := h.proofService.SubmitProof(c.Param("id"), currentUser.Driver.UserID, input)
+	if err != nil {
+		cleanup()
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Proof of delivery submitted, delivery completed", proof)
+}
+
+// RequestStopOTP mengirim kode serah terima ke penerima pada satu titik antar.
+func (h *DeliveryProofHandler) RequestStopOTP(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can request handover codes", nil)
+		return
+	}
+
+	result, err := h.proofService.RequestStopOTP(c.Param("id"), c.Param("stopId"), currentUser.Driver.UserID)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Handover code sent", result)
+}
+
+// SubmitStopProof menerima bukti serah terima untuk satu titik antar (form sama dengan SubmitProof).
+func (h *DeliveryProofHandler) SubmitStopProof(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can submit proof of delivery", nil)
+		return
+	}
+
+	input, cleanup, ok := parseProofForm(c, currentUser.ID)
+	if !ok {
+		return
+	}
+	proof, err := h.proofService.SubmitStopProof(c.Param("id"), c.Param("stopId"), currentUser.Driver.UserID, input)
+	if err != nil {
+		cleanup()
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Proof of delivery submitted for stop", proof)
+}
+
+// parseProofForm membaca form bukti serah terima dan menyimpan foto & tanda tangan.
+// cleanup menghapus file tersebut jika bukti ditolak agar tidak ada file yatim di disk.
+func parseProofForm(c *gin.Context, userID uuid.UUID) (dto.SubmitDeliveryProofInput, func(), bool) {
+	recipientName := strings.TrimSpace(c.PostForm("recipient_name"))
+	otp := strings.TrimSpace(c.PostForm("otp"))
+	if recipientName == "" || otp == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "recipient_name and otp are required", nil)
+		return dto.SubmitDeliveryProofInput{}, nil, false
+	}
+
+	photoPath, photoURL, err := saveDeliveryImage(c, "photo", userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+		return dto.SubmitDeliveryProofInput{}, nil, false
+	}
+	signaturePath, signatureURL, err := saveDeliveryImage(c, "signature", userID)
+	if err != nil {
+		os.Remove(photoPath)
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+		return dto.SubmitDeliveryProofInput{}, nil, false
+	}
+
+	input := dto.SubmitDeliveryProofInput{
+		RecipientName: recipientName,
+		OTP:           otp,
+		PhotoURL:      photoURL,
+		SignatureURL:  signatureURL,
+		Lat:           parseOptionalFloat(c.PostForm("lat")),
+		Lng:           parseOptionalFloat(c.PostForm("lng")),
+	}
+	cleanup := func() {
+		os.Remove(photoPath)
+		os.Remove(signaturePath)
+	}
+	return input, cleanup, true
+}
+
+// OpenDispute dipakai petani untuk menahan pembayaran karena masalah pengiriman.
+func (h *DeliveryProofHandler) OpenDispute(c *gin.Context) {
+	var input dto.OpenDeliveryDisputeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only farmers can open delivery disputes", nil)
+		return
+	}
+
+	dispute, err := h.disputeService.OpenDispute(c.Param("id"), currentUser.Farmer.UserID, input)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Dispute opened, payment is on hold", dispute)
+}
+
+// GetDisputes menampilkan daftar sengketa pengiriman untuk admin. Query: ?status=open
+func (h *DeliveryProofHandler) GetDisputes(c *gin.Context) {
+	disputes, err := h.disputeService.GetDisputes(c.Query("status"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve disputes", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Disputes retrieved successfully", disputes)
+}
+
+// GetDisputeDetail menampilkan sengketa beserta bukti serah terima dan timeline pengiriman.
+func (h *DeliveryProofHandler) GetDisputeDetail(c *gin.Context) {
+	disputeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid dispute ID format", err)
+		return
+	}
+
+	detail, err := h.disputeService.GetDisputeDetail(disputeID)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Dispute retrieved successfully", detail)
+}
+
+// ResolveDispute mencatat keputusan admin atas sebuah sengketa.
+func (h *DeliveryProofHandler) ResolveDispute(c *gin.Context) {
+	disputeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid dispute ID format", err)
+		return
+	}
+
+	var input dto.ResolveDeliveryDisputeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	dispute, err := h.disputeService.ResolveDispute(disputeID, currentUser.ID, input)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Dispute updated successfully", dispute)
+}
+
+// saveDeliveryImage menyimpan gambar dari form field ke public/uploads/deliveries
+// dan mengembalikan path lokal serta URL publiknya.
+func saveDeliveryImage(c *gin.Context, field string, userID uuid.UUID) (string, string, error) {
+	file, err := c.FormFile(field)
+	if err != nil {
+		return "", "", fmt.Errorf("%s image not provided", field)
+	}
+	if file.Size > 2*1024*1024 { // 2MB
+		return "", "", fmt.Errorf("%s image exceeds 2MB limit", field)
+	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+		return "", "", errors.New("invalid file type. Only JPG, JPEG, PNG are allowed")
+	}
+
+	newFileName := fmt.Sprintf("%s-%s-%d%s", userID, field, time.Now().UnixNano(), ext)
+	savePath := filepath.Join("public", "uploads", "deliveries", newFileName)
+	if err := c.SaveUploadedFile(file, savePath); err != nil {
+		return "", "", fmt.Errorf("failed to save %s image", field)
+	}
+
+	appUrl := config.AppConfig_.App.APP_URL
+	return savePath, fmt.Sprintf("%s/static/uploads/deliveries/%s", appUrl, newFileName), nil
+}
+
+func parseOptionalFloat(raw string) *float64 {
+	if raw == "" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
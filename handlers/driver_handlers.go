@@ -4,9 +4,13 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
 	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
 )
 
 type DriverHandler struct {
@@ -56,3 +60,87 @@ func (h *DriverHandler) GetDriver(c *gin.Context) {
 
 	c.JSON(http.StatusOK, driver)
 }
+
+// GetPresence menampilkan status online driver yang sedang login.
+func (h *DriverHandler) GetPresence(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers have presence", nil)
+		return
+	}
+
+	presence, err := h.service.GetPresence(currentUser.Driver.UserID)
+	if err != nil {
+		respondPresenceError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Presence retrieved successfully", presence)
+}
+
+// GoOnline menandai driver siap menerima pengiriman.
+func (h *DriverHandler) GoOnline(c *gin.Context) {
+	var input dto.DriverPresenceRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can go online", nil)
+		return
+	}
+
+	presence, err := h.service.SetOnline(currentUser.Driver.UserID, input)
+	if err != nil {
+		respondPresenceError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "You are now online", presence)
+}
+
+// GoOffline mengeluarkan driver dari pencarian driver terdekat.
+func (h *DriverHandler) GoOffline(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can go offline", nil)
+		return
+	}
+
+	presence, err := h.service.SetOffline(currentUser.Driver.UserID)
+	if err != nil {
+		respondPresenceError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "You are now offline", presence)
+}
+
+// Heartbeat dikirim berkala oleh aplikasi driver untuk memperbarui posisi terkini.
+func (h *DriverHandler) Heartbeat(c *gin.Context) {
+	var input dto.DriverPresenceRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can send heartbeats", nil)
+		return
+	}
+
+	presence, err := h.service.Heartbeat(currentUser.Driver.UserID, input)
+	if err != nil {
+		respondPresenceError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Heartbeat received", presence)
+}
+
+func respondPresenceError(c *gin.Context, err error) {
+	if strings.Contains(err.Error(), "not found") {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update presence", err)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type DriverRouteHandler struct {
+	routeService services.DriverRouteService
+}
+
+func NewDriverRouteHandler(service services.DriverRouteService) *DriverRouteHandler {
+	return &DriverRouteHandler{routeService: service}
+}
+
+// GetMyRoutes menampilkan semua rute reguler milik driver yang sedang login.
+func (h *DriverRouteHandler) GetMyRoutes(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can manage routes", nil)
+		return
+	}
+
+	routes, err := h.routeService.GetMyRoutes(currentUser.Driver.UserID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve routes", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Routes retrieved successfully", routes)
+}
+
+// CreateRoute menambahkan rute reguler baru untuk driver.
+func (h *DriverRouteHandler) CreateRoute(c *gin.Context) {
+	var input dto.DriverRouteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can manage routes", nil)
+		return
+	}
+
+	route, err := h.routeService.CreateRoute(currentUser.Driver.UserID, input)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Route created successfully", route)
+}
+
+// UpdateRoute memperbarui rute reguler milik driver.
+func (h *DriverRouteHandler) UpdateRoute(c *gin.Context) {
+	routeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid route ID format", err)
+		return
+	}
+
+	var input dto.DriverRouteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can manage routes", nil)
+		return
+	}
+
+	route, err := h.routeService.UpdateRoute(routeID, currentUser.Driver.UserID, input)
+	if err != nil {
+		respondRouteError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Route updated successfully", route)
+}
+
+// DeleteRoute menghapus rute reguler milik driver.
+func (h *DriverRouteHandler) DeleteRoute(c *gin.Context) {
+	routeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid route ID format", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can manage routes", nil)
+		return
+	}
+
+	if err := h.routeService.DeleteRoute(routeID, currentUser.Driver.UserID); err != nil {
+		respondRouteError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Route deleted successfully", nil)
+}
+
+// SearchDrivers membantu petani mencari driver berdasarkan rute reguler.
+// Query: ?origin=...&destination=...&date=YYYY-MM-DD
+func (h *DriverRouteHandler) SearchDrivers(c *gin.Context) {
+	results, err := h.routeService.SearchDrivers(c.Query("origin"), c.Query("destination"), c.Query("date"))
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid") {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to search drivers", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Drivers retrieved successfully", results)
+}
+
+func respondRouteError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "forbidden"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error(), nil)
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+	case strings.Contains(err.Error(), "invalid"), strings.Contains(err.Error(), "must be"):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process route", err)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+// ECommerceRefundHandler melayani admin untuk pengembalian dana pembayaran e-commerce,
+// mis. pembayaran yang masuk setelah pesanannya dibatalkan.
+type ECommerceRefundHandler struct {
+	paymentService services.ECommercePaymentService
+}
+
+func NewECommerceRefundHandler(s services.ECommercePaymentService) *ECommerceRefundHandler {
+	return &ECommerceRefundHandler{paymentService: s}
+}
+
+// GetRefunds menampilkan pengembalian dana pembayaran untuk admin (default: pending).
+func (h *ECommerceRefundHandler) GetRefunds(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != models.PaymentRefundPending && status != models.PaymentRefundCompleted {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid status, use pending or completed", nil)
+		return
+	}
+	refunds, err := h.paymentService.GetRefunds(status)
+	if err != nil {
+		respondECommerceRefundError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Payment refunds retrieved successfully", refunds)
+}
+
+func (h *ECommerceRefundHandler) CompleteRefund(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid payment ID format", err)
+		return
+	}
+	if err := h.paymentService.CompleteRefund(paymentID); err != nil {
+		respondECommerceRefundError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Payment refund marked as completed", nil)
+}
+
+func respondECommerceRefundError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+	case strings.Contains(err.Error(), "invalid"):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process payment refund", err)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type InventoryHandler struct {
+	inventoryService services.InventoryService
+}
+
+func NewInventoryHandler(service services.InventoryService) *InventoryHandler {
+	return &InventoryHandler{inventoryService: service}
+}
+
+// GetStockMovements menampilkan riwayat pergerakan stok satu produk milik petani.
+func (h *InventoryHandler) GetStockMovements(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID format", err)
+		return
+	}
+	var query dto.StockMovementQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only farmers can view stock movements", nil)
+		return
+	}
+
+	history, err := h.inventoryService.GetProductMovements(productID, query, currentUser.Farmer.UserID)
+	if err != nil {
+		respondInventoryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Stock movements retrieved successfully", history)
+}
+
+// RecordStockMovement mencatat restock, koreksi, atau retur untuk satu varian.
+func (h *InventoryHandler) RecordStockMovement(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID format", err)
+		return
+	}
+	variantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid variant ID format", err)
+		return
+	}
+	var input dto.StockMovementRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only farmers can record stock movements", nil)
+		return
+	}
+
+	movement, err := h.inventoryService.RecordMovement(productID, variantID, input, currentUser.Farmer.UserID)
+	if err != nil {
+		respondInventoryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Stock movement recorded successfully", movement)
+}
+
+func respondInventoryError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "forbidden"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error(), nil)
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+	case strings.Contains(err.Error(), "invalid"):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process stock movement", err)
+	}
+}
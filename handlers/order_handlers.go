@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type OrderHandler struct {
+	orderService services.OrderService
+}
+
+func NewOrderHandler(service services.OrderService) *OrderHandler {
+	return &OrderHandler{orderService: service}
+}
+
+// GetMyOrders menampilkan riwayat pesanan milik pembeli yang sedang login.
+func (h *OrderHandler) GetMyOrders(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+
+	orders, err := h.orderService.GetMyOrders(currentUser.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve orders", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Orders retrieved successfully", orders)
+}
+
+// GetIncomingOrders menampilkan pesanan masuk untuk petani, opsional difilter ?status=.
+func (h *OrderHandler) GetIncomingOrders(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: User is not a farmer", nil)
+		return
+	}
+
+	orders, err := h.orderService.GetIncomingOrders(currentUser.Farmer.UserID, c.Query("status"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve orders", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Orders retrieved successfully", orders)
+}
+
+// GetOrderDetail menampilkan pesanan beserta timeline pengirimannya.
+func (h *OrderHandler) GetOrderDetail(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid order ID", err)
+		return
+	}
+	currentUser := c.MustGet("user").(*models.User)
+
+	detail, err := h.orderService.GetOrderDetail(orderID, currentUser)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Order retrieved successfully", detail)
+}
+
+// ShipOrder dipakai petani untuk mengirim pesanan lewat kurir, antar sendiri, atau ambil di tempat.
+func (h *OrderHandler) ShipOrder(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid order ID", err)
+		return
+	}
+	var input dto.ShipOrderRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only farmers can ship orders", nil)
+		return
+	}
+
+	order, err := h.orderService.ShipOrder(orderID, currentUser.Farmer.UserID, input)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Order fulfilment started", order)
+}
+
+// ConfirmReceived dipakai pembeli untuk menyelesaikan pesanan yang diantar sendiri atau diambil.
+func (h *OrderHandler) ConfirmReceived(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid order ID", err)
+		return
+	}
+	currentUser := c.MustGet("user").(*models.User)
+
+	order, err := h.orderService.ConfirmReceived(orderID, currentUser.ID)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Order marked as received", order)
+}
+
+func respondOrderError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "forbidden"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error(), nil)
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+	case strings.Contains(err.Error(), "invalid"):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process order", err)
+	}
+}
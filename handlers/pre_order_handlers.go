@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type PreOrderHandler struct {
+	preOrderService services.PreOrderService
+}
+
+func NewPreOrderHandler(service services.PreOrderService) *PreOrderHandler {
+	return &PreOrderHandler{preOrderService: service}
+}
+
+// GetOpenCampaigns menampilkan kampanye pre-order produk yang masih bisa dipesan.
+func (h *PreOrderHandler) GetOpenCampaigns(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID format", err)
+		return
+	}
+	campaigns, err := h.preOrderService.GetOpenCampaigns(productID)
+	if err != nil {
+		respondPreOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Pre-order campaigns retrieved successfully", campaigns)
+}
+
+// GetCampaigns menampilkan semua kampanye pre-order produk milik petani.
+func (h *PreOrderHandler) GetCampaigns(c *gin.Context) {
+	productID, farmerID, ok := productAndFarmer(c)
+	if !ok {
+		return
+	}
+	campaigns, err := h.preOrderService.GetCampaigns(productID, farmerID)
+	if err != nil {
+		respondPreOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Pre-order campaigns retrieved successfully", campaigns)
+}
+
+func (h *PreOrderHandler) CreateCampaign(c *gin.Context) {
+	productID, farmerID, ok := productAndFarmer(c)
+	if !ok {
+		return
+	}
+	var input dto.PreOrderCampaignRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	campaign, err := h.preOrderService.CreateCampaign(productID, input, farmerID)
+	if err != nil {
+		respondPreOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Pre-order campaign created successfully", campaign)
+}
+
+func (h *PreOrderHandler) GetCampaignPreOrders(c *gin.Context) {
+	productID, farmerID, ok := productAndFarmer(c)
+	if !ok {
+		return
+	}
+	campaignID, err := uuid.Parse(c.Param("campaignId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid campaign ID format", err)
+		return
+	}
+
+	preOrders, err := h.preOrderService.GetCampaignPreOrders(productID, campaignID, farmerID)
+	if err != nil {
+		respondPreOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Pre-orders retrieved successfully", preOrders)
+}
+
+func (h *PreOrderHandler) CancelCampaign(c *gin.Context) {
+	productID, farmerID, ok := productAndFarmer(c)
+	if !ok {
+		return
+	}
+	campaignID, err := uuid.Parse(c.Param("campaignId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid campaign ID format", err)
+		return
+	}
+
+	if err := h.preOrderService.CancelCampaign(productID, campaignID, farmerID); err != nil {
+		respondPreOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Pre-order campaign cancelled successfully", nil)
+}
+
+// HarvestCampaign mencatat hasil panen dan mengalokasikannya ke pre-order pembeli.
+func (h *PreOrderHandler) HarvestCampaign(c *gin.Context) {
+	productID, farmerID, ok := productAndFarmer(c)
+	if !ok {
+		return
+	}
+	campaignID, err := uuid.Parse(c.Param("campaignId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid campaign ID format", err)
+		return
+	}
+	var input dto.HarvestRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	result, err := h.preOrderService.HarvestCampaign(productID, campaignID, input, farmerID)
+	if err != nil {
+		respondPreOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Harvest allocated to pre-orders successfully", result)
+}
+
+// PlacePreOrder membuat pre-order; jika ada uang muka, respons berisi token pembayaran.
+func (h *PreOrderHandler) PlacePreOrder(c *gin.Context) {
+	var input dto.CreatePreOrderRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+	currentUser := c.MustGet("user").(*models.User)
+
+	preOrder, err := h.preOrderService.PlacePreOrder(currentUser.ID, input)
+	if err != nil {
+		respondPreOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Pre-order placed successfully", preOrder)
+}
+
+func (h *PreOrderHandler) GetMyPreOrders(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+	preOrders, err := h.preOrderService.GetMyPreOrders(currentUser.ID)
+	if err != nil {
+		respondPreOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Pre-orders retrieved successfully", preOrders)
+}
+
+// PayBalance membuat pembayaran pelunasan pre-order setelah panen.
+func (h *PreOrderHandler) PayBalance(c *gin.Context) {
+	preOrderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid pre-order ID format", err)
+		return
+	}
+	currentUser := c.MustGet("user").(*models.User)
+
+	preOrder, err := h.preOrderService.PayBalance(preOrderID, currentUser.ID)
+	if err != nil {
+		respondPreOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Balance payment initiated successfully", preOrder)
+}
+
+// GetRefunds menampilkan pengembalian dana pre-order untuk admin (default: pending).
+func (h *PreOrderHandler) GetRefunds(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != models.PreOrderRefundPending && status != models.PreOrderRefundCompleted {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid status, use pending or completed", nil)
+		return
+	}
+	refunds, err := h.preOrderService.GetRefunds(status)
+	if err != nil {
+		respondPreOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Pre-order refunds retrieved successfully", refunds)
+}
+
+func (h *PreOrderHandler) CompleteRefund(c *gin.Context) {
+	preOrderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid pre-order ID format", err)
+		return
+	}
+	if err := h.preOrderService.CompleteRefund(preOrderID); err != nil {
+		respondPreOrderError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Pre-order refund marked as completed", nil)
+}
+
+func respondPreOrderError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "forbidden"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error(), nil)
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+	case strings.Contains(err.Error(), "invalid"), strings.Contains(err.Error(), "address is required"):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process pre-order request", err)
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type PriceTierHandler struct {
+	priceTierService services.PriceTierService
+}
+
+func NewPriceTierHandler(service services.PriceTierService) *PriceTierHandler {
+	return &PriceTierHandler{priceTierService: service}
+}
+
+// GetTiers menampilkan semua tier harga varian, termasuk tier khusus grup pembeli.
+func (h *PriceTierHandler) GetTiers(c *gin.Context) {
+	productID, farmerID, ok := productAndFarmer(c)
+	if !ok {
+		return
+	}
+	variantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid variant ID format", err)
+		return
+	}
+
+	tiers, err := h.priceTierService.GetVariantTiers(productID, variantID, farmerID)
+	if err != nil {
+		respondPriceTierError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Price tiers retrieved successfully", tiers)
+}
+
+// ReplaceTiers mengganti seluruh tier harga varian; kirim daftar kosong untuk menghapus semua tier.
+func (h *PriceTierHandler) ReplaceTiers(c *gin.Context) {
+	productID, farmerID, ok := productAndFarmer(c)
+	if !ok {
+		return
+	}
+	variantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid variant ID format", err)
+		return
+	}
+	var input dto.PriceTierSetRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	tiers, err := h.priceTierService.ReplaceVariantTiers(productID, variantID, input, farmerID)
+	if err != nil {
+		respondPriceTierError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Price tiers saved successfully", tiers)
+}
+
+func respondPriceTierError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "forbidden"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error(), nil)
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+	case strings.Contains(err.Error(), "invalid"):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process price tier request", err)
+	}
+}
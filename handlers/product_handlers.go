@@ -41,6 +41,14 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 
 	product, err := h.productService.CreateProduct(input, currentUser.Farmer.UserID)
 	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create product", err)
 		return
 	}
@@ -48,14 +56,33 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusCreated, "Product created successfully", product)
 }
 
-// GetAllProducts menangani pengambilan semua produk (publik).
+// GetAllProducts menangani pencarian katalog produk (publik) dengan filter,
+// urutan, pagination, dan facet kategori.
 func (h *ProductHandler) GetAllProducts(c *gin.Context) {
-	products, err := h.productService.GetAllProducts()
+	var filter dto.ProductSearchRequest
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return
+	}
+	if (filter.Sort == "distance" || filter.RadiusKm != nil) && !filter.HasLocation() {
+		utils.ErrorResponse(c, http.StatusBadRequest, "lat and lng are required to filter or sort by distance", nil)
+		return
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		utils.ErrorResponse(c, http.StatusBadRequest, "min_price must not be greater than max_price", nil)
+		return
+	}
+
+	result, err := h.productService.SearchProducts(filter)
 	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve products", err)
 		return
 	}
-	utils.SuccessResponse(c, http.StatusOK, "Products retrieved successfully", products)
+	utils.SuccessResponse(c, http.StatusOK, "Products retrieved successfully", result)
 }
 
 func (h *ProductHandler) GetMyProducts(c *gin.Context) {
@@ -206,4 +233,99 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Product deleted successfully", nil)
-}
\ No newline at end of file
+}
+// AddVariant menambah varian (satuan jual) pada produk milik petani.
+func (h *ProductHandler) AddVariant(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID format", err)
+		return
+	}
+	var input dto.ProductVariantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only farmers can manage product variants", nil)
+		return
+	}
+
+	variant, err := h.productService.AddVariant(productID, input, currentUser.Farmer.UserID)
+	if err != nil {
+		respondProductVariantError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Variant created successfully", variant)
+}
+
+func (h *ProductHandler) UpdateVariant(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID format", err)
+		return
+	}
+	variantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid variant ID format", err)
+		return
+	}
+	var input dto.ProductVariantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only farmers can manage product variants", nil)
+		return
+	}
+
+	variant, err := h.productService.UpdateVariant(productID, variantID, input, currentUser.Farmer.UserID)
+	if err != nil {
+		respondProductVariantError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Variant updated successfully", variant)
+}
+
+func (h *ProductHandler) DeleteVariant(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID format", err)
+		return
+	}
+	variantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid variant ID format", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only farmers can manage product variants", nil)
+		return
+	}
+
+	if err := h.productService.DeleteVariant(productID, variantID, currentUser.Farmer.UserID); err != nil {
+		respondProductVariantError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Variant deleted successfully", nil)
+}
+
+func respondProductVariantError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "forbidden"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error(), nil)
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+	case strings.Contains(err.Error(), "invalid"):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process product variant", err)
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type RestockHandler struct {
+	restockService services.RestockService
+}
+
+func NewRestockHandler(service services.RestockService) *RestockHandler {
+	return &RestockHandler{restockService: service}
+}
+
+// GetSchedules menampilkan semua jadwal panen sebuah produk milik petani.
+func (h *RestockHandler) GetSchedules(c *gin.Context) {
+	productID, farmerID, ok := productAndFarmer(c)
+	if !ok {
+		return
+	}
+	schedules, err := h.restockService.GetSchedules(productID, farmerID)
+	if err != nil {
+		respondRestockError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Restock schedules retrieved successfully", schedules)
+}
+
+func (h *RestockHandler) CreateSchedule(c *gin.Context) {
+	productID, farmerID, ok := productAndFarmer(c)
+	if !ok {
+		return
+	}
+	var input dto.RestockScheduleRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	schedule, err := h.restockService.CreateSchedule(productID, input, farmerID)
+	if err != nil {
+		respondRestockError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Restock schedule created successfully", schedule)
+}
+
+func (h *RestockHandler) UpdateSchedule(c *gin.Context) {
+	productID, farmerID, ok := productAndFarmer(c)
+	if !ok {
+		return
+	}
+	scheduleID, err := uuid.Parse(c.Param("scheduleId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid schedule ID format", err)
+		return
+	}
+	var input dto.RestockScheduleRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	schedule, err := h.restockService.UpdateSchedule(productID, scheduleID, input, farmerID)
+	if err != nil {
+		respondRestockError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Restock schedule updated successfully", schedule)
+}
+
+func (h *RestockHandler) CancelSchedule(c *gin.Context) {
+	productID, farmerID, ok := productAndFarmer(c)
+	if !ok {
+		return
+	}
+	scheduleID, err := uuid.Parse(c.Param("scheduleId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid schedule ID format", err)
+		return
+	}
+
+	if err := h.restockService.CancelSchedule(productID, scheduleID, farmerID); err != nil {
+		respondRestockError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Restock schedule cancelled successfully", nil)
+}
+
+// MarkHarvested mencatat hasil panen aktual dan menambahkannya ke stok.
+func (h *RestockHandler) MarkHarvested(c *gin.Context) {
+	productID, farmerID, ok := productAndFarmer(c)
+	if !ok {
+		return
+	}
+	scheduleID, err := uuid.Parse(c.Param("scheduleId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid schedule ID format", err)
+		return
+	}
+	var input dto.HarvestRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	schedule, err := h.restockService.MarkHarvested(productID, scheduleID, input, farmerID)
+	if err != nil {
+		respondRestockError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Harvest recorded successfully", schedule)
+}
+
+// Subscribe mendaftarkan pembeli untuk notifikasi saat produk tersedia kembali.
+func (h *RestockHandler) Subscribe(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID format", err)
+		return
+	}
+	currentUser := c.MustGet("user").(*models.User)
+
+	subscription, err := h.restockService.Subscribe(productID, currentUser.ID)
+	if err != nil {
+		respondRestockError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Subscribed to back-in-stock alerts", subscription)
+}
+
+func (h *RestockHandler) Unsubscribe(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID format", err)
+		return
+	}
+	currentUser := c.MustGet("user").(*models.User)
+
+	if err := h.restockService.Unsubscribe(productID, currentUser.ID); err != nil {
+		respondRestockError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Unsubscribed from back-in-stock alerts", nil)
+}
+
+// productAndFarmer membaca ID produk dari path dan memastikan pemanggil adalah petani.
+func productAndFarmer(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID format", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only farmers can manage restock schedules", nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return productID, currentUser.Farmer.UserID, true
+}
+
+func respondRestockError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "forbidden"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error(), nil)
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+	case strings.Contains(err.Error(), "invalid"):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process restock request", err)
+	}
+}
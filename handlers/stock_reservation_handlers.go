@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type StockReservationHandler struct {
+	reservationService services.StockReservationService
+}
+
+func NewStockReservationHandler(service services.StockReservationService) *StockReservationHandler {
+	return &StockReservationHandler{reservationService: service}
+}
+
+// GetStuckReservations menampilkan laporan reservasi stok yang macet (admin).
+func (h *StockReservationHandler) GetStuckReservations(c *gin.Context) {
+	report, err := h.reservationService.GetStuckReservations()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve stuck reservations", err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Stuck reservations retrieved successfully", report)
+}
+
+// ReleaseReservation dipakai admin untuk mengembalikan stok dari satu reservasi macet.
+func (h *StockReservationHandler) ReleaseReservation(c *gin.Context) {
+	reservationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid reservation ID format", err)
+		return
+	}
+
+	if err := h.reservationService.ReleaseReservation(reservationID); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+		case strings.Contains(err.Error(), "invalid"):
+			utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to release reservation", err)
+		}
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Reservation released successfully", nil)
+}
@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 	"github.com/whsasmita/AgroLink_API/dto"
 	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/pkg/tracking"
 	"github.com/whsasmita/AgroLink_API/services"
 	"github.com/whsasmita/AgroLink_API/utils"
 )
@@ -48,6 +55,72 @@ func (h *TrackingHandler) UpdateLocation(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "Location updated successfully", nil)
 }
 
+// UploadBatch menerima sekumpulan titik lokasi yang direkam driver saat offline.
+func (h *TrackingHandler) UploadBatch(c *gin.Context) {
+	deliveryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid delivery ID", err)
+		return
+	}
+
+	var input dto.BatchLocationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Driver == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only drivers can update location", nil)
+		return
+	}
+
+	result, err := h.trackingService.UploadBatch(deliveryID, currentUser.Driver.UserID, input.Points)
+	if err != nil {
+		respondDeliveryError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Locations uploaded successfully", result)
+}
+
+// GetRouteHistory mengembalikan jejak perjalanan pengiriman.
+// Query: ?format=json (default, statistik + GeoJSON), geojson, atau gpx.
+func (h *TrackingHandler) GetRouteHistory(c *gin.Context) {
+	currentUser := c.MustGet("user").(*models.User)
+
+	history, err := h.trackingService.GetRouteHistory(c.Param("id"), currentUser)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "forbidden"):
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error(), nil)
+		case strings.Contains(err.Error(), "not found"):
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve route history", err)
+		}
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "gpx":
+		body, err := services.BuildRouteGPX(history)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate GPX", err)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=rute_%s.gpx", history.DeliveryID))
+		c.Data(http.StatusOK, "application/gpx+xml", body)
+	case "geojson":
+		c.Header("Content-Type", "application/geo+json")
+		c.JSON(http.StatusOK, services.BuildRouteGeoJSON(history))
+	default:
+		utils.SuccessResponse(c, http.StatusOK, "Route history retrieved successfully", gin.H{
+			"stats":   history.Stats,
+			"geojson": services.BuildRouteGeoJSON(history),
+		})
+	}
+}
+
 // GetLatestLocation adalah handler untuk petani mendapatkan lokasi terakhir.
 func (h *TrackingHandler) GetLatestLocation(c *gin.Context) {
 	deliveryID := c.Param("id")
@@ -70,4 +143,116 @@ func (h *TrackingHandler) GetLatestLocation(c *gin.Context) {
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Latest location retrieved successfully", location)
-}
\ No newline at end of file
+}
+
+// trackingHeartbeat menjaga koneksi stream tetap hidup melewati proxy.
+const trackingHeartbeat = 25 * time.Second
+
+// subscribe adalah langkah bersama StreamSSE dan StreamWS: otorisasi + snapshot awal.
+func (h *TrackingHandler) subscribe(c *gin.Context) (*tracking.Event, <-chan tracking.Event, func(), bool) {
+	currentUser := c.MustGet("user").(*models.User)
+	snapshot, events, unsubscribe, err := h.trackingService.Subscribe(c.Param("id"), currentUser)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "forbidden"):
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error(), nil)
+		case strings.Contains(err.Error(), "not found"):
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to subscribe to tracking", err)
+		}
+		return nil, nil, nil, false
+	}
+
+	deliveryID, _ := uuid.Parse(c.Param("id"))
+	initial := &tracking.Event{Type: tracking.EventSnapshot, DeliveryID: deliveryID, Data: snapshot, At: time.Now()}
+	return initial, events, unsubscribe, true
+}
+
+// StreamSSE mengalirkan lokasi, status dan ETA pengiriman via Server-Sent Events.
+// EventSource tidak bisa mengirim header, sehingga token boleh dikirim lewat ?token=.
+func (h *TrackingHandler) StreamSSE(c *gin.Context) {
+	initial, events, unsubscribe, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent(initial.Type, initial)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(trackingHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, open := <-events:
+			if !open {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now())
+			return true
+		}
+	})
+}
+
+// StreamWS mengalirkan lokasi, status dan ETA pengiriman via WebSocket.
+func (h *TrackingHandler) StreamWS(c *gin.Context) {
+	initial, events, unsubscribe, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer unsubscribe()
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Tracking WS upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// Reader loop hanya untuk mendeteksi klien menutup koneksi
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := conn.WriteJSON(initial); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(trackingHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case event, open := <-events:
+			if !open {
+				return
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
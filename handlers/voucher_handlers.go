@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/services"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+// VoucherHandler dipakai admin untuk promo platform dan petani untuk voucher tokonya.
+type VoucherHandler struct {
+	voucherService services.VoucherService
+}
+
+func NewVoucherHandler(service services.VoucherService) *VoucherHandler {
+	return &VoucherHandler{voucherService: service}
+}
+
+func (h *VoucherHandler) GetVouchers(c *gin.Context) {
+	farmerID, ok := voucherScope(c)
+	if !ok {
+		return
+	}
+	vouchers, err := h.voucherService.GetVouchers(farmerID)
+	if err != nil {
+		respondVoucherError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Vouchers retrieved successfully", vouchers)
+}
+
+func (h *VoucherHandler) CreateVoucher(c *gin.Context) {
+	farmerID, ok := voucherScope(c)
+	if !ok {
+		return
+	}
+	var input dto.VoucherRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+	currentUser := c.MustGet("user").(*models.User)
+
+	voucher, err := h.voucherService.CreateVoucher(input, currentUser.ID, farmerID)
+	if err != nil {
+		respondVoucherError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Voucher created successfully", voucher)
+}
+
+func (h *VoucherHandler) UpdateVoucher(c *gin.Context) {
+	farmerID, ok := voucherScope(c)
+	if !ok {
+		return
+	}
+	voucherID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid voucher ID format", err)
+		return
+	}
+	var input dto.VoucherRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data", err)
+		return
+	}
+
+	voucher, err := h.voucherService.UpdateVoucher(voucherID, input, farmerID)
+	if err != nil {
+		respondVoucherError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Voucher updated successfully", voucher)
+}
+
+// voucherScope mengembalikan ID petani untuk voucher toko, atau nil untuk admin.
+func voucherScope(c *gin.Context) (*uuid.UUID, bool) {
+	currentUser := c.MustGet("user").(*models.User)
+	if currentUser.Role == "admin" {
+		return nil, true
+	}
+	if currentUser.Farmer == nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: Only farmers can manage store vouchers", nil)
+		return nil, false
+	}
+	return &currentUser.Farmer.UserID, true
+}
+
+func respondVoucherError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "forbidden"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error(), nil)
+	case strings.Contains(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error(), nil)
+	case strings.Contains(err.Error(), "invalid"):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process voucher request", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -15,11 +16,20 @@ import (
 	"github.com/whsasmita/AgroLink_API/handlers"
 	"github.com/whsasmita/AgroLink_API/middleware"
 	"github.com/whsasmita/AgroLink_API/pkg/chat"
+	"github.com/whsasmita/AgroLink_API/pkg/tracking"
 	"github.com/whsasmita/AgroLink_API/repositories"
 	"github.com/whsasmita/AgroLink_API/routes"
 	"github.com/whsasmita/AgroLink_API/services"
 )
 
+// Interval job latar belakang untuk reservasi stok kedaluwarsa, notifikasi stok,
+// dan pre-order yang tidak dibayar.
+const (
+	stockReservationReleaseInterval = 5 * time.Minute
+	stockAlertInterval              = 10 * time.Minute
+	preOrderExpiryInterval          = 15 * time.Minute
+)
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(".env"); err != nil {
@@ -91,11 +101,39 @@ func main() {
 	webhookRepo := repositories.NewWebhookLogRepository(db)
 	deliveryRepo := repositories.NewDeliveryRepository(db)
 	orderRepo := repositories.NewOrderRepository(db)
-	productRepo := repositories.NewProductRepository(db)
+	productVariantRepo := repositories.NewProductVariantRepository(db)
 	ecommPaymentRepo := repositories.NewECommercePaymentRepository(db)
+	deliveryDisputeRepo := repositories.NewDeliveryDisputeRepository(db)
+	productRepo := repositories.NewProductRepository(db)
+	stockReservationRepo := repositories.NewStockReservationRepository(db)
+	inventoryService := services.NewInventoryService(
+		repositories.NewInventoryMovementRepository(db), productRepo, productVariantRepo, orderRepo, db,
+	)
+	voucherService := services.NewVoucherService(repositories.NewVoucherRepository(db), repositories.NewProfitRepository(db), db)
+	stockReservationService := services.NewStockReservationService(
+		stockReservationRepo, productVariantRepo, orderRepo, repositories.NewCartRepository(db), inventoryService, voucherService, db,
+	)
+	notificationService := services.NewNotificationService(repositories.NewNotificationRepository(db), services.NewEmailService(), userRepo)
 	eCommercePaymentService := services.NewECommercePaymentService(
-		ecommPaymentRepo, orderRepo, userRepo, productRepo, db,
+		ecommPaymentRepo, orderRepo, userRepo, stockReservationService, voucherService, notificationService, db,
+	)
+	// Lepas reservasi stok dari pesanan yang tidak dibayar sampai batas waktu
+	go stockReservationService.RunReleaseJob(stockReservationReleaseInterval)
+
+	// Notifikasi stok menipis ke petani & produk tersedia kembali ke pembeli
+	restockService := services.NewRestockService(
+		repositories.NewRestockScheduleRepository(db), repositories.NewBackInStockSubscriptionRepository(db),
+		productRepo, productVariantRepo, inventoryService, notificationService, db,
+	)
+	go restockService.RunAlertJob(stockAlertInterval)
+
+	// Webhook pembayaran pre-order diteruskan ke PreOrderService (didaftarkan oleh constructor-nya)
+	preOrderService := services.NewPreOrderService(
+		repositories.NewPreOrderCampaignRepository(db), repositories.NewPreOrderRepository(db),
+		productRepo, productVariantRepo, orderRepo, repositories.NewAddressRepository(db),
+		ecommPaymentRepo, eCommercePaymentService, inventoryService, notificationService, db,
 	)
+	go preOrderService.RunExpiryJob(preOrderExpiryInterval)
 	paymentService := services.NewPaymentService(
 		invoiceRepo,
 		transactionRepo,
@@ -104,6 +142,8 @@ func main() {
 		projectRepo,
 		userRepo,
 		deliveryRepo,
+		deliveryDisputeRepo,
+		tracking.Default(),
 		db,
 	)
 	webhookHandler := handlers.NewWebhookHandler(
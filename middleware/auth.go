@@ -10,6 +10,10 @@ import (
 	"github.com/whsasmita/AgroLink_API/utils"
 )
 
+// sseQueryTokenRoute adalah satu-satunya rute yang boleh menerima token lewat query ?token=
+// untuk EventSource, agar token di URL tidak berlaku untuk endpoint lain.
+const sseQueryTokenRoute = "/deliveries/:id/track/stream"
+
 func AuthMiddleware(userRepo repositories.UserRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var tokenString string
@@ -37,6 +41,13 @@ func AuthMiddleware(userRepo repositories.UserRepository) gin.HandlerFunc {
 			}
 		}
 
+		// 3) EventSource (SSE) tidak bisa mengirim header, izinkan token via query
+		//    hanya untuk rute stream pelacakan pengiriman
+		if tokenString == "" && strings.HasSuffix(c.FullPath(), sseQueryTokenRoute) &&
+			strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+			tokenString = c.Query("token")
+		}
+
 		if tokenString == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
 			return
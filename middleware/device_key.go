@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+// DeviceKeyMiddleware mengautentikasi perangkat IoT cold-chain lewat header X-Device-Key.
+// Perangkat yang valid disimpan di context sebagai "device".
+func DeviceKeyMiddleware(conditionRepo repositories.DeliveryConditionRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := strings.TrimSpace(c.GetHeader("X-Device-Key"))
+		if apiKey == "" {
+			utils.Unauthorized(c, "Missing device key")
+			c.Abort()
+			return
+		}
+
+		device, err := conditionRepo.FindDeviceByKeyHash(utils.HashAPIKey(apiKey))
+		if err != nil || !device.IsActive() {
+			utils.Unauthorized(c, "Invalid device key")
+			c.Abort()
+			return
+		}
+
+		c.Set("device", device)
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Address adalah alamat pengiriman di buku alamat pembeli untuk pesanan e-commerce.
+type Address struct {
+	ID            uuid.UUID `gorm:"type:char(36);primary_key" json:"id"`
+	UserID        uuid.UUID `gorm:"type:char(36);not null;index" json:"user_id"`
+	Label         string    `gorm:"type:varchar(50);not null" json:"label"` // mis. "Rumah", "Kantor"
+	RecipientName string    `gorm:"type:varchar(100);not null" json:"recipient_name"`
+	PhoneNumber   string    `gorm:"type:varchar(20);not null" json:"phone_number"`
+	Street        string    `gorm:"type:text;not null" json:"street"`
+	Province      string    `gorm:"type:varchar(100);not null" json:"province"`
+	City          string    `gorm:"type:varchar(100);not null" json:"city"`
+	District      string    `gorm:"type:varchar(100);not null" json:"district"`
+	PostalCode    string    `gorm:"type:varchar(10);not null" json:"postal_code"`
+	Latitude      *float64  `gorm:"type:decimal(10,8)" json:"latitude"`
+	Longitude     *float64  `gorm:"type:decimal(11,8)" json:"longitude"`
+	IsDefault     bool      `gorm:"default:false" json:"is_default"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (a *Address) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// FullAddress menyusun alamat lengkap satu baris, mis. untuk label pengiriman.
+func (a *Address) FullAddress() string {
+	parts := []string{a.Street, a.District, a.City, a.Province, a.PostalCode}
+	filled := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			filled = append(filled, part)
+		}
+	}
+	return strings.Join(filled, ", ")
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BackInStockSubscription adalah permintaan pembeli untuk diberi tahu saat produk
+// yang sedang habis kembali tersedia. NotifiedAt kosong berarti langganan masih aktif.
+type BackInStockSubscription struct {
+	ID         uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex:idx_restock_sub_user_product" json:"user_id"`
+	ProductID  uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex:idx_restock_sub_user_product;index" json:"product_id"`
+	NotifiedAt *time.Time `gorm:"index" json:"notified_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	Product *Product `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	User    *User    `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (s *BackInStockSubscription) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
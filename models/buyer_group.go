@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BuyerGroup adalah kelompok pembeli dengan daftar harga sendiri, mis. "Grosir Terverifikasi"
+// untuk restoran dan pedagang pasar. Keanggotaan diatur oleh admin.
+type BuyerGroup struct {
+	ID          uuid.UUID `gorm:"type:char(36);primary_key" json:"id"`
+	Name        string    `gorm:"type:varchar(100);not null;uniqueIndex" json:"name"`
+	Description *string   `gorm:"type:varchar(255)" json:"description"`
+	IsActive    bool      `gorm:"not null;default:true" json:"is_active"` // Grup nonaktif tidak mendapat harga khusus
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (g *BuyerGroup) BeforeCreate(tx *gorm.DB) error {
+	if g.ID == uuid.Nil {
+		g.ID = uuid.New()
+	}
+	return nil
+}
+
+// BuyerGroupMember menghubungkan pembeli ke grupnya. Satu pembeli hanya masuk satu grup.
+type BuyerGroupMember struct {
+	ID           uuid.UUID `gorm:"type:char(36);primary_key" json:"id"`
+	BuyerGroupID uuid.UUID `gorm:"type:char(36);not null;index" json:"buyer_group_id"`
+	UserID       uuid.UUID `gorm:"type:char(36);not null;uniqueIndex" json:"user_id"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	BuyerGroup *BuyerGroup `gorm:"foreignKey:BuyerGroupID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	User       *User       `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (m *BuyerGroupMember) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
@@ -6,13 +6,26 @@ import (
 	"github.com/google/uuid"
 )
 
+// CartHoldTTL adalah lama stok ditahan untuk item keranjang sejak terakhir ditambah atau
+// diubah. Setelah lewat, job latar belakang melepas stoknya dan checkout menahannya ulang.
+const CartHoldTTL = 60 * time.Minute
+
 type Cart struct {
 	ID        uuid.UUID `gorm:"type:char(36);primary_key;default:(UUID())"`
-	UserID    uuid.UUID `gorm:"type:char(36);not null;index:idx_cart_user_product,unique"`
-	ProductID uuid.UUID `gorm:"type:char(36);not null;index:idx_cart_user_product,unique"`
-	Quantity  int       `gorm:"not null;default:1"`
+	UserID    uuid.UUID `gorm:"type:char(36);not null;index:idx_cart_user_variant,unique"`
+	ProductID uuid.UUID `gorm:"type:char(36);not null;index"`
+	VariantID uuid.UUID `gorm:"type:char(36);index:idx_cart_user_variant,unique"`
+	Quantity  float64   `gorm:"type:decimal(12,3);not null;default:1"`
+	HeldQuantity  float64    `gorm:"type:decimal(12,3);not null;default:0"` // Stok varian yang sedang ditahan untuk item ini
+	HoldExpiresAt *time.Time `gorm:"index"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	User    User    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	Product Product `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Variant ProductVariant `gorm:"foreignKey:VariantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// HoldShortfall adalah jumlah yang belum ditahan, mis. setelah tahanan kedaluwarsa dilepas.
+func (c *Cart) HoldShortfall() float64 {
+	return RoundQuantity(c.Quantity - c.HeldQuantity)
 }
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Category adalah node pada pohon kategori produk (mis. Sayuran > Daun) yang dikelola admin.
+type Category struct {
+	ID        uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	ParentID  *uuid.UUID `gorm:"type:char(36);index" json:"parent_id"` // nil untuk kategori utama
+	Name      string     `gorm:"type:varchar(100);not null" json:"name"`
+	Slug      string     `gorm:"type:varchar(120);not null;uniqueIndex" json:"slug"`
+	IconURL   *string    `gorm:"type:varchar(255)" json:"icon_url"`
+	SortOrder int        `gorm:"not null;default:0" json:"sort_order"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+
+	Parent *Category `gorm:"foreignKey:ParentID" json:"-"`
+}
+
+func (c *Category) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -18,15 +19,115 @@ type Delivery struct {
 	PickupLat          float64
 	PickupLng          float64
 	DestinationAddress string
+	DestinationLat     *float64 `gorm:"type:decimal(10,8)"`
+	DestinationLng     *float64 `gorm:"type:decimal(11,8)"`
+
+	// Penerima barang di tujuan akhir; email dipakai untuk mengirim OTP serah terima
+	RecipientName  *string `gorm:"type:varchar(100)"`
+	RecipientPhone *string `gorm:"type:varchar(20)"`
+	RecipientEmail *string `gorm:"type:varchar(100)"`
 
 	ItemDescription string
-	ItemWeight      float64 // dalam kg
+	ItemWeight      float64    // dalam kg
+	PickupDate      *time.Time `gorm:"type:date"`
+
+	// Pengiriman gabungan: beberapa pesanan/muatan, diselesaikan per titik antar
+	IsConsolidated bool `gorm:"default:false"`
+
+	// Ambang batas cold-chain; pembacaan di luar rentang memicu peringatan ke petani
+	MinTemperatureC *float64 `gorm:"type:decimal(5,2)"`
+	MaxTemperatureC *float64 `gorm:"type:decimal(5,2)"`
+	MaxHumidityPct  *float64 `gorm:"type:decimal(5,2)"`
 
-	Status string `gorm:"type:enum('pending_driver', 'pending_signature', 'pending_payment', 'in_transit', 'delivered', 'cancelled');default:'pending_driver'"`
+	// Diisi oleh RoutingProvider saat delivery dibuat
+	EstimatedDistanceKm      *float64 `gorm:"type:decimal(10,2)"`
+	EstimatedDurationMinutes *int
+	RouteSource              *string `gorm:"type:varchar(20)"`
+
+	// Harga yang dikunci saat petani memilih driver (lihat PricingService)
+	QuotedPrice  *float64       `gorm:"type:decimal(12,2)"`
+	QuoteDetails datatypes.JSON `gorm:"type:json"`
+	QuotedAt     *time.Time
+
+	Status string `gorm:"type:enum('pending_driver', 'pending_signature', 'pending_payment', 'pickup_pending', 'at_pickup', 'picked_up', 'in_transit', 'out_for_delivery', 'arrived', 'delivered', 'failed', 'returning', 'returned', 'cancelled');default:'pending_driver'"`
 
 	// Relasi
 	Contract  *Contract
-	CreatedAt time.Time `json:"created_at"`
+	Stops     []DeliveryStop `gorm:"foreignKey:DeliveryID;constraint:OnDelete:CASCADE"`
+	Proof     *DeliveryProof `gorm:"foreignKey:DeliveryID"`
+	Orders    []Order        `gorm:"foreignKey:DeliveryID" json:"-"` // pesanan e-commerce yang diantar
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// deliveryTransitions adalah daftar perpindahan status yang diizinkan.
+// Status yang tidak memiliki entri (delivered, returned, cancelled) bersifat final;
+// status failed menunggu keputusan petani (kirim ulang, kembalikan, atau batalkan).
+// Pickup_pending, at_pickup, dan picked_up boleh langsung ke delivered agar petani tetap bisa
+// mengonfirmasi penerimaan ketika driver tidak memperbarui status di aplikasi.
+// At_pickup kembali ke pickup_pending bila driver pergi tanpa konfirmasi pickup.
+var deliveryTransitions = map[string][]string{
+	DeliveryStatusPendingDriver:    {DeliveryStatusPendingSignature, DeliveryStatusCancelled},
+	DeliveryStatusPendingSignature: {DeliveryStatusPendingPayment, DeliveryStatusPendingDriver, DeliveryStatusCancelled},
+	DeliveryStatusPendingPayment:   {DeliveryStatusPickupPending, DeliveryStatusCancelled},
+	DeliveryStatusPickupPending:    {DeliveryStatusAtPickup, DeliveryStatusPickedUp, DeliveryStatusDelivered, DeliveryStatusFailed, DeliveryStatusCancelled},
+	DeliveryStatusAtPickup:         {DeliveryStatusPickedUp, DeliveryStatusPickupPending, DeliveryStatusDelivered, DeliveryStatusFailed, DeliveryStatusCancelled},
+	DeliveryStatusPickedUp:         {DeliveryStatusInTransit, DeliveryStatusDelivered, DeliveryStatusFailed},
+	DeliveryStatusInTransit:        {DeliveryStatusOutForDelivery, DeliveryStatusArrived, DeliveryStatusDelivered, DeliveryStatusFailed},
+	DeliveryStatusOutForDelivery:   {DeliveryStatusArrived, DeliveryStatusDelivered, DeliveryStatusFailed},
+	DeliveryStatusArrived:          {DeliveryStatusInTransit, DeliveryStatusDelivered, DeliveryStatusFailed}, // berangkat lagi ke titik berikutnya
+	DeliveryStatusFailed:           {DeliveryStatusPickupPending, DeliveryStatusInTransit, DeliveryStatusReturning, DeliveryStatusCancelled},
+	DeliveryStatusReturning:        {DeliveryStatusReturned},
+}
+
+// HasConditionThresholds bernilai true jika petani menetapkan ambang batas cold-chain.
+func (d *Delivery) HasConditionThresholds() bool {
+	return d.MinTemperatureC != nil || d.MaxTemperatureC != nil || d.MaxHumidityPct != nil
+}
+
+// NextStatuses mengembalikan status yang boleh dituju dari status saat ini.
+func (d *Delivery) NextStatuses() []string {
+	return deliveryTransitions[d.Status]
+}
+
+// CanTransitionTo memeriksa apakah perpindahan ke status tujuan diizinkan.
+func (d *Delivery) CanTransitionTo(status string) bool {
+	for _, next := range deliveryTransitions[d.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTrackable bernilai true selama driver sedang menjalankan pengiriman di lapangan.
+func (d *Delivery) IsTrackable() bool {
+	switch d.Status {
+	case DeliveryStatusPickupPending, DeliveryStatusAtPickup, DeliveryStatusPickedUp, DeliveryStatusInTransit,
+		DeliveryStatusOutForDelivery, DeliveryStatusArrived, DeliveryStatusReturning:
+		return true
+	}
+	return false
+}
+
+// PendingStops mengembalikan titik antar yang belum diserahterimakan.
+func (d *Delivery) PendingStops() []DeliveryStop {
+	pending := make([]DeliveryStop, 0, len(d.Stops))
+	for _, stop := range d.Stops {
+		if stop.Status != DeliveryStopStatusDelivered {
+			pending = append(pending, stop)
+		}
+	}
+	return pending
+}
+
+// HasOrderBuyer bernilai true jika user adalah pembeli salah satu pesanan yang diantar.
+func (d *Delivery) HasOrderBuyer(userID uuid.UUID) bool {
+	for _, order := range d.Orders {
+		if order.UserID == userID {
+			return true
+		}
+	}
+	return false
 }
 
 // BeforeCreate hook for Delivery
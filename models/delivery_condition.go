@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Sumber pembacaan kondisi muatan.
+const (
+	ConditionSourceDriver = "driver"
+	ConditionSourceDevice = "device"
+)
+
+// DeliveryConditionReading adalah satu catatan kondisi muatan (cold-chain):
+// suhu, kelembapan, dan/atau foto kondisi produk selama pengiriman.
+type DeliveryConditionReading struct {
+	ID           uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	DeliveryID   uuid.UUID  `gorm:"type:char(36);not null;index:idx_condition_delivery_time" json:"delivery_id"`
+	Source       string     `gorm:"type:enum('driver','device');not null" json:"source"`
+	RecordedByID *uuid.UUID `gorm:"type:char(36)" json:"recorded_by_id"` // driver
+	DeviceID     *uuid.UUID `gorm:"type:char(36)" json:"device_id"`
+
+	TemperatureC *float64 `gorm:"type:decimal(5,2)" json:"temperature_c"`
+	HumidityPct  *float64 `gorm:"type:decimal(5,2)" json:"humidity_pct"`
+	PhotoURL     *string  `gorm:"type:varchar(255)" json:"photo_url"`
+	Notes        *string  `gorm:"type:text" json:"notes"`
+	Lat          *float64 `gorm:"type:decimal(10,8)" json:"lat"`
+	Lng          *float64 `gorm:"type:decimal(11,8)" json:"lng"`
+
+	// IsAlert bernilai true jika pembacaan berada di luar ambang batas pengiriman
+	IsAlert     bool      `gorm:"default:false" json:"is_alert"`
+	AlertReason *string   `gorm:"type:varchar(255)" json:"alert_reason"`
+	RecordedAt  time.Time `gorm:"not null;index:idx_condition_delivery_time" json:"recorded_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (r *DeliveryConditionReading) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	if r.RecordedAt.IsZero() {
+		r.RecordedAt = time.Now()
+	}
+	return nil
+}
+
+// ColdChainDevice adalah sensor IoT milik petani yang mengirim pembacaan lewat API key.
+// Hanya hash dari key yang disimpan; key asli ditampilkan sekali saat pendaftaran.
+type ColdChainDevice struct {
+	ID         uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	FarmerID   uuid.UUID  `gorm:"type:char(36);not null;index" json:"farmer_id"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	KeyPrefix  string     `gorm:"type:varchar(12);not null" json:"key_prefix"`
+	KeyHash    string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (d *ColdChainDevice) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsActive bernilai true jika key perangkat belum dicabut.
+func (d *ColdChainDevice) IsActive() bool {
+	return d.RevokedAt == nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DeliveryDispute adalah keberatan petani atas sebuah pengiriman sebelum dana dilepas ke driver.
+// Selama sengketa masih terbuka, pembayaran ke driver ditahan.
+type DeliveryDispute struct {
+	ID         uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	DeliveryID uuid.UUID  `gorm:"type:char(36);not null;index" json:"delivery_id"`
+	RaisedByID uuid.UUID  `gorm:"type:char(36);not null" json:"raised_by_id"`
+	Type       string     `gorm:"type:varchar(30);not null" json:"type"`
+	Reason     string     `gorm:"type:text;not null" json:"reason"`
+	Status     string     `gorm:"type:varchar(20);not null;default:'open'" json:"status"`
+	Resolution *string    `gorm:"type:text" json:"resolution"`
+	ResolvedBy *uuid.UUID `gorm:"type:char(36)" json:"resolved_by"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	Delivery *Delivery `gorm:"foreignKey:DeliveryID" json:"delivery,omitempty"`
+}
+
+func (dd *DeliveryDispute) BeforeCreate(tx *gorm.DB) error {
+	if dd.ID == uuid.Nil {
+		dd.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsOpen bernilai true selama sengketa belum diputuskan admin.
+func (dd *DeliveryDispute) IsOpen() bool {
+	switch dd.Status {
+	case DisputeStatusResolved, DisputeStatusClosed:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Jenis event pada riwayat pengiriman.
+const (
+	DeliveryEventCreated         = "created"
+	DeliveryEventDriverSelected  = "driver_selected"
+	DeliveryEventContractSigned  = "contract_signed"
+	DeliveryEventPaymentSettled  = "payment_settled"
+	DeliveryEventPickedUp        = "picked_up"
+	DeliveryEventDeparted        = "departed"
+	DeliveryEventArrived         = "arrived"
+	DeliveryEventDelivered       = "delivered"
+	DeliveryEventFailed          = "failed"
+	DeliveryEventPaymentReleased = "payment_released"
+	DeliveryEventStopDelivered   = "stop_delivered"
+
+	// Penanganan pengiriman gagal
+	DeliveryEventRedeliveryScheduled = "redelivery_scheduled"
+	DeliveryEventReturnStarted       = "return_started"
+	DeliveryEventReturned            = "returned"
+	DeliveryEventCancelled           = "cancelled"
+
+	// Event otomatis dari geofence & pemantauan posisi driver
+	DeliveryEventArrivedAtPickup      = "arrived_at_pickup"
+	DeliveryEventDepartedPickup       = "departed_pickup"
+	DeliveryEventArrivedAtDestination = "arrived_at_destination"
+	DeliveryEventDelayAlert           = "delay_alert"
+
+	// Pembacaan cold-chain di luar ambang batas
+	DeliveryEventConditionAlert = "condition_alert"
+)
+
+// DeliveryEvent adalah catatan append-only setiap perubahan status sebuah Delivery.
+type DeliveryEvent struct {
+	ID         uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	DeliveryID uuid.UUID  `gorm:"type:char(36);not null;index" json:"delivery_id"`
+	EventType  string     `gorm:"type:varchar(50);not null" json:"event_type"`
+	FromStatus string     `gorm:"type:varchar(30)" json:"from_status"`
+	ToStatus   string     `gorm:"type:varchar(30);not null" json:"to_status"`
+	ActorID    *uuid.UUID `gorm:"type:char(36)" json:"actor_id"`
+	ActorRole  string     `gorm:"type:varchar(20)" json:"actor_role"` // farmer, driver, system
+	Notes      *string    `gorm:"type:text" json:"notes"`
+	Lat        *float64   `gorm:"type:decimal(10,8)" json:"lat"`
+	Lng        *float64   `gorm:"type:decimal(11,8)" json:"lng"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (de *DeliveryEvent) BeforeCreate(tx *gorm.DB) error {
+	if de.ID == uuid.Nil {
+		de.ID = uuid.New()
+	}
+	return nil
+}
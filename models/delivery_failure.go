@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Alasan kegagalan pengiriman yang dilaporkan driver.
+const (
+	FailureReasonRecipientAbsent  = "recipient_absent"
+	FailureReasonGoodsRejected    = "goods_rejected"
+	FailureReasonAddressNotFound  = "address_not_found"
+	FailureReasonVehicleBreakdown = "vehicle_breakdown"
+	FailureReasonOther            = "other"
+)
+
+// Keputusan petani atas pengiriman yang gagal.
+const (
+	FailureResolutionRedeliver      = "redeliver"
+	FailureResolutionReturnToOrigin = "return_to_origin"
+	FailureResolutionCancel         = "cancel"
+)
+
+// DeliveryFailure mencatat laporan kegagalan dari driver beserta keputusan petani
+// dan konsekuensi biayanya. Resolution kosong berarti masih menunggu keputusan.
+type DeliveryFailure struct {
+	ID               uuid.UUID `gorm:"type:char(36);primary_key" json:"id"`
+	DeliveryID       uuid.UUID `gorm:"type:char(36);not null;index" json:"delivery_id"`
+	ReportedByID     uuid.UUID `gorm:"type:char(36);not null" json:"reported_by_id"`
+	Reason           string    `gorm:"type:enum('recipient_absent','goods_rejected','address_not_found','vehicle_breakdown','other');not null" json:"reason"`
+	Notes            string    `gorm:"type:text;not null" json:"notes"`
+	PhotoURL         string    `gorm:"type:varchar(255);not null" json:"photo_url"`
+	Lat              *float64  `gorm:"type:decimal(10,8)" json:"lat"`
+	Lng              *float64  `gorm:"type:decimal(11,8)" json:"lng"`
+	FailedFromStatus string    `gorm:"type:varchar(30);not null" json:"failed_from_status"`
+
+	Resolution      *string    `gorm:"type:enum('redeliver','return_to_origin','cancel')" json:"resolution"`
+	ResolutionNotes *string    `gorm:"type:text" json:"resolution_notes"`
+	ResolvedByID    *uuid.UUID `gorm:"type:char(36)" json:"resolved_by_id"`
+	ResolvedAt      *time.Time `json:"resolved_at"`
+
+	// Konsekuensi biaya: tagihan tambahan ke petani, atau pembagian dana escrow saat batal
+	FeeAmount          float64    `gorm:"type:decimal(12,2);default:0" json:"fee_amount"`
+	FeeInvoiceID       *uuid.UUID `gorm:"type:char(36)" json:"fee_invoice_id"`
+	DriverCompensation float64    `gorm:"type:decimal(12,2);default:0" json:"driver_compensation"`
+	RefundAmount       float64    `gorm:"type:decimal(12,2);default:0" json:"refund_amount"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsDriverFault bernilai true jika kegagalan disebabkan pihak driver,
+// sehingga petani tidak dikenai biaya tambahan.
+func (f *DeliveryFailure) IsDriverFault() bool {
+	return f.Reason == FailureReasonVehicleBreakdown
+}
+
+// GoodsPickedUp bernilai true jika barang sudah berada di tangan driver saat gagal.
+func (f *DeliveryFailure) GoodsPickedUp() bool {
+	return f.FailedFromStatus != DeliveryStatusPickupPending && f.FailedFromStatus != DeliveryStatusAtPickup
+}
+
+func (f *DeliveryFailure) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// deliveryProofFinalTarget adalah HandoverTarget untuk bukti serah terima di tujuan akhir.
+const deliveryProofFinalTarget = "final"
+
+// DeliveryProof adalah bukti serah terima barang kepada penerima.
+// Baris dibuat saat driver meminta OTP dan dilengkapi saat bukti dikirim.
+// StopID diisi untuk bukti per titik antar; kosong untuk tujuan akhir.
+type DeliveryProof struct {
+	ID           uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	DeliveryID   uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex:idx_delivery_proof_handover" json:"delivery_id"`
+	StopID       *uuid.UUID `gorm:"type:char(36);index" json:"stop_id"`
+	DriverID     uuid.UUID  `gorm:"type:char(36);not null" json:"driver_id"`
+	OTPHash      string     `gorm:"type:varchar(255)" json:"-"`
+	OTPExpiresAt *time.Time `json:"-"`
+	OTPAttempts  int        `gorm:"default:0" json:"-"` // Akumulasi percobaan salah, tidak direset saat kirim ulang
+	OTPSendCount int        `gorm:"default:0" json:"-"`
+	OTPSentAt    *time.Time `json:"-"`
+	OTPSentTo    *string    `gorm:"type:varchar(100)" json:"otp_sent_to"`
+
+	// HandoverTarget berisi StopID atau "final"; dipakai untuk unique index karena MySQL
+	// menganggap NULL selalu berbeda sehingga stop_id tidak bisa menjamin satu bukti akhir.
+	HandoverTarget string `gorm:"type:varchar(36);not null;uniqueIndex:idx_delivery_proof_handover" json:"-"`
+
+	RecipientName *string    `gorm:"type:varchar(100)" json:"recipient_name"`
+	PhotoURL      *string    `gorm:"type:varchar(255)" json:"photo_url"`
+	SignatureURL  *string    `gorm:"type:varchar(255)" json:"signature_url"`
+	Lat           *float64   `gorm:"type:decimal(10,8)" json:"lat"`
+	Lng           *float64   `gorm:"type:decimal(11,8)" json:"lng"`
+	VerifiedAt    *time.Time `json:"verified_at"` // OTP terkonfirmasi & bukti lengkap
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// BeforeSave menyelaraskan HandoverTarget dengan StopID.
+func (dp *DeliveryProof) BeforeSave(tx *gorm.DB) error {
+	dp.HandoverTarget = deliveryProofFinalTarget
+	if dp.StopID != nil {
+		dp.HandoverTarget = dp.StopID.String()
+	}
+	return nil
+}
+
+func (dp *DeliveryProof) BeforeCreate(tx *gorm.DB) error {
+	if dp.ID == uuid.Nil {
+		dp.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Status serah terima per titik antar.
+const (
+	DeliveryStopStatusPending   = "pending"
+	DeliveryStopStatusDelivered = "delivered"
+)
+
+// DeliveryStop adalah titik antar tambahan sebelum tujuan akhir sebuah Delivery.
+// Pada pengiriman gabungan, setiap pesanan/muatan memiliki titik antar sendiri.
+type DeliveryStop struct {
+	ID             uuid.UUID `gorm:"type:char(36);primary_key"`
+	DeliveryID     uuid.UUID `gorm:"type:char(36);not null;index"`
+	Sequence       int       `gorm:"not null"` // urutan kunjungan, mulai dari 1
+	Address        string    `gorm:"type:text;not null"`
+	Lat            float64   `gorm:"type:decimal(10,8);not null"`
+	Lng            float64   `gorm:"type:decimal(11,8);not null"`
+	RecipientName  *string   `gorm:"type:varchar(100)"`
+	RecipientPhone *string   `gorm:"type:varchar(20)"`
+	RecipientEmail *string   `gorm:"type:varchar(100)"` // Tujuan OTP serah terima titik ini
+	Notes          *string   `gorm:"type:text"`
+
+	// Muatan yang diturunkan di titik ini; OrderID kosong untuk hasil panen non-pesanan
+	OrderID         *uuid.UUID `gorm:"type:char(36);index"`
+	ItemDescription *string    `gorm:"type:text"`
+	ItemWeight      *float64   `gorm:"type:decimal(10,2)"`
+
+	Status      string `gorm:"type:enum('pending','delivered');not null;default:'pending'"`
+	DeliveredAt *time.Time
+	Proof       *DeliveryProof `gorm:"foreignKey:StopID"`
+	CreatedAt   time.Time
+}
+
+func (ds *DeliveryStop) BeforeCreate(tx *gorm.DB) error {
+	if ds.ID == uuid.Nil {
+		ds.ID = uuid.New()
+	}
+	return nil
+}
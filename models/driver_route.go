@@ -1,12 +1,132 @@
 // models/driver_route.go
 package models
 
-import "github.com/google/uuid"
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
 
 type DriverRoute struct {
-    ID            uuid.UUID `gorm:"type:char(36);primary_key"`
-    DriverID      uuid.UUID `gorm:"type:char(36);not null"`
-    Origin        string    // Kota/area asal
-    Destination   string    // Kota/area tujuan
-    DaysAvailable string    // Contoh: "Senin, Rabu, Jumat"
-}
\ No newline at end of file
+	ID            uuid.UUID `gorm:"type:char(36);primary_key" json:"id"`
+	DriverID      uuid.UUID `gorm:"type:char(36);not null;index" json:"driver_id"`
+	Origin        string    `gorm:"type:varchar(100);not null" json:"origin"`      // Kota/area asal
+	Destination   string    `gorm:"type:varchar(100);not null" json:"destination"` // Kota/area tujuan
+	DaysAvailable string    `gorm:"type:varchar(100)" json:"days_available"`       // Contoh: "Senin, Rabu, Jumat"
+	Weekdays      string    `gorm:"type:varchar(20)" json:"weekdays"`              // Terstruktur, contoh: "1,3,5" (0 = Minggu)
+	StartTime     string    `gorm:"type:varchar(5)" json:"start_time"`             // "HH:MM"
+	EndTime       string    `gorm:"type:varchar(5)" json:"end_time"`               // "HH:MM"
+	Notes         *string   `gorm:"type:text" json:"notes"`
+	IsActive      bool      `gorm:"default:true" json:"is_active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	Driver *Driver `gorm:"foreignKey:DriverID;references:UserID" json:"driver,omitempty"`
+}
+
+func (dr *DriverRoute) BeforeCreate(tx *gorm.DB) error {
+	if dr.ID == uuid.Nil {
+		dr.ID = uuid.New()
+	}
+	return nil
+}
+
+// WeekdayList mengembalikan hari operasional rute. Data lama yang belum memiliki
+// kolom Weekdays dibaca dari teks DaysAvailable.
+func (dr *DriverRoute) WeekdayList() []time.Weekday {
+	if strings.TrimSpace(dr.Weekdays) == "" {
+		return ParseWeekdays(dr.DaysAvailable)
+	}
+	var days []time.Weekday
+	for _, part := range strings.Split(dr.Weekdays, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && n >= 0 && n <= 6 {
+			days = append(days, time.Weekday(n))
+		}
+	}
+	return days
+}
+
+// FormatWeekdays mengubah daftar hari menjadi format kolom Weekdays, contoh "1,3,5".
+func FormatWeekdays(days []time.Weekday) string {
+	sorted := append([]time.Weekday(nil), days...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	parts := make([]string, 0, len(sorted))
+	for i, d := range sorted {
+		if i > 0 && sorted[i-1] == d {
+			continue
+		}
+		parts = append(parts, strconv.Itoa(int(d)))
+	}
+	return strings.Join(parts, ",")
+}
+
+var weekdayNamesID = [...]string{"Minggu", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu"}
+
+// WeekdayNames mengubah daftar hari menjadi teks yang mudah dibaca, contoh "Senin, Rabu, Jumat".
+func WeekdayNames(days []time.Weekday) string {
+	names := make([]string, 0, len(days))
+	for _, d := range days {
+		names = append(names, weekdayNamesID[d])
+	}
+	return strings.Join(names, ", ")
+}
+
+var indonesianWeekdays = map[string]time.Weekday{
+	"minggu": time.Sunday, "ahad": time.Sunday, "sunday": time.Sunday,
+	"senin": time.Monday, "monday": time.Monday,
+	"selasa": time.Tuesday, "tuesday": time.Tuesday,
+	"rabu": time.Wednesday, "wednesday": time.Wednesday,
+	"kamis": time.Thursday, "thursday": time.Thursday,
+	"jumat": time.Friday, "jum'at": time.Friday, "friday": time.Friday,
+	"sabtu": time.Saturday, "saturday": time.Saturday,
+}
+
+// ParseWeekdays membaca teks hari bebas seperti "Senin, Rabu, Jumat",
+// "Senin - Jumat" atau "Setiap hari" menjadi daftar hari.
+func ParseWeekdays(text string) []time.Weekday {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return nil
+	}
+	if strings.Contains(text, "setiap hari") || strings.Contains(text, "tiap hari") || text == "daily" {
+		return []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+	}
+
+	seen := make(map[time.Weekday]bool)
+	var days []time.Weekday
+	add := func(d time.Weekday) {
+		if !seen[d] {
+			seen[d] = true
+			days = append(days, d)
+		}
+	}
+
+	text = strings.NewReplacer(" dan ", ",", "s/d", "-", "s.d.", "-", " sampai ", "-").Replace(text)
+	for _, part := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ';' }) {
+		// Rentang, misalnya "senin - jumat" atau "senin s/d jumat"
+		if bounds := strings.Split(part, "-"); len(bounds) == 2 {
+			from, okFrom := indonesianWeekdays[strings.TrimSpace(bounds[0])]
+			to, okTo := indonesianWeekdays[strings.TrimSpace(bounds[1])]
+			if okFrom && okTo {
+				for d := from; ; d = (d + 1) % 7 {
+					add(d)
+					if d == to {
+						break
+					}
+				}
+				continue
+			}
+		}
+		for _, word := range strings.Fields(part) {
+			if d, ok := indonesianWeekdays[word]; ok {
+				add(d)
+			}
+		}
+	}
+	return days
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Jenis pergerakan stok pada ledger inventaris.
+const (
+	MovementRestock     = "restock"     // stok masuk (panen/kiriman baru)
+	MovementReservation = "reservation" // stok ditahan keranjang atau pesanan
+	MovementRelease     = "release"     // stok yang ditahan dikembalikan ke stok tersedia
+	MovementSale        = "sale"        // pesanan dibayar, stok fisik keluar
+	MovementAdjustment  = "adjustment"  // koreksi manual (susut, rusak, stock opname)
+	MovementReturn      = "return"      // barang pesanan kembali ke petani
+)
+
+// InventoryMovement adalah satu baris ledger inventaris yang hanya bisa ditambah.
+// Jumlah StockDelta dan ReservedDelta seluruh baris sebuah varian sama dengan
+// Stock dan ReservedStock varian tersebut.
+type InventoryMovement struct {
+	ID            uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	ProductID     uuid.UUID  `gorm:"type:char(36);not null;index" json:"product_id"`
+	VariantID     uuid.UUID  `gorm:"type:char(36);not null;index:idx_inventory_variant_created" json:"variant_id"`
+	Type          string     `gorm:"type:enum('restock','reservation','release','sale','adjustment','return');not null;index" json:"type"`
+	StockDelta    float64    `gorm:"type:decimal(12,3);not null;default:0" json:"stock_delta"`
+	ReservedDelta float64    `gorm:"type:decimal(12,3);not null;default:0" json:"reserved_delta"`
+	StockAfter    float64    `gorm:"type:decimal(12,3);not null" json:"stock_after"`
+	ReservedAfter float64    `gorm:"type:decimal(12,3);not null" json:"reserved_after"`
+	OrderID       *uuid.UUID `gorm:"type:char(36);index" json:"order_id"`
+	ActorID       *uuid.UUID `gorm:"type:char(36)" json:"actor_id"` // Pengguna yang memicu pergerakan; kosong untuk proses sistem
+	Note          *string    `gorm:"type:varchar(255)" json:"note"`
+	CreatedAt     time.Time  `gorm:"index:idx_inventory_variant_created" json:"created_at"`
+}
+
+func (m *InventoryMovement) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
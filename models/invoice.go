@@ -12,6 +12,8 @@ type Invoice struct {
 	ID        uuid.UUID `gorm:"type:char(36);primary_key"`
 	ProjectID   *uuid.UUID `gorm:"type:char(36)"`
 	DeliveryID  *uuid.UUID `gorm:"type:char(36)"` // <-- [TAMBAHAN]
+	// Purpose membedakan tagihan jasa utama dari biaya tambahan pengiriman gagal
+	Purpose   string    `gorm:"type:varchar(20);not null;default:'service'"`
 	FarmerID  uuid.UUID `gorm:"type:char(36);not null"`
 	Amount    float64   `gorm:"type:decimal(12,2)"`
 	PlatformFee float64 `gorm:"type:decimal(10,2)"`
@@ -26,6 +28,13 @@ type Invoice struct {
 	Delivery *Delivery `gorm:"foreignKey:DeliveryID"`
 }
 
+// Jenis tagihan (Invoice.Purpose).
+const (
+	InvoicePurposeService       = "service"
+	InvoicePurposeRedeliveryFee = "redelivery_fee"
+	InvoicePurposeReturnFee     = "return_fee"
+)
+
 func (i *Invoice) BeforeCreate(tx *gorm.DB) (err error) {
 	if i.ID == uuid.Nil {
 		i.ID = uuid.New()
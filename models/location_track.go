@@ -9,16 +9,21 @@ import (
 
 type LocationTrack struct {
 	ID         uuid.UUID `gorm:"type:char(36);primary_key"`
-	DeliveryID uuid.UUID `gorm:"type:char(36);not null;index"`
+	DeliveryID uuid.UUID `gorm:"type:char(36);not null;index;index:idx_location_tracks_delivery_time,priority:1"`
 	Lat        float64   `gorm:"type:decimal(10,8);not null"`
 	Lng        float64   `gorm:"type:decimal(11,8);not null"`
-	Timestamp  time.Time
+	Accuracy   *float64  `gorm:"type:decimal(8,2)"`               // meter, dari GPS perangkat
+	Source     string    `gorm:"type:varchar(10);default:'live'"` // live atau batch (unggahan offline)
+	Timestamp  time.Time `gorm:"index:idx_location_tracks_delivery_time,priority:2"`
 }
 
 func (lt *LocationTrack) BeforeCreate(tx *gorm.DB) (err error) {
 	if lt.ID == uuid.Nil {
 		lt.ID = uuid.New()
 	}
-	lt.Timestamp = time.Now()
+	// Titik dari unggahan offline membawa waktu perekaman dari perangkat
+	if lt.Timestamp.IsZero() {
+		lt.Timestamp = time.Now()
+	}
 	return
-}
\ No newline at end of file
+}
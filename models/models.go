@@ -41,14 +41,33 @@ const (
 	AssignmentStatusTerminated = "terminated"
 
 	// Delivery status
+	DeliveryStatusPendingDriver    = "pending_driver"
+	DeliveryStatusPendingSignature = "pending_signature"
+	DeliveryStatusPendingPayment   = "pending_payment"
 	DeliveryStatusScheduled        = "scheduled"
 	DeliveryStatusPickupPending    = "pickup_pending"
+	DeliveryStatusAtPickup         = "at_pickup" // driver tiba di lokasi pickup, menunggu barang diserahkan
 	DeliveryStatusPickedUp         = "picked_up"
 	DeliveryStatusInTransit        = "in_transit"
 	DeliveryStatusOutForDelivery   = "out_for_delivery"
+	DeliveryStatusArrived          = "arrived"
 	DeliveryStatusDelivered        = "delivered"
 	DeliveryStatusFailed           = "failed"
 	DeliveryStatusCancelled        = "cancelled"
+	DeliveryStatusReturning        = "returning" // barang dibawa kembali ke lokasi pickup
+	DeliveryStatusReturned         = "returned"
+
+	// Order (e-commerce) status
+	OrderStatusPending   = "pending"
+	OrderStatusPaid      = "paid"
+	OrderStatusShipped   = "shipped"
+	OrderStatusCompleted = "completed"
+	OrderStatusCancelled = "cancelled"
+
+	// Order fulfillment methods
+	FulfillmentCourier      = "courier"       // diantar driver lewat modul Delivery
+	FulfillmentSelfDelivery = "self_delivery" // diantar sendiri oleh petani
+	FulfillmentPickup       = "pickup"        // diambil pembeli di lokasi petani
 
 	// Transaction status
 	TransactionStatusPending   = "pending"
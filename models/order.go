@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,10 +13,31 @@ type Order struct {
 	UserID          uuid.UUID `gorm:"type:char(36);not null;index"`
 	FarmerID        uuid.UUID `gorm:"type:char(36);not null;index"`
 	InvoiceNumber   string    `gorm:"type:varchar(50);uniqueIndex;not null"`
-	TotalAmount     float64   `gorm:"type:decimal(12,2);not null;default:0.00"`
+	TotalAmount     float64   `gorm:"type:decimal(12,2);not null;default:0.00"` // Yang dibayar pembeli: SubTotal + ShippingFee - DiscountAmount
 	Status          string    `gorm:"type:enum('pending','paid','shipped','completed','cancelled');not null;default:'pending'"`
 	ShippingAddress *string   `gorm:"type:text"`
 
+	// Rincian total pesanan. Diskon voucher yang ditanggung platform (PlatformDiscount)
+	// tidak mengurangi pendapatan petani.
+	SubTotal         float64    `gorm:"type:decimal(12,2);not null;default:0.00"`
+	ShippingFee      float64    `gorm:"type:decimal(12,2);not null;default:0.00"`
+	DiscountAmount   float64    `gorm:"type:decimal(12,2);not null;default:0.00"`
+	PlatformDiscount float64    `gorm:"type:decimal(12,2);not null;default:0.00"`
+	VoucherID        *uuid.UUID `gorm:"type:char(36);index"`
+
+	// Salinan alamat saat checkout; tidak berubah bila buku alamat pembeli diedit
+	ShippingAddressID *uuid.UUID `gorm:"type:char(36)"`
+	ShippingRecipient *string    `gorm:"type:varchar(100)"`
+	ShippingPhone     *string    `gorm:"type:varchar(20)"`
+	ShippingLat       *float64   `gorm:"type:decimal(10,8)"`
+	ShippingLng       *float64   `gorm:"type:decimal(11,8)"`
+
+	// Pemenuhan pesanan: kurir (Delivery), antar sendiri, atau ambil di tempat
+	FulfillmentMethod *string    `gorm:"type:enum('courier','self_delivery','pickup')"`
+	DeliveryID        *uuid.UUID `gorm:"type:char(36);index"`
+	ShippedAt         *time.Time
+	CompletedAt       *time.Time
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 
@@ -23,6 +45,41 @@ type Order struct {
 	Farmer   Farmer   `gorm:"foreignKey:FarmerID"`
 	Items    []OrderItem        `gorm:"foreignKey:OrderID"`
 	Payments []ECommercePayment `gorm:"many2many:ecommerce_payment_orders;"`
+	Delivery *Delivery          `gorm:"foreignKey:DeliveryID"`
+}
+
+// ApplyShippingAddress menyalin alamat dari buku alamat ke pesanan.
+func (o *Order) ApplyShippingAddress(address *Address) {
+	full := address.FullAddress()
+	o.ShippingAddress = &full
+	o.ShippingAddressID = &address.ID
+	o.ShippingRecipient = &address.RecipientName
+	o.ShippingPhone = &address.PhoneNumber
+	o.ShippingLat = address.Latitude
+	o.ShippingLng = address.Longitude
+}
+
+// RecalculateTotal menghitung ulang TotalAmount dari rincian pesanan.
+func (o *Order) RecalculateTotal() {
+	o.TotalAmount = math.Round((o.SubTotal+o.ShippingFee-o.DiscountAmount)*100) / 100
+}
+
+// SellerAmount adalah nilai pesanan untuk petani: yang dibayar pembeli ditambah diskon
+// yang ditanggung platform.
+func (o *Order) SellerAmount() float64 {
+	return math.Round((o.TotalAmount+o.PlatformDiscount)*100) / 100
+}
+
+// CanBeShipped bernilai true jika pesanan sudah dibayar dan belum memiliki
+// pengiriman aktif. Pengiriman yang batal atau dikembalikan boleh diganti.
+func (o *Order) CanBeShipped() bool {
+	if o.Status != OrderStatusPaid {
+		return false
+	}
+	if o.Delivery == nil {
+		return o.DeliveryID == nil
+	}
+	return o.Delivery.Status == DeliveryStatusCancelled || o.Delivery.Status == DeliveryStatusReturned
 }
 
 func (o *Order) BeforeCreate(tx *gorm.DB) (err error) {
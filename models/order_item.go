@@ -10,8 +10,12 @@ type OrderItem struct {
 	ID                   uuid.UUID `gorm:"type:char(36);primary_key;default:(UUID())"`
 	OrderID              uuid.UUID `gorm:"type:char(36);not null;index"`
 	ProductID            uuid.UUID `gorm:"type:char(36);not null;index"`
-	Quantity             int       `gorm:"not null;default:1"`
+	VariantID            *uuid.UUID `gorm:"type:char(36);index"`
+	VariantName          string    `gorm:"type:varchar(100)"` // Salinan nama & satuan varian saat pembelian
+	Unit                 string    `gorm:"type:varchar(20)"`
+	Quantity             float64   `gorm:"type:decimal(12,3);not null;default:1"`
 	PriceAtPurchase      float64   `gorm:"type:decimal(12,2);not null;default:0.00"`
+	PriceTierID          *uuid.UUID `gorm:"type:char(36)"` // Tier grosir/grup yang dipakai untuk PriceAtPurchase, jika ada
 	NameAtPurchase       string    `gorm:"type:varchar(200);not null"`
 	DescriptionAtPurchase *string   `gorm:"type:text"`
 	SubTotal             float64   `gorm:"type:decimal(12,2);not null;default:0.00"`
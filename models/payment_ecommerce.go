@@ -1,18 +1,40 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Tujuan pembayaran e-commerce. Pembayaran pre-order tidak langsung melunasi order;
+// webhook-nya diteruskan ke PreOrderService.
+const (
+	PaymentPurposeCheckout        = "checkout"
+	PaymentPurposePreOrderDeposit = "preorder_deposit"
+	PaymentPurposePreOrderBalance = "preorder_balance"
+)
+
+// Status pengembalian dana pembayaran e-commerce, mis. pembayaran yang baru masuk
+// setelah pesanannya dibatalkan karena kedaluwarsa.
+const (
+	PaymentRefundPending   = "pending"
+	PaymentRefundCompleted = "completed"
+)
+
 type ECommercePayment struct {
 	ID         uuid.UUID `gorm:"type:char(36);primary_key"`
 	UserID     uuid.UUID `gorm:"type:char(36);not null;index"`
 	GrandTotal float64   `gorm:"type:decimal(12,2);not null"`
 	Status     string    `gorm:"type:enum('pending','paid','failed');default:'pending'"`
+	Purpose    string    `gorm:"type:enum('checkout','preorder_deposit','preorder_balance');not null;default:'checkout'"`
 	SnapToken  string    `gorm:"type:text"`
+	// Dana yang harus dikembalikan ke pembeli, dicairkan admin
+	RefundAmount float64    `gorm:"type:decimal(12,2);not null;default:0"`
+	RefundStatus *string    `gorm:"type:enum('pending','completed');index"`
+	RefundReason *string    `gorm:"type:varchar(255)"`
+	RefundedAt   *time.Time
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 	// [PERBAIKAN] Relasi Many-to-Many ke Order
@@ -26,3 +48,14 @@ func (p *ECommercePayment) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+// QueueRefund menambahkan dana yang harus dikembalikan ke pembeli, untuk dicairkan admin.
+func (p *ECommercePayment) QueueRefund(amount float64, reason string) {
+	if amount <= 0 {
+		return
+	}
+	status := PaymentRefundPending
+	p.RefundAmount = math.Round((p.RefundAmount+amount)*100) / 100
+	p.RefundStatus = &status
+	p.RefundReason = &reason
+}
@@ -30,7 +30,7 @@ type Payout struct {
     ID               uuid.UUID `gorm:"type:char(36);primary_key"`
     TransactionID    uuid.UUID `gorm:"type:char(36);not null"`
     PayeeID          uuid.UUID `gorm:"column:payee_id;type:char(36);not null"`
-    PayeeType        string    `gorm:"type:enum('worker','driver');not null"`
+    PayeeType        string    `gorm:"type:enum('worker','driver','farmer');not null"` // farmer = refund
     Amount           float64   `gorm:"type:decimal(12,2)"`
     Status           string    `gorm:"type:enum('pending_disbursement','completed','failed');default:'pending_disbursement'"`
     ReleasedAt       time.Time
@@ -40,6 +40,7 @@ type Payout struct {
     Transaction Transaction `gorm:"foreignKey:TransactionID"`
 	Worker *Worker `gorm:"-"`
 	Driver *Driver `gorm:"-"`
+	Farmer *Farmer `gorm:"-"`
 
     // JANGAN gunakan pointer relasi polimorfik dengan FK
     // Gunakan asosiasi manual atau skip constraint sepenuhnya
@@ -69,6 +70,13 @@ func (p *Payout) LoadPayee(db *gorm.DB) error {
 			return err
 		}
 		p.Driver = &driver
+	case "farmer":
+		var farmer Farmer
+		// Refund ke petani, mis. karena pengiriman dibatalkan
+		if err := db.Preload("User").Where("user_id = ?", p.PayeeID).First(&farmer).Error; err != nil {
+			return err
+		}
+		p.Farmer = &farmer
 	default:
 		return fmt.Errorf("unknown payee type: %s", p.PayeeType)
 	}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Status kampanye pre-order.
+const (
+	PreOrderCampaignOpen      = "open"      // menerima pre-order sampai jendela panen dimulai
+	PreOrderCampaignHarvested = "harvested" // hasil panen sudah dialokasikan ke pre-order
+	PreOrderCampaignCancelled = "cancelled"
+)
+
+// Status pre-order pembeli.
+const (
+	PreOrderStatusAwaitingDeposit = "awaiting_deposit" // menunggu pembayaran uang muka
+	PreOrderStatusReserved        = "reserved"         // kuota sudah dipegang, menunggu panen
+	PreOrderStatusAwaitingBalance = "awaiting_balance" // sudah panen, stok ditahan, menunggu pelunasan
+	PreOrderStatusCompleted       = "completed"        // lunas; pesanan diteruskan ke alur pengiriman biasa
+	PreOrderStatusCancelled       = "cancelled"
+)
+
+// Status pengembalian dana pre-order.
+const (
+	PreOrderRefundPending   = "pending"
+	PreOrderRefundCompleted = "completed"
+)
+
+// Batas waktu pelunasan setelah panen. Pre-order yang tidak dilunasi dibatalkan dan
+// uang mukanya hangus.
+const PreOrderBalanceDueDays = 3
+
+// PreOrderCampaign adalah penawaran pre-order petani untuk satu varian yang akan dipanen.
+// Pre-order diterima sampai HarvestStart selama kuota masih tersisa.
+type PreOrderCampaign struct {
+	ID             uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	ProductID      uuid.UUID  `gorm:"type:char(36);not null;index" json:"product_id"`
+	VariantID      uuid.UUID  `gorm:"type:char(36);not null;index" json:"variant_id"`
+	FarmerID       uuid.UUID  `gorm:"type:char(36);not null;index" json:"farmer_id"`
+	Price          float64    `gorm:"type:decimal(12,2);not null" json:"price"` // Harga per Unit varian
+	Quota          float64    `gorm:"type:decimal(12,3);not null" json:"quota"` // Total kuantitas yang boleh dipesan
+	ReservedQuota  float64    `gorm:"type:decimal(12,3);not null;default:0" json:"reserved_quota"`
+	DepositPercent int        `gorm:"not null;default:0" json:"deposit_percent"` // 0 = bayar penuh saat panen, 100 = bayar penuh di muka
+	HarvestStart   time.Time  `gorm:"type:date;not null;index" json:"harvest_start"`
+	HarvestEnd     time.Time  `gorm:"type:date;not null" json:"harvest_end"`
+	Status         string     `gorm:"type:enum('open','harvested','cancelled');not null;default:'open';index" json:"status"`
+	ActualQuantity *float64   `gorm:"type:decimal(12,3)" json:"actual_quantity"`
+	HarvestedAt    *time.Time `json:"harvested_at"`
+	Notes          *string    `gorm:"type:varchar(255)" json:"notes"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	Product *Product        `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Variant *ProductVariant `gorm:"foreignKey:VariantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (c *PreOrderCampaign) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// RemainingQuota adalah kuantitas yang masih bisa dipesan.
+func (c *PreOrderCampaign) RemainingQuota() float64 {
+	remaining := RoundQuantity(c.Quota - c.ReservedQuota)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// PreOrder adalah pesanan pembeli atas sebuah kampanye. Setiap pre-order punya satu Order
+// pending yang baru dibayar lunas (status paid) setelah panen dan pelunasan.
+type PreOrder struct {
+	ID                uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	CampaignID        uuid.UUID  `gorm:"type:char(36);not null;index" json:"campaign_id"`
+	OrderID           uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex" json:"order_id"`
+	UserID            uuid.UUID  `gorm:"type:char(36);not null;index" json:"user_id"`
+	Quantity          float64    `gorm:"type:decimal(12,3);not null" json:"quantity"`
+	AllocatedQuantity *float64   `gorm:"type:decimal(12,3)" json:"allocated_quantity"` // Diisi saat panen; bisa kurang dari Quantity
+	UnitPrice         float64    `gorm:"type:decimal(12,2);not null" json:"unit_price"`
+	TotalAmount       float64    `gorm:"type:decimal(12,2);not null" json:"total_amount"` // Disesuaikan ke kuantitas teralokasi setelah panen
+	DepositAmount     float64    `gorm:"type:decimal(12,2);not null;default:0" json:"deposit_amount"`
+	PaidAmount        float64    `gorm:"type:decimal(12,2);not null;default:0" json:"paid_amount"`
+	Status            string     `gorm:"type:enum('awaiting_deposit','reserved','awaiting_balance','completed','cancelled');not null;index" json:"status"`
+	DepositPaymentID  *uuid.UUID `gorm:"type:char(36);index" json:"deposit_payment_id"`
+	BalancePaymentID  *uuid.UUID `gorm:"type:char(36);index" json:"balance_payment_id"`
+	BalanceDueAt      *time.Time `gorm:"index" json:"balance_due_at"`
+	RefundAmount      float64    `gorm:"type:decimal(12,2);not null;default:0" json:"refund_amount"`   // Total dana yang pernah diantrekan untuk dikembalikan
+	RefundedAmount    float64    `gorm:"type:decimal(12,2);not null;default:0" json:"refunded_amount"` // Bagian RefundAmount yang sudah ditransfer admin
+	RefundStatus      *string    `gorm:"type:enum('pending','completed');index" json:"refund_status"`
+	RefundReason      *string    `gorm:"type:varchar(255)" json:"refund_reason"`
+	RefundedAt        *time.Time `json:"refunded_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	Campaign *PreOrderCampaign `gorm:"foreignKey:CampaignID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Order    *Order            `gorm:"foreignKey:OrderID" json:"-"`
+	User     *User             `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (p *PreOrder) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// OutstandingBalance adalah sisa tagihan yang belum dibayar pembeli, dibulatkan ke rupiah
+// penuh karena Midtrans hanya menerima nominal bulat.
+func (p *PreOrder) OutstandingBalance() float64 {
+	balance := math.Round(p.TotalAmount - (p.PaidAmount - p.RefundAmount))
+	if balance < 0 {
+		return 0
+	}
+	return balance
+}
+
+// PendingRefund adalah dana yang sudah diantrekan tetapi belum ditransfer ke pembeli.
+func (p *PreOrder) PendingRefund() float64 {
+	pending := math.Round((p.RefundAmount-p.RefundedAmount)*100) / 100
+	if pending < 0 {
+		return 0
+	}
+	return pending
+}
+
+// QueueRefund menambahkan dana yang harus dikembalikan ke pembeli, untuk dicairkan admin.
+// Pengembalian yang sudah ditransfer tetap tercatat di RefundedAmount sehingga admin
+// hanya mencairkan selisihnya.
+func (p *PreOrder) QueueRefund(amount float64, reason string) {
+	if amount <= 0 {
+		return
+	}
+	status := PreOrderRefundPending
+	p.RefundAmount = math.Round((p.RefundAmount+amount)*100) / 100
+	p.RefundStatus = &status
+	p.RefundReason = &reason
+}
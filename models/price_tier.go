@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PriceTier adalah harga khusus satu varian mulai kuantitas tertentu. Tanpa BuyerGroupID
+// tier berlaku untuk semua pembeli (harga grosir bertingkat); dengan BuyerGroupID tier
+// menjadi bagian daftar harga grup tersebut.
+type PriceTier struct {
+	ID           uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	ProductID    uuid.UUID  `gorm:"type:char(36);not null;index" json:"product_id"`
+	VariantID    uuid.UUID  `gorm:"type:char(36);not null;index" json:"variant_id"`
+	BuyerGroupID *uuid.UUID `gorm:"type:char(36);index" json:"buyer_group_id"`
+	MinQuantity  float64    `gorm:"type:decimal(12,3);not null" json:"min_quantity"` // Dalam Unit varian
+	Price        float64    `gorm:"type:decimal(12,2);not null" json:"price"`        // Harga per Unit
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	BuyerGroup *BuyerGroup `gorm:"foreignKey:BuyerGroupID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (t *PriceTier) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// BestPrice memilih harga satuan termurah yang berlaku untuk kuantitas tersebut: harga
+// dasar varian atau tier yang ambang kuantitasnya terpenuhi. Tier yang dikirim harus
+// sudah difilter sesuai grup pembeli. Mengembalikan tier yang dipakai, atau nil.
+func BestPrice(basePrice, quantity float64, tiers []PriceTier) (float64, *PriceTier) {
+	price := basePrice
+	var applied *PriceTier
+	for i := range tiers {
+		if tiers[i].MinQuantity <= quantity && tiers[i].Price < price {
+			price = tiers[i].Price
+			applied = &tiers[i]
+		}
+	}
+	return price, applied
+}
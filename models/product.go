@@ -13,17 +13,26 @@ type Product struct {
 	FarmerID    uuid.UUID `gorm:"type:char(36);not null;index"`
 	Description string    `gorm:"type:text"`
 	Location    *string   `gorm:"type:varchar(150)"`
-	Category    *string   `gorm:"type:varchar(100)"`
-	Price       float64   `gorm:"type:decimal(12,2);not null;default:0.00"`
-	Stock       int       `gorm:"not null;default:0"`
-	ReservedStock int `gorm:"not null;default:0"`
+	// Koordinat lokasi produk untuk filter radius & urutan jarak pada pencarian
+	Latitude    *float64  `gorm:"type:decimal(10,8)"`
+	Longitude   *float64  `gorm:"type:decimal(11,8)"`
+	CategoryID  *uuid.UUID `gorm:"type:char(36);index"`
+	// Kategori teks bebas lama; hanya dipakai untuk migrasi ke CategoryID
+	LegacyCategory *string `gorm:"column:category;type:varchar(100)"`
+	// Harga terendah di antara varian; disinkronkan otomatis untuk filter & urutan harga
+	Price       float64   `gorm:"type:decimal(12,2);not null;default:0.00;index"`
 	ImageURLs   datatypes.JSON `gorm:"column:image_urls"` 
 	Rating      *float64  `gorm:"type:decimal(3,2)"`
 
-	CreatedAt time.Time
+	CreatedAt time.Time `gorm:"index"`
 	UpdatedAt time.Time
 
+	DistanceKm *float64 `gorm:"->;-:migration"` // hanya dibaca dari query pencarian berdasarkan jarak
+
 	Farmer     Farmer      `gorm:"foreignKey:FarmerID"`
+	Category   *Category   `gorm:"foreignKey:CategoryID"`
+	Variants   []ProductVariant `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	RestockSchedules []RestockSchedule `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	OrderItems []OrderItem `gorm:"foreignKey:ProductID"`
 	CartItems  []Cart      `gorm:"foreignKey:ProductID"`
 }
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProductVariant adalah satuan jual sebuah produk (mis. "1 kg", "Ikat", "Karung 10 kg")
+// dengan harga, stok, dan SKU sendiri. Kuantitas mendukung desimal untuk barang timbang.
+type ProductVariant struct {
+	ID            uuid.UUID `gorm:"type:char(36);primary_key" json:"id"`
+	ProductID     uuid.UUID `gorm:"type:char(36);not null;index" json:"product_id"`
+	Name          string    `gorm:"type:varchar(100);not null" json:"name"`
+	SKU           *string   `gorm:"type:varchar(64);uniqueIndex" json:"sku"`
+	Unit          string    `gorm:"type:varchar(20);not null" json:"unit"`              // kg, gram, ikat, karung, pcs, ...
+	Price         float64   `gorm:"type:decimal(12,2);not null" json:"price"`           // Harga per satu Unit
+	Stock         float64   `gorm:"type:decimal(12,3);not null;default:0" json:"stock"` // Dalam Unit
+	ReservedStock float64   `gorm:"type:decimal(12,3);not null;default:0" json:"reserved_stock"`
+	MinOrderQty   float64   `gorm:"type:decimal(12,3);not null;default:1" json:"min_order_qty"`
+	QuantityStep  float64   `gorm:"type:decimal(12,3);not null;default:1" json:"quantity_step"` // mis. 0.5 untuk kelipatan 0,5 kg
+	SortOrder     int       `gorm:"not null;default:0" json:"sort_order"`
+	// Petani diberi notifikasi saat stok tersedia turun ke/di bawah ambang ini
+	LowStockThreshold *float64   `gorm:"type:decimal(12,3)" json:"low_stock_threshold"`
+	LowStockAlertedAt *time.Time `json:"-"` // Diisi saat notifikasi terkirim, dikosongkan lagi setelah stok pulih
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	Product    *Product    `gorm:"foreignKey:ProductID" json:"-"`
+	PriceTiers []PriceTier `gorm:"foreignKey:VariantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (v *ProductVariant) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// AvailableStock adalah stok yang belum direservasi keranjang atau pesanan.
+func (v *ProductVariant) AvailableStock() float64 {
+	available := RoundQuantity(v.Stock - v.ReservedStock)
+	if available < 0 {
+		return 0
+	}
+	return available
+}
+
+// ValidateQuantity memastikan kuantitas memenuhi minimum order dan kelipatan satuan varian.
+func (v *ProductVariant) ValidateQuantity(qty float64) error {
+	if qty <= 0 {
+		return fmt.Errorf("invalid quantity: must be greater than 0")
+	}
+	if qty < v.MinOrderQty {
+		return fmt.Errorf("invalid quantity: minimum order for %s is %g %s", v.Name, v.MinOrderQty, v.Unit)
+	}
+	if v.QuantityStep > 0 {
+		steps := qty / v.QuantityStep
+		if math.Abs(steps-math.Round(steps)) > 1e-6 {
+			return fmt.Errorf("invalid quantity: %s is sold in multiples of %g %s", v.Name, v.QuantityStep, v.Unit)
+		}
+	}
+	return nil
+}
+
+// RoundQuantity membulatkan kuantitas ke 3 desimal sesuai presisi kolom decimal(12,3),
+// agar penjumlahan float tidak menyisakan selisih kecil pada stok.
+func RoundQuantity(qty float64) float64 {
+	return math.Round(qty*1000) / 1000
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Status jadwal panen/restock.
+const (
+	RestockStatusPlanned   = "planned"
+	RestockStatusHarvested = "harvested"
+	RestockStatusCancelled = "cancelled"
+)
+
+// RestockSchedule adalah rencana panen/restock yang diumumkan petani untuk satu varian.
+// Jadwal yang masih planned ditampilkan sebagai "segera hadir" di halaman produk.
+type RestockSchedule struct {
+	ID               uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	ProductID        uuid.UUID  `gorm:"type:char(36);not null;index" json:"product_id"`
+	VariantID        uuid.UUID  `gorm:"type:char(36);not null;index" json:"variant_id"`
+	ExpectedQuantity float64    `gorm:"type:decimal(12,3);not null" json:"expected_quantity"`
+	ExpectedDate     time.Time  `gorm:"type:date;not null;index" json:"expected_date"`
+	Status           string     `gorm:"type:enum('planned','harvested','cancelled');not null;default:'planned';index" json:"status"`
+	ActualQuantity   *float64   `gorm:"type:decimal(12,3)" json:"actual_quantity"`
+	HarvestedAt      *time.Time `json:"harvested_at"`
+	Notes            *string    `gorm:"type:varchar(255)" json:"notes"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	Variant *ProductVariant `gorm:"foreignKey:VariantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (r *RestockSchedule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Status reservasi stok per item pesanan.
+const (
+	ReservationStatusActive    = "active"    // stok masih ditahan untuk pesanan yang belum dibayar
+	ReservationStatusCommitted = "committed" // pembayaran sukses, stok sudah benar-benar dikurangi
+	ReservationStatusReleased  = "released"  // stok dikembalikan ke stok tersedia
+)
+
+// Batas waktu pembayaran Midtrans untuk pesanan e-commerce, dan umur reservasi stok.
+// Reservasi diberi jeda setelah pembayaran kedaluwarsa agar webhook yang terlambat
+// masih bisa diproses sebelum stok dilepas.
+const (
+	PaymentExpiryMinutes = 60
+	ReservationTTL       = (PaymentExpiryMinutes + 15) * time.Minute
+)
+
+// Alasan pelepasan reservasi.
+const (
+	ReservationReleaseExpired       = "expired"
+	ReservationReleasePaymentFailed = "payment_failed"
+	ReservationReleaseOversold      = "oversold" // stok sudah terjual saat pembayaran terlambat masuk
+	ReservationReleaseManual        = "manual"
+)
+
+// StockReservation mencatat stok varian yang ditahan oleh satu item pesanan.
+// Reservasi yang masih active setelah ExpiresAt dilepas oleh job latar belakang.
+type StockReservation struct {
+	ID            uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	OrderID       uuid.UUID  `gorm:"type:char(36);not null;index" json:"order_id"`
+	OrderItemID   uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex" json:"order_item_id"`
+	ProductID     uuid.UUID  `gorm:"type:char(36);not null" json:"product_id"`
+	VariantID     uuid.UUID  `gorm:"type:char(36);not null;index" json:"variant_id"`
+	Quantity      float64    `gorm:"type:decimal(12,3);not null" json:"quantity"`
+	Status        string     `gorm:"type:enum('active','committed','released');not null;default:'active';index" json:"status"`
+	ExpiresAt     time.Time  `gorm:"not null;index" json:"expires_at"`
+	CommittedAt   *time.Time `json:"committed_at"`
+	ReleasedAt    *time.Time `json:"released_at"`
+	ReleaseReason *string    `gorm:"type:varchar(30)" json:"release_reason"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	Order   *Order          `gorm:"foreignKey:OrderID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Variant *ProductVariant `gorm:"foreignKey:VariantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (r *StockReservation) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -7,7 +7,6 @@ import (
 	"gorm.io/gorm"
 )
 
-// TODO tambahkan model alamat untuk shipping product ecommerce
 // User represents the main user table
 type User struct {
 	ID             uuid.UUID `gorm:"type:char(36);primary_key;default:(UUID())" json:"id"`
@@ -89,10 +88,13 @@ type Driver struct {
 	ReviewCount     int       `gorm:"default:0" json:"review_count"`
 	TotalDeliveries int       `gorm:"default:0" json:"total_deliveries"`
 	CreatedAt       time.Time `json:"created_at"`
-	Distance        float64   `gorm:"-" json:"distance"`
+	Distance        float64   `gorm:"->;-:migration" json:"distance"` // hanya dibaca dari query FindNearby
 
 	CurrentLat *float64 `gorm:"type:decimal(10,8)"`
 	CurrentLng *float64 `gorm:"type:decimal(11,8)"`
+	// Presence: driver dianggap tersedia jika online dan heartbeat terakhir masih baru
+	IsOnline   bool       `gorm:"default:false;index" json:"is_online"`
+	LastSeenAt *time.Time `gorm:"index" json:"last_seen_at"`
 	// Relationships
 	User         User          `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
 	Deliveries   []Delivery    `gorm:"foreignKey:DriverID"`
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Jenis potongan voucher.
+const (
+	VoucherTypePercentage   = "percentage"    // persen dari belanja, bisa dibatasi MaxDiscount
+	VoucherTypeFixed        = "fixed"         // potongan nominal tetap
+	VoucherTypeFreeShipping = "free_shipping" // menanggung ongkos kirim pesanan
+)
+
+// Penanggung biaya promo.
+const (
+	VoucherFundedByPlatform = "platform" // petani tetap menerima harga penuh, selisihnya biaya platform
+	VoucherFundedByFarmer   = "farmer"   // potongan mengurangi pendapatan petani
+)
+
+// Status pemakaian voucher. Pemakaian dipegang (reserved) sejak checkout dan baru
+// dianggap terpakai (redeemed) setelah pembayaran sukses; pembayaran gagal/kedaluwarsa
+// mengembalikan kuotanya (released).
+const (
+	VoucherRedemptionReserved = "reserved"
+	VoucherRedemptionRedeemed = "redeemed"
+	VoucherRedemptionReleased = "released"
+)
+
+// Voucher adalah kode promo yang dipakai pembeli saat checkout. Voucher dengan FarmerID
+// hanya berlaku untuk produk petani tersebut (voucher toko); tanpa FarmerID berlaku
+// untuk seluruh keranjang.
+type Voucher struct {
+	ID           uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	Code         string     `gorm:"type:varchar(32);not null;uniqueIndex" json:"code"`
+	Name         string     `gorm:"type:varchar(100);not null" json:"name"`
+	Description  *string    `gorm:"type:varchar(255)" json:"description"`
+	DiscountType string     `gorm:"type:enum('percentage','fixed','free_shipping');not null" json:"discount_type"`
+	Value        float64    `gorm:"type:decimal(12,2);not null;default:0" json:"value"` // Persen, nominal, atau batas ongkir (0 = gratis penuh)
+	MaxDiscount  *float64   `gorm:"type:decimal(12,2)" json:"max_discount"`             // Batas potongan voucher persen
+	MinSpend     float64    `gorm:"type:decimal(12,2);not null;default:0" json:"min_spend"`
+	FundedBy     string     `gorm:"type:enum('platform','farmer');not null" json:"funded_by"`
+	FarmerID     *uuid.UUID `gorm:"type:char(36);index" json:"farmer_id"`
+	UsageLimit   *int       `json:"usage_limit"`                              // Kuota total; nil = tanpa batas
+	PerUserLimit int        `gorm:"not null;default:1" json:"per_user_limit"` // 0 = tanpa batas
+	UsedCount    int        `gorm:"not null;default:0" json:"used_count"`     // Termasuk pemakaian yang menunggu pembayaran
+	StartsAt     time.Time  `gorm:"not null" json:"starts_at"`
+	EndsAt       time.Time  `gorm:"not null;index" json:"ends_at"`
+	IsActive     bool       `gorm:"not null;default:true" json:"is_active"`
+	CreatedBy    uuid.UUID  `gorm:"type:char(36);not null" json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (v *Voucher) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// NormalizeVoucherCode menyeragamkan kode voucher agar pencarian tidak peka huruf besar/kecil.
+func NormalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CheckAvailable memastikan voucher aktif, dalam masa berlaku, dan kuotanya masih ada.
+func (v *Voucher) CheckAvailable(now time.Time) error {
+	if !v.IsActive {
+		return errors.New("invalid voucher: voucher is not active")
+	}
+	if now.Before(v.StartsAt) {
+		return errors.New("invalid voucher: voucher is not yet valid")
+	}
+	if now.After(v.EndsAt) {
+		return errors.New("invalid voucher: voucher has expired")
+	}
+	if v.UsageLimit != nil && v.UsedCount >= *v.UsageLimit {
+		return errors.New("invalid voucher: voucher usage limit has been reached")
+	}
+	return nil
+}
+
+// Discount menghitung total potongan untuk belanja yang memenuhi syarat voucher.
+// Potongan barang tidak melebihi subtotal, potongan ongkir tidak melebihi ongkirnya.
+// Potongan dibulatkan ke rupiah penuh karena Midtrans hanya menerima nominal bulat.
+func (v *Voucher) Discount(subTotal, shippingFee float64) float64 {
+	var discount float64
+	switch v.DiscountType {
+	case VoucherTypePercentage:
+		discount = subTotal * v.Value / 100
+		if v.MaxDiscount != nil && discount > *v.MaxDiscount {
+			discount = *v.MaxDiscount
+		}
+	case VoucherTypeFixed:
+		discount = math.Min(v.Value, subTotal)
+	case VoucherTypeFreeShipping:
+		discount = shippingFee
+		if v.Value > 0 && discount > v.Value {
+			discount = v.Value
+		}
+	}
+	return math.Round(discount)
+}
+
+// VoucherRedemption mencatat pemakaian voucher pada satu pembayaran beserta pembagian
+// biayanya antara platform dan petani.
+type VoucherRedemption struct {
+	ID             uuid.UUID  `gorm:"type:char(36);primary_key" json:"id"`
+	VoucherID      uuid.UUID  `gorm:"type:char(36);not null;index" json:"voucher_id"`
+	UserID         uuid.UUID  `gorm:"type:char(36);not null;index" json:"user_id"`
+	PaymentID      uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex" json:"payment_id"`
+	Status         string     `gorm:"type:enum('reserved','redeemed','released');not null;default:'reserved';index" json:"status"`
+	DiscountAmount float64    `gorm:"type:decimal(12,2);not null" json:"discount_amount"`
+	PlatformFunded float64    `gorm:"type:decimal(12,2);not null;default:0" json:"platform_funded"`
+	FarmerFunded   float64    `gorm:"type:decimal(12,2);not null;default:0" json:"farmer_funded"`
+	RedeemedAt     *time.Time `json:"redeemed_at"`
+	ReleasedAt     *time.Time `json:"released_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	Voucher *Voucher          `gorm:"foreignKey:VoucherID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	User    *User             `gorm:"foreignKey:UserID" json:"-"`
+	Payment *ECommercePayment `gorm:"foreignKey:PaymentID" json:"-"`
+}
+
+func (r *VoucherRedemption) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
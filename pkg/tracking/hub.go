@@ -0,0 +1,91 @@
+package tracking
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Jenis event yang dikirim ke pemantau pengiriman.
+const (
+	EventSnapshot = "snapshot"
+	EventLocation = "location"
+	EventStatus   = "status"
+	EventETA      = "eta"
+	EventTimeline = "timeline" // event riwayat tanpa perubahan status (mis. peringatan terlambat)
+)
+
+// subscriberBuffer adalah jumlah event yang boleh tertahan per pemantau
+// sebelum event berikutnya dibuang (klien lambat tidak boleh menahan publisher).
+const subscriberBuffer = 32
+
+// Event adalah satu pembaruan untuk sebuah pengiriman.
+type Event struct {
+	Type       string    `json:"type"`
+	DeliveryID uuid.UUID `json:"delivery_id"`
+	Data       any       `json:"data"`
+	At         time.Time `json:"at"`
+}
+
+// Hub mendistribusikan event pengiriman ke semua pemantau (WebSocket/SSE) per DeliveryID.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[uuid.UUID]map[chan Event]struct{})}
+}
+
+var defaultHub = NewHub()
+
+// Default mengembalikan hub bersama yang dipakai seluruh aplikasi.
+func Default() *Hub {
+	return defaultHub
+}
+
+// Subscribe mendaftarkan pemantau untuk sebuah pengiriman. Panggil fungsi
+// yang dikembalikan untuk berhenti berlangganan.
+func (h *Hub) Subscribe(deliveryID uuid.UUID) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[deliveryID] == nil {
+		h.subscribers[deliveryID] = make(map[chan Event]struct{})
+	}
+	h.subscribers[deliveryID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[deliveryID], ch)
+			if len(h.subscribers[deliveryID]) == 0 {
+				delete(h.subscribers, deliveryID)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// Publish mengirim event ke semua pemantau pengiriman tanpa pernah memblokir.
+func (h *Hub) Publish(event Event) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subscribers[event.DeliveryID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("WARN: tracking subscriber for delivery %s is too slow, dropping %s event", event.DeliveryID, event.Type)
+		}
+	}
+}
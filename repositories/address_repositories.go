@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+)
+
+type AddressRepository interface {
+	Create(tx *gorm.DB, address *models.Address) error
+	Update(tx *gorm.DB, address *models.Address) error
+	Delete(tx *gorm.DB, address *models.Address) error
+	FindByID(id uuid.UUID) (*models.Address, error)
+	FindAllByUserID(userID uuid.UUID) ([]models.Address, error)
+	FindDefaultByUserID(tx *gorm.DB, userID uuid.UUID) (*models.Address, error)
+	ClearDefault(tx *gorm.DB, userID uuid.UUID) error
+}
+
+type addressRepository struct{ db *gorm.DB }
+
+func NewAddressRepository(db *gorm.DB) AddressRepository {
+	return &addressRepository{db: db}
+}
+
+func (r *addressRepository) Create(tx *gorm.DB, address *models.Address) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(address).Error
+}
+
+func (r *addressRepository) Update(tx *gorm.DB, address *models.Address) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Save(address).Error
+}
+
+func (r *addressRepository) Delete(tx *gorm.DB, address *models.Address) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Delete(address).Error
+}
+
+func (r *addressRepository) FindByID(id uuid.UUID) (*models.Address, error) {
+	var address models.Address
+	err := r.db.Where("id = ?", id).First(&address).Error
+	return &address, err
+}
+
+// FindAllByUserID mengambil buku alamat dengan alamat utama di urutan pertama.
+func (r *addressRepository) FindAllByUserID(userID uuid.UUID) ([]models.Address, error) {
+	var addresses []models.Address
+	err := r.db.Where("user_id = ?", userID).Order("is_default DESC, created_at DESC").Find(&addresses).Error
+	return addresses, err
+}
+
+// FindDefaultByUserID mengambil alamat utama; jika tidak ada, alamat terbaru.
+func (r *addressRepository) FindDefaultByUserID(tx *gorm.DB, userID uuid.UUID) (*models.Address, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var address models.Address
+	err := tx.Where("user_id = ?", userID).Order("is_default DESC, created_at DESC").First(&address).Error
+	return &address, err
+}
+
+func (r *addressRepository) ClearDefault(tx *gorm.DB, userID uuid.UUID) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.Address{}).Where("user_id = ? AND is_default = ?", userID, true).
+		Update("is_default", false).Error
+}
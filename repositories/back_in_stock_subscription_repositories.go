@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BackInStockSubscriptionRepository interface {
+	Save(subscription *models.BackInStockSubscription) error
+	FindByUserAndProduct(userID, productID uuid.UUID) (*models.BackInStockSubscription, error)
+	Delete(userID, productID uuid.UUID) error
+	FindRestockedPending(limit int) ([]models.BackInStockSubscription, error)
+	MarkNotified(ids []uuid.UUID, notifiedAt time.Time) error
+}
+
+type backInStockSubscriptionRepository struct{ db *gorm.DB }
+
+func NewBackInStockSubscriptionRepository(db *gorm.DB) BackInStockSubscriptionRepository {
+	return &backInStockSubscriptionRepository{db: db}
+}
+
+func (r *backInStockSubscriptionRepository) Save(subscription *models.BackInStockSubscription) error {
+	return r.db.Omit(clause.Associations).Save(subscription).Error
+}
+
+func (r *backInStockSubscriptionRepository) FindByUserAndProduct(userID, productID uuid.UUID) (*models.BackInStockSubscription, error) {
+	var subscription models.BackInStockSubscription
+	err := r.db.Where("user_id = ? AND product_id = ?", userID, productID).First(&subscription).Error
+	return &subscription, err
+}
+
+func (r *backInStockSubscriptionRepository) Delete(userID, productID uuid.UUID) error {
+	result := r.db.Where("user_id = ? AND product_id = ?", userID, productID).Delete(&models.BackInStockSubscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindRestockedPending mengambil langganan aktif yang produknya kini punya stok tersedia.
+func (r *backInStockSubscriptionRepository) FindRestockedPending(limit int) ([]models.BackInStockSubscription, error) {
+	var subscriptions []models.BackInStockSubscription
+	err := r.db.Preload("Product").
+		Where("notified_at IS NULL").
+		Where("EXISTS (SELECT 1 FROM product_variants pv WHERE pv.product_id = back_in_stock_subscriptions.product_id AND pv.stock - pv.reserved_stock > 0)").
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *backInStockSubscriptionRepository) MarkNotified(ids []uuid.UUID, notifiedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&models.BackInStockSubscription{}).Where("id IN ?", ids).Update("notified_at", notifiedAt).Error
+}
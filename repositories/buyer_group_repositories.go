@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BuyerGroupRepository interface {
+	Create(group *models.BuyerGroup) error
+	Update(group *models.BuyerGroup) error
+	FindByID(id uuid.UUID) (*models.BuyerGroup, error)
+	FindByName(name string) (*models.BuyerGroup, error)
+	FindAll() ([]models.BuyerGroup, error)
+	CountMembers(groupID uuid.UUID) (int64, error)
+	FindMembers(groupID uuid.UUID) ([]models.BuyerGroupMember, error)
+	SaveMember(member *models.BuyerGroupMember) error
+	FindMemberByUserID(userID uuid.UUID) (*models.BuyerGroupMember, error)
+	RemoveMember(groupID, userID uuid.UUID) error
+	FindActiveGroupIDByUserID(tx *gorm.DB, userID uuid.UUID) (*uuid.UUID, error)
+}
+
+type buyerGroupRepository struct{ db *gorm.DB }
+
+func NewBuyerGroupRepository(db *gorm.DB) BuyerGroupRepository {
+	return &buyerGroupRepository{db: db}
+}
+
+func (r *buyerGroupRepository) Create(group *models.BuyerGroup) error {
+	return r.db.Create(group).Error
+}
+
+func (r *buyerGroupRepository) Update(group *models.BuyerGroup) error {
+	return r.db.Save(group).Error
+}
+
+func (r *buyerGroupRepository) FindByID(id uuid.UUID) (*models.BuyerGroup, error) {
+	var group models.BuyerGroup
+	err := r.db.Where("id = ?", id).First(&group).Error
+	return &group, err
+}
+
+func (r *buyerGroupRepository) FindByName(name string) (*models.BuyerGroup, error) {
+	var group models.BuyerGroup
+	err := r.db.Where("name = ?", name).First(&group).Error
+	return &group, err
+}
+
+func (r *buyerGroupRepository) FindAll() ([]models.BuyerGroup, error) {
+	var groups []models.BuyerGroup
+	err := r.db.Order("name ASC").Find(&groups).Error
+	return groups, err
+}
+
+func (r *buyerGroupRepository) CountMembers(groupID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.BuyerGroupMember{}).Where("buyer_group_id = ?", groupID).Count(&count).Error
+	return count, err
+}
+
+func (r *buyerGroupRepository) FindMembers(groupID uuid.UUID) ([]models.BuyerGroupMember, error) {
+	var members []models.BuyerGroupMember
+	err := r.db.Preload("User").Where("buyer_group_id = ?", groupID).Order("created_at ASC").Find(&members).Error
+	return members, err
+}
+
+// SaveMember membuat atau memindahkan keanggotaan pembeli (satu pembeli satu grup).
+func (r *buyerGroupRepository) SaveMember(member *models.BuyerGroupMember) error {
+	return r.db.Omit(clause.Associations).Save(member).Error
+}
+
+func (r *buyerGroupRepository) FindMemberByUserID(userID uuid.UUID) (*models.BuyerGroupMember, error) {
+	var member models.BuyerGroupMember
+	err := r.db.Where("user_id = ?", userID).First(&member).Error
+	return &member, err
+}
+
+func (r *buyerGroupRepository) RemoveMember(groupID, userID uuid.UUID) error {
+	result := r.db.Where("buyer_group_id = ? AND user_id = ?", groupID, userID).Delete(&models.BuyerGroupMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindActiveGroupIDByUserID mengembalikan grup aktif pembeli, atau nil bila tidak punya.
+func (r *buyerGroupRepository) FindActiveGroupIDByUserID(tx *gorm.DB, userID uuid.UUID) (*uuid.UUID, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var groupIDs []uuid.UUID
+	err := tx.Model(&models.BuyerGroupMember{}).
+		Joins("JOIN buyer_groups ON buyer_groups.id = buyer_group_members.buyer_group_id").
+		Where("buyer_group_members.user_id = ? AND buyer_groups.is_active = ?", userID, true).
+		Limit(1).Pluck("buyer_group_members.buyer_group_id", &groupIDs).Error
+	if err != nil || len(groupIDs) == 0 {
+		return nil, err
+	}
+	return &groupIDs[0], nil
+}
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/whsasmita/AgroLink_API/models"
 	"gorm.io/gorm"
@@ -8,13 +10,15 @@ import (
 
 type CartRepository interface {
 	FindByUserID(userID uuid.UUID) ([]models.Cart, error)
-	FindByUserAndProduct(userID, productID uuid.UUID) (*models.Cart, error)
+	FindByUserAndVariant(userID, variantID uuid.UUID) (*models.Cart, error)
 	FindByUserIDWithTx(tx *gorm.DB, userID uuid.UUID) ([]models.Cart, error)
-	FindByUserAndProductWithTx(tx *gorm.DB, userID, productID uuid.UUID) (*models.Cart, error) // Baru
+	FindByUserAndVariantWithTx(tx *gorm.DB, userID, variantID uuid.UUID) (*models.Cart, error) // Baru
 	Create(tx *gorm.DB, cartItem *models.Cart) error
 	Update(tx *gorm.DB, cartItem *models.Cart) error
-	Delete(tx *gorm.DB, userID, productID uuid.UUID) error
+	Delete(tx *gorm.DB, userID, variantID uuid.UUID) error
 	ClearCart(tx *gorm.DB, userID uuid.UUID) error // <-- [BARU]
+	FindByIDWithTx(tx *gorm.DB, id uuid.UUID) (*models.Cart, error)
+	FindExpiredHolds(now time.Time, limit int) ([]models.Cart, error)
 }
 
 type cartRepository struct{ db *gorm.DB }
@@ -25,27 +29,27 @@ func NewCartRepository(db *gorm.DB) CartRepository {
 
 func (r *cartRepository) FindByUserID(userID uuid.UUID) ([]models.Cart, error) {
 	var cartItems []models.Cart
-	err := r.db.Preload("Product").Where("user_id = ?", userID).Find(&cartItems).Error
+	err := r.db.Preload("Product").Preload("Variant").Where("user_id = ?", userID).Find(&cartItems).Error
 	return cartItems, err
 }
 
 func (r *cartRepository) FindByUserIDWithTx(tx *gorm.DB, userID uuid.UUID) ([]models.Cart, error) {
 	var cartItems []models.Cart
 	// Preload Product sangat penting di sini untuk validasi stok dan pengelompokan
-	err := tx.Preload("Product").Where("user_id = ?", userID).Find(&cartItems).Error
+	err := tx.Preload("Product").Preload("Variant").Where("user_id = ?", userID).Find(&cartItems).Error
 	return cartItems, err
 }
 
-func (r *cartRepository) FindByUserAndProduct(userID, productID uuid.UUID) (*models.Cart, error) {
+func (r *cartRepository) FindByUserAndVariant(userID, variantID uuid.UUID) (*models.Cart, error) {
 	var cartItem models.Cart
-	err := r.db.Where("user_id = ? AND product_id = ?", userID, productID).First(&cartItem).Error
+	err := r.db.Where("user_id = ? AND variant_id = ?", userID, variantID).First(&cartItem).Error
 	return &cartItem, err
 }
 
 // [BARU] Versi transaksional
-func (r *cartRepository) FindByUserAndProductWithTx(tx *gorm.DB, userID, productID uuid.UUID) (*models.Cart, error) {
+func (r *cartRepository) FindByUserAndVariantWithTx(tx *gorm.DB, userID, variantID uuid.UUID) (*models.Cart, error) {
 	var cartItem models.Cart
-	err := tx.Where("user_id = ? AND product_id = ?", userID, productID).First(&cartItem).Error
+	err := tx.Where("user_id = ? AND variant_id = ?", userID, variantID).First(&cartItem).Error
 	return &cartItem, err
 }
 
@@ -57,10 +61,24 @@ func (r *cartRepository) Update(tx *gorm.DB, cartItem *models.Cart) error {
 	return tx.Save(cartItem).Error
 }
 
-func (r *cartRepository) Delete(tx *gorm.DB, userID, productID uuid.UUID) error {
-	return tx.Where("user_id = ? AND product_id = ?", userID, productID).Delete(&models.Cart{}).Error
+func (r *cartRepository) Delete(tx *gorm.DB, userID, variantID uuid.UUID) error {
+	return tx.Where("user_id = ? AND variant_id = ?", userID, variantID).Delete(&models.Cart{}).Error
 }
 
 func (r *cartRepository) ClearCart(tx *gorm.DB, userID uuid.UUID) error {
 	return tx.Where("user_id = ?", userID).Delete(&models.Cart{}).Error
-}
\ No newline at end of file
+}
+
+func (r *cartRepository) FindByIDWithTx(tx *gorm.DB, id uuid.UUID) (*models.Cart, error) {
+	var cartItem models.Cart
+	err := tx.Where("id = ?", id).First(&cartItem).Error
+	return &cartItem, err
+}
+
+// FindExpiredHolds mengambil item keranjang yang masih menahan stok setelah batas waktunya.
+func (r *cartRepository) FindExpiredHolds(now time.Time, limit int) ([]models.Cart, error) {
+	var cartItems []models.Cart
+	err := r.db.Where("held_quantity > 0 AND hold_expires_at < ?", now).
+		Order("hold_expires_at ASC").Limit(limit).Find(&cartItems).Error
+	return cartItems, err
+}
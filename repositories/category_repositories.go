@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+)
+
+// CategoryProductCount adalah jumlah produk yang langsung terhubung ke satu kategori.
+type CategoryProductCount struct {
+	CategoryID uuid.UUID
+	Count      int64
+}
+
+type CategoryRepository interface {
+	Create(tx *gorm.DB, category *models.Category) error
+	Update(tx *gorm.DB, category *models.Category) error
+	Delete(tx *gorm.DB, category *models.Category) error
+	FindByID(id uuid.UUID) (*models.Category, error)
+	FindBySlug(slug string) (*models.Category, error)
+	FindAll() ([]models.Category, error)
+	CountChildren(id uuid.UUID) (int64, error)
+	CountProducts(id uuid.UUID) (int64, error)
+	CountProductsPerCategory() ([]CategoryProductCount, error)
+}
+
+type categoryRepository struct{ db *gorm.DB }
+
+func NewCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+func (r *categoryRepository) Create(tx *gorm.DB, category *models.Category) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(category).Error
+}
+
+func (r *categoryRepository) Update(tx *gorm.DB, category *models.Category) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Save(category).Error
+}
+
+func (r *categoryRepository) Delete(tx *gorm.DB, category *models.Category) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Delete(category).Error
+}
+
+func (r *categoryRepository) FindByID(id uuid.UUID) (*models.Category, error) {
+	var category models.Category
+	err := r.db.Where("id = ?", id).First(&category).Error
+	return &category, err
+}
+
+func (r *categoryRepository) FindBySlug(slug string) (*models.Category, error) {
+	var category models.Category
+	err := r.db.Where("slug = ?", slug).First(&category).Error
+	return &category, err
+}
+
+// FindAll mengambil seluruh kategori terurut sesuai sort_order untuk disusun menjadi pohon.
+func (r *categoryRepository) FindAll() ([]models.Category, error) {
+	var categories []models.Category
+	err := r.db.Order("sort_order ASC, name ASC").Find(&categories).Error
+	return categories, err
+}
+
+func (r *categoryRepository) CountChildren(id uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Category{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (r *categoryRepository) CountProducts(id uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Product{}).Where("category_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// CountProductsPerCategory menghitung produk per kategori (tanpa turunan); agregasi ke
+// kategori induk dilakukan di service.
+func (r *categoryRepository) CountProductsPerCategory() ([]CategoryProductCount, error) {
+	var counts []CategoryProductCount
+	err := r.db.Model(&models.Product{}).
+		Select("category_id, COUNT(*) AS count").
+		Where("category_id IS NOT NULL").
+		Group("category_id").
+		Scan(&counts).Error
+	return counts, err
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+)
+
+type DeliveryConditionRepository interface {
+	CreateReading(reading *models.DeliveryConditionReading) error
+	FindReadingsByDeliveryID(deliveryID uuid.UUID) ([]models.DeliveryConditionReading, error)
+
+	CreateDevice(device *models.ColdChainDevice) error
+	UpdateDevice(device *models.ColdChainDevice) error
+	FindDeviceByID(id uuid.UUID) (*models.ColdChainDevice, error)
+	FindDeviceByKeyHash(keyHash string) (*models.ColdChainDevice, error)
+	FindDevicesByFarmerID(farmerID uuid.UUID) ([]models.ColdChainDevice, error)
+	TouchDevice(id uuid.UUID, at time.Time) error
+}
+
+type deliveryConditionRepository struct{ db *gorm.DB }
+
+func NewDeliveryConditionRepository(db *gorm.DB) DeliveryConditionRepository {
+	return &deliveryConditionRepository{db: db}
+}
+
+func (r *deliveryConditionRepository) CreateReading(reading *models.DeliveryConditionReading) error {
+	return r.db.Create(reading).Error
+}
+
+// FindReadingsByDeliveryID mengambil seluruh pembacaan kondisi secara kronologis.
+func (r *deliveryConditionRepository) FindReadingsByDeliveryID(deliveryID uuid.UUID) ([]models.DeliveryConditionReading, error) {
+	var readings []models.DeliveryConditionReading
+	err := r.db.Where("delivery_id = ?", deliveryID).Order("recorded_at ASC").Find(&readings).Error
+	return readings, err
+}
+
+func (r *deliveryConditionRepository) CreateDevice(device *models.ColdChainDevice) error {
+	return r.db.Create(device).Error
+}
+
+func (r *deliveryConditionRepository) UpdateDevice(device *models.ColdChainDevice) error {
+	return r.db.Save(device).Error
+}
+
+func (r *deliveryConditionRepository) FindDeviceByID(id uuid.UUID) (*models.ColdChainDevice, error) {
+	var device models.ColdChainDevice
+	err := r.db.Where("id = ?", id).First(&device).Error
+	return &device, err
+}
+
+func (r *deliveryConditionRepository) FindDeviceByKeyHash(keyHash string) (*models.ColdChainDevice, error) {
+	var device models.ColdChainDevice
+	err := r.db.Where("key_hash = ?", keyHash).First(&device).Error
+	return &device, err
+}
+
+func (r *deliveryConditionRepository) FindDevicesByFarmerID(farmerID uuid.UUID) ([]models.ColdChainDevice, error) {
+	var devices []models.ColdChainDevice
+	err := r.db.Where("farmer_id = ?", farmerID).Order("created_at DESC").Find(&devices).Error
+	return devices, err
+}
+
+// TouchDevice mencatat waktu terakhir perangkat mengirim data tanpa memuat ulang barisnya.
+func (r *deliveryConditionRepository) TouchDevice(id uuid.UUID, at time.Time) error {
+	return r.db.Model(&models.ColdChainDevice{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DeliveryFailureRepository interface {
+	Create(tx *gorm.DB, failure *models.DeliveryFailure) error
+	Update(tx *gorm.DB, failure *models.DeliveryFailure) error
+	FindOpenByDeliveryID(deliveryID uuid.UUID) (*models.DeliveryFailure, error)
+	FindOpenByDeliveryIDForUpdate(tx *gorm.DB, deliveryID uuid.UUID) (*models.DeliveryFailure, error)
+	FindAllByDeliveryID(deliveryID uuid.UUID) ([]models.DeliveryFailure, error)
+}
+
+type deliveryFailureRepository struct{ db *gorm.DB }
+
+func NewDeliveryFailureRepository(db *gorm.DB) DeliveryFailureRepository {
+	return &deliveryFailureRepository{db: db}
+}
+
+func (r *deliveryFailureRepository) Create(tx *gorm.DB, failure *models.DeliveryFailure) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(failure).Error
+}
+
+func (r *deliveryFailureRepository) Update(tx *gorm.DB, failure *models.DeliveryFailure) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Save(failure).Error
+}
+
+// FindOpenByDeliveryID mengambil laporan kegagalan terbaru yang belum diputuskan petani.
+func (r *deliveryFailureRepository) FindOpenByDeliveryID(deliveryID uuid.UUID) (*models.DeliveryFailure, error) {
+	var failure models.DeliveryFailure
+	err := r.db.Where("delivery_id = ? AND resolution IS NULL", deliveryID).
+		Order("created_at DESC").First(&failure).Error
+	return &failure, err
+}
+
+// FindOpenByDeliveryIDForUpdate mengunci laporan kegagalan terbuka di dalam transaksi
+// agar satu laporan tidak diputuskan dua kali.
+func (r *deliveryFailureRepository) FindOpenByDeliveryIDForUpdate(tx *gorm.DB, deliveryID uuid.UUID) (*models.DeliveryFailure, error) {
+	var failure models.DeliveryFailure
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("delivery_id = ? AND resolution IS NULL", deliveryID).
+		Order("created_at DESC").First(&failure).Error
+	return &failure, err
+}
+
+func (r *deliveryFailureRepository) FindAllByDeliveryID(deliveryID uuid.UUID) ([]models.DeliveryFailure, error) {
+	var failures []models.DeliveryFailure
+	err := r.db.Where("delivery_id = ?", deliveryID).Order("created_at ASC").Find(&failures).Error
+	return failures, err
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DeliveryProofRepository interface {
+	Save(tx *gorm.DB, proof *models.DeliveryProof) error
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.DeliveryProof, error)
+	UpdateOTPAttempts(tx *gorm.DB, proof *models.DeliveryProof) error
+	FindByDeliveryID(deliveryID uuid.UUID) (*models.DeliveryProof, error)
+}
+
+type deliveryProofRepository struct{ db *gorm.DB }
+
+func NewDeliveryProofRepository(db *gorm.DB) DeliveryProofRepository {
+	return &deliveryProofRepository{db: db}
+}
+
+// Save tidak menulis otp_attempts; penghitung hanya diubah lewat UpdateOTPAttempts
+// agar salinan bukti yang usang tidak mengembalikan percobaan yang sudah tercatat.
+func (r *deliveryProofRepository) Save(tx *gorm.DB, proof *models.DeliveryProof) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Omit("otp_attempts").Save(proof).Error
+}
+
+// FindByIDForUpdate mengunci baris bukti di dalam transaksi agar verifikasi OTP
+// tidak berjalan bersamaan.
+func (r *deliveryProofRepository) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.DeliveryProof, error) {
+	var proof models.DeliveryProof
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&proof).Error
+	return &proof, err
+}
+
+// UpdateOTPAttempts hanya memperbarui penghitung percobaan OTP dan hash kode.
+func (r *deliveryProofRepository) UpdateOTPAttempts(tx *gorm.DB, proof *models.DeliveryProof) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.DeliveryProof{}).Where("id = ?", proof.ID).Updates(map[string]interface{}{
+		"otp_attempts": proof.OTPAttempts,
+		"otp_hash":     proof.OTPHash,
+	}).Error
+}
+
+func (r *deliveryProofRepository) FindByDeliveryID(deliveryID uuid.UUID) (*models.DeliveryProof, error) {
+	var proof models.DeliveryProof
+	err := r.db.Where("delivery_id = ? AND stop_id IS NULL", deliveryID).First(&proof).Error
+	return &proof, err
+}
+
+type DeliveryDisputeRepository interface {
+	Create(dispute *models.DeliveryDispute) error
+	Update(dispute *models.DeliveryDispute) error
+	FindByID(id uuid.UUID) (*models.DeliveryDispute, error)
+	FindAll(status string) ([]models.DeliveryDispute, error)
+	HasOpenDispute(deliveryID uuid.UUID) (bool, error)
+}
+
+type deliveryDisputeRepository struct{ db *gorm.DB }
+
+func NewDeliveryDisputeRepository(db *gorm.DB) DeliveryDisputeRepository {
+	return &deliveryDisputeRepository{db: db}
+}
+
+func (r *deliveryDisputeRepository) Create(dispute *models.DeliveryDispute) error {
+	return r.db.Create(dispute).Error
+}
+
+func (r *deliveryDisputeRepository) Update(dispute *models.DeliveryDispute) error {
+	return r.db.Omit("Delivery").Save(dispute).Error
+}
+
+func (r *deliveryDisputeRepository) FindByID(id uuid.UUID) (*models.DeliveryDispute, error) {
+	var dispute models.DeliveryDispute
+	err := r.db.Preload("Delivery.Proof", "stop_id IS NULL").Preload("Delivery.Stops.Proof").
+		Where("id = ?", id).First(&dispute).Error
+	return &dispute, err
+}
+
+// FindAll mengambil semua sengketa, opsional difilter berdasarkan status.
+func (r *deliveryDisputeRepository) FindAll(status string) ([]models.DeliveryDispute, error) {
+	var disputes []models.DeliveryDispute
+	query := r.db.Preload("Delivery.Proof", "stop_id IS NULL")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at DESC").Find(&disputes).Error
+	return disputes, err
+}
+
+func (r *deliveryDisputeRepository) HasOpenDispute(deliveryID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.DeliveryDispute{}).
+		Where("delivery_id = ? AND status NOT IN ?", deliveryID, []string{models.DisputeStatusResolved, models.DisputeStatusClosed}).
+		Count(&count).Error
+	return count > 0, err
+}
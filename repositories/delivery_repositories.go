@@ -4,19 +4,44 @@ import (
 	"github.com/google/uuid"
 	"github.com/whsasmita/AgroLink_API/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DeliveryRepository interface {
-	Create(delivery *models.Delivery) error
+	Create(tx *gorm.DB, delivery *models.Delivery) error
 	FindByID(id string) (*models.Delivery, error)
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.Delivery, error)
 	// [PERBAIKAN] Tambahkan *gorm.DB sebagai argumen
 	Update(tx *gorm.DB, delivery *models.Delivery) error
+	UpdateRouteEstimate(tx *gorm.DB, delivery *models.Delivery) error
+	UpdateConditionThresholds(tx *gorm.DB, delivery *models.Delivery) error
+	UpdateStatus(tx *gorm.DB, id uuid.UUID, status string) error
+	AssignDriver(tx *gorm.DB, delivery *models.Delivery) error
 	FindByContractID(contractID string) (*models.Delivery, error)
 	FindAllByUserID(userID uuid.UUID, role string) ([]models.Delivery, error)
 	CountActiveDeliveries() (int64, error)
+	CountActiveByDriverIDs(driverIDs []uuid.UUID) (map[uuid.UUID]int64, error)
+	CreateEvent(tx *gorm.DB, event *models.DeliveryEvent) error
+	FindEventsByDeliveryID(deliveryID string) ([]models.DeliveryEvent, error)
+	HasEvent(tx *gorm.DB, deliveryID uuid.UUID, eventType string) (bool, error)
+	UpdateStop(tx *gorm.DB, stop *models.DeliveryStop) error
+	UpdateLinkedOrders(tx *gorm.DB, deliveryID uuid.UUID, fromStatuses []string, updates map[string]interface{}) error
+}
 
+// inProgressStatuses adalah status ketika barang sedang dijemput atau diantar.
+var inProgressStatuses = []string{
+	models.DeliveryStatusPickupPending,
+	models.DeliveryStatusAtPickup,
+	models.DeliveryStatusPickedUp,
+	models.DeliveryStatusInTransit,
+	models.DeliveryStatusOutForDelivery,
+	models.DeliveryStatusArrived,
+	models.DeliveryStatusReturning,
 }
 
+// activeDriverStatuses adalah status di mana driver sedang terikat pada sebuah pengiriman.
+var activeDriverStatuses = append([]string{models.DeliveryStatusPendingPayment}, inProgressStatuses...)
+
 type deliveryRepository struct{ db *gorm.DB }
 
 func NewDeliveryRepository(db *gorm.DB) DeliveryRepository {
@@ -28,20 +53,87 @@ func (r *deliveryRepository) FindByContractID(contractID string) (*models.Delive
 	err := r.db.Where("contract_id = ?", contractID).First(&delivery).Error
 	return &delivery, err
 }
-func (r *deliveryRepository) Create(delivery *models.Delivery) error {
-	return r.db.Create(delivery).Error
+func (r *deliveryRepository) Create(tx *gorm.DB, delivery *models.Delivery) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(delivery).Error
 }
 
 func (r *deliveryRepository) FindByID(id string) (*models.Delivery, error) {
 	var delivery models.Delivery
-	err := r.db.Where("id = ?", id).First(&delivery).Error
+	err := r.db.Preload("Stops", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sequence ASC")
+	}).Preload("Stops.Proof").Preload("Proof", "stop_id IS NULL").Preload("Orders").
+		Where("id = ?", id).First(&delivery).Error
+	return &delivery, err
+}
+
+// FindByIDForUpdate mengunci baris delivery (tanpa relasi) agar pelepasan dana tidak diproses ganda.
+func (r *deliveryRepository) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.Delivery, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var delivery models.Delivery
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&delivery).Error
 	return &delivery, err
 }
 
 // [PERBAIKAN] Ubah fungsi untuk menerima dan menggunakan objek transaksi
 func (r *deliveryRepository) Update(tx *gorm.DB, delivery *models.Delivery) error {
 	// Gunakan 'tx' yang dioper dari service, bukan 'r.db'
-	return tx.Save(delivery).Error
+	if tx == nil {
+		tx = r.db
+	}
+	// Relasi (stops, bukti) dikelola oleh repository masing-masing
+	return tx.Omit(clause.Associations).Save(delivery).Error
+}
+
+// UpdateRouteEstimate hanya menyimpan kolom estimasi rute agar tidak menimpa status
+// yang mungkin diubah bersamaan.
+func (r *deliveryRepository) UpdateRouteEstimate(tx *gorm.DB, delivery *models.Delivery) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.Delivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"estimated_distance_km":      delivery.EstimatedDistanceKm,
+		"estimated_duration_minutes": delivery.EstimatedDurationMinutes,
+		"route_source":               delivery.RouteSource,
+	}).Error
+}
+
+// UpdateConditionThresholds hanya menyimpan ambang suhu dan kelembapan muatan.
+func (r *deliveryRepository) UpdateConditionThresholds(tx *gorm.DB, delivery *models.Delivery) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.Delivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"min_temperature_c": delivery.MinTemperatureC,
+		"max_temperature_c": delivery.MaxTemperatureC,
+		"max_humidity_pct":  delivery.MaxHumidityPct,
+	}).Error
+}
+
+// UpdateStatus hanya menyimpan kolom status; dipanggil setelah baris delivery dikunci.
+func (r *deliveryRepository) UpdateStatus(tx *gorm.DB, id uuid.UUID, status string) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.Delivery{}).Where("id = ?", id).Update("status", status).Error
+}
+
+// AssignDriver menyimpan driver terpilih beserta kontrak dan harga yang dikunci.
+func (r *deliveryRepository) AssignDriver(tx *gorm.DB, delivery *models.Delivery) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.Delivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"driver_id":     delivery.DriverID,
+		"contract_id":   delivery.ContractID,
+		"quoted_price":  delivery.QuotedPrice,
+		"quote_details": delivery.QuoteDetails,
+		"quoted_at":     delivery.QuotedAt,
+	}).Error
 }
 
 func (r *deliveryRepository) FindAllByUserID(userID uuid.UUID, role string) ([]models.Delivery, error) {
@@ -64,7 +156,74 @@ func (r *deliveryRepository) FindAllByUserID(userID uuid.UUID, role string) ([]m
 func (r *deliveryRepository) CountActiveDeliveries() (int64, error) {
 	var count int64
 	err := r.db.Model(&models.Delivery{}).
-		Where("status = ?", "in_transit").
+		Where("status IN ?", inProgressStatuses).
 		Count(&count).Error
 	return count, err
-}
\ No newline at end of file
+}
+
+func (r *deliveryRepository) CountActiveByDriverIDs(driverIDs []uuid.UUID) (map[uuid.UUID]int64, error) {
+	counts := make(map[uuid.UUID]int64)
+	if len(driverIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		DriverID uuid.UUID
+		Total    int64
+	}
+	err := r.db.Model(&models.Delivery{}).
+		Select("driver_id, COUNT(*) AS total").
+		Where("driver_id IN ? AND status IN ?", driverIDs, activeDriverStatuses).
+		Group("driver_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.DriverID] = row.Total
+	}
+	return counts, nil
+}
+
+func (r *deliveryRepository) CreateEvent(tx *gorm.DB, event *models.DeliveryEvent) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(event).Error
+}
+
+func (r *deliveryRepository) FindEventsByDeliveryID(deliveryID string) ([]models.DeliveryEvent, error) {
+	var events []models.DeliveryEvent
+	err := r.db.Where("delivery_id = ?", deliveryID).Order("created_at ASC").Find(&events).Error
+	return events, err
+}
+
+// HasEvent memeriksa apakah delivery sudah memiliki event dengan jenis tertentu.
+func (r *deliveryRepository) HasEvent(tx *gorm.DB, deliveryID uuid.UUID, eventType string) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var count int64
+	err := tx.Model(&models.DeliveryEvent{}).
+		Where("delivery_id = ? AND event_type = ?", deliveryID, eventType).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *deliveryRepository) UpdateStop(tx *gorm.DB, stop *models.DeliveryStop) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Omit(clause.Associations).Save(stop).Error
+}
+
+// UpdateLinkedOrders memperbarui pesanan e-commerce yang diantar oleh delivery ini,
+// hanya untuk pesanan yang statusnya masih termasuk fromStatuses.
+func (r *deliveryRepository) UpdateLinkedOrders(tx *gorm.DB, deliveryID uuid.UUID, fromStatuses []string, updates map[string]interface{}) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(&models.Order{}).
+		Where("delivery_id = ? AND status IN ?", deliveryID, fromStatuses).
+		Updates(updates).Error
+}
@@ -1,12 +1,19 @@
 package repositories
 
 import (
+	"github.com/google/uuid"
 	"github.com/whsasmita/AgroLink_API/models"
 	"gorm.io/gorm"
 )
 
 type DriverRouteRepository interface {
 	Create(route *models.DriverRoute) error
+	Update(route *models.DriverRoute) error
+	Delete(id uuid.UUID) error
+	FindByID(id uuid.UUID) (*models.DriverRoute, error)
+	FindAllByDriverID(driverID uuid.UUID) ([]models.DriverRoute, error)
+	// weekday bernilai nil berarti tanpa filter hari
+	Search(origin, destination string, weekday *int) ([]models.DriverRoute, error)
 }
 
 type driverRouteRepository struct{ db *gorm.DB }
@@ -17,4 +24,42 @@ func NewDriverRouteRepository(db *gorm.DB) DriverRouteRepository {
 
 func (r *driverRouteRepository) Create(route *models.DriverRoute) error {
 	return r.db.Create(route).Error
-}
\ No newline at end of file
+}
+
+func (r *driverRouteRepository) Update(route *models.DriverRoute) error {
+	return r.db.Omit("Driver").Save(route).Error
+}
+
+func (r *driverRouteRepository) Delete(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&models.DriverRoute{}).Error
+}
+
+func (r *driverRouteRepository) FindByID(id uuid.UUID) (*models.DriverRoute, error) {
+	var route models.DriverRoute
+	err := r.db.Where("id = ?", id).First(&route).Error
+	return &route, err
+}
+
+func (r *driverRouteRepository) FindAllByDriverID(driverID uuid.UUID) ([]models.DriverRoute, error) {
+	var routes []models.DriverRoute
+	err := r.db.Where("driver_id = ?", driverID).Order("created_at DESC").Find(&routes).Error
+	return routes, err
+}
+
+func (r *driverRouteRepository) Search(origin, destination string, weekday *int) ([]models.DriverRoute, error) {
+	var routes []models.DriverRoute
+	query := r.db.Preload("Driver.User").Where("is_active = ?", true)
+
+	if origin != "" {
+		query = query.Where("origin LIKE ?", "%"+origin+"%")
+	}
+	if destination != "" {
+		query = query.Where("destination LIKE ?", "%"+destination+"%")
+	}
+	if weekday != nil {
+		query = query.Where("FIND_IN_SET(?, weekdays) > 0", *weekday)
+	}
+
+	err := query.Order("origin ASC, destination ASC").Find(&routes).Error
+	return routes, err
+}
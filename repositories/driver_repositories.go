@@ -4,6 +4,7 @@ package repositories
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/whsasmita/AgroLink_API/models"
@@ -14,10 +15,14 @@ type DriverRepository interface {
 	GetDrivers(sortBy, order string, limit, offset int) ([]models.Driver, int64, error)
 	GetDriverByID(id string) (models.Driver, error)
 	FindNearby(lat, lng float64, radius int) ([]models.Driver, error)
+	UpdatePresence(driverID uuid.UUID, online bool, lat, lng *float64) error
 	UpdateRating(tx *gorm.DB, driverID uuid.UUID, newRating float64, reviewCount int) error
 	// Anda bisa menambahkan method pencarian yang lebih spesifik nanti
 }
 
+// DriverPresenceTTL adalah batas umur heartbeat sebelum driver dianggap tidak aktif.
+const DriverPresenceTTL = 5 * time.Minute
+
 type driverRepository struct {
 	db *gorm.DB
 }
@@ -86,8 +91,11 @@ func (r *driverRepository) FindNearby(lat, lng float64, radius int) ([]models.Dr
 
 	err := r.db.
 		Preload("User"). // <-- [TAMBAHAN] Muat data User untuk mendapatkan nama driver
+		Preload("DriverRoutes").
 		Select(fmt.Sprintf("*, %s AS distance", haversine)).
 		Where(fmt.Sprintf("%s <= ?", haversine), radius).
+		// Hanya driver yang online dan heartbeat-nya belum kedaluwarsa
+		Where("is_online = ? AND last_seen_at >= ?", true, time.Now().Add(-DriverPresenceTTL)).
 		Order("distance ASC").
 		Find(&drivers).Error
 
@@ -99,4 +107,25 @@ func (r *driverRepository) UpdateRating(tx *gorm.DB, driverID uuid.UUID, newRati
         "rating":       newRating,
         "review_count": reviewCount,
     }).Error
-}
\ No newline at end of file
+}
+
+// UpdatePresence memperbarui status online, waktu terakhir terlihat dan posisi driver.
+// Posisi hanya diubah jika lat/lng diisi.
+func (r *driverRepository) UpdatePresence(driverID uuid.UUID, online bool, lat, lng *float64) error {
+	updates := map[string]interface{}{
+		"is_online":    online,
+		"last_seen_at": time.Now(),
+	}
+	if lat != nil && lng != nil {
+		updates["current_lat"] = *lat
+		updates["current_lng"] = *lng
+	}
+	result := r.db.Model(&models.Driver{}).Where("user_id = ?", driverID).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("driver with ID %s not found", driverID)
+	}
+	return nil
+}
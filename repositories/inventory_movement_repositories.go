@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+)
+
+// InventoryBalance adalah saldo stok sebuah varian yang dihitung ulang dari ledger.
+type InventoryBalance struct {
+	VariantID     uuid.UUID
+	StockTotal    float64
+	ReservedTotal float64
+}
+
+// InventoryMovementRepository sengaja tidak menyediakan Update/Delete: ledger hanya bisa ditambah.
+type InventoryMovementRepository interface {
+	Create(tx *gorm.DB, movement *models.InventoryMovement) error
+	FindByProduct(productID uuid.UUID, filter dto.StockMovementQuery) ([]models.InventoryMovement, int64, error)
+	SumByProductID(productID uuid.UUID) ([]InventoryBalance, error)
+}
+
+type inventoryMovementRepository struct{ db *gorm.DB }
+
+func NewInventoryMovementRepository(db *gorm.DB) InventoryMovementRepository {
+	return &inventoryMovementRepository{db: db}
+}
+
+func (r *inventoryMovementRepository) Create(tx *gorm.DB, movement *models.InventoryMovement) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(movement).Error
+}
+
+func (r *inventoryMovementRepository) FindByProduct(productID uuid.UUID, filter dto.StockMovementQuery) ([]models.InventoryMovement, int64, error) {
+	var movements []models.InventoryMovement
+	var total int64
+
+	query := r.db.Model(&models.InventoryMovement{}).Where("product_id = ?", productID)
+	if filter.VariantID != "" {
+		query = query.Where("variant_id = ?", filter.VariantID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	err := query.Order("created_at DESC").Offset(offset).Limit(filter.Limit).Find(&movements).Error
+	return movements, total, err
+}
+
+// SumByProductID menjumlahkan seluruh delta ledger per varian produk.
+func (r *inventoryMovementRepository) SumByProductID(productID uuid.UUID) ([]InventoryBalance, error) {
+	var balances []InventoryBalance
+	err := r.db.Model(&models.InventoryMovement{}).
+		Select("variant_id, COALESCE(SUM(stock_delta), 0) AS stock_total, COALESCE(SUM(reserved_delta), 0) AS reserved_total").
+		Where("product_id = ?", productID).
+		Group("variant_id").
+		Scan(&balances).Error
+	return balances, err
+}
@@ -12,6 +12,7 @@ type InvoiceRepository interface {
 	FindFirstPending() (*models.Invoice, error)
 	UpdateStatus(id string, status string) error
 	FindByDeliveryID(deliveryID string) (*models.Invoice, error)
+	FindAllByDeliveryID(deliveryID string) ([]models.Invoice, error)
 }
 
 type invoiceRepository struct{ db *gorm.DB }
@@ -21,7 +22,10 @@ func NewInvoiceRepository(db *gorm.DB) InvoiceRepository {
 }
 
 func (r *invoiceRepository) Create(tx *gorm.DB, invoice *models.Invoice) error {
-	return r.db.Create(invoice).Error
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(invoice).Error
 }
 
 func (r *invoiceRepository) FindByID(id string) (*models.Invoice, error) {
@@ -46,8 +50,16 @@ func (r *invoiceRepository) FindFirstPending() (*models.Invoice, error) {
 	return &invoice, err
 }
 
+// FindByDeliveryID mengambil tagihan jasa utama sebuah pengiriman.
 func (r *invoiceRepository) FindByDeliveryID(deliveryID string) (*models.Invoice, error) {
 	var invoice models.Invoice
-	err := r.db.Where("delivery_id = ?", deliveryID).First(&invoice).Error
+	err := r.db.Where("delivery_id = ? AND purpose = ?", deliveryID, models.InvoicePurposeService).First(&invoice).Error
 	return &invoice, err
+}
+
+// FindAllByDeliveryID mengambil semua tagihan pengiriman, termasuk biaya tambahan.
+func (r *invoiceRepository) FindAllByDeliveryID(deliveryID string) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	err := r.db.Where("delivery_id = ?", deliveryID).Order("created_at ASC").Find(&invoices).Error
+	return invoices, err
 }
\ No newline at end of file
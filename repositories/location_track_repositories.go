@@ -1,6 +1,9 @@
 package repositories
 
 import (
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/whsasmita/AgroLink_API/models"
 	"gorm.io/gorm"
 )
@@ -8,6 +11,11 @@ import (
 type LocationTrackRepository interface {
 	Create(track *models.LocationTrack) error
 	FindLatestByDeliveryID(deliveryID string) (*models.LocationTrack, error)
+	CreateBatch(tracks []models.LocationTrack) error
+	FindAllByDeliveryID(deliveryID string) ([]models.LocationTrack, error)
+	FindBetween(deliveryID uuid.UUID, from, to time.Time) ([]models.LocationTrack, error)
+	FindLastBefore(deliveryID uuid.UUID, before time.Time) (*models.LocationTrack, error)
+	FindTimestampsBetween(deliveryID uuid.UUID, from, to time.Time) ([]time.Time, error)
 }
 
 type locationTrackRepository struct{ db *gorm.DB }
@@ -24,4 +32,40 @@ func (r *locationTrackRepository) FindLatestByDeliveryID(deliveryID string) (*mo
 	var track models.LocationTrack
 	err := r.db.Where("delivery_id = ?", deliveryID).Order("timestamp DESC").First(&track).Error
 	return &track, err
-}
\ No newline at end of file
+}
+
+func (r *locationTrackRepository) CreateBatch(tracks []models.LocationTrack) error {
+	if len(tracks) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(tracks, 100).Error
+}
+
+// FindLastBefore mengambil titik terakhir sebelum waktu tertentu, sebagai acuan filter batch.
+func (r *locationTrackRepository) FindLastBefore(deliveryID uuid.UUID, before time.Time) (*models.LocationTrack, error) {
+	var track models.LocationTrack
+	err := r.db.Where("delivery_id = ? AND timestamp < ?", deliveryID, before).
+		Order("timestamp DESC").First(&track).Error
+	return &track, err
+}
+
+func (r *locationTrackRepository) FindTimestampsBetween(deliveryID uuid.UUID, from, to time.Time) ([]time.Time, error) {
+	var timestamps []time.Time
+	err := r.db.Model(&models.LocationTrack{}).
+		Where("delivery_id = ? AND timestamp BETWEEN ? AND ?", deliveryID, from, to).
+		Pluck("timestamp", &timestamps).Error
+	return timestamps, err
+}
+
+func (r *locationTrackRepository) FindAllByDeliveryID(deliveryID string) ([]models.LocationTrack, error) {
+	var tracks []models.LocationTrack
+	err := r.db.Where("delivery_id = ?", deliveryID).Order("timestamp ASC").Find(&tracks).Error
+	return tracks, err
+}
+
+func (r *locationTrackRepository) FindBetween(deliveryID uuid.UUID, from, to time.Time) ([]models.LocationTrack, error) {
+	var tracks []models.LocationTrack
+	err := r.db.Where("delivery_id = ? AND timestamp BETWEEN ? AND ?", deliveryID, from, to).
+		Order("timestamp ASC").Find(&tracks).Error
+	return tracks, err
+}
@@ -1,19 +1,27 @@
 package repositories
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/whsasmita/AgroLink_API/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // OrderRepository mendefinisikan operasi database untuk Order dan OrderItem.
 type OrderRepository interface {
-	CreateWithItems(tx *gorm.DB, order *models.Order, cartItems []models.Cart) error
+	CreateWithItems(tx *gorm.DB, order *models.Order, items []models.OrderItem) error
 	UpdateStatusByPaymentID(tx *gorm.DB, paymentID uuid.UUID, status string) error
+	CancelPendingByIDs(tx *gorm.DB, orderIDs []uuid.UUID) error
 	FindByID(id uuid.UUID) (*models.Order, error)
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.Order, error)
 	FindAllByUserID(userID uuid.UUID) ([]models.Order, error)
+	FindAllByFarmerID(farmerID uuid.UUID, status string) ([]models.Order, error)
+	Update(tx *gorm.DB, order *models.Order) error
+	UpdateItem(tx *gorm.DB, item *models.OrderItem) error
+	LinkDelivery(tx *gorm.DB, orderIDs []uuid.UUID, deliveryID uuid.UUID, method string) error
 	FindOrdersByPaymentID(tx *gorm.DB, paymentID uuid.UUID) ([]models.Order, error)
 	CountNewOrders(since time.Time) (int64, error)
 }
@@ -26,29 +34,30 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 }
 
 // CreateWithItems membuat Order baru beserta semua OrderItems-nya dalam satu transaksi.
-func (r *orderRepository) CreateWithItems(tx *gorm.DB, order *models.Order, cartItems []models.Cart) error {
+// Harga item sudah dihitung pemanggil (termasuk tier grosir) dan disimpan apa adanya.
+func (r *orderRepository) CreateWithItems(tx *gorm.DB, order *models.Order, items []models.OrderItem) error {
 	// 1. Buat record Order utama
 	if err := tx.Create(order).Error; err != nil {
 		return err
 	}
 
-	// 2. Loop melalui item keranjang dan buat OrderItem untuk masing-masing
-	for _, cartItem := range cartItems {
-		orderItem := models.OrderItem{
-			ID:              uuid.New(),
-			OrderID:         order.ID,
-			ProductID:       cartItem.ProductID,
-			Quantity:        cartItem.Quantity,
-			PriceAtPurchase: cartItem.Product.Price, // Simpan harga saat pembelian
+	// 2. Simpan setiap item dengan OrderID yang baru dibuat
+	for _, orderItem := range items {
+		if orderItem.ID == uuid.Nil {
+			orderItem.ID = uuid.New()
 		}
+		orderItem.OrderID = order.ID
 		if err := tx.Create(&orderItem).Error; err != nil {
 			return err // Jika satu item gagal, seluruh transaksi akan dibatalkan
 		}
+		order.Items = append(order.Items, orderItem)
 	}
 	return nil
 }
 
-// UpdateStatusByPaymentID memperbarui status semua Order yang terkait dengan satu ID pembayaran.
+// UpdateStatusByPaymentID memperbarui status Order yang terkait dengan satu ID pembayaran.
+// Hanya order yang masih pending yang diubah, sehingga pembayaran yang terlambat tidak
+// menghidupkan kembali order yang sudah dibatalkan.
 func (r *orderRepository) UpdateStatusByPaymentID(tx *gorm.DB, paymentID uuid.UUID, status string) error {
 	// 1. Cari semua OrderID dari tabel penghubung (pivot table)
 	var orderIDs []uuid.UUID
@@ -61,23 +70,73 @@ func (r *orderRepository) UpdateStatusByPaymentID(tx *gorm.DB, paymentID uuid.UU
 	}
 
 	// 2. Update status semua order yang ditemukan
-	return tx.Model(&models.Order{}).Where("id IN ?", orderIDs).Update("status", status).Error
+	return tx.Model(&models.Order{}).Where("id IN ? AND status = ?", orderIDs, models.OrderStatusPending).Update("status", status).Error
+}
+
+// CancelPendingByIDs membatalkan order yang masih pending; order dengan status lain tidak disentuh.
+func (r *orderRepository) CancelPendingByIDs(tx *gorm.DB, orderIDs []uuid.UUID) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if len(orderIDs) == 0 {
+		return nil
+	}
+	return tx.Model(&models.Order{}).
+		Where("id IN ? AND status = ?", orderIDs, models.OrderStatusPending).
+		Update("status", models.OrderStatusCancelled).Error
 }
 
 // FindByID mencari satu Order berdasarkan ID-nya, termasuk semua item di dalamnya.
 func (r *orderRepository) FindByID(id uuid.UUID) (*models.Order, error) {
 	var order models.Order
-	err := r.db.Preload("Items.Product").Where("id = ?", id).First(&order).Error
+	err := r.db.Preload("Items.Product").Preload("Delivery").Preload("User").Where("id = ?", id).First(&order).Error
+	return &order, err
+}
+
+// FindByIDForUpdate mengunci satu Order beserta item-itemnya di dalam transaksi.
+func (r *orderRepository) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.Order, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var order models.Order
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").Where("id = ?", id).First(&order).Error
 	return &order, err
 }
 
 // FindAllByUserID mencari semua riwayat Order milik seorang pengguna.
 func (r *orderRepository) FindAllByUserID(userID uuid.UUID) ([]models.Order, error) {
 	var orders []models.Order
-	err := r.db.Preload("Items.Product").Where("user_id = ?", userID).Order("created_at DESC").Find(&orders).Error
+	err := r.db.Preload("Items.Product").Preload("Delivery").Where("user_id = ?", userID).Order("created_at DESC").Find(&orders).Error
+	return orders, err
+}
+
+// FindAllByFarmerID mencari pesanan masuk milik seorang petani, opsional difilter status.
+func (r *orderRepository) FindAllByFarmerID(farmerID uuid.UUID, status string) ([]models.Order, error) {
+	var orders []models.Order
+	query := r.db.Preload("Items.Product").Preload("Delivery").Where("farmer_id = ?", farmerID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at DESC").Find(&orders).Error
 	return orders, err
 }
 
+// Update menyimpan perubahan pada Order tanpa menyentuh relasinya.
+func (r *orderRepository) Update(tx *gorm.DB, order *models.Order) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Omit(clause.Associations).Save(order).Error
+}
+
+// UpdateItem menyimpan perubahan pada satu OrderItem, mis. kuantitas pre-order yang disesuaikan hasil panen.
+func (r *orderRepository) UpdateItem(tx *gorm.DB, item *models.OrderItem) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Omit(clause.Associations).Save(item).Error
+}
+
 func (r *orderRepository) FindOrdersByPaymentID(tx *gorm.DB, paymentID uuid.UUID) ([]models.Order, error) {
 	var orders []models.Order
 
@@ -104,4 +163,29 @@ func (r *orderRepository) CountNewOrders(since time.Time) (int64, error) {
 		Where("status = ? AND created_at > ?", "paid", since).
 		Count(&count).Error
 	return count, err
-}
\ No newline at end of file
+}
+
+// LinkDelivery menghubungkan beberapa pesanan ke satu pengiriman sekaligus.
+// Hanya pesanan 'paid' yang belum memiliki pengiriman aktif yang ditautkan; jika ada
+// pesanan yang sudah berubah sejak diperiksa, seluruh penautan ditolak.
+func (r *orderRepository) LinkDelivery(tx *gorm.DB, orderIDs []uuid.UUID, deliveryID uuid.UUID, method string) error {
+	if tx == nil {
+		tx = r.db
+	}
+	inactiveDeliveries := tx.Model(&models.Delivery{}).Select("id").
+		Where("status IN ?", []string{models.DeliveryStatusCancelled, models.DeliveryStatusReturned})
+	result := tx.Model(&models.Order{}).
+		Where("id IN ? AND status = ?", orderIDs, models.OrderStatusPaid).
+		Where("delivery_id IS NULL OR delivery_id IN (?)", inactiveDeliveries).
+		Updates(map[string]interface{}{
+			"delivery_id":        deliveryID,
+			"fulfillment_method": method,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != int64(len(orderIDs)) {
+		return errors.New("invalid order state: some orders are no longer paid or already have a delivery")
+	}
+	return nil
+}
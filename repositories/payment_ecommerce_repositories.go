@@ -6,6 +6,7 @@ import (
 	"github.com/whsasmita/AgroLink_API/dto"
 	"github.com/whsasmita/AgroLink_API/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ECommercePaymentRepository mendefinisikan operasi database untuk pembayaran e-commerce.
@@ -13,6 +14,8 @@ type ECommercePaymentRepository interface {
 	Create(tx *gorm.DB, payment *models.ECommercePayment) error
 	Update(tx *gorm.DB, payment *models.ECommercePayment) error
 	FindByID(id string) (*models.ECommercePayment, error)
+	FindByIDForUpdate(tx *gorm.DB, id string) (*models.ECommercePayment, error)
+	FindByRefundStatus(status string) ([]models.ECommercePayment, error)
 	UpdateStatus(tx *gorm.DB, id string, status string) error
 	GetAllPayments(page, limit int) ([]models.ECommercePayment, int64, error)
 	GetRevenueStats(startDate, endDate time.Time) (total float64, trend []dto.DailyDataPoint, err error)
@@ -45,6 +48,24 @@ func (r *eCommercePaymentRepository) FindByID(id string) (*models.ECommercePayme
 	return &payment, err
 }
 
+// FindByIDForUpdate mengunci record pembayaran agar webhook yang datang bersamaan
+// tidak memprosesnya dua kali.
+func (r *eCommercePaymentRepository) FindByIDForUpdate(tx *gorm.DB, id string) (*models.ECommercePayment, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var payment models.ECommercePayment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&payment).Error
+	return &payment, err
+}
+
+// FindByRefundStatus dipakai admin untuk melihat pengembalian dana yang harus dicairkan.
+func (r *eCommercePaymentRepository) FindByRefundStatus(status string) ([]models.ECommercePayment, error) {
+	var payments []models.ECommercePayment
+	err := r.db.Preload("User").Where("refund_status = ?", status).Order("updated_at ASC").Find(&payments).Error
+	return payments, err
+}
+
 // UpdateStatus memperbarui kolom status dari sebuah record pembayaran.
 func (r *eCommercePaymentRepository) UpdateStatus(tx *gorm.DB, id string, status string) error {
 	return tx.Model(&models.ECommercePayment{}).Where("id = ?", id).Update("status", status).Error
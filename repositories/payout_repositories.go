@@ -21,7 +21,10 @@ func NewPayoutRepository(db *gorm.DB) PayoutRepository {
 }
 
 func (r *payoutRepository) Create(tx *gorm.DB, payout *models.Payout) error {
-	return r.db.Create(payout).Error
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(payout).Error
 }
 func (r *payoutRepository) FindPendingPayouts() ([]models.Payout, error) {
 	var payouts []models.Payout
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PreOrderCampaignRepository interface {
+	Create(tx *gorm.DB, campaign *models.PreOrderCampaign) error
+	Update(tx *gorm.DB, campaign *models.PreOrderCampaign) error
+	FindByID(id uuid.UUID) (*models.PreOrderCampaign, error)
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.PreOrderCampaign, error)
+	FindAllByProductID(productID uuid.UUID) ([]models.PreOrderCampaign, error)
+	FindOpenByProductID(productID uuid.UUID, today time.Time) ([]models.PreOrderCampaign, error)
+}
+
+type preOrderCampaignRepository struct{ db *gorm.DB }
+
+func NewPreOrderCampaignRepository(db *gorm.DB) PreOrderCampaignRepository {
+	return &preOrderCampaignRepository{db: db}
+}
+
+func (r *preOrderCampaignRepository) Create(tx *gorm.DB, campaign *models.PreOrderCampaign) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(campaign).Error
+}
+
+func (r *preOrderCampaignRepository) Update(tx *gorm.DB, campaign *models.PreOrderCampaign) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Omit(clause.Associations).Save(campaign).Error
+}
+
+func (r *preOrderCampaignRepository) FindByID(id uuid.UUID) (*models.PreOrderCampaign, error) {
+	var campaign models.PreOrderCampaign
+	err := r.db.Preload("Variant").Where("id = ?", id).First(&campaign).Error
+	return &campaign, err
+}
+
+func (r *preOrderCampaignRepository) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.PreOrderCampaign, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var campaign models.PreOrderCampaign
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&campaign).Error
+	return &campaign, err
+}
+
+// FindAllByProductID mengambil semua kampanye produk untuk halaman kelola petani.
+func (r *preOrderCampaignRepository) FindAllByProductID(productID uuid.UUID) ([]models.PreOrderCampaign, error) {
+	var campaigns []models.PreOrderCampaign
+	err := r.db.Preload("Variant").Where("product_id = ?", productID).
+		Order("harvest_start ASC, created_at ASC").Find(&campaigns).Error
+	return campaigns, err
+}
+
+// FindOpenByProductID mengambil kampanye yang masih menerima pre-order (panen belum dimulai).
+func (r *preOrderCampaignRepository) FindOpenByProductID(productID uuid.UUID, today time.Time) ([]models.PreOrderCampaign, error) {
+	var campaigns []models.PreOrderCampaign
+	err := r.db.Preload("Variant").
+		Where("product_id = ? AND status = ? AND harvest_start > ?", productID, models.PreOrderCampaignOpen, today).
+		Order("harvest_start ASC, created_at ASC").Find(&campaigns).Error
+	return campaigns, err
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PreOrderRepository interface {
+	Create(tx *gorm.DB, preOrder *models.PreOrder) error
+	Update(tx *gorm.DB, preOrder *models.PreOrder) error
+	FindByID(tx *gorm.DB, id uuid.UUID) (*models.PreOrder, error)
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.PreOrder, error)
+	FindByPaymentID(tx *gorm.DB, paymentID uuid.UUID) (*models.PreOrder, error)
+	FindActiveByCampaignIDForUpdate(tx *gorm.DB, campaignID uuid.UUID) ([]models.PreOrder, error)
+	FindAllByCampaignID(campaignID uuid.UUID) ([]models.PreOrder, error)
+	FindAllByUserID(userID uuid.UUID) ([]models.PreOrder, error)
+	FindStaleDepositIDs(createdBefore time.Time, limit int) ([]uuid.UUID, error)
+	FindOverdueBalanceIDs(now time.Time, limit int) ([]uuid.UUID, error)
+	FindByRefundStatus(status string) ([]models.PreOrder, error)
+}
+
+type preOrderRepository struct{ db *gorm.DB }
+
+func NewPreOrderRepository(db *gorm.DB) PreOrderRepository {
+	return &preOrderRepository{db: db}
+}
+
+func (r *preOrderRepository) Create(tx *gorm.DB, preOrder *models.PreOrder) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(preOrder).Error
+}
+
+func (r *preOrderRepository) Update(tx *gorm.DB, preOrder *models.PreOrder) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Omit(clause.Associations).Save(preOrder).Error
+}
+
+func (r *preOrderRepository) FindByID(tx *gorm.DB, id uuid.UUID) (*models.PreOrder, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var preOrder models.PreOrder
+	err := tx.Where("id = ?", id).First(&preOrder).Error
+	return &preOrder, err
+}
+
+func (r *preOrderRepository) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.PreOrder, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var preOrder models.PreOrder
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&preOrder).Error
+	return &preOrder, err
+}
+
+// FindByPaymentID mencari pre-order pemilik pembayaran uang muka atau pelunasan.
+func (r *preOrderRepository) FindByPaymentID(tx *gorm.DB, paymentID uuid.UUID) (*models.PreOrder, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var preOrder models.PreOrder
+	err := tx.Where("deposit_payment_id = ? OR balance_payment_id = ?", paymentID, paymentID).First(&preOrder).Error
+	return &preOrder, err
+}
+
+// FindActiveByCampaignIDForUpdate mengambil pre-order yang belum selesai/batal, urut
+// waktu pemesanan karena hasil panen dialokasikan siapa cepat dia dapat.
+func (r *preOrderRepository) FindActiveByCampaignIDForUpdate(tx *gorm.DB, campaignID uuid.UUID) ([]models.PreOrder, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var preOrders []models.PreOrder
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("campaign_id = ? AND status IN ?", campaignID, []string{
+			models.PreOrderStatusAwaitingDeposit, models.PreOrderStatusReserved, models.PreOrderStatusAwaitingBalance,
+		}).
+		Order("created_at ASC").Find(&preOrders).Error
+	return preOrders, err
+}
+
+func (r *preOrderRepository) FindAllByCampaignID(campaignID uuid.UUID) ([]models.PreOrder, error) {
+	var preOrders []models.PreOrder
+	err := r.db.Preload("User").Where("campaign_id = ?", campaignID).Order("created_at ASC").Find(&preOrders).Error
+	return preOrders, err
+}
+
+func (r *preOrderRepository) FindAllByUserID(userID uuid.UUID) ([]models.PreOrder, error) {
+	var preOrders []models.PreOrder
+	err := r.db.Preload("Campaign.Product").Preload("Campaign.Variant").
+		Where("user_id = ?", userID).Order("created_at DESC").Find(&preOrders).Error
+	return preOrders, err
+}
+
+// FindStaleDepositIDs mencari pre-order yang uang mukanya tidak kunjung dibayar
+// (mis. webhook kedaluwarsa tidak pernah diterima).
+func (r *preOrderRepository) FindStaleDepositIDs(createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Model(&models.PreOrder{}).
+		Where("status = ? AND created_at < ?", models.PreOrderStatusAwaitingDeposit, createdBefore).
+		Order("created_at ASC").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// FindOverdueBalanceIDs mencari pre-order yang melewati batas waktu pelunasan.
+func (r *preOrderRepository) FindOverdueBalanceIDs(now time.Time, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Model(&models.PreOrder{}).
+		Where("status = ? AND balance_due_at < ?", models.PreOrderStatusAwaitingBalance, now).
+		Order("balance_due_at ASC").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// FindByRefundStatus dipakai admin untuk melihat pengembalian dana yang harus dicairkan.
+func (r *preOrderRepository) FindByRefundStatus(status string) ([]models.PreOrder, error) {
+	var preOrders []models.PreOrder
+	err := r.db.Preload("User").Preload("Campaign.Product").Preload("Campaign.Variant").
+		Where("refund_status = ?", status).Order("updated_at ASC").Find(&preOrders).Error
+	return preOrders, err
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+)
+
+type PriceTierRepository interface {
+	FindByVariantID(variantID uuid.UUID) ([]models.PriceTier, error)
+	ReplaceForVariant(tx *gorm.DB, variantID uuid.UUID, tiers []models.PriceTier) error
+	FindApplicable(tx *gorm.DB, variantIDs []uuid.UUID, buyerGroupID *uuid.UUID) ([]models.PriceTier, error)
+}
+
+type priceTierRepository struct{ db *gorm.DB }
+
+func NewPriceTierRepository(db *gorm.DB) PriceTierRepository {
+	return &priceTierRepository{db: db}
+}
+
+func (r *priceTierRepository) FindByVariantID(variantID uuid.UUID) ([]models.PriceTier, error) {
+	var tiers []models.PriceTier
+	err := r.db.Preload("BuyerGroup").Where("variant_id = ?", variantID).
+		Order("buyer_group_id IS NOT NULL, min_quantity ASC").Find(&tiers).Error
+	return tiers, err
+}
+
+// ReplaceForVariant mengganti seluruh tier varian dengan daftar baru.
+func (r *priceTierRepository) ReplaceForVariant(tx *gorm.DB, variantID uuid.UUID, tiers []models.PriceTier) error {
+	if tx == nil {
+		tx = r.db
+	}
+	if err := tx.Where("variant_id = ?", variantID).Delete(&models.PriceTier{}).Error; err != nil {
+		return err
+	}
+	if len(tiers) == 0 {
+		return nil
+	}
+	return tx.Create(&tiers).Error
+}
+
+// FindApplicable mengambil tier umum dan tier milik grup pembeli (jika ada) untuk varian-varian tersebut.
+func (r *priceTierRepository) FindApplicable(tx *gorm.DB, variantIDs []uuid.UUID, buyerGroupID *uuid.UUID) ([]models.PriceTier, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var tiers []models.PriceTier
+	if len(variantIDs) == 0 {
+		return tiers, nil
+	}
+	query := tx.Where("variant_id IN ?", variantIDs)
+	if buyerGroupID != nil {
+		query = query.Where("(buyer_group_id IS NULL OR buyer_group_id = ?)", *buyerGroupID)
+	} else {
+		query = query.Where("buyer_group_id IS NULL")
+	}
+	err := query.Order("min_quantity ASC").Find(&tiers).Error
+	return tiers, err
+}
@@ -1,20 +1,24 @@
 package repositories
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
 	"github.com/whsasmita/AgroLink_API/models"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 type ProductRepository interface {
-	Create(product *models.Product) error
-	FindAll() ([]models.Product, error)
+	Create(tx *gorm.DB, product *models.Product) error
+	Search(filter dto.ProductSearchRequest) ([]models.Product, int64, error)
+	CategoryFacets(filter dto.ProductSearchRequest) ([]dto.CategoryFacet, error)
 	FindAllByFarmerID(farmerID uuid.UUID) ([]models.Product, error) // <-- [BARU]
 	FindByID(id uuid.UUID) (*models.Product, error)
 	Update(tx *gorm.DB, product *models.Product) error
 	Delete(id uuid.UUID) error
-	UpdateStock(tx *gorm.DB, product *models.Product) error
 	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.Product, error)
 }
 
@@ -24,26 +28,118 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
 	return &productRepository{db: db}
 }
 
-func (r *productRepository) Create(product *models.Product) error {
-	return r.db.Create(product).Error
+func (r *productRepository) Create(tx *gorm.DB, product *models.Product) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(product).Error
 }
 
 func (r *productRepository) FindAllByFarmerID(farmerID uuid.UUID) ([]models.Product, error) {
 	var products []models.Product
 	// Lakukan Preload untuk mendapatkan data relasi yang relevan
-	err := r.db.Preload("Farmer.User").Where("farmer_id = ?", farmerID).Order("created_at DESC").Find(&products).Error
+	err := r.db.Preload("Farmer.User").Preload("Category").Preload("Variants", orderVariants).Preload("Variants.PriceTiers", orderPriceTiers).Preload("Variants.PriceTiers.BuyerGroup").Preload("RestockSchedules", upcomingRestocks).Where("farmer_id = ?", farmerID).Order("created_at DESC").Find(&products).Error
 	return products, err
 }
 
-func (r *productRepository) FindAll() ([]models.Product, error) {
+// orderVariants mengurutkan varian yang di-preload sesuai urutan tampilan petani.
+func orderVariants(db *gorm.DB) *gorm.DB {
+	return db.Order("sort_order ASC, price ASC")
+}
+
+// orderPriceTiers menampilkan tier umum lebih dulu, lalu tier grup, masing-masing per ambang kuantitas.
+func orderPriceTiers(db *gorm.DB) *gorm.DB {
+	return db.Order("buyer_group_id IS NOT NULL, min_quantity ASC")
+}
+
+// upcomingRestocks hanya memuat jadwal panen yang masih direncanakan dan belum lewat,
+// dipakai untuk status "segera hadir" di halaman produk.
+func upcomingRestocks(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ? AND expected_date >= CURDATE()", models.RestockStatusPlanned).Order("expected_date ASC")
+}
+
+// distanceExpr menghitung jarak (km) dari titik pencarian ke koordinat produk (haversine).
+// Argumen acos dibatasi ke [-1, 1] karena galat pembulatan pada titik yang sama persis
+// bisa menghasilkan nilai sedikit di atas 1 dan membuat acos mengembalikan NULL.
+func distanceExpr(lat, lng float64) string {
+	return fmt.Sprintf("(6371 * acos(LEAST(1, GREATEST(-1, cos(radians(%f)) * cos(radians(latitude)) * cos(radians(longitude) - radians(%f)) + sin(radians(%f)) * sin(radians(latitude))))))", lat, lng, lat)
+}
+
+// applyProductFilters menerapkan filter pencarian katalog. Filter kategori bisa dilewati
+// agar facet tetap menampilkan jumlah untuk semua kategori.
+func applyProductFilters(query *gorm.DB, filter dto.ProductSearchRequest, withCategory bool) *gorm.DB {
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("(title LIKE ? OR description LIKE ?)", like, like)
+	}
+	if withCategory && filter.Category != "" {
+		query = query.Where("products.category_id IN ?", filter.CategoryIDs)
+	}
+	if filter.MinPrice != nil {
+		query = query.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.InStock {
+		query = query.Where("EXISTS (SELECT 1 FROM product_variants pv WHERE pv.product_id = products.id AND pv.stock - pv.reserved_stock > 0)")
+	}
+	if filter.FarmerID != "" {
+		query = query.Where("farmer_id = ?", filter.FarmerID)
+	}
+	if filter.HasLocation() && filter.RadiusKm != nil {
+		query = query.Where("latitude IS NOT NULL AND longitude IS NOT NULL").
+			Where(distanceExpr(*filter.Lat, *filter.Lng)+" <= ?", *filter.RadiusKm)
+	}
+	return query
+}
+
+// Search mengambil produk sesuai filter dengan pagination berbasis halaman.
+func (r *productRepository) Search(filter dto.ProductSearchRequest) ([]models.Product, int64, error) {
 	var products []models.Product
-	err := r.db.Preload("Farmer.User").Order("created_at DESC").Find(&products).Error
-	return products, err
+	var total int64
+
+	query := applyProductFilters(r.db.Model(&models.Product{}), filter, true)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.HasLocation() {
+		query = query.Select("*, " + distanceExpr(*filter.Lat, *filter.Lng) + " AS distance_km")
+	}
+	switch filter.Sort {
+	case "price_asc":
+		query = query.Order("price ASC")
+	case "price_desc":
+		query = query.Order("price DESC")
+	case "rating":
+		// Produk tanpa rating diletakkan di akhir
+		query = query.Order("rating IS NULL").Order("rating DESC")
+	case "distance":
+		query = query.Order("distance_km IS NULL").Order("distance_km ASC")
+	}
+	query = query.Order("created_at DESC")
+
+	offset := (filter.Page - 1) * filter.Limit
+	err := query.Preload("Farmer.User").Preload("Category").Preload("Variants", orderVariants).Preload("Variants.PriceTiers", orderPriceTiers).Preload("Variants.PriceTiers.BuyerGroup").Preload("RestockSchedules", upcomingRestocks).Limit(filter.Limit).Offset(offset).Find(&products).Error
+	return products, total, err
+}
+
+// CategoryFacets menghitung jumlah produk per kategori untuk filter yang sama (tanpa filter kategori).
+func (r *productRepository) CategoryFacets(filter dto.ProductSearchRequest) ([]dto.CategoryFacet, error) {
+	var facets []dto.CategoryFacet
+	err := applyProductFilters(r.db.Model(&models.Product{}), filter, false).
+		Select("categories.id AS category_id, categories.name, categories.slug, COUNT(*) AS count").
+		Joins("JOIN categories ON categories.id = products.category_id").
+		Group("categories.id, categories.name, categories.slug").
+		Order("count DESC").
+		Scan(&facets).Error
+	return facets, err
 }
 
 func (r *productRepository) FindByID(id uuid.UUID) (*models.Product, error) {
 	var product models.Product
-	err := r.db.Preload("Farmer.User").Where("id = ?", id).First(&product).Error
+	err := r.db.Preload("Farmer.User").Preload("Category").Preload("Variants", orderVariants).Preload("Variants.PriceTiers", orderPriceTiers).Preload("Variants.PriceTiers.BuyerGroup").Preload("RestockSchedules", upcomingRestocks).Where("id = ?", id).First(&product).Error
 	return &product, err
 }
 
@@ -60,10 +156,3 @@ func (r *productRepository) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*model
 	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&product).Error
 	return &product, err
 }
-
-func (r *productRepository) UpdateStock(tx *gorm.DB, product *models.Product) error {
-	return tx.Model(product).Updates(map[string]interface{}{
-		"stock":          product.Stock,
-		"reserved_stock": product.ReservedStock,
-	}).Error
-}
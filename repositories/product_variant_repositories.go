@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProductVariantRepository interface {
+	Create(tx *gorm.DB, variant *models.ProductVariant) error
+	Update(tx *gorm.DB, variant *models.ProductVariant) error
+	Delete(tx *gorm.DB, variant *models.ProductVariant) error
+	FindByID(id uuid.UUID) (*models.ProductVariant, error)
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.ProductVariant, error)
+	FindAllByProductID(tx *gorm.DB, productID uuid.UUID) ([]models.ProductVariant, error)
+	FindBySKU(sku string) (*models.ProductVariant, error)
+	UpdateStock(tx *gorm.DB, variant *models.ProductVariant) error
+	SyncProductPrice(tx *gorm.DB, productID uuid.UUID) error
+	FindLowStockUnalerted() ([]models.ProductVariant, error)
+	MarkLowStockAlerted(ids []uuid.UUID, alertedAt time.Time) error
+	ClearRecoveredLowStockAlerts() (int64, error)
+}
+
+type productVariantRepository struct{ db *gorm.DB }
+
+func NewProductVariantRepository(db *gorm.DB) ProductVariantRepository {
+	return &productVariantRepository{db: db}
+}
+
+func (r *productVariantRepository) Create(tx *gorm.DB, variant *models.ProductVariant) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(variant).Error
+}
+
+func (r *productVariantRepository) Update(tx *gorm.DB, variant *models.ProductVariant) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Save(variant).Error
+}
+
+func (r *productVariantRepository) Delete(tx *gorm.DB, variant *models.ProductVariant) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Delete(variant).Error
+}
+
+func (r *productVariantRepository) FindByID(id uuid.UUID) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	err := r.db.Preload("Product").Where("id = ?", id).First(&variant).Error
+	return &variant, err
+}
+
+// FindByIDForUpdate mengunci baris varian selama transaksi perubahan stok.
+func (r *productVariantRepository) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Product").Where("id = ?", id).First(&variant).Error
+	return &variant, err
+}
+
+func (r *productVariantRepository) FindAllByProductID(tx *gorm.DB, productID uuid.UUID) ([]models.ProductVariant, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var variants []models.ProductVariant
+	err := tx.Where("product_id = ?", productID).Order("sort_order ASC, price ASC").Find(&variants).Error
+	return variants, err
+}
+
+func (r *productVariantRepository) FindBySKU(sku string) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	err := r.db.Where("sku = ?", sku).First(&variant).Error
+	return &variant, err
+}
+
+func (r *productVariantRepository) UpdateStock(tx *gorm.DB, variant *models.ProductVariant) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Model(variant).Updates(map[string]interface{}{
+		"stock":          variant.Stock,
+		"reserved_stock": variant.ReservedStock,
+	}).Error
+}
+
+// SyncProductPrice menyamakan products.price dengan harga varian termurah, dipakai
+// untuk filter dan urutan harga pada pencarian katalog.
+func (r *productVariantRepository) SyncProductPrice(tx *gorm.DB, productID uuid.UUID) error {
+	if tx == nil {
+		tx = r.db
+	}
+	var minPrice float64
+	if err := tx.Model(&models.ProductVariant{}).Where("product_id = ?", productID).
+		Select("COALESCE(MIN(price), 0)").Scan(&minPrice).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Product{}).Where("id = ?", productID).Update("price", minPrice).Error
+}
+
+// FindLowStockUnalerted mengambil varian yang stok tersedianya sudah mencapai ambang
+// batas petani tetapi belum diberi notifikasi.
+func (r *productVariantRepository) FindLowStockUnalerted() ([]models.ProductVariant, error) {
+	var variants []models.ProductVariant
+	err := r.db.Preload("Product").
+		Where("low_stock_threshold IS NOT NULL AND low_stock_alerted_at IS NULL").
+		Where("stock - reserved_stock <= low_stock_threshold").
+		Find(&variants).Error
+	return variants, err
+}
+
+func (r *productVariantRepository) MarkLowStockAlerted(ids []uuid.UUID, alertedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&models.ProductVariant{}).Where("id IN ?", ids).Update("low_stock_alerted_at", alertedAt).Error
+}
+
+// ClearRecoveredLowStockAlerts mengosongkan penanda notifikasi untuk varian yang stoknya
+// sudah kembali di atas ambang, agar penurunan berikutnya memicu notifikasi lagi.
+func (r *productVariantRepository) ClearRecoveredLowStockAlerts() (int64, error) {
+	result := r.db.Model(&models.ProductVariant{}).
+		Where("low_stock_alerted_at IS NOT NULL").
+		Where("(low_stock_threshold IS NULL OR stock - reserved_stock > low_stock_threshold)").
+		Update("low_stock_alerted_at", nil)
+	return result.RowsAffected, result.Error
+}
@@ -13,6 +13,7 @@ type ProfitRepository interface {
 	// sourceType: "" | "utama" | "ecommerce"
 	GetDailySummary(start, end time.Time, sourceType string) ([]dto.PlatformProfitDailySummaryResponse, error)
 	GetTotalSummary(start, end time.Time, sourceType string) (*dto.PlatformProfitTotalSummaryResponse, error)
+	Create(tx *gorm.DB, profit *models.PlatformProfit) error
 }
 
 type profitRepository struct {
@@ -23,6 +24,13 @@ func NewProfitRepository(db *gorm.DB) ProfitRepository {
 	return &profitRepository{db: db}
 }
 
+func (r *profitRepository) Create(tx *gorm.DB, profit *models.PlatformProfit) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(profit).Error
+}
+
 // GetTotalSummary → agregat total gross/gateway/net dalam periode
 func (r *profitRepository) GetTotalSummary(start, end time.Time, sourceType string) (*dto.PlatformProfitTotalSummaryResponse, error) {
 	var result dto.PlatformProfitTotalSummaryResponse
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RestockScheduleRepository interface {
+	Create(tx *gorm.DB, schedule *models.RestockSchedule) error
+	Update(tx *gorm.DB, schedule *models.RestockSchedule) error
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.RestockSchedule, error)
+	FindAllByProductID(productID uuid.UUID) ([]models.RestockSchedule, error)
+}
+
+type restockScheduleRepository struct{ db *gorm.DB }
+
+func NewRestockScheduleRepository(db *gorm.DB) RestockScheduleRepository {
+	return &restockScheduleRepository{db: db}
+}
+
+func (r *restockScheduleRepository) Create(tx *gorm.DB, schedule *models.RestockSchedule) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(schedule).Error
+}
+
+func (r *restockScheduleRepository) Update(tx *gorm.DB, schedule *models.RestockSchedule) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Omit(clause.Associations).Save(schedule).Error
+}
+
+func (r *restockScheduleRepository) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.RestockSchedule, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var schedule models.RestockSchedule
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&schedule).Error
+	return &schedule, err
+}
+
+// FindAllByProductID mengambil semua jadwal panen produk, yang terdekat lebih dulu.
+func (r *restockScheduleRepository) FindAllByProductID(productID uuid.UUID) ([]models.RestockSchedule, error) {
+	var schedules []models.RestockSchedule
+	err := r.db.Preload("Variant").Where("product_id = ?", productID).
+		Order("expected_date ASC, created_at ASC").Find(&schedules).Error
+	return schedules, err
+}
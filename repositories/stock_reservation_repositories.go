@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StockReservationRepository interface {
+	Create(tx *gorm.DB, reservation *models.StockReservation) error
+	Update(tx *gorm.DB, reservation *models.StockReservation) error
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.StockReservation, error)
+	FindByOrderIDsForUpdate(tx *gorm.DB, orderIDs []uuid.UUID) ([]models.StockReservation, error)
+	FindExpiredOrderIDs(now time.Time, limit int) ([]uuid.UUID, error)
+	FindStuck(now time.Time) ([]models.StockReservation, error)
+}
+
+type stockReservationRepository struct{ db *gorm.DB }
+
+func NewStockReservationRepository(db *gorm.DB) StockReservationRepository {
+	return &stockReservationRepository{db: db}
+}
+
+func (r *stockReservationRepository) Create(tx *gorm.DB, reservation *models.StockReservation) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(reservation).Error
+}
+
+func (r *stockReservationRepository) Update(tx *gorm.DB, reservation *models.StockReservation) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Omit(clause.Associations).Save(reservation).Error
+}
+
+// FindByIDForUpdate mengunci satu reservasi agar tidak diproses ganda oleh webhook dan job.
+func (r *stockReservationRepository) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.StockReservation, error) {
+	var reservation models.StockReservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&reservation).Error
+	return &reservation, err
+}
+
+// FindByOrderIDsForUpdate mengunci semua reservasi (apa pun statusnya) milik order-order tersebut.
+func (r *stockReservationRepository) FindByOrderIDsForUpdate(tx *gorm.DB, orderIDs []uuid.UUID) ([]models.StockReservation, error) {
+	var reservations []models.StockReservation
+	if len(orderIDs) == 0 {
+		return reservations, nil
+	}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id IN ?", orderIDs).
+		Order("created_at ASC").
+		Find(&reservations).Error
+	return reservations, err
+}
+
+// FindExpiredOrderIDs mengambil order yang masih pending tetapi reservasinya sudah lewat batas waktu.
+func (r *stockReservationRepository) FindExpiredOrderIDs(now time.Time, limit int) ([]uuid.UUID, error) {
+	var orderIDs []uuid.UUID
+	err := r.db.Model(&models.StockReservation{}).
+		Joins("JOIN orders ON orders.id = stock_reservations.order_id").
+		Where("stock_reservations.status = ? AND stock_reservations.expires_at <= ?", models.ReservationStatusActive, now).
+		Where("orders.status = ?", models.OrderStatusPending).
+		Distinct().
+		Limit(limit).
+		Pluck("stock_reservations.order_id", &orderIDs).Error
+	return orderIDs, err
+}
+
+// FindStuck mengambil reservasi aktif yang seharusnya sudah selesai: lewat batas waktu,
+// atau order-nya sudah tidak pending lagi (dibayar/dibatalkan) tetapi stoknya masih ditahan.
+func (r *stockReservationRepository) FindStuck(now time.Time) ([]models.StockReservation, error) {
+	var reservations []models.StockReservation
+	err := r.db.Preload("Order").Preload("Variant.Product").
+		Joins("JOIN orders ON orders.id = stock_reservations.order_id").
+		Where("stock_reservations.status = ?", models.ReservationStatusActive).
+		Where("(stock_reservations.expires_at <= ? OR orders.status <> ?)", now, models.OrderStatusPending).
+		Order("stock_reservations.expires_at ASC").
+		Find(&reservations).Error
+	return reservations, err
+}
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type VoucherRepository interface {
+	Create(voucher *models.Voucher) error
+	Update(tx *gorm.DB, voucher *models.Voucher) error
+	FindByID(id uuid.UUID) (*models.Voucher, error)
+	FindByCode(code string) (*models.Voucher, error)
+	FindByCodeForUpdate(tx *gorm.DB, code string) (*models.Voucher, error)
+	FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.Voucher, error)
+	FindAll(farmerID *uuid.UUID) ([]models.Voucher, error)
+	CountActiveRedemptions(tx *gorm.DB, voucherID, userID uuid.UUID) (int64, error)
+	CreateRedemption(tx *gorm.DB, redemption *models.VoucherRedemption) error
+	UpdateRedemption(tx *gorm.DB, redemption *models.VoucherRedemption) error
+	FindRedemptionByPaymentIDForUpdate(tx *gorm.DB, paymentID uuid.UUID) (*models.VoucherRedemption, error)
+	FindRedemptionByOrderIDForUpdate(tx *gorm.DB, orderID uuid.UUID) (*models.VoucherRedemption, error)
+}
+
+type voucherRepository struct{ db *gorm.DB }
+
+func NewVoucherRepository(db *gorm.DB) VoucherRepository {
+	return &voucherRepository{db: db}
+}
+
+func (r *voucherRepository) Create(voucher *models.Voucher) error {
+	return r.db.Create(voucher).Error
+}
+
+func (r *voucherRepository) Update(tx *gorm.DB, voucher *models.Voucher) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Omit(clause.Associations).Save(voucher).Error
+}
+
+func (r *voucherRepository) FindByID(id uuid.UUID) (*models.Voucher, error) {
+	var voucher models.Voucher
+	err := r.db.Where("id = ?", id).First(&voucher).Error
+	return &voucher, err
+}
+
+func (r *voucherRepository) FindByCode(code string) (*models.Voucher, error) {
+	var voucher models.Voucher
+	err := r.db.Where("code = ?", code).First(&voucher).Error
+	return &voucher, err
+}
+
+// FindByCodeForUpdate mengunci voucher agar kuota pemakaiannya tidak terlampaui oleh checkout bersamaan.
+func (r *voucherRepository) FindByCodeForUpdate(tx *gorm.DB, code string) (*models.Voucher, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var voucher models.Voucher
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&voucher).Error
+	return &voucher, err
+}
+
+func (r *voucherRepository) FindByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.Voucher, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var voucher models.Voucher
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&voucher).Error
+	return &voucher, err
+}
+
+// FindAll mengambil semua voucher, atau hanya voucher toko milik petani jika farmerID diisi.
+func (r *voucherRepository) FindAll(farmerID *uuid.UUID) ([]models.Voucher, error) {
+	var vouchers []models.Voucher
+	query := r.db.Order("created_at DESC")
+	if farmerID != nil {
+		query = query.Where("farmer_id = ?", *farmerID)
+	}
+	err := query.Find(&vouchers).Error
+	return vouchers, err
+}
+
+// CountActiveRedemptions menghitung pemakaian voucher oleh pembeli yang belum dilepas.
+func (r *voucherRepository) CountActiveRedemptions(tx *gorm.DB, voucherID, userID uuid.UUID) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var count int64
+	err := tx.Model(&models.VoucherRedemption{}).
+		Where("voucher_id = ? AND user_id = ? AND status <> ?", voucherID, userID, models.VoucherRedemptionReleased).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *voucherRepository) CreateRedemption(tx *gorm.DB, redemption *models.VoucherRedemption) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(redemption).Error
+}
+
+func (r *voucherRepository) UpdateRedemption(tx *gorm.DB, redemption *models.VoucherRedemption) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Omit(clause.Associations).Save(redemption).Error
+}
+
+func (r *voucherRepository) FindRedemptionByPaymentIDForUpdate(tx *gorm.DB, paymentID uuid.UUID) (*models.VoucherRedemption, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var redemption models.VoucherRedemption
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("payment_id = ?", paymentID).First(&redemption).Error
+	return &redemption, err
+}
+
+// FindRedemptionByOrderIDForUpdate mencari pemakaian voucher dari pembayaran yang mencakup order tersebut.
+func (r *voucherRepository) FindRedemptionByOrderIDForUpdate(tx *gorm.DB, orderID uuid.UUID) (*models.VoucherRedemption, error) {
+	if tx == nil {
+		tx = r.db
+	}
+	var redemption models.VoucherRedemption
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Joins("JOIN ecommerce_payment_orders ON ecommerce_payment_orders.e_commerce_payment_id = voucher_redemptions.payment_id").
+		Where("ecommerce_payment_orders.order_id = ?", orderID).
+		First(&redemption).Error
+	return &redemption, err
+}
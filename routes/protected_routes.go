@@ -5,6 +5,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/whsasmita/AgroLink_API/handlers"
 	"github.com/whsasmita/AgroLink_API/middleware"
+	"github.com/whsasmita/AgroLink_API/pkg/tracking"
 	"github.com/whsasmita/AgroLink_API/repositories"
 	"github.com/whsasmita/AgroLink_API/services"
 	"gorm.io/gorm"
@@ -34,9 +35,26 @@ func ProtectedRoutes(router *gin.RouterGroup, db *gorm.DB, chatHandler *handlers
 	deliveryRepo := repositories.NewDeliveryRepository(db)
 	locationTrackRepo := repositories.NewLocationTrackRepository(db)
 	driverRepo := repositories.NewDriverRepository(db)
+	driverRouteRepo := repositories.NewDriverRouteRepository(db)
+	deliveryProofRepo := repositories.NewDeliveryProofRepository(db)
+	deliveryDisputeRepo := repositories.NewDeliveryDisputeRepository(db)
+	deliveryFailureRepo := repositories.NewDeliveryFailureRepository(db)
+	deliveryConditionRepo := repositories.NewDeliveryConditionRepository(db)
 	productRepo := repositories.NewProductRepository(db)
+	productVariantRepo := repositories.NewProductVariantRepository(db)
+	categoryRepo := repositories.NewCategoryRepository(db)
 	cartRepo := repositories.NewCartRepository(db)
+	addressRepo := repositories.NewAddressRepository(db)
 	orderRepo := repositories.NewOrderRepository(db)
+	stockReservationRepo := repositories.NewStockReservationRepository(db)
+	inventoryMovementRepo := repositories.NewInventoryMovementRepository(db)
+	restockScheduleRepo := repositories.NewRestockScheduleRepository(db)
+	backInStockRepo := repositories.NewBackInStockSubscriptionRepository(db)
+	preOrderCampaignRepo := repositories.NewPreOrderCampaignRepository(db)
+	preOrderRepo := repositories.NewPreOrderRepository(db)
+	buyerGroupRepo := repositories.NewBuyerGroupRepository(db)
+	priceTierRepo := repositories.NewPriceTierRepository(db)
+	voucherRepo := repositories.NewVoucherRepository(db)
 	ecommPaymentRepo := repositories.NewECommercePaymentRepository(db)
 	userVerificationRepo := repositories.NewUserVerificationRepository(db)
 	profitRepo := repositories.NewProfitRepository(db)
@@ -45,28 +63,51 @@ func ProtectedRoutes(router *gin.RouterGroup, db *gorm.DB, chatHandler *handlers
 	// workerRepo dan projectRepo sudah ada
 
 	// 2. Inisialisasi Services
+	trackingHub := tracking.Default()
 	geminiChatService := services.NewGeminiChatService(geminiRepo)
 	authService := services.NewAuthService(userRepo)
 	profileService := services.NewProfileService(userRepo, userVerificationRepo)
 	farmService := services.NewFarmService(farmRepo)
 	projectService := services.NewProjectService(projectRepo, assignRepo, invoiceRepo)
-	contractService := services.NewContractService(contractRepo, projectService, invoiceRepo, deliveryRepo, db)
+	contractService := services.NewContractService(contractRepo, projectService, invoiceRepo, deliveryRepo, trackingHub, db)
 	emailService := services.NewEmailService()
 	notificationService := services.NewNotificationService(notifRepo, emailService, userRepo)
 	appService := services.NewApplicationService(appRepo, projectRepo, contractRepo, assignRepo, notificationService, db)
-	paymentService := services.NewPaymentService(invoiceRepo, transactionRepo, payoutRepo, assignRepo, projectRepo, userRepo, deliveryRepo, db)
+	paymentService := services.NewPaymentService(invoiceRepo, transactionRepo, payoutRepo, assignRepo, projectRepo, userRepo, deliveryRepo, deliveryDisputeRepo, trackingHub, db)
 	reviewService := services.NewReviewService(reviewRepo, workerRepo, projectRepo, driverRepo, deliveryRepo, db)
-	deliveryService := services.NewDeliveryService(deliveryRepo, driverRepo, contractRepo, db)
+	pricingService := services.NewPricingService()
+	routingProvider := services.NewRoutingProvider()
+	driverMatchingService := services.NewDriverMatchingService(deliveryRepo, pricingService)
+	deliveryService := services.NewDeliveryService(deliveryRepo, driverRepo, contractRepo, orderRepo, pricingService, routingProvider, driverMatchingService, trackingHub, db)
+	driverRouteService := services.NewDriverRouteService(driverRouteRepo)
+	driverService := services.NewDriverService(driverRepo)
+	deliveryProofService := services.NewDeliveryProofService(deliveryRepo, deliveryProofRepo, orderRepo, notificationService, emailService, trackingHub, db)
+	deliveryDisputeService := services.NewDeliveryDisputeService(deliveryDisputeRepo, deliveryRepo, invoiceRepo, deliveryConditionRepo, notificationService)
+	deliveryConditionService := services.NewDeliveryConditionService(deliveryConditionRepo, deliveryRepo, notificationService, trackingHub, db)
+	deliveryFailureService := services.NewDeliveryFailureService(deliveryRepo, deliveryFailureRepo, invoiceRepo, transactionRepo, payoutRepo, notificationService, trackingHub, db)
 	offerService := services.NewOfferService(projectRepo, contractRepo, assignRepo, userRepo, db)
-	trackingService := services.NewTrackingService(locationTrackRepo, deliveryRepo)
-	productService := services.NewProductService(productRepo, db)
-	cartService := services.NewCartService(cartRepo, productRepo, db)
+	trackingService := services.NewTrackingService(locationTrackRepo, deliveryRepo, routingProvider, trackingHub, notificationService, db)
+	inventoryService := services.NewInventoryService(inventoryMovementRepo, productRepo, productVariantRepo, orderRepo, db)
+	productService := services.NewProductService(productRepo, productVariantRepo, categoryRepo, inventoryService, db)
+	restockService := services.NewRestockService(restockScheduleRepo, backInStockRepo, productRepo, productVariantRepo, inventoryService, notificationService, db)
+	categoryService := services.NewCategoryService(categoryRepo)
+	buyerGroupService := services.NewBuyerGroupService(buyerGroupRepo, userRepo)
+	priceTierService := services.NewPriceTierService(priceTierRepo, buyerGroupRepo, productRepo, db)
+	cartService := services.NewCartService(cartRepo, productVariantRepo, inventoryService, priceTierService, db)
+	voucherService := services.NewVoucherService(voucherRepo, profitRepo, db)
+	stockReservationService := services.NewStockReservationService(stockReservationRepo, productVariantRepo, orderRepo, cartRepo, inventoryService, voucherService, db)
 	eCommercePaymentService := services.NewECommercePaymentService(
-		ecommPaymentRepo, orderRepo, userRepo, productRepo, db,
+		ecommPaymentRepo, orderRepo, userRepo, stockReservationService, voucherService, notificationService, db,
 	)
 	checkoutService := services.NewCheckoutService(
-		cartRepo, productRepo, orderRepo, eCommercePaymentService, db,
+		cartRepo, productVariantRepo, orderRepo, addressRepo, eCommercePaymentService, stockReservationService, inventoryService, priceTierService, voucherService, db,
 	)
+	preOrderService := services.NewPreOrderService(
+		preOrderCampaignRepo, preOrderRepo, productRepo, productVariantRepo, orderRepo, addressRepo,
+		ecommPaymentRepo, eCommercePaymentService, inventoryService, notificationService, db,
+	)
+	addressService := services.NewAddressService(addressRepo, db)
+	orderService := services.NewOrderService(orderRepo, deliveryRepo, deliveryService, notificationService)
 	adminService := services.NewAdminService(
 		payoutRepo,
 		userRepo,
@@ -94,10 +135,26 @@ func ProtectedRoutes(router *gin.RouterGroup, db *gorm.DB, chatHandler *handlers
 	offerHandler := handlers.NewOfferHandler(offerService)
 	reviewHandler := handlers.NewReviewHandler(reviewService, deliveryService)
 	deliveryHandler := handlers.NewDeliveryHandler(deliveryService)
+	deliveryProofHandler := handlers.NewDeliveryProofHandler(deliveryProofService, deliveryDisputeService)
+	deliveryFailureHandler := handlers.NewDeliveryFailureHandler(deliveryFailureService)
+	deliveryConditionHandler := handlers.NewDeliveryConditionHandler(deliveryConditionService)
+	driverRouteHandler := handlers.NewDriverRouteHandler(driverRouteService)
+	driverHandler := handlers.NewDriverHandler(driverService)
 	productHandler := handlers.NewProductHandler(productService)
+	inventoryHandler := handlers.NewInventoryHandler(inventoryService)
+	restockHandler := handlers.NewRestockHandler(restockService)
+	preOrderHandler := handlers.NewPreOrderHandler(preOrderService)
+	eCommerceRefundHandler := handlers.NewECommerceRefundHandler(eCommercePaymentService)
+	buyerGroupHandler := handlers.NewBuyerGroupHandler(buyerGroupService)
+	priceTierHandler := handlers.NewPriceTierHandler(priceTierService)
+	voucherHandler := handlers.NewVoucherHandler(voucherService)
 	cartHandler := handlers.NewCartHandler(cartService)
 	checkoutHandler := handlers.NewCheckoutHandler(checkoutService)
+	addressHandler := handlers.NewAddressHandler(addressService)
+	orderHandler := handlers.NewOrderHandler(orderService)
 	adminHandler := handlers.NewAdminHandler(adminService)
+	categoryHandler := handlers.NewCategoryHandler(categoryService)
+	stockReservationHandler := handlers.NewStockReservationHandler(stockReservationService)
 	profitHandler := handlers.NewProfitHandler(profitService)
 
 	// deliveryRepo sudah diinisialisasi sebelumnya
@@ -188,17 +245,68 @@ func ProtectedRoutes(router *gin.RouterGroup, db *gorm.DB, chatHandler *handlers
 	// Middleware di sini bisa disesuaikan jika ada endpoint yang bisa diakses kedua peran
 	{
 		deliveries.POST("/", middleware.RoleMiddleware("farmer"), deliveryHandler.CreateDelivery)
+		deliveries.POST("/consolidated", middleware.RoleMiddleware("farmer"), deliveryHandler.CreateConsolidatedDelivery)
 		deliveries.GET("/:id/find-drivers", middleware.RoleMiddleware("farmer"), deliveryHandler.FindDrivers)
 		deliveries.POST("/:id/select-driver/:driverId", middleware.RoleMiddleware("farmer"), deliveryHandler.SelectDriver)
 		deliveries.GET("/my", deliveryHandler.GetMyDeliveries)
+		deliveries.GET("/:id", deliveryHandler.GetDeliveryDetail)
+		deliveries.POST("/:id/status", middleware.RoleMiddleware("driver"), deliveryHandler.UpdateStatus)
 		deliveries.GET("/:id/track", middleware.RoleMiddleware("farmer"), trackingHandler.GetLatestLocation)
+		deliveries.GET("/:id/track/stream", trackingHandler.StreamSSE)
+		deliveries.GET("/:id/track/ws", trackingHandler.StreamWS)
+		deliveries.GET("/:id/route", trackingHandler.GetRouteHistory)
 		deliveries.POST("/:id/location", middleware.RoleMiddleware("driver"), trackingHandler.UpdateLocation)
+		deliveries.POST("/:id/locations/batch", middleware.RoleMiddleware("driver"), trackingHandler.UploadBatch)
 		deliveries.POST("/:id/release-payment", middleware.RoleMiddleware("farmer"), paymentHandler.ReleaseDeliveryPayment)
+		deliveries.POST("/:id/proof/otp", middleware.RoleMiddleware("driver"), deliveryProofHandler.RequestOTP)
+		deliveries.POST("/:id/proof", middleware.RoleMiddleware("driver"), deliveryProofHandler.SubmitProof)
+		deliveries.POST("/:id/stops/:stopId/proof/otp", middleware.RoleMiddleware("driver"), deliveryProofHandler.RequestStopOTP)
+		deliveries.POST("/:id/stops/:stopId/proof", middleware.RoleMiddleware("driver"), deliveryProofHandler.SubmitStopProof)
+		deliveries.POST("/:id/disputes", middleware.RoleMiddleware("farmer"), deliveryProofHandler.OpenDispute)
+		deliveries.POST("/:id/failure", middleware.RoleMiddleware("driver"), deliveryFailureHandler.ReportFailure)
+		deliveries.POST("/:id/failure/resolve", middleware.RoleMiddleware("farmer"), deliveryFailureHandler.ResolveFailure)
+		deliveries.GET("/:id/failures", deliveryFailureHandler.GetFailures)
+		deliveries.PUT("/:id/condition/thresholds", middleware.RoleMiddleware("farmer"), deliveryConditionHandler.SetThresholds)
+		deliveries.POST("/:id/condition", middleware.RoleMiddleware("driver"), deliveryConditionHandler.RecordReading)
+		deliveries.GET("/:id/condition", deliveryConditionHandler.GetConditionReport)
+		deliveries.GET("/:id/report", deliveryConditionHandler.DownloadDeliveryReport)
+	}
+	// Cold-Chain Device Routes (sensor IoT milik petani)
+	coldChainDevices := router.Group("/cold-chain/devices")
+	coldChainDevices.Use(middleware.RoleMiddleware("farmer"))
+	{
+		coldChainDevices.POST("/", deliveryConditionHandler.RegisterDevice)
+		coldChainDevices.GET("/", deliveryConditionHandler.GetMyDevices)
+		coldChainDevices.DELETE("/:id", deliveryConditionHandler.RevokeDevice)
+	}
+	// Driver Presence Routes (status online & heartbeat)
+	driverPresence := router.Group("/drivers/me")
+	driverPresence.Use(middleware.RoleMiddleware("driver"))
+	{
+		driverPresence.GET("/presence", driverHandler.GetPresence)
+		driverPresence.POST("/online", driverHandler.GoOnline)
+		driverPresence.POST("/offline", driverHandler.GoOffline)
+		driverPresence.POST("/heartbeat", driverHandler.Heartbeat)
 	}
+	// Driver Route Routes (rute reguler driver)
+	driverRoutes := router.Group("/driver-routes")
+	{
+		driverRoutes.GET("/search", middleware.RoleMiddleware("farmer"), driverRouteHandler.SearchDrivers)
+		driverRoutes.GET("/my", middleware.RoleMiddleware("driver"), driverRouteHandler.GetMyRoutes)
+		driverRoutes.POST("/", middleware.RoleMiddleware("driver"), driverRouteHandler.CreateRoute)
+		driverRoutes.PUT("/:id", middleware.RoleMiddleware("driver"), driverRouteHandler.UpdateRoute)
+		driverRoutes.DELETE("/:id", middleware.RoleMiddleware("driver"), driverRouteHandler.DeleteRoute)
+	}
+
 	products := router.Group("/products")
 	{
 		// [RUTE BARU] Pastikan ini didaftarkan SEBELUM rute /:id
 		products.GET("/my", middleware.RoleMiddleware("farmer"), productHandler.GetMyProducts)
+		// Langganan notifikasi produk tersedia kembali (semua pengguna)
+		products.POST("/:id/restock-subscription", restockHandler.Subscribe)
+		products.DELETE("/:id/restock-subscription", restockHandler.Unsubscribe)
+		// Kampanye pre-order yang masih bisa dipesan (semua pengguna)
+		products.GET("/:id/pre-order-campaigns/open", preOrderHandler.GetOpenCampaigns)
 		// Rute lain untuk farmer
 		products.Use(middleware.RoleMiddleware("farmer"))
 		{
@@ -206,6 +314,23 @@ func ProtectedRoutes(router *gin.RouterGroup, db *gorm.DB, chatHandler *handlers
 			products.POST("/upload-image", productHandler.UploadImage)
 			products.PUT("/:id", productHandler.UpdateProduct)
 			products.DELETE("/:id", productHandler.DeleteProduct)
+			products.POST("/:id/variants", productHandler.AddVariant)
+			products.PUT("/:id/variants/:variantId", productHandler.UpdateVariant)
+			products.DELETE("/:id/variants/:variantId", productHandler.DeleteVariant)
+			products.GET("/:id/variants/:variantId/price-tiers", priceTierHandler.GetTiers)
+			products.PUT("/:id/variants/:variantId/price-tiers", priceTierHandler.ReplaceTiers)
+			products.GET("/:id/stock-movements", inventoryHandler.GetStockMovements)
+			products.POST("/:id/variants/:variantId/stock-movements", inventoryHandler.RecordStockMovement)
+			products.GET("/:id/restock-schedules", restockHandler.GetSchedules)
+			products.POST("/:id/restock-schedules", restockHandler.CreateSchedule)
+			products.PUT("/:id/restock-schedules/:scheduleId", restockHandler.UpdateSchedule)
+			products.DELETE("/:id/restock-schedules/:scheduleId", restockHandler.CancelSchedule)
+			products.POST("/:id/restock-schedules/:scheduleId/harvest", restockHandler.MarkHarvested)
+			products.GET("/:id/pre-order-campaigns", preOrderHandler.GetCampaigns)
+			products.POST("/:id/pre-order-campaigns", preOrderHandler.CreateCampaign)
+			products.GET("/:id/pre-order-campaigns/:campaignId/pre-orders", preOrderHandler.GetCampaignPreOrders)
+			products.POST("/:id/pre-order-campaigns/:campaignId/cancel", preOrderHandler.CancelCampaign)
+			products.POST("/:id/pre-order-campaigns/:campaignId/harvest", preOrderHandler.HarvestCampaign)
 		}
 	}
 
@@ -213,13 +338,45 @@ func ProtectedRoutes(router *gin.RouterGroup, db *gorm.DB, chatHandler *handlers
 	{
 		cart.GET("/", cartHandler.GetCart)
 		cart.POST("/", cartHandler.AddToCart)
-		cart.PUT("/:productId", cartHandler.UpdateCartItem)
-		cart.DELETE("/:productId", cartHandler.RemoveFromCart)
+		cart.PUT("/:variantId", cartHandler.UpdateCartItem)
+		cart.DELETE("/:variantId", cartHandler.RemoveFromCart)
+	}
+	addresses := router.Group("/addresses")
+	{
+		addresses.GET("/", addressHandler.GetMyAddresses)
+		addresses.POST("/", addressHandler.CreateAddress)
+		addresses.GET("/:id", addressHandler.GetAddress)
+		addresses.PUT("/:id", addressHandler.UpdateAddress)
+		addresses.DELETE("/:id", addressHandler.DeleteAddress)
+		addresses.POST("/:id/default", addressHandler.SetDefaultAddress)
 	}
 	checkout := router.Group("/checkout")
 	{
 		checkout.POST("/", checkoutHandler.CreateOrders)
 		checkout.POST("/direct", checkoutHandler.DirectCheckout)
+		checkout.POST("/preview", checkoutHandler.PreviewCheckout)
+	}
+	// Voucher toko milik petani (ditanggung petani)
+	vouchers := router.Group("/vouchers")
+	vouchers.Use(middleware.RoleMiddleware("farmer"))
+	{
+		vouchers.GET("/my", voucherHandler.GetVouchers)
+		vouchers.POST("/", voucherHandler.CreateVoucher)
+		vouchers.PUT("/:id", voucherHandler.UpdateVoucher)
+	}
+	preOrders := router.Group("/pre-orders")
+	{
+		preOrders.POST("/", preOrderHandler.PlacePreOrder)
+		preOrders.GET("/my", preOrderHandler.GetMyPreOrders)
+		preOrders.POST("/:id/pay-balance", preOrderHandler.PayBalance)
+	}
+	orders := router.Group("/orders")
+	{
+		orders.GET("/my", orderHandler.GetMyOrders)
+		orders.GET("/incoming", middleware.RoleMiddleware("farmer"), orderHandler.GetIncomingOrders)
+		orders.GET("/:id", orderHandler.GetOrderDetail)
+		orders.POST("/:id/ship", middleware.RoleMiddleware("farmer"), orderHandler.ShipOrder)
+		orders.POST("/:id/confirm-received", orderHandler.ConfirmReceived)
 	}
 
 	admin := router.Group("/admin")
@@ -240,5 +397,33 @@ func ProtectedRoutes(router *gin.RouterGroup, db *gorm.DB, chatHandler *handlers
 		admin.GET("/transactions", adminHandler.GetTransactions)
 		admin.GET("/transactions/export", adminHandler.ExportTransactions)
 		admin.GET("/reports/profit", profitHandler.GetPlatformProfitReport)
+		admin.GET("/delivery-disputes", deliveryProofHandler.GetDisputes)
+		admin.GET("/delivery-disputes/:id", deliveryProofHandler.GetDisputeDetail)
+		admin.POST("/delivery-disputes/:id/resolve", deliveryProofHandler.ResolveDispute)
+
+		// Kategori produk
+		admin.POST("/categories", categoryHandler.CreateCategory)
+		admin.PUT("/categories/:id", categoryHandler.UpdateCategory)
+		admin.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+		// Reservasi stok yang macet
+		admin.GET("/reservations/stuck", stockReservationHandler.GetStuckReservations)
+		admin.POST("/reservations/:id/release", stockReservationHandler.ReleaseReservation)
+		// Pengembalian dana pre-order (panen kurang / dibatalkan)
+		admin.GET("/pre-order-refunds", preOrderHandler.GetRefunds)
+		admin.POST("/pre-order-refunds/:id/complete", preOrderHandler.CompleteRefund)
+		// Pengembalian dana pembayaran yang masuk setelah pesanan dibatalkan
+		admin.GET("/ecommerce-refunds", eCommerceRefundHandler.GetRefunds)
+		admin.POST("/ecommerce-refunds/:id/complete", eCommerceRefundHandler.CompleteRefund)
+		// Grup pembeli untuk harga grosir B2B
+		admin.GET("/buyer-groups", buyerGroupHandler.GetGroups)
+		admin.POST("/buyer-groups", buyerGroupHandler.CreateGroup)
+		admin.PUT("/buyer-groups/:id", buyerGroupHandler.UpdateGroup)
+		admin.GET("/buyer-groups/:id/members", buyerGroupHandler.GetMembers)
+		admin.POST("/buyer-groups/:id/members", buyerGroupHandler.AddMember)
+		admin.DELETE("/buyer-groups/:id/members/:userId", buyerGroupHandler.RemoveMember)
+		// Voucher & promo platform
+		admin.GET("/vouchers", voucherHandler.GetVouchers)
+		admin.POST("/vouchers", voucherHandler.CreateVoucher)
+		admin.PUT("/vouchers/:id", voucherHandler.UpdateVoucher)
 	}
 }
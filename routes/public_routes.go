@@ -3,6 +3,8 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/whsasmita/AgroLink_API/handlers"
+	"github.com/whsasmita/AgroLink_API/middleware"
+	"github.com/whsasmita/AgroLink_API/pkg/tracking"
 	"github.com/whsasmita/AgroLink_API/repositories"
 	"github.com/whsasmita/AgroLink_API/services"
 	"gorm.io/gorm"
@@ -39,9 +41,22 @@ func PublicRoutes(router *gin.RouterGroup, db *gorm.DB) {
 	driverService := services.NewDriverService(driverRepo)
 	driverHandler := handlers.NewDriverHandler(driverService)
 
+	// Komponen Cold-Chain untuk perangkat IoT (autentikasi lewat X-Device-Key)
+	notifRepo := repositories.NewNotificationRepository(db)
+	deliveryRepo := repositories.NewDeliveryRepository(db)
+	deliveryConditionRepo := repositories.NewDeliveryConditionRepository(db)
+	notificationService := services.NewNotificationService(notifRepo, services.NewEmailService(), userRepo)
+	deliveryConditionService := services.NewDeliveryConditionService(deliveryConditionRepo, deliveryRepo, notificationService, tracking.Default(), db)
+	deliveryConditionHandler := handlers.NewDeliveryConditionHandler(deliveryConditionService)
+
 	productRepo := repositories.NewProductRepository(db)
-	productService := services.NewProductService(productRepo, db)
+	categoryRepo := repositories.NewCategoryRepository(db)
+	productVariantRepo := repositories.NewProductVariantRepository(db)
+	inventoryService := services.NewInventoryService(repositories.NewInventoryMovementRepository(db), productRepo, productVariantRepo, repositories.NewOrderRepository(db), db)
+	productService := services.NewProductService(productRepo, productVariantRepo, categoryRepo, inventoryService, db)
 	productHandler := handlers.NewProductHandler(productService)
+	categoryService := services.NewCategoryService(categoryRepo)
+	categoryHandler := handlers.NewCategoryHandler(categoryService)
 
 	// transactionRepo := repositories.NewTransactionRepository(db)
 	// userRepo sudah diinisialisasi di atas
@@ -86,11 +101,23 @@ func PublicRoutes(router *gin.RouterGroup, db *gorm.DB) {
 		projects.GET("/", projectHandler.FindAllProjects)
 	}
 
+	iot := router.Group("/iot")
+	iot.Use(middleware.DeviceKeyMiddleware(deliveryConditionRepo))
+	{
+		iot.POST("/deliveries/:id/readings", deliveryConditionHandler.RecordDeviceReadings)
+	}
+
 	products := router.Group("/products")
 	{
 		products.GET("/", productHandler.GetAllProducts)
 		products.GET("/:id", productHandler.GetProductByID)
 	}
 
+	categories := router.Group("/categories")
+	{
+		categories.GET("/", categoryHandler.GetCategoryTree)
+		categories.GET("/:slug", categoryHandler.GetCategoryBySlug)
+	}
+
 	// Tambahkan juga routes lain seperti: search, contracts, payments, reviews, notifications ke sini.
 }
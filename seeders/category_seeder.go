@@ -0,0 +1,57 @@
+package seeders
+
+import (
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+)
+
+type seedCategory struct {
+	Name     string
+	Slug     string
+	Children []seedCategory
+}
+
+// defaultCategoryTree adalah pohon kategori awal; admin dapat mengubahnya setelah seeding.
+var defaultCategoryTree = []seedCategory{
+	{Name: "Sayuran", Slug: "sayuran", Children: []seedCategory{
+		{Name: "Sayuran Daun", Slug: "sayuran-daun"},
+		{Name: "Sayuran Buah", Slug: "sayuran-buah"},
+		{Name: "Umbi & Bumbu", Slug: "umbi-bumbu"},
+	}},
+	{Name: "Buah", Slug: "buah"},
+	{Name: "Kopi", Slug: "kopi"},
+	{Name: "Oleh-oleh", Slug: "oleh-oleh"},
+	{Name: "Lainnya", Slug: "lainnya"},
+}
+
+// SeedCategories membuat pohon kategori default jika slug-nya belum ada.
+func SeedCategories(db *gorm.DB) {
+	log.Println("Seeding product categories...")
+	seedCategoryLevel(db, defaultCategoryTree, nil)
+}
+
+func seedCategoryLevel(db *gorm.DB, nodes []seedCategory, parentID *uuid.UUID) {
+	for i, node := range nodes {
+		var category models.Category
+		if err := db.Where("slug = ?", node.Slug).First(&category).Error; err != nil {
+			category = models.Category{ParentID: parentID, Name: node.Name, Slug: node.Slug, SortOrder: i}
+			if err := db.Create(&category).Error; err != nil {
+				log.Printf("Failed to create category %s: %v", node.Slug, err)
+				continue
+			}
+		}
+		seedCategoryLevel(db, node.Children, &category.ID)
+	}
+}
+
+// categoryIDBySlug mencari ID kategori hasil seeding; nil jika belum ada.
+func categoryIDBySlug(db *gorm.DB, slug string) *uuid.UUID {
+	var category models.Category
+	if err := db.Where("slug = ?", slug).First(&category).Error; err != nil {
+		return nil
+	}
+	return &category.ID
+}
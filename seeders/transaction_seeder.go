@@ -380,33 +380,45 @@ func slugify(s string) string {
 	return out
 }
 
-func categoryForProduct(title string) *string {
+// categoryForProduct menebak slug kategori dari nama produk untuk data seed.
+func categoryForProduct(title string) string {
 	t := strings.ToLower(title)
 
-	var cat string
 	switch {
 	case strings.Contains(t, "kopi"):
-		cat = "Kopi"
+		return "kopi"
 	case strings.Contains(t, "salak") || strings.Contains(t, "alpukat") ||
 		strings.Contains(t, "mangga") || strings.Contains(t, "jeruk") ||
 		strings.Contains(t, "apel") || strings.Contains(t, "naga") ||
 		strings.Contains(t, "rambutan") || strings.Contains(t, "nanas") ||
 		strings.Contains(t, "stowberry") || strings.Contains(t, "stroberi"):
-		cat = "Buah"
+		return "buah"
+	case strings.Contains(t, "selada") || strings.Contains(t, "kubis"):
+		return "sayuran-daun"
+	case strings.Contains(t, "bawang") || strings.Contains(t, "wortel") ||
+		strings.Contains(t, "kentang"):
+		return "umbi-bumbu"
 	case strings.Contains(t, "cabe") || strings.Contains(t, "cabai") ||
-		strings.Contains(t, "bawang") || strings.Contains(t, "tomat") ||
-		strings.Contains(t, "kubis") || strings.Contains(t, "buncis") ||
-		strings.Contains(t, "wortel") || strings.Contains(t, "kentang") ||
-		strings.Contains(t, "selada") || strings.Contains(t, "jagung") ||
-		strings.Contains(t, "labu"):
-		cat = "Sayur"
+		strings.Contains(t, "tomat") || strings.Contains(t, "buncis") ||
+		strings.Contains(t, "jagung") || strings.Contains(t, "labu"):
+		return "sayuran-buah"
 	case strings.Contains(t, "dodol"):
-		cat = "Oleh-oleh"
+		return "oleh-oleh"
 	default:
-		cat = "Lainnya"
+		return "lainnya"
 	}
+}
 
-	return &cat
+// seedDefaultVariant membuat satu varian per kg untuk produk seed.
+func seedDefaultVariant(price float64, stock int) models.ProductVariant {
+	return models.ProductVariant{
+		Name:         "1 kg",
+		Unit:         "kg",
+		Price:        price,
+		Stock:        float64(stock),
+		MinOrderQty:  1,
+		QuantityStep: 1,
+	}
 }
 
 func getDefaultFarmerID(db *gorm.DB) (uuid.UUID, error) {
@@ -625,10 +637,9 @@ func SeedProducts(db *gorm.DB) {
 			FarmerID:      farmer.ID,
 			Description:   fmt.Sprintf("%s dari %s", ps.Produk, farmer.Name),
 			Location:      nil, // bisa diisi nanti
-			Category:      categoryForProduct(ps.Produk),
+			CategoryID:    categoryIDBySlug(db, categoryForProduct(ps.Produk)),
 			Price:         ps.LastPrice,
-			Stock:         stock,
-			ReservedStock: 0,
+			Variants:      []models.ProductVariant{seedDefaultVariant(ps.LastPrice, stock)},
 			ImageURLs:     datatypes.JSON(imgJSON),
 			Rating:        rating,
 			CreatedAt:     ps.LastDate.AddDate(0, 0, -1),
@@ -666,10 +677,9 @@ func SeedProducts(db *gorm.DB) {
 			FarmerID:      farmer.ID,
 			Description:   fmt.Sprintf("%s dari %s", title, farmer.Name),
 			Location:      nil,
-			Category:      categoryForProduct(title),
+			CategoryID:    categoryIDBySlug(db, categoryForProduct(title)),
 			Price:         10000.0, // default placeholder
-			Stock:         50,
-			ReservedStock: 0,
+			Variants:      []models.ProductVariant{seedDefaultVariant(10000.0, 50)},
 			ImageURLs:     datatypes.JSON(imgJSON),
 			Rating:        nil, // belum ada penjualan → belum ada rating
 			CreatedAt:     time.Now().AddDate(0, 0, -7),
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"gorm.io/gorm"
+)
+
+type AddressService interface {
+	CreateAddress(userID uuid.UUID, input dto.AddressRequest) (*models.Address, error)
+	GetMyAddresses(userID uuid.UUID) ([]models.Address, error)
+	GetAddress(addressID, userID uuid.UUID) (*models.Address, error)
+	UpdateAddress(addressID, userID uuid.UUID, input dto.AddressRequest) (*models.Address, error)
+	DeleteAddress(addressID, userID uuid.UUID) error
+	SetDefaultAddress(addressID, userID uuid.UUID) (*models.Address, error)
+}
+
+type addressService struct {
+	addressRepo repositories.AddressRepository
+	db          *gorm.DB
+}
+
+func NewAddressService(addressRepo repositories.AddressRepository, db *gorm.DB) AddressService {
+	return &addressService{addressRepo: addressRepo, db: db}
+}
+
+// CreateAddress menambah alamat. Alamat pertama otomatis menjadi alamat utama.
+func (s *addressService) CreateAddress(userID uuid.UUID, input dto.AddressRequest) (*models.Address, error) {
+	address := &models.Address{UserID: userID}
+	applyAddressInput(address, input)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.addressRepo.FindDefaultByUserID(tx, userID); errors.Is(err, gorm.ErrRecordNotFound) {
+			address.IsDefault = true
+		}
+		if address.IsDefault {
+			if err := s.addressRepo.ClearDefault(tx, userID); err != nil {
+				return err
+			}
+		}
+		return s.addressRepo.Create(tx, address)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create address: %w", err)
+	}
+	return address, nil
+}
+
+func (s *addressService) GetMyAddresses(userID uuid.UUID) ([]models.Address, error) {
+	return s.addressRepo.FindAllByUserID(userID)
+}
+
+func (s *addressService) GetAddress(addressID, userID uuid.UUID) (*models.Address, error) {
+	address, err := s.addressRepo.FindByID(addressID)
+	if err != nil {
+		return nil, errors.New("address not found")
+	}
+	if address.UserID != userID {
+		return nil, errors.New("forbidden: you do not own this address")
+	}
+	return address, nil
+}
+
+// UpdateAddress mengubah alamat. Pesanan lama tidak terpengaruh karena menyimpan salinan alamat.
+func (s *addressService) UpdateAddress(addressID, userID uuid.UUID, input dto.AddressRequest) (*models.Address, error) {
+	address, err := s.GetAddress(addressID, userID)
+	if err != nil {
+		return nil, err
+	}
+	wasDefault := address.IsDefault
+	applyAddressInput(address, input)
+	// Alamat utama hanya berpindah lewat alamat lain yang dijadikan utama
+	address.IsDefault = wasDefault || input.IsDefault
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if address.IsDefault && !wasDefault {
+			if err := s.addressRepo.ClearDefault(tx, userID); err != nil {
+				return err
+			}
+		}
+		return s.addressRepo.Update(tx, address)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update address: %w", err)
+	}
+	return address, nil
+}
+
+// DeleteAddress menghapus alamat. Jika alamat utama dihapus, alamat terbaru menggantikannya.
+func (s *addressService) DeleteAddress(addressID, userID uuid.UUID) error {
+	address, err := s.GetAddress(addressID, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.addressRepo.Delete(tx, address); err != nil {
+			return fmt.Errorf("failed to delete address: %w", err)
+		}
+		if !address.IsDefault {
+			return nil
+		}
+		next, err := s.addressRepo.FindDefaultByUserID(tx, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		next.IsDefault = true
+		return s.addressRepo.Update(tx, next)
+	})
+}
+
+func (s *addressService) SetDefaultAddress(addressID, userID uuid.UUID) (*models.Address, error) {
+	address, err := s.GetAddress(addressID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if address.IsDefault {
+		return address, nil
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.addressRepo.ClearDefault(tx, userID); err != nil {
+			return err
+		}
+		address.IsDefault = true
+		return s.addressRepo.Update(tx, address)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set default address: %w", err)
+	}
+	return address, nil
+}
+
+func applyAddressInput(address *models.Address, input dto.AddressRequest) {
+	address.Label = strings.TrimSpace(input.Label)
+	address.RecipientName = strings.TrimSpace(input.RecipientName)
+	address.PhoneNumber = strings.TrimSpace(input.PhoneNumber)
+	address.Street = strings.TrimSpace(input.Street)
+	address.Province = strings.TrimSpace(input.Province)
+	address.City = strings.TrimSpace(input.City)
+	address.District = strings.TrimSpace(input.District)
+	address.PostalCode = strings.TrimSpace(input.PostalCode)
+	address.Latitude = input.Latitude
+	address.Longitude = input.Longitude
+	address.IsDefault = input.IsDefault
+}
@@ -163,6 +163,12 @@ func (s *adminService) GetPendingPayouts() ([]dto.PayoutDetailResponse, error) {
 			if p.Transaction.Invoice.DeliveryID != nil {
 				dto.ContextTitle = "Pengiriman: " + p.Transaction.Invoice.Delivery.ItemDescription
 			}
+		} else if p.PayeeType == "farmer" && p.Farmer != nil {
+			// Refund dana escrow pengiriman yang dibatalkan
+			dto.PayeeName = p.Farmer.User.Name
+			if p.Transaction.Invoice.DeliveryID != nil {
+				dto.ContextTitle = "Refund pengiriman: " + p.Transaction.Invoice.Delivery.ItemDescription
+			}
 		}
 		response = append(response, dto)
 	}
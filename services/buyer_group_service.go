@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"gorm.io/gorm"
+)
+
+// BuyerGroupService mengelola grup pembeli (mis. grosir terverifikasi) yang mendapat
+// daftar harga sendiri. Hanya dipakai admin.
+type BuyerGroupService interface {
+	GetGroups() ([]dto.BuyerGroupResponse, error)
+	CreateGroup(input dto.BuyerGroupRequest) (*dto.BuyerGroupResponse, error)
+	UpdateGroup(groupID uuid.UUID, input dto.BuyerGroupRequest) (*dto.BuyerGroupResponse, error)
+	GetMembers(groupID uuid.UUID) ([]dto.BuyerGroupMemberResponse, error)
+	AddMember(groupID, userID uuid.UUID) error
+	RemoveMember(groupID, userID uuid.UUID) error
+}
+
+type buyerGroupService struct {
+	groupRepo repositories.BuyerGroupRepository
+	userRepo  repositories.UserRepository
+}
+
+func NewBuyerGroupService(groupRepo repositories.BuyerGroupRepository, userRepo repositories.UserRepository) BuyerGroupService {
+	return &buyerGroupService{groupRepo: groupRepo, userRepo: userRepo}
+}
+
+func (s *buyerGroupService) GetGroups() ([]dto.BuyerGroupResponse, error) {
+	groups, err := s.groupRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]dto.BuyerGroupResponse, 0, len(groups))
+	for _, group := range groups {
+		count, err := s.groupRepo.CountMembers(group.ID)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, toBuyerGroupResponse(group, count))
+	}
+	return responses, nil
+}
+
+func (s *buyerGroupService) CreateGroup(input dto.BuyerGroupRequest) (*dto.BuyerGroupResponse, error) {
+	name := strings.TrimSpace(input.Name)
+	if err := s.ensureNameAvailable(name, uuid.Nil); err != nil {
+		return nil, err
+	}
+	group := &models.BuyerGroup{Name: name, Description: input.Description, IsActive: true}
+	if input.IsActive != nil {
+		group.IsActive = *input.IsActive
+	}
+	if err := s.groupRepo.Create(group); err != nil {
+		return nil, fmt.Errorf("failed to create buyer group: %w", err)
+	}
+	response := toBuyerGroupResponse(*group, 0)
+	return &response, nil
+}
+
+// UpdateGroup mengubah nama/deskripsi grup. Menonaktifkan grup menghentikan harga
+// khususnya tanpa menghapus anggota maupun tier harganya.
+func (s *buyerGroupService) UpdateGroup(groupID uuid.UUID, input dto.BuyerGroupRequest) (*dto.BuyerGroupResponse, error) {
+	group, err := s.groupRepo.FindByID(groupID)
+	if err != nil {
+		return nil, errors.New("buyer group not found")
+	}
+	name := strings.TrimSpace(input.Name)
+	if err := s.ensureNameAvailable(name, groupID); err != nil {
+		return nil, err
+	}
+	group.Name = name
+	group.Description = input.Description
+	if input.IsActive != nil {
+		group.IsActive = *input.IsActive
+	}
+	if err := s.groupRepo.Update(group); err != nil {
+		return nil, fmt.Errorf("failed to update buyer group: %w", err)
+	}
+	count, err := s.groupRepo.CountMembers(groupID)
+	if err != nil {
+		return nil, err
+	}
+	response := toBuyerGroupResponse(*group, count)
+	return &response, nil
+}
+
+func (s *buyerGroupService) GetMembers(groupID uuid.UUID) ([]dto.BuyerGroupMemberResponse, error) {
+	if _, err := s.groupRepo.FindByID(groupID); err != nil {
+		return nil, errors.New("buyer group not found")
+	}
+	members, err := s.groupRepo.FindMembers(groupID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]dto.BuyerGroupMemberResponse, 0, len(members))
+	for _, m := range members {
+		response := dto.BuyerGroupMemberResponse{UserID: m.UserID, JoinedAt: m.CreatedAt}
+		if m.User != nil {
+			response.Name = m.User.Name
+			response.Email = m.User.Email
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// AddMember memasukkan pembeli ke grup. Pembeli yang sudah berada di grup lain dipindahkan.
+func (s *buyerGroupService) AddMember(groupID, userID uuid.UUID) error {
+	if _, err := s.groupRepo.FindByID(groupID); err != nil {
+		return errors.New("buyer group not found")
+	}
+	if _, err := s.userRepo.FindByID(userID.String()); err != nil {
+		return errors.New("user not found")
+	}
+
+	member, err := s.groupRepo.FindMemberByUserID(userID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		member = &models.BuyerGroupMember{UserID: userID}
+	}
+	member.BuyerGroupID = groupID
+	return s.groupRepo.SaveMember(member)
+}
+
+func (s *buyerGroupService) RemoveMember(groupID, userID uuid.UUID) error {
+	if err := s.groupRepo.RemoveMember(groupID, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("buyer group member not found")
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *buyerGroupService) ensureNameAvailable(name string, groupID uuid.UUID) error {
+	if name == "" {
+		return errors.New("invalid name: must not be empty")
+	}
+	existing, err := s.groupRepo.FindByName(name)
+	if err == nil && existing.ID != groupID {
+		return errors.New("invalid name: buyer group already exists")
+	}
+	return nil
+}
+
+func toBuyerGroupResponse(group models.BuyerGroup, memberCount int64) dto.BuyerGroupResponse {
+	return dto.BuyerGroupResponse{
+		ID:          group.ID,
+		Name:        group.Name,
+		Description: group.Description,
+		IsActive:    group.IsActive,
+		MemberCount: memberCount,
+		CreatedAt:   group.CreatedAt,
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/whsasmita/AgroLink_API/dto"
@@ -14,18 +15,60 @@ import (
 type CartService interface {
 	GetCart(userID uuid.UUID) (*dto.CartResponse, error)
 	AddToCart(userID uuid.UUID, input dto.AddToCartInput) (*models.Cart, error)
-	UpdateCartItem(userID, productID uuid.UUID, input dto.UpdateCartInput) (*models.Cart, error)
-	RemoveFromCart(userID, productID uuid.UUID) error
+	UpdateCartItem(userID, variantID uuid.UUID, input dto.UpdateCartInput) (*models.Cart, error)
+	RemoveFromCart(userID, variantID uuid.UUID) error
 }
 
 type cartService struct {
 	cartRepo    repositories.CartRepository
-	productRepo repositories.ProductRepository
+	variantRepo repositories.ProductVariantRepository
+	inventory   InventoryService
+	pricing     PriceTierService
 	db          *gorm.DB
 }
 
-func NewCartService(cartRepo repositories.CartRepository, productRepo repositories.ProductRepository, db *gorm.DB) CartService {
-	return &cartService{cartRepo: cartRepo, productRepo: productRepo, db: db}
+func NewCartService(cartRepo repositories.CartRepository, variantRepo repositories.ProductVariantRepository, inventory InventoryService, pricing PriceTierService, db *gorm.DB) CartService {
+	return &cartService{cartRepo: cartRepo, variantRepo: variantRepo, inventory: inventory, pricing: pricing, db: db}
+}
+
+// lockVariantForPurchase mengunci varian yang dibeli. Jika variant_id kosong, varian
+// tunggal produk dipakai; produk dengan beberapa varian wajib memilih salah satunya.
+func lockVariantForPurchase(tx *gorm.DB, variantRepo repositories.ProductVariantRepository, productID uuid.UUID, variantID *uuid.UUID) (*models.ProductVariant, error) {
+	if variantID == nil {
+		variants, err := variantRepo.FindAllByProductID(tx, productID)
+		if err != nil || len(variants) == 0 {
+			return nil, errors.New("product not found")
+		}
+		if len(variants) > 1 {
+			return nil, errors.New("variant_id is required for products with multiple variants")
+		}
+		variantID = &variants[0].ID
+	}
+	variant, err := variantRepo.FindByIDForUpdate(tx, *variantID)
+	if err != nil || variant.ProductID != productID {
+		return nil, errors.New("product variant not found")
+	}
+	return variant, nil
+}
+
+// holdCartStock menyesuaikan stok yang ditahan item keranjang dengan kuantitasnya dan
+// memperpanjang batas waktu tahanan. Varian harus sudah dikunci; item disimpan oleh pemanggil.
+func holdCartStock(tx *gorm.DB, inventory InventoryService, variant *models.ProductVariant, cartItem *models.Cart, actorID *uuid.UUID, note string) error {
+	delta := cartItem.HoldShortfall()
+	if delta > variant.AvailableStock() {
+		return errors.New("insufficient stock")
+	}
+	movementType := models.MovementReservation
+	if delta < 0 {
+		movementType = models.MovementRelease
+	}
+	if err := inventory.ApplyStockChange(tx, variant, StockChange{Type: movementType, ReservedDelta: delta, ActorID: actorID, Note: note}); err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(models.CartHoldTTL)
+	cartItem.HeldQuantity = cartItem.Quantity
+	cartItem.HoldExpiresAt = &expiresAt
+	return nil
 }
 
 func (s *cartService) GetCart(userID uuid.UUID) (*dto.CartResponse, error) {
@@ -33,6 +76,15 @@ func (s *cartService) GetCart(userID uuid.UUID) (*dto.CartResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	lines := make([]PriceLine, 0, len(cartItems))
+	for _, item := range cartItems {
+		lines = append(lines, PriceLine{VariantID: item.VariantID, BasePrice: item.Variant.Price, Quantity: item.Quantity})
+	}
+	quotes, err := s.pricing.QuotePrices(nil, userID, lines)
+	if err != nil {
+		return nil, err
+	}
+
 	var itemsResponse []dto.CartItemResponse
 	var totalPrice float64
 	for _, item := range cartItems {
@@ -43,74 +95,93 @@ func (s *cartService) GetCart(userID uuid.UUID) (*dto.CartResponse, error) {
 				firstImage = images[0]
 			}
 		}
-		subtotal := float64(item.Quantity) * item.Product.Price
+		price := quotes[item.VariantID].UnitPrice
+		subtotal := roundTo(item.Quantity*price, 2)
 		itemsResponse = append(itemsResponse, dto.CartItemResponse{
-			ProductID: item.ProductID, Title: item.Product.Title,
-			Price: item.Product.Price, ImageURL: firstImage,
+			ProductID: item.ProductID, VariantID: item.VariantID, Title: item.Product.Title,
+			VariantName: item.Variant.Name, Unit: item.Variant.Unit,
+			Price: price, BasePrice: item.Variant.Price, ImageURL: firstImage,
 			Quantity: item.Quantity, Subtotal: subtotal,
 		})
 		totalPrice += subtotal
 	}
-	return &dto.CartResponse{Items: itemsResponse, TotalPrice: totalPrice}, nil
+	return &dto.CartResponse{Items: itemsResponse, TotalPrice: roundTo(totalPrice, 2)}, nil
 }
 
 func (s *cartService) AddToCart(userID uuid.UUID, input dto.AddToCartInput) (*models.Cart, error) {
 	var finalCartItem *models.Cart
+	quantity := models.RoundQuantity(input.Quantity)
 	err := s.db.Transaction(func(tx *gorm.DB) error {
-		product, err := s.productRepo.FindByIDForUpdate(tx, input.ProductID)
-		if err != nil { return errors.New("product not found") }
-
-		availableStock := product.Stock - product.ReservedStock
-		if availableStock < input.Quantity { return errors.New("insufficient stock") }
+		variant, err := lockVariantForPurchase(tx, s.variantRepo, input.ProductID, input.VariantID)
+		if err != nil {
+			return err
+		}
 
-		cartItem, err := s.cartRepo.FindByUserAndProductWithTx(tx, userID, input.ProductID)
-		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) { return err }
+		cartItem, err := s.cartRepo.FindByUserAndVariantWithTx(tx, userID, variant.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
 
 		if cartItem.ID != uuid.Nil {
-			cartItem.Quantity += input.Quantity
-			if err := s.cartRepo.Update(tx, cartItem); err != nil { return err }
+			cartItem.Quantity = models.RoundQuantity(cartItem.Quantity + quantity)
+			if err := variant.ValidateQuantity(cartItem.Quantity); err != nil {
+				return err
+			}
+			if err := holdCartStock(tx, s.inventory, variant, cartItem, &userID, "Masuk keranjang"); err != nil {
+				return err
+			}
+			if err := s.cartRepo.Update(tx, cartItem); err != nil {
+				return err
+			}
 			finalCartItem = cartItem
-		} else {
-			newCartItem := &models.Cart{UserID: userID, ProductID: input.ProductID, Quantity: input.Quantity}
-			if err := s.cartRepo.Create(tx, newCartItem); err != nil { return err }
-			finalCartItem = newCartItem
-		}
-		
-		product.ReservedStock += input.Quantity
-		if err := s.productRepo.UpdateStock(tx, product); err != nil { return err }
-		
+			return nil
+		}
+
+		if err := variant.ValidateQuantity(quantity); err != nil {
+			return err
+		}
+		newCartItem := &models.Cart{UserID: userID, ProductID: variant.ProductID, VariantID: variant.ID, Quantity: quantity}
+		if err := holdCartStock(tx, s.inventory, variant, newCartItem, &userID, "Masuk keranjang"); err != nil {
+			return err
+		}
+		if err := s.cartRepo.Create(tx, newCartItem); err != nil {
+			return err
+		}
+		finalCartItem = newCartItem
 		return nil
 	})
 	return finalCartItem, err
 }
 
-func (s *cartService) UpdateCartItem(userID, productID uuid.UUID, input dto.UpdateCartInput) (*models.Cart, error) {
+func (s *cartService) UpdateCartItem(userID, variantID uuid.UUID, input dto.UpdateCartInput) (*models.Cart, error) {
 	var updatedCartItem *models.Cart
+	quantity := models.RoundQuantity(input.Quantity)
 	err := s.db.Transaction(func(tx *gorm.DB) error {
-		if input.Quantity == 0 {
-			// Jika kuantitas 0, panggil fungsi Remove (yang juga transaksional)
-			// Kita perlu membuat varian RemoveFromCart yang menerima 'tx'
-			return s.removeFromCartTx(tx, userID, productID)
+		if quantity == 0 {
+			// Jika kuantitas 0, item dihapus dan reservasinya dilepas
+			return s.removeFromCartTx(tx, userID, variantID)
 		}
 
-		product, err := s.productRepo.FindByIDForUpdate(tx, productID)
-		if err != nil { return errors.New("product not found") }
-
-		cartItem, err := s.cartRepo.FindByUserAndProductWithTx(tx, userID, productID)
-		if err != nil { return errors.New("item not found in cart") }
-
-		qtyDifference := input.Quantity - cartItem.Quantity
-		availableStock := product.Stock - product.ReservedStock
-
-		if qtyDifference > availableStock {
-			return errors.New("insufficient stock")
+		variant, err := s.variantRepo.FindByIDForUpdate(tx, variantID)
+		if err != nil {
+			return errors.New("product variant not found")
+		}
+		if err := variant.ValidateQuantity(quantity); err != nil {
+			return err
 		}
 
-		cartItem.Quantity = input.Quantity
-		if err := s.cartRepo.Update(tx, cartItem); err != nil { return err }
+		cartItem, err := s.cartRepo.FindByUserAndVariantWithTx(tx, userID, variantID)
+		if err != nil {
+			return errors.New("item not found in cart")
+		}
 
-		product.ReservedStock += qtyDifference
-		if err := s.productRepo.UpdateStock(tx, product); err != nil { return err }
+		cartItem.Quantity = quantity
+		if err := holdCartStock(tx, s.inventory, variant, cartItem, &userID, "Ubah jumlah di keranjang"); err != nil {
+			return err
+		}
+		if err := s.cartRepo.Update(tx, cartItem); err != nil {
+			return err
+		}
 
 		updatedCartItem = cartItem
 		return nil
@@ -122,27 +193,39 @@ func (s *cartService) UpdateCartItem(userID, productID uuid.UUID, input dto.Upda
 	return updatedCartItem, err
 }
 
-func (s *cartService) RemoveFromCart(userID, productID uuid.UUID) error {
+func (s *cartService) RemoveFromCart(userID, variantID uuid.UUID) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		return s.removeFromCartTx(tx, userID, productID)
+		return s.removeFromCartTx(tx, userID, variantID)
 	})
 }
 
 // Fungsi helper internal untuk dipanggil di dalam transaksi
-func (s *cartService) removeFromCartTx(tx *gorm.DB, userID, productID uuid.UUID) error {
-	cartItem, err := s.cartRepo.FindByUserAndProductWithTx(tx, userID, productID)
+func (s *cartService) removeFromCartTx(tx *gorm.DB, userID, variantID uuid.UUID) error {
+	// Varian dikunci lebih dulu agar jumlah tahanan tidak berubah oleh job pelepasan
+	variant, err := s.variantRepo.FindByIDForUpdate(tx, variantID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) { return errors.New("item not found in cart") }
+		return errors.New("product variant not found")
+	}
+
+	cartItem, err := s.cartRepo.FindByUserAndVariantWithTx(tx, userID, variantID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("item not found in cart")
+		}
 		return err
 	}
-	
-	product, err := s.productRepo.FindByIDForUpdate(tx, productID)
-	if err != nil { return errors.New("product not found") }
-	
-	if err := s.cartRepo.Delete(tx, userID, productID); err != nil { return err }
-	
-	product.ReservedStock -= cartItem.Quantity
-	if product.ReservedStock < 0 { product.ReservedStock = 0 }
-	
-	return s.productRepo.UpdateStock(tx, product)
-}
\ No newline at end of file
+
+	if err := s.cartRepo.Delete(tx, userID, variantID); err != nil {
+		return err
+	}
+	if cartItem.HeldQuantity <= 0 {
+		return nil // Tahanan sudah dilepas karena kedaluwarsa
+	}
+
+	return s.inventory.ApplyStockChange(tx, variant, StockChange{
+		Type:          models.MovementRelease,
+		ReservedDelta: -cartItem.HeldQuantity,
+		ActorID:       &userID,
+		Note:          "Dihapus dari keranjang",
+	})
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+type CategoryService interface {
+	GetCategoryTree() ([]dto.CategoryResponse, error)
+	GetCategoryBySlug(slug string) (*dto.CategoryDetailResponse, error)
+	CreateCategory(input dto.CategoryRequest) (*models.Category, error)
+	UpdateCategory(categoryID uuid.UUID, input dto.CategoryRequest) (*models.Category, error)
+	DeleteCategory(categoryID uuid.UUID) error
+}
+
+type categoryService struct {
+	categoryRepo repositories.CategoryRepository
+}
+
+func NewCategoryService(categoryRepo repositories.CategoryRepository) CategoryService {
+	return &categoryService{categoryRepo: categoryRepo}
+}
+
+// GetCategoryTree menyusun seluruh kategori menjadi pohon beserta jumlah produknya.
+func (s *categoryService) GetCategoryTree() ([]dto.CategoryResponse, error) {
+	categories, counts, err := s.loadCategoriesWithCounts()
+	if err != nil {
+		return nil, err
+	}
+	return buildCategoryTree(categories, counts, nil), nil
+}
+
+// GetCategoryBySlug menampilkan satu kategori, subkategorinya, dan breadcrumb ke kategori utama.
+func (s *categoryService) GetCategoryBySlug(slug string) (*dto.CategoryDetailResponse, error) {
+	categories, counts, err := s.loadCategoriesWithCounts()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.Category, len(categories))
+	var target *models.Category
+	for i := range categories {
+		byID[categories[i].ID] = categories[i]
+		if categories[i].Slug == slug {
+			target = &categories[i]
+		}
+	}
+	if target == nil {
+		return nil, errors.New("category not found")
+	}
+
+	children := buildCategoryTree(categories, counts, &target.ID)
+	node := toCategoryResponse(*target, children, counts)
+
+	var breadcrumb []dto.CategorySummary
+	for current, ok := *target, true; ok; current, ok = parentOf(byID, current) {
+		breadcrumb = append([]dto.CategorySummary{toCategorySummary(current)}, breadcrumb...)
+	}
+	return &dto.CategoryDetailResponse{CategoryResponse: node, Breadcrumb: breadcrumb}, nil
+}
+
+func (s *categoryService) CreateCategory(input dto.CategoryRequest) (*models.Category, error) {
+	category := &models.Category{}
+	if err := s.applyCategoryInput(category, input); err != nil {
+		return nil, err
+	}
+	if err := s.categoryRepo.Create(nil, category); err != nil {
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+	return category, nil
+}
+
+func (s *categoryService) UpdateCategory(categoryID uuid.UUID, input dto.CategoryRequest) (*models.Category, error) {
+	category, err := s.categoryRepo.FindByID(categoryID)
+	if err != nil {
+		return nil, errors.New("category not found")
+	}
+	if err := s.applyCategoryInput(category, input); err != nil {
+		return nil, err
+	}
+	if err := s.categoryRepo.Update(nil, category); err != nil {
+		return nil, fmt.Errorf("failed to update category: %w", err)
+	}
+	return category, nil
+}
+
+// DeleteCategory hanya mengizinkan penghapusan kategori tanpa subkategori dan tanpa produk.
+func (s *categoryService) DeleteCategory(categoryID uuid.UUID) error {
+	category, err := s.categoryRepo.FindByID(categoryID)
+	if err != nil {
+		return errors.New("category not found")
+	}
+	if children, err := s.categoryRepo.CountChildren(category.ID); err != nil {
+		return err
+	} else if children > 0 {
+		return errors.New("invalid state: category still has subcategories")
+	}
+	if products, err := s.categoryRepo.CountProducts(category.ID); err != nil {
+		return err
+	} else if products > 0 {
+		return errors.New("invalid state: category still has products, move them first")
+	}
+	return s.categoryRepo.Delete(nil, category)
+}
+
+// applyCategoryInput memvalidasi induk (tidak boleh dirinya sendiri atau turunannya)
+// dan keunikan slug sebelum mengisi field kategori.
+func (s *categoryService) applyCategoryInput(category *models.Category, input dto.CategoryRequest) error {
+	if input.ParentID != nil {
+		if category.ID != uuid.Nil && *input.ParentID == category.ID {
+			return errors.New("invalid parent: category cannot be its own parent")
+		}
+		if _, err := s.categoryRepo.FindByID(*input.ParentID); err != nil {
+			return errors.New("parent category not found")
+		}
+		if category.ID != uuid.Nil {
+			categories, err := s.categoryRepo.FindAll()
+			if err != nil {
+				return err
+			}
+			for _, id := range descendantCategoryIDs(categories, category.ID) {
+				if id == *input.ParentID {
+					return errors.New("invalid parent: category cannot be moved under its own subcategory")
+				}
+			}
+		}
+	}
+
+	slug := utils.Slugify(input.Slug)
+	if slug == "" {
+		slug = utils.Slugify(input.Name)
+	}
+	if slug == "" {
+		return errors.New("invalid slug: name must contain letters or digits")
+	}
+	if existing, err := s.categoryRepo.FindBySlug(slug); err == nil && existing.ID != category.ID {
+		return fmt.Errorf("invalid slug: %s is already used", slug)
+	}
+
+	category.ParentID = input.ParentID
+	category.Name = strings.TrimSpace(input.Name)
+	category.Slug = slug
+	category.IconURL = input.IconURL
+	category.SortOrder = input.SortOrder
+	return nil
+}
+
+func (s *categoryService) loadCategoriesWithCounts() ([]models.Category, map[uuid.UUID]int64, error) {
+	categories, err := s.categoryRepo.FindAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	direct, err := s.categoryRepo.CountProductsPerCategory()
+	if err != nil {
+		return nil, nil, err
+	}
+	directCounts := make(map[uuid.UUID]int64, len(direct))
+	for _, d := range direct {
+		directCounts[d.CategoryID] = d.Count
+	}
+	// Jumlah produk kategori induk mencakup seluruh subkategorinya
+	counts := make(map[uuid.UUID]int64, len(categories))
+	for _, c := range categories {
+		for _, id := range descendantCategoryIDs(categories, c.ID) {
+			counts[c.ID] += directCounts[id]
+		}
+	}
+	return categories, counts, nil
+}
+
+// buildCategoryTree menyusun anak-anak dari parentID (nil = kategori utama) secara rekursif.
+// Urutan mengikuti hasil repository (sort_order, lalu nama).
+func buildCategoryTree(categories []models.Category, counts map[uuid.UUID]int64, parentID *uuid.UUID) []dto.CategoryResponse {
+	nodes := []dto.CategoryResponse{}
+	for _, c := range categories {
+		if (parentID == nil && c.ParentID != nil) || (parentID != nil && (c.ParentID == nil || *c.ParentID != *parentID)) {
+			continue
+		}
+		nodes = append(nodes, toCategoryResponse(c, buildCategoryTree(categories, counts, &c.ID), counts))
+	}
+	return nodes
+}
+
+// descendantCategoryIDs mengembalikan ID kategori beserta seluruh turunannya.
+func descendantCategoryIDs(categories []models.Category, rootID uuid.UUID) []uuid.UUID {
+	ids := []uuid.UUID{rootID}
+	for i := 0; i < len(ids); i++ {
+		for _, c := range categories {
+			if c.ParentID != nil && *c.ParentID == ids[i] {
+				ids = append(ids, c.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func parentOf(byID map[uuid.UUID]models.Category, category models.Category) (models.Category, bool) {
+	if category.ParentID == nil {
+		return models.Category{}, false
+	}
+	parent, ok := byID[*category.ParentID]
+	return parent, ok
+}
+
+func toCategoryResponse(c models.Category, children []dto.CategoryResponse, counts map[uuid.UUID]int64) dto.CategoryResponse {
+	return dto.CategoryResponse{
+		ID:           c.ID,
+		ParentID:     c.ParentID,
+		Name:         c.Name,
+		Slug:         c.Slug,
+		IconURL:      c.IconURL,
+		SortOrder:    c.SortOrder,
+		ProductCount: counts[c.ID],
+		Children:     children,
+	}
+}
+
+func toCategorySummary(c models.Category) dto.CategorySummary {
+	return dto.CategorySummary{ID: c.ID, Name: c.Name, Slug: c.Slug}
+}
@@ -13,43 +13,196 @@ import (
 )
 
 type CheckoutService interface {
-	CreateOrdersFromCart(userID uuid.UUID) (*dto.PaymentInitiationResponse, error)
+	CreateOrdersFromCart(userID uuid.UUID, input dto.CheckoutRequest) (*dto.PaymentInitiationResponse, error)
 	CreateDirectCheckout(userID uuid.UUID, input DirectCheckoutInput) (*dto.PaymentInitiationResponse, error)
+	PreviewCheckout(userID uuid.UUID, input dto.CheckoutPreviewRequest) (*dto.CheckoutPreviewResponse, error)
 }
 
 type DirectCheckoutInput struct {
-	ProductID uuid.UUID `json:"product_id" binding:"required"`
-	Quantity  int       `json:"quantity" binding:"required,gt=0"`
+	ProductID   uuid.UUID  `json:"product_id" binding:"required"`
+	VariantID   *uuid.UUID `json:"variant_id"` // Wajib jika produk memiliki lebih dari satu varian
+	Quantity    float64    `json:"quantity" binding:"required,gt=0"`
+	AddressID   *uuid.UUID `json:"address_id"` // Default: alamat utama pembeli
+	VoucherCode *string    `json:"voucher_code"`
 }
 
 type checkoutService struct {
-	cartRepo       repositories.CartRepository    // Asumsi dari modul inti
-	productRepo    repositories.ProductRepository // Asumsi dari modul inti
+	cartRepo       repositories.CartRepository // Asumsi dari modul inti
+	variantRepo    repositories.ProductVariantRepository
 	orderRepo      repositories.OrderRepository
+	addressRepo    repositories.AddressRepository
 	paymentService ECommercePaymentService
+	reservations   StockReservationService
+	inventory      InventoryService
+	pricing        PriceTierService
+	vouchers       VoucherService
 	db             *gorm.DB
 }
 
 func NewCheckoutService(
 	cartRepo repositories.CartRepository,
-	productRepo repositories.ProductRepository,
+	variantRepo repositories.ProductVariantRepository,
 	orderRepo repositories.OrderRepository,
+	addressRepo repositories.AddressRepository,
 	paymentService ECommercePaymentService,
+	reservations StockReservationService,
+	inventory InventoryService,
+	pricing PriceTierService,
+	vouchers VoucherService,
 	db *gorm.DB,
 ) CheckoutService {
 	return &checkoutService{
 		cartRepo:       cartRepo,
-		productRepo:    productRepo,
+		variantRepo:    variantRepo,
 		orderRepo:      orderRepo,
+		addressRepo:    addressRepo,
 		paymentService: paymentService,
+		reservations:   reservations,
+		inventory:      inventory,
+		pricing:        pricing,
+		vouchers:       vouchers,
 		db:             db,
 	}
 }
 
-func (s *checkoutService) CreateOrdersFromCart(userID uuid.UUID) (*dto.PaymentInitiationResponse, error) {
+// resolveShippingAddress mengambil alamat pilihan pembeli, atau alamat utamanya.
+// Checkout tanpa alamat pengiriman ditolak.
+func resolveShippingAddress(tx *gorm.DB, addressRepo repositories.AddressRepository, userID uuid.UUID, addressID *uuid.UUID) (*models.Address, error) {
+	if addressID == nil {
+		address, err := addressRepo.FindDefaultByUserID(tx, userID)
+		if err != nil {
+			return nil, errors.New("shipping address is required, please add an address first")
+		}
+		return address, nil
+	}
+	address, err := addressRepo.FindByID(*addressID)
+	if err != nil || address.UserID != userID {
+		return nil, errors.New("shipping address not found")
+	}
+	return address, nil
+}
+
+// cartOrderDraft adalah pesanan satu petani dari isi keranjang yang belum disimpan.
+type cartOrderDraft struct {
+	Order models.Order
+	Items []models.OrderItem
+}
+
+// cartPriceLines menyusun baris harga dari item keranjang. Variant item harus sudah terisi.
+func cartPriceLines(cartItems []models.Cart) []PriceLine {
+	lines := make([]PriceLine, 0, len(cartItems))
+	for _, item := range cartItems {
+		lines = append(lines, PriceLine{VariantID: item.VariantID, BasePrice: item.Variant.Price, Quantity: item.Quantity})
+	}
+	return lines
+}
+
+// draftCartOrders mengelompokkan item keranjang menjadi satu pesanan per petani dengan
+// harga satuan sesuai tier dan ongkos kirim per pesanan. Urutan pesanan mengikuti
+// urutan item di keranjang.
+func draftCartOrders(userID uuid.UUID, cartItems []models.Cart, quotes map[uuid.UUID]PriceQuote) []*cartOrderDraft {
+	var drafts []*cartOrderDraft
+	byFarmer := make(map[uuid.UUID]*cartOrderDraft)
+	for _, item := range cartItems {
+		draft, ok := byFarmer[item.Product.FarmerID]
+		if !ok {
+			draft = &cartOrderDraft{Order: models.Order{
+				UserID:      userID,
+				FarmerID:    item.Product.FarmerID,
+				ShippingFee: ecommerceShippingFee(),
+			}}
+			byFarmer[item.Product.FarmerID] = draft
+			drafts = append(drafts, draft)
+		}
+
+		quote := quotes[item.VariantID]
+		variantID := item.VariantID
+		subTotal := roundTo(item.Quantity*quote.UnitPrice, 2)
+		draft.Items = append(draft.Items, models.OrderItem{
+			ProductID:       item.ProductID,
+			VariantID:       &variantID,
+			VariantName:     item.Variant.Name,
+			Unit:            item.Variant.Unit,
+			Quantity:        item.Quantity,
+			PriceAtPurchase: quote.UnitPrice, // Harga saat pembelian, setelah tier
+			PriceTierID:     quote.TierID,
+			NameAtPurchase:  item.Product.Title,
+			SubTotal:        subTotal,
+		})
+		draft.Order.SubTotal = roundTo(draft.Order.SubTotal+subTotal, 2)
+	}
+	for _, draft := range drafts {
+		draft.Order.RecalculateTotal()
+	}
+	return drafts
+}
+
+func draftOrders(drafts []*cartOrderDraft) []*models.Order {
+	orders := make([]*models.Order, 0, len(drafts))
+	for _, draft := range drafts {
+		orders = append(orders, &draft.Order)
+	}
+	return orders
+}
+
+// voucherCode mengembalikan kode voucher yang diisi pembeli, atau string kosong.
+func voucherCode(code *string) string {
+	if code == nil {
+		return ""
+	}
+	return models.NormalizeVoucherCode(*code)
+}
+
+// PreviewCheckout menghitung ringkasan checkout keranjang (harga tier, ongkir, potongan
+// voucher) tanpa membuat pesanan maupun memakai kuota voucher.
+func (s *checkoutService) PreviewCheckout(userID uuid.UUID, input dto.CheckoutPreviewRequest) (*dto.CheckoutPreviewResponse, error) {
+	cartItems, err := s.cartRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cartItems) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+	quotes, err := s.pricing.QuotePrices(nil, userID, cartPriceLines(cartItems))
+	if err != nil {
+		return nil, err
+	}
+	drafts := draftCartOrders(userID, cartItems, quotes)
+
+	response := &dto.CheckoutPreviewResponse{Orders: make([]dto.CheckoutOrderSummary, 0, len(drafts))}
+	if code := voucherCode(input.VoucherCode); code != "" {
+		if err := s.vouchers.QuoteOrders(userID, code, draftOrders(drafts)); err != nil {
+			return nil, err
+		}
+		response.VoucherCode = &code
+	}
+	for _, draft := range drafts {
+		order := draft.Order
+		response.Orders = append(response.Orders, dto.CheckoutOrderSummary{
+			FarmerID:         order.FarmerID,
+			SubTotal:         order.SubTotal,
+			ShippingFee:      order.ShippingFee,
+			DiscountAmount:   order.DiscountAmount,
+			PlatformDiscount: order.PlatformDiscount,
+			TotalAmount:      order.TotalAmount,
+		})
+		response.SubTotal = roundTo(response.SubTotal+order.SubTotal, 2)
+		response.ShippingFee = roundTo(response.ShippingFee+order.ShippingFee, 2)
+		response.DiscountAmount = roundTo(response.DiscountAmount+order.DiscountAmount, 2)
+		response.GrandTotal = roundTo(response.GrandTotal+order.TotalAmount, 2)
+	}
+	return response, nil
+}
+
+func (s *checkoutService) CreateOrdersFromCart(userID uuid.UUID, input dto.CheckoutRequest) (*dto.PaymentInitiationResponse, error) {
 	var snapResponse *dto.PaymentInitiationResponse
 
 	err := s.db.Transaction(func(tx *gorm.DB) error {
+		address, err := resolveShippingAddress(tx, s.addressRepo, userID, input.AddressID)
+		if err != nil {
+			return err
+		}
+
 		cartItems, err := s.cartRepo.FindByUserIDWithTx(tx, userID)
 		if err != nil {
 			return err
@@ -58,55 +211,89 @@ func (s *checkoutService) CreateOrdersFromCart(userID uuid.UUID) (*dto.PaymentIn
 			return errors.New("cart is empty")
 		}
 
-		itemsByFarmer := make(map[uuid.UUID][]models.Cart)
-		var grandTotal float64
-		var createdOrders []models.Order
-
-		// 2. Validasi Stok, Kelompokkan Item & Hitung Total
-		for _, item := range cartItems {
-			product, err := s.productRepo.FindByIDForUpdate(tx, item.ProductID)
+		// 2. Validasi Stok
+		for i, item := range cartItems {
+			variant, err := s.variantRepo.FindByIDForUpdate(tx, item.VariantID)
 			if err != nil {
-				return fmt.Errorf("product %s not found", item.ProductID)
+				return fmt.Errorf("product variant %s not found", item.VariantID)
 			}
-			availableStock := product.Stock - product.ReservedStock
-			if availableStock < item.Quantity {
-				return fmt.Errorf("insufficient stock for product: %s", product.Title)
+			if err := variant.ValidateQuantity(item.Quantity); err != nil {
+				return err
 			}
-			// product.ReservedStock -= item.Quantity // Hapus reservasi keranjang
-			// if err := s.productRepo.UpdateStock(tx, product); err != nil {
-			// 	return err
-			// }
-			itemsByFarmer[item.Product.FarmerID] = append(itemsByFarmer[item.Product.FarmerID], item)
-			grandTotal += float64(item.Quantity) * product.Price
-		}
-
-		// 3. Buat Order Terpisah untuk Setiap Petani
-		for farmerID, items := range itemsByFarmer {
-			var orderTotal float64
-			for _, item := range items {
-				orderTotal += float64(item.Quantity) * item.Product.Price
+			// Stok item keranjang ditahan sejak dimasukkan ke keranjang; tahanan yang sudah
+			// kedaluwarsa ditahan ulang di sini. Baris keranjang dibaca ulang setelah varian
+			// dikunci agar tidak bentrok dengan job pelepasan tahanan.
+			held, err := s.cartRepo.FindByIDWithTx(tx, item.ID)
+			if err != nil {
+				return err
 			}
+			if shortfall := held.HoldShortfall(); shortfall > 0 {
+				if variant.AvailableStock() < shortfall {
+					return fmt.Errorf("insufficient stock for product: %s (%s)", item.Product.Title, variant.Name)
+				}
+				change := StockChange{Type: models.MovementReservation, ReservedDelta: shortfall, ActorID: &userID, Note: "Checkout keranjang"}
+				if err := s.inventory.ApplyStockChange(tx, variant, change); err != nil {
+					return err
+				}
+			}
+			// Reservasi keranjang berpindah ke order dan dicatat lewat TrackOrder di bawah.
+			if variant.ReservedStock < item.Quantity || variant.Stock < item.Quantity {
+				return fmt.Errorf("insufficient stock for product: %s (%s)", item.Product.Title, variant.Name)
+			}
+			cartItems[i].Variant = *variant
+		}
 
-			newOrder := models.Order{
-				FarmerID:      farmerID,
-				UserID:        userID,
-				InvoiceNumber: fmt.Sprintf("ORD-%d", time.Now().UnixNano()),
-				TotalAmount:   orderTotal,
+		// 3. Hitung harga sesuai tier grosir & grup pembeli, kelompokkan per petani
+		quotes, err := s.pricing.QuotePrices(tx, userID, cartPriceLines(cartItems))
+		if err != nil {
+			return err
+		}
+		drafts := draftCartOrders(userID, cartItems, quotes)
+
+		// 4. Terapkan voucher ke pesanan yang memenuhi syarat
+		var redemption *models.VoucherRedemption
+		if code := voucherCode(input.VoucherCode); code != "" {
+			redemption, err = s.vouchers.ApplyToOrders(tx, userID, code, draftOrders(drafts))
+			if err != nil {
+				return err
 			}
-			if err := s.orderRepo.CreateWithItems(tx, &newOrder, items); err != nil {
+		}
+
+		// 5. Buat Order Terpisah untuk Setiap Petani
+		var grandTotal float64
+		var createdOrders []models.Order
+		for _, draft := range drafts {
+			newOrder := draft.Order
+			newOrder.InvoiceNumber = fmt.Sprintf("ORD-%d", time.Now().UnixNano())
+			newOrder.ApplyShippingAddress(address)
+			if err := s.orderRepo.CreateWithItems(tx, &newOrder, draft.Items); err != nil {
 				return err
 			}
+			if err := s.reservations.TrackOrder(tx, &newOrder); err != nil {
+				return err
+			}
+			grandTotal += newOrder.TotalAmount
 			createdOrders = append(createdOrders, newOrder)
 		}
+		grandTotal = roundTo(grandTotal, 2)
+		if grandTotal <= 0 {
+			return errors.New("invalid voucher: order total after discount must be greater than zero")
+		}
 
-		// 4. Kosongkan Keranjang
+		// 6. Kosongkan Keranjang
 		if err := s.cartRepo.ClearCart(tx, userID); err != nil {
 			return err
 		}
-		paymentResponse, err := s.paymentService.InitiatePayment(tx, userID, createdOrders, grandTotal)
+		payment, paymentResponse, err := s.paymentService.InitiatePaymentFor(tx, userID, createdOrders, grandTotal, models.PaymentPurposeCheckout)
 		if err != nil {
 			return err
 		}
+		if redemption != nil {
+			if err := s.vouchers.RecordRedemption(tx, redemption, payment.ID); err != nil {
+				return err
+			}
+			paymentResponse.Discount = redemption.DiscountAmount
+		}
 
 		snapResponse = paymentResponse
 		return nil // Commit
@@ -119,25 +306,52 @@ func (s *checkoutService) CreateDirectCheckout(userID uuid.UUID, input DirectChe
 	var snapResponse *dto.PaymentInitiationResponse
 
 	err := s.db.Transaction(func(tx *gorm.DB) error {
-		// 1. Ambil & Kunci Produk
-		product, err := s.productRepo.FindByIDForUpdate(tx, input.ProductID)
+		address, err := resolveShippingAddress(tx, s.addressRepo, userID, input.AddressID)
 		if err != nil {
-			return errors.New("product not found")
+			return err
 		}
 
-		// 2. Validasi Stok
-		availableStock := product.Stock - product.ReservedStock
-		if availableStock < input.Quantity {
+		// 1. Ambil & Kunci Varian Produk
+		quantity := models.RoundQuantity(input.Quantity)
+		variant, err := lockVariantForPurchase(tx, s.variantRepo, input.ProductID, input.VariantID)
+		if err != nil {
+			return err
+		}
+		product := variant.Product
+
+		// 2. Validasi Kuantitas & Stok
+		if err := variant.ValidateQuantity(quantity); err != nil {
+			return err
+		}
+		if variant.AvailableStock() < quantity {
 			return errors.New("insufficient stock")
 		}
 
 		// 3. Buat SATU Order (karena hanya 1 produk, 1 petani)
-		grandTotal := float64(input.Quantity) * product.Price
+		quote, err := quoteUnitPrice(s.pricing, tx, userID, variant, quantity)
+		if err != nil {
+			return err
+		}
+		subTotal := roundTo(quantity*quote.UnitPrice, 2)
 		newOrder := models.Order{
 			UserID:        userID,
 			FarmerID:      product.FarmerID, // Langsung dari produk
 			InvoiceNumber: fmt.Sprintf("ORD-%d", time.Now().UnixNano()),
-			TotalAmount:   grandTotal,
+			SubTotal:      subTotal,
+			ShippingFee:   ecommerceShippingFee(),
+		}
+		newOrder.RecalculateTotal()
+		newOrder.ApplyShippingAddress(address)
+
+		var redemption *models.VoucherRedemption
+		if code := voucherCode(input.VoucherCode); code != "" {
+			redemption, err = s.vouchers.ApplyToOrders(tx, userID, code, []*models.Order{&newOrder})
+			if err != nil {
+				return err
+			}
+		}
+		if newOrder.TotalAmount <= 0 {
+			return errors.New("invalid voucher: order total after discount must be greater than zero")
 		}
 		// Buat record Order
 		if err := tx.Create(&newOrder).Error; err != nil {
@@ -149,8 +363,14 @@ func (s *checkoutService) CreateDirectCheckout(userID uuid.UUID, input DirectChe
 			ID:              uuid.New(),
 			OrderID:         newOrder.ID,
 			ProductID:       input.ProductID,
-			Quantity:        input.Quantity,
-			PriceAtPurchase: product.Price,
+			VariantID:       &variant.ID,
+			VariantName:     variant.Name,
+			Unit:            variant.Unit,
+			Quantity:        quantity,
+			PriceAtPurchase: quote.UnitPrice,
+			PriceTierID:     quote.TierID,
+			NameAtPurchase:  product.Title,
+			SubTotal:        subTotal,
 		}
 		if err := tx.Create(&orderItem).Error; err != nil {
 			return err
@@ -158,17 +378,32 @@ func (s *checkoutService) CreateDirectCheckout(userID uuid.UUID, input DirectChe
 
 		// 5. [PENTING] Reservasi Stok
 		// Kita harus "memesan" stok ini agar tidak diambil orang lain
-		product.ReservedStock += input.Quantity
-		if err := s.productRepo.UpdateStock(tx, product); err != nil {
+		change := StockChange{
+			Type:          models.MovementReservation,
+			ReservedDelta: quantity,
+			OrderID:       &newOrder.ID,
+			ActorID:       &userID,
+		}
+		if err := s.inventory.ApplyStockChange(tx, variant, change); err != nil {
+			return err
+		}
+		newOrder.Items = []models.OrderItem{orderItem}
+		if err := s.reservations.TrackOrder(tx, &newOrder); err != nil {
 			return err
 		}
 
 		// 6. Buat Pembayaran Induk
 		createdOrders := []models.Order{newOrder}
-		paymentResponse, err := s.paymentService.InitiatePayment(tx, userID, createdOrders, grandTotal)
+		payment, paymentResponse, err := s.paymentService.InitiatePaymentFor(tx, userID, createdOrders, newOrder.TotalAmount, models.PaymentPurposeCheckout)
 		if err != nil {
 			return err
 		}
+		if redemption != nil {
+			if err := s.vouchers.RecordRedemption(tx, redemption, payment.ID); err != nil {
+				return err
+			}
+			paymentResponse.Discount = redemption.DiscountAmount
+		}
 
 		snapResponse = paymentResponse
 		return nil // Commit
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"gorm.io/gorm"
+)
+
+// CreateConsolidatedDelivery membuat satu pengiriman untuk beberapa pesanan dan/atau
+// muatan hasil panen. Titik antar diurutkan dari lokasi pickup dengan nearest-neighbour
+// + 2-opt (kecuali optimize_route=false); tujuan akhir adalah titik antar terakhir.
+func (s *deliveryService) CreateConsolidatedDelivery(input dto.CreateConsolidatedDeliveryRequest, farmerID uuid.UUID) (*dto.ConsolidatedDeliveryResponse, error) {
+	var pickupDate *time.Time
+	if input.PickupDate != "" {
+		parsed, err := time.Parse("2006-01-02", input.PickupDate)
+		if err != nil {
+			return nil, errors.New("invalid pickup_date format, use YYYY-MM-DD")
+		}
+		pickupDate = &parsed
+	}
+
+	stops := make([]models.DeliveryStop, 0, len(input.Stops))
+	points := make([]dto.GeoPoint, 0, len(input.Stops))
+	orderIDs := make([]uuid.UUID, 0)
+	seenOrders := make(map[uuid.UUID]bool)
+	var totalWeight float64
+	for i, in := range input.Stops {
+		if in.OrderID != nil {
+			if seenOrders[*in.OrderID] {
+				return nil, fmt.Errorf("invalid input: order %s appears in more than one stop", in.OrderID)
+			}
+			seenOrders[*in.OrderID] = true
+			orderIDs = append(orderIDs, *in.OrderID)
+		}
+		stop, err := s.buildConsolidatedStop(in, farmerID)
+		if err != nil {
+			return nil, fmt.Errorf("stop %d: %w", i+1, err)
+		}
+		stops = append(stops, stop)
+		points = append(points, dto.GeoPoint{Lat: stop.Lat, Lng: stop.Lng})
+		totalWeight += in.ItemWeight
+	}
+
+	pickup := dto.GeoPoint{Lat: input.PickupLat, Lng: input.PickupLng}
+	sequence := make([]int, len(stops))
+	for i := range sequence {
+		sequence[i] = i
+	}
+	if input.OptimizeRoute == nil || *input.OptimizeRoute {
+		sequence = optimizeStopSequence(pickup, points)
+	}
+
+	ordered := make([]models.DeliveryStop, 0, len(stops))
+	orderedPoints := []dto.GeoPoint{pickup}
+	for i, idx := range sequence {
+		stop := stops[idx]
+		stop.Sequence = i + 1
+		ordered = append(ordered, stop)
+		orderedPoints = append(orderedPoints, points[idx])
+	}
+	last := ordered[len(ordered)-1]
+
+	newDelivery := &models.Delivery{
+		FarmerID:           farmerID,
+		PickupAddress:      input.PickupAddress,
+		PickupLat:          input.PickupLat,
+		PickupLng:          input.PickupLng,
+		DestinationAddress: last.Address,
+		DestinationLat:     &last.Lat,
+		DestinationLng:     &last.Lng,
+		ItemDescription:    fmt.Sprintf("Pengiriman gabungan %d titik antar", len(ordered)),
+		ItemWeight:         totalWeight,
+		PickupDate:         pickupDate,
+		IsConsolidated:     true,
+		Stops:              ordered,
+		Status:             models.DeliveryStatusPendingDriver,
+	}
+	// Pesanan ditautkan dalam transaksi yang sama agar delivery tidak tersimpan tanpa pesanannya
+	linkOrders := func(tx *gorm.DB, delivery *models.Delivery) error {
+		if len(orderIDs) == 0 {
+			return nil
+		}
+		if err := s.orderRepo.LinkDelivery(tx, orderIDs, delivery.ID, models.FulfillmentCourier); err != nil {
+			return fmt.Errorf("failed to link orders to delivery: %w", err)
+		}
+		return nil
+	}
+	if err := s.saveNewDelivery(newDelivery, farmerID, linkOrders); err != nil {
+		return nil, err
+	}
+
+	return &dto.ConsolidatedDeliveryResponse{
+		Delivery:            newDelivery,
+		InputDistanceKm:     roundTo(pathDistanceKm(append([]dto.GeoPoint{pickup}, points...)), 2),
+		OptimizedDistanceKm: roundTo(pathDistanceKm(orderedPoints), 2),
+	}, nil
+}
+
+// buildConsolidatedStop menyusun satu titik antar. Untuk pesanan e-commerce, alamat,
+// penerima dan deskripsi muatan diambil dari pesanan jika tidak diisi.
+func (s *deliveryService) buildConsolidatedStop(in dto.ConsolidatedStopInput, farmerID uuid.UUID) (models.DeliveryStop, error) {
+	weight := in.ItemWeight
+	stop := models.DeliveryStop{
+		Address:        strings.TrimSpace(in.Address),
+		RecipientName:  in.RecipientName,
+		RecipientPhone: in.RecipientPhone,
+		RecipientEmail: in.RecipientEmail,
+		Notes:          in.Notes,
+		OrderID:        in.OrderID,
+		ItemWeight:     &weight,
+		Status:         models.DeliveryStopStatusPending,
+	}
+	description := strings.TrimSpace(in.ItemDescription)
+
+	if in.OrderID == nil {
+		if description == "" {
+			return stop, errors.New("invalid input: item_description is required for stops without an order")
+		}
+		if stop.Address == "" {
+			return stop, errors.New("invalid input: address is required for stops without an order")
+		}
+		if in.Lat == nil || in.Lng == nil {
+			return stop, errors.New("invalid input: lat and lng are required for stops without an order")
+		}
+		stop.Lat, stop.Lng = *in.Lat, *in.Lng
+		stop.ItemDescription = &description
+		return stop, nil
+	}
+
+	order, err := s.orderRepo.FindByID(*in.OrderID)
+	if err != nil {
+		return stop, fmt.Errorf("order %s not found", in.OrderID)
+	}
+	if order.FarmerID != farmerID {
+		return stop, errors.New("forbidden: you do not own this order")
+	}
+	if !order.CanBeShipped() {
+		return stop, fmt.Errorf("invalid order state: order %s with status %s cannot be shipped", order.InvoiceNumber, order.Status)
+	}
+	if stop.Address == "" && order.ShippingAddress != nil {
+		stop.Address = *order.ShippingAddress
+	}
+	if stop.Address == "" {
+		return stop, fmt.Errorf("invalid input: address is required because order %s has no shipping address", order.InvoiceNumber)
+	}
+	// Koordinat default diambil dari alamat pengiriman pesanan
+	lat, lng := in.Lat, in.Lng
+	if lat == nil || lng == nil {
+		lat, lng = order.ShippingLat, order.ShippingLng
+	}
+	if lat == nil || lng == nil {
+		return stop, fmt.Errorf("invalid input: lat and lng are required because order %s has no shipping coordinates", order.InvoiceNumber)
+	}
+	stop.Lat, stop.Lng = *lat, *lng
+	if stop.RecipientName == nil {
+		stop.RecipientName = order.ShippingRecipient
+	}
+	if stop.RecipientName == nil {
+		stop.RecipientName = &order.User.Name
+	}
+	if stop.RecipientPhone == nil {
+		stop.RecipientPhone = order.ShippingPhone
+	}
+	if stop.RecipientPhone == nil {
+		stop.RecipientPhone = order.User.PhoneNumber
+	}
+	if stop.RecipientEmail == nil {
+		stop.RecipientEmail = &order.User.Email
+	}
+	if description == "" {
+		description = orderItemSummary(order)
+	}
+	stop.ItemDescription = &description
+	return stop, nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/whsasmita/AgroLink_API/dto"
 	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/pkg/tracking"
 	"github.com/whsasmita/AgroLink_API/repositories"
 	"gorm.io/gorm"
 )
@@ -27,6 +28,7 @@ type contractService struct {
 	invoiceRepo    repositories.InvoiceRepository
 	projectService ProjectService
 	deliveryRepo   repositories.DeliveryRepository
+	hub            *tracking.Hub
 	db             *gorm.DB
 }
 
@@ -35,6 +37,7 @@ func NewContractService(
 	projectService ProjectService,
 	invoiceRepo repositories.InvoiceRepository,
 	deliveryRepo repositories.DeliveryRepository,
+	hub *tracking.Hub,
 	db *gorm.DB,
 ) ContractService {
 	return &contractService{
@@ -42,6 +45,7 @@ func NewContractService(
 		invoiceRepo:    invoiceRepo,
 		projectService: projectService,
 		deliveryRepo:   deliveryRepo,
+		hub:            hub,
 		db:             db,
 	}
 }
@@ -71,6 +75,7 @@ func (s *contractService) GetMyContracts(userID uuid.UUID) ([]dto.MyContractResp
 }
 
 func (s *contractService) SignContract(contractID string, userID uuid.UUID) (*dto.SignContractResponse, error) {
+	liveEvents := &deliveryEventBatch{}
 	tx := s.db.Begin()
 	if tx.Error != nil {
 		return nil, tx.Error
@@ -127,7 +132,11 @@ func (s *contractService) SignContract(contractID string, userID uuid.UUID) (*dt
 			return nil, errors.New("associated delivery not found")
 		}
 
-		totalAmount := 150000.0
+		// Gunakan harga yang dikunci saat petani memilih driver
+		totalAmount := defaultDeliveryFare
+		if delivery.QuotedPrice != nil {
+			totalAmount = *delivery.QuotedPrice
+		}
 		platformFee := totalAmount * 0.05
 		newInvoice := &models.Invoice{
 			DeliveryID:  &delivery.ID,
@@ -143,10 +152,14 @@ func (s *contractService) SignContract(contractID string, userID uuid.UUID) (*dt
 			return nil, fmt.Errorf("failed to create invoice: %w", err)
 		}
 
-		delivery.Status = "pending_payment"
-		if err := s.deliveryRepo.Update(tx, delivery); err != nil {
+		if err := transitionDelivery(tx, s.deliveryRepo, liveEvents, delivery, deliveryTransition{
+			To:        models.DeliveryStatusPendingPayment,
+			EventType: models.DeliveryEventContractSigned,
+			ActorID:   contract.DriverID,
+			ActorRole: "driver",
+		}); err != nil {
 			tx.Rollback()
-			return nil, fmt.Errorf("failed to update delivery status: %w", err)
+			return nil, err
 		}
 	case "work":
 		go s.projectService.CheckAndFinalizeProject(*contract.ProjectID)
@@ -155,6 +168,7 @@ func (s *contractService) SignContract(contractID string, userID uuid.UUID) (*dt
 	if err := tx.Commit().Error; err != nil {
 		return nil, err
 	}
+	liveEvents.publish(s.hub)
 
 	response := &dto.SignContractResponse{
 		ContractID: contract.ID,
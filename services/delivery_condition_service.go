@@ -0,0 +1,419 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/SebastiaanKlippert/go-wkhtmltopdf"
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/pkg/tracking"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"github.com/whsasmita/AgroLink_API/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// conditionAlertCooldown mencegah notifikasi beruntun dari sensor yang mengirim tiap menit
+	conditionAlertCooldown = 30 * time.Minute
+	coldChainKeyPrefix     = "agl_dev_"
+)
+
+type DeliveryConditionService interface {
+	SetThresholds(deliveryID string, farmerID uuid.UUID, input dto.SetConditionThresholdsRequest) (*models.Delivery, error)
+	RecordDriverReading(deliveryID string, driverID uuid.UUID, input dto.ConditionReadingInput) (*models.DeliveryConditionReading, error)
+	RecordDeviceReadings(deliveryID string, device *models.ColdChainDevice, readings []dto.ConditionReadingInput) ([]models.DeliveryConditionReading, error)
+	GetConditionReport(deliveryID string, user *models.User) (*dto.DeliveryConditionReport, error)
+	GenerateDeliveryReportPDF(deliveryID string, user *models.User) (*bytes.Buffer, error)
+
+	RegisterDevice(farmerID uuid.UUID, input dto.RegisterColdChainDeviceRequest) (*dto.RegisterColdChainDeviceResponse, error)
+	GetMyDevices(farmerID uuid.UUID) ([]models.ColdChainDevice, error)
+	RevokeDevice(deviceID, farmerID uuid.UUID) (*models.ColdChainDevice, error)
+}
+
+type deliveryConditionService struct {
+	conditionRepo repositories.DeliveryConditionRepository
+	deliveryRepo  repositories.DeliveryRepository
+	notifService  NotificationService
+	hub           *tracking.Hub
+	db            *gorm.DB
+}
+
+func NewDeliveryConditionService(
+	conditionRepo repositories.DeliveryConditionRepository,
+	deliveryRepo repositories.DeliveryRepository,
+	notifService NotificationService,
+	hub *tracking.Hub,
+	db *gorm.DB,
+) DeliveryConditionService {
+	return &deliveryConditionService{
+		conditionRepo: conditionRepo,
+		deliveryRepo:  deliveryRepo,
+		notifService:  notifService,
+		hub:           hub,
+		db:            db,
+	}
+}
+
+// SetThresholds dipakai petani untuk menentukan rentang suhu dan kelembapan yang aman.
+func (s *deliveryConditionService) SetThresholds(deliveryID string, farmerID uuid.UUID, input dto.SetConditionThresholdsRequest) (*models.Delivery, error) {
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	if delivery.FarmerID != farmerID {
+		return nil, errors.New("forbidden: you do not own this delivery")
+	}
+	if input.MinTemperatureC != nil && input.MaxTemperatureC != nil && *input.MinTemperatureC > *input.MaxTemperatureC {
+		return nil, errors.New("invalid input: min_temperature_c must not exceed max_temperature_c")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Ambang tidak boleh diubah setelah pengiriman selesai agar riwayat peringatan tetap sahih
+		if err := lockDeliveryStatus(tx, s.deliveryRepo, delivery); err != nil {
+			return err
+		}
+		switch delivery.Status {
+		case models.DeliveryStatusDelivered, models.DeliveryStatusFailed,
+			models.DeliveryStatusCancelled, models.DeliveryStatusReturned:
+			return fmt.Errorf("invalid state: cannot change condition thresholds for delivery with status %s", delivery.Status)
+		}
+		delivery.MinTemperatureC = input.MinTemperatureC
+		delivery.MaxTemperatureC = input.MaxTemperatureC
+		delivery.MaxHumidityPct = input.MaxHumidityPct
+		if err := s.deliveryRepo.UpdateConditionThresholds(tx, delivery); err != nil {
+			return fmt.Errorf("failed to update delivery thresholds: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// RecordDriverReading mencatat pembacaan manual dan/atau foto kondisi dari driver.
+func (s *deliveryConditionService) RecordDriverReading(deliveryID string, driverID uuid.UUID, input dto.ConditionReadingInput) (*models.DeliveryConditionReading, error) {
+	if input.TemperatureC == nil && input.HumidityPct == nil && input.PhotoURL == nil {
+		return nil, errors.New("invalid input: provide temperature_c, humidity_pct or a condition photo")
+	}
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	if delivery.DriverID == nil || *delivery.DriverID != driverID {
+		return nil, errors.New("forbidden: you are not assigned to this delivery")
+	}
+	if !delivery.IsTrackable() {
+		return nil, fmt.Errorf("invalid state: cannot record condition for delivery with status %s", delivery.Status)
+	}
+
+	reading := newConditionReading(delivery, input)
+	reading.Source = models.ConditionSourceDriver
+	reading.RecordedByID = &driverID
+	if err := s.conditionRepo.CreateReading(reading); err != nil {
+		return nil, fmt.Errorf("failed to save condition reading: %w", err)
+	}
+	s.raiseConditionAlert(delivery, reading)
+	return reading, nil
+}
+
+// RecordDeviceReadings mencatat batch pembacaan dari sensor IoT. Perangkat hanya boleh
+// mengirim data untuk pengiriman milik petani yang mendaftarkannya.
+func (s *deliveryConditionService) RecordDeviceReadings(deliveryID string, device *models.ColdChainDevice, inputs []dto.ConditionReadingInput) ([]models.DeliveryConditionReading, error) {
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	if delivery.FarmerID != device.FarmerID {
+		return nil, errors.New("forbidden: device is not registered to the owner of this delivery")
+	}
+	if !delivery.IsTrackable() {
+		return nil, fmt.Errorf("invalid state: cannot record condition for delivery with status %s", delivery.Status)
+	}
+
+	readings := make([]models.DeliveryConditionReading, 0, len(inputs))
+	var latestAlert *models.DeliveryConditionReading
+	for _, input := range inputs {
+		if input.TemperatureC == nil && input.HumidityPct == nil {
+			continue
+		}
+		reading := newConditionReading(delivery, input)
+		reading.Source = models.ConditionSourceDevice
+		reading.DeviceID = &device.ID
+		if err := s.conditionRepo.CreateReading(reading); err != nil {
+			return nil, fmt.Errorf("failed to save condition reading: %w", err)
+		}
+		readings = append(readings, *reading)
+		if reading.IsAlert && (latestAlert == nil || reading.RecordedAt.After(latestAlert.RecordedAt)) {
+			latestAlert = reading
+		}
+	}
+	if len(readings) == 0 {
+		return nil, errors.New("invalid input: every reading must contain temperature_c or humidity_pct")
+	}
+
+	if err := s.conditionRepo.TouchDevice(device.ID, time.Now()); err != nil {
+		log.Printf("WARN: failed to update last_used_at for device %s: %v", device.ID, err)
+	}
+	if latestAlert != nil {
+		s.raiseConditionAlert(delivery, latestAlert)
+	}
+	return readings, nil
+}
+
+// newConditionReading menyusun pembacaan dan menandainya bila melewati ambang batas.
+func newConditionReading(delivery *models.Delivery, input dto.ConditionReadingInput) *models.DeliveryConditionReading {
+	reading := &models.DeliveryConditionReading{
+		DeliveryID:   delivery.ID,
+		TemperatureC: input.TemperatureC,
+		HumidityPct:  input.HumidityPct,
+		PhotoURL:     input.PhotoURL,
+		Notes:        input.Notes,
+		Lat:          input.Lat,
+		Lng:          input.Lng,
+	}
+	if input.RecordedAt != nil && !input.RecordedAt.After(time.Now().Add(time.Minute)) {
+		reading.RecordedAt = *input.RecordedAt
+	}
+	if reasons := conditionViolations(delivery, reading); len(reasons) > 0 {
+		reason := strings.Join(reasons, "; ")
+		reading.IsAlert = true
+		reading.AlertReason = &reason
+	}
+	return reading
+}
+
+// conditionViolations mengembalikan daftar pelanggaran ambang batas pada satu pembacaan.
+func conditionViolations(delivery *models.Delivery, reading *models.DeliveryConditionReading) []string {
+	var reasons []string
+	if t := reading.TemperatureC; t != nil {
+		if delivery.MinTemperatureC != nil && *t < *delivery.MinTemperatureC {
+			reasons = append(reasons, fmt.Sprintf("suhu %.1f°C di bawah batas minimum %.1f°C", *t, *delivery.MinTemperatureC))
+		}
+		if delivery.MaxTemperatureC != nil && *t > *delivery.MaxTemperatureC {
+			reasons = append(reasons, fmt.Sprintf("suhu %.1f°C di atas batas maksimum %.1f°C", *t, *delivery.MaxTemperatureC))
+		}
+	}
+	if h := reading.HumidityPct; h != nil && delivery.MaxHumidityPct != nil && *h > *delivery.MaxHumidityPct {
+		reasons = append(reasons, fmt.Sprintf("kelembapan %.0f%% di atas batas maksimum %.0f%%", *h, *delivery.MaxHumidityPct))
+	}
+	return reasons
+}
+
+// raiseConditionAlert mencatat event condition_alert dan memberi tahu petani,
+// paling sering sekali per conditionAlertCooldown.
+func (s *deliveryConditionService) raiseConditionAlert(delivery *models.Delivery, reading *models.DeliveryConditionReading) {
+	if !reading.IsAlert {
+		return
+	}
+	events, err := s.deliveryRepo.FindEventsByDeliveryID(delivery.ID.String())
+	if err != nil {
+		log.Printf("WARN: failed to load events for delivery %s: %v", delivery.ID, err)
+		return
+	}
+	if last := lastDeliveryEvent(events, models.DeliveryEventConditionAlert); last != nil &&
+		time.Since(last.CreatedAt) < conditionAlertCooldown {
+		return
+	}
+
+	message := fmt.Sprintf("Kondisi muatan pukul %s: %s", reading.RecordedAt.Format("15:04"), *reading.AlertReason)
+	liveEvents := &deliveryEventBatch{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return recordDeliveryEvent(tx, s.deliveryRepo, liveEvents, delivery, delivery.Status, deliveryTransition{
+			EventType: models.DeliveryEventConditionAlert,
+			ActorID:   reading.RecordedByID,
+			ActorRole: reading.Source,
+			Notes:     &message,
+			Lat:       reading.Lat,
+			Lng:       reading.Lng,
+		})
+	})
+	if err != nil {
+		log.Printf("WARN: failed to record condition alert for delivery %s: %v", delivery.ID, err)
+		return
+	}
+	liveEvents.publish(s.hub)
+	s.notifService.CreateNotification(delivery.FarmerID, "Peringatan Kondisi Muatan: "+delivery.ItemDescription, message,
+		fmt.Sprintf("/deliveries/%s", delivery.ID), "warning")
+}
+
+// GetConditionReport menampilkan laporan kondisi kepada pihak yang boleh memantau pengiriman.
+func (s *deliveryConditionService) GetConditionReport(deliveryID string, user *models.User) (*dto.DeliveryConditionReport, error) {
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	if !canWatchDelivery(delivery, user) && user.Role != "admin" {
+		return nil, errors.New("forbidden: you are not involved in this delivery")
+	}
+	return loadConditionReport(s.conditionRepo, delivery)
+}
+
+// loadConditionReport dipakai bersama oleh laporan kondisi, detail sengketa, dan PDF pengiriman.
+func loadConditionReport(repo repositories.DeliveryConditionRepository, delivery *models.Delivery) (*dto.DeliveryConditionReport, error) {
+	readings, err := repo.FindReadingsByDeliveryID(delivery.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load condition readings: %w", err)
+	}
+	return buildConditionReport(delivery, readings), nil
+}
+
+func buildConditionReport(delivery *models.Delivery, readings []models.DeliveryConditionReading) *dto.DeliveryConditionReport {
+	report := &dto.DeliveryConditionReport{
+		MinTemperatureC: delivery.MinTemperatureC,
+		MaxTemperatureC: delivery.MaxTemperatureC,
+		MaxHumidityPct:  delivery.MaxHumidityPct,
+		ReadingCount:    len(readings),
+		Photos:          []models.DeliveryConditionReading{},
+		Readings:        readings,
+	}
+	var temps, humidities []float64
+	for i := range readings {
+		r := readings[i]
+		if r.IsAlert {
+			report.AlertCount++
+		}
+		if r.TemperatureC != nil {
+			temps = append(temps, *r.TemperatureC)
+		}
+		if r.HumidityPct != nil {
+			humidities = append(humidities, *r.HumidityPct)
+		}
+		if r.PhotoURL != nil {
+			report.Photos = append(report.Photos, r)
+		}
+	}
+	if len(readings) > 0 {
+		report.FirstReadingAt = &readings[0].RecordedAt
+		report.LastReadingAt = &readings[len(readings)-1].RecordedAt
+	}
+	report.Temperature = summarizeCondition(temps)
+	report.Humidity = summarizeCondition(humidities)
+	return report
+}
+
+func summarizeCondition(values []float64) *dto.ConditionStats {
+	if len(values) == 0 {
+		return nil
+	}
+	stats := &dto.ConditionStats{Min: values[0], Max: values[0]}
+	var sum float64
+	for _, v := range values {
+		if v < stats.Min {
+			stats.Min = v
+		}
+		if v > stats.Max {
+			stats.Max = v
+		}
+		sum += v
+	}
+	stats.Avg = roundTo(sum/float64(len(values)), 2)
+	return stats
+}
+
+// GenerateDeliveryReportPDF menyusun dokumen pengiriman: data muatan, timeline,
+// bukti serah terima, dan laporan kondisi cold-chain.
+func (s *deliveryConditionService) GenerateDeliveryReportPDF(deliveryID string, user *models.User) (*bytes.Buffer, error) {
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	if !canWatchDelivery(delivery, user) && user.Role != "admin" {
+		return nil, errors.New("forbidden: you are not involved in this delivery")
+	}
+	events, err := s.deliveryRepo.FindEventsByDeliveryID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load delivery timeline: %w", err)
+	}
+	condition, err := loadConditionReport(s.conditionRepo, delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"Delivery":      delivery,
+		"Timeline":      events,
+		"Condition":     condition,
+		"TanggalCetak":  time.Now().Format("2 January 2006 15:04"),
+		"HasThresholds": delivery.HasConditionThresholds(),
+		"TanggalPickup": "-",
+	}
+	if delivery.PickupDate != nil {
+		data["TanggalPickup"] = delivery.PickupDate.Format("2 January 2006")
+	}
+
+	tmpl, err := template.New("delivery_report_template.html").Funcs(template.FuncMap{
+		"deref": func(v *float64) string {
+			if v == nil {
+				return "-"
+			}
+			return fmt.Sprintf("%.1f", *v)
+		},
+		"datetime": func(t time.Time) string { return t.Format("02/01/2006 15:04") },
+	}).ParseFiles("templates/delivery_report_template.html")
+	if err != nil {
+		return nil, fmt.Errorf("could not parse html template: %w", err)
+	}
+	var htmlBuffer bytes.Buffer
+	if err := tmpl.Execute(&htmlBuffer, data); err != nil {
+		return nil, fmt.Errorf("could not execute html template: %w", err)
+	}
+
+	pdfg, err := wkhtmltopdf.NewPDFGenerator()
+	if err != nil {
+		return nil, fmt.Errorf("could not create PDF generator: %w", err)
+	}
+	pdfg.AddPage(wkhtmltopdf.NewPageReader(&htmlBuffer))
+	if err := pdfg.Create(); err != nil {
+		return nil, fmt.Errorf("could not create PDF: %w", err)
+	}
+	return pdfg.Buffer(), nil
+}
+
+// RegisterDevice mendaftarkan sensor IoT dan mengembalikan API key-nya satu kali.
+func (s *deliveryConditionService) RegisterDevice(farmerID uuid.UUID, input dto.RegisterColdChainDeviceRequest) (*dto.RegisterColdChainDeviceResponse, error) {
+	apiKey, err := utils.GenerateAPIKey(coldChainKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	device := &models.ColdChainDevice{
+		FarmerID:  farmerID,
+		Name:      strings.TrimSpace(input.Name),
+		KeyPrefix: apiKey[:len(coldChainKeyPrefix)+4],
+		KeyHash:   utils.HashAPIKey(apiKey),
+	}
+	if err := s.conditionRepo.CreateDevice(device); err != nil {
+		return nil, fmt.Errorf("failed to register device: %w", err)
+	}
+	return &dto.RegisterColdChainDeviceResponse{Device: device, APIKey: apiKey}, nil
+}
+
+func (s *deliveryConditionService) GetMyDevices(farmerID uuid.UUID) ([]models.ColdChainDevice, error) {
+	return s.conditionRepo.FindDevicesByFarmerID(farmerID)
+}
+
+// RevokeDevice mencabut API key perangkat; pembacaan lama tetap tersimpan.
+func (s *deliveryConditionService) RevokeDevice(deviceID, farmerID uuid.UUID) (*models.ColdChainDevice, error) {
+	device, err := s.conditionRepo.FindDeviceByID(deviceID)
+	if err != nil {
+		return nil, errors.New("device not found")
+	}
+	if device.FarmerID != farmerID {
+		return nil, errors.New("forbidden: you do not own this device")
+	}
+	if device.IsActive() {
+		now := time.Now()
+		device.RevokedAt = &now
+		if err := s.conditionRepo.UpdateDevice(device); err != nil {
+			return nil, fmt.Errorf("failed to revoke device: %w", err)
+		}
+	}
+	return device, nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/repositories"
+)
+
+type DeliveryDisputeService interface {
+	OpenDispute(deliveryID string, farmerID uuid.UUID, input dto.OpenDeliveryDisputeRequest) (*models.DeliveryDispute, error)
+	GetDisputes(status string) ([]models.DeliveryDispute, error)
+	GetDisputeDetail(disputeID uuid.UUID) (*dto.DeliveryDisputeDetailResponse, error)
+	ResolveDispute(disputeID, adminID uuid.UUID, input dto.ResolveDeliveryDisputeRequest) (*models.DeliveryDispute, error)
+}
+
+type deliveryDisputeService struct {
+	disputeRepo   repositories.DeliveryDisputeRepository
+	deliveryRepo  repositories.DeliveryRepository
+	invoiceRepo   repositories.InvoiceRepository
+	conditionRepo repositories.DeliveryConditionRepository
+	notifService  NotificationService
+}
+
+func NewDeliveryDisputeService(
+	disputeRepo repositories.DeliveryDisputeRepository,
+	deliveryRepo repositories.DeliveryRepository,
+	invoiceRepo repositories.InvoiceRepository,
+	conditionRepo repositories.DeliveryConditionRepository,
+	notifService NotificationService,
+) DeliveryDisputeService {
+	return &deliveryDisputeService{
+		disputeRepo:   disputeRepo,
+		deliveryRepo:  deliveryRepo,
+		invoiceRepo:   invoiceRepo,
+		conditionRepo: conditionRepo,
+		notifService:  notifService,
+	}
+}
+
+// OpenDispute mencatat keberatan petani. Hanya bisa diajukan setelah pembayaran
+// lunas dan sebelum dana dilepas ke driver.
+func (s *deliveryDisputeService) OpenDispute(deliveryID string, farmerID uuid.UUID, input dto.OpenDeliveryDisputeRequest) (*models.DeliveryDispute, error) {
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	if delivery.FarmerID != farmerID {
+		return nil, errors.New("forbidden: you do not own this delivery")
+	}
+
+	invoice, err := s.invoiceRepo.FindByDeliveryID(deliveryID)
+	if err != nil || invoice.Status != "paid" {
+		return nil, errors.New("invalid state: disputes can only be opened for paid deliveries")
+	}
+	events, err := s.deliveryRepo.FindEventsByDeliveryID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load delivery history: %w", err)
+	}
+	for _, e := range events {
+		if e.EventType == models.DeliveryEventPaymentReleased {
+			return nil, errors.New("invalid state: payment for this delivery has already been released")
+		}
+	}
+
+	open, err := s.disputeRepo.HasOpenDispute(delivery.ID)
+	if err != nil {
+		return nil, err
+	}
+	if open {
+		return nil, errors.New("invalid state: this delivery already has an open dispute")
+	}
+
+	dispute := &models.DeliveryDispute{
+		DeliveryID: delivery.ID,
+		RaisedByID: farmerID,
+		Type:       input.Type,
+		Reason:     input.Reason,
+		Status:     models.DisputeStatusOpen,
+	}
+	if err := s.disputeRepo.Create(dispute); err != nil {
+		return nil, fmt.Errorf("failed to open dispute: %w", err)
+	}
+
+	if delivery.DriverID != nil {
+		s.notifService.CreateNotification(*delivery.DriverID, "Sengketa Pengiriman",
+			fmt.Sprintf("Petani mengajukan keberatan atas pengiriman \"%s\". Pembayaran ditahan hingga sengketa diputuskan.", delivery.ItemDescription),
+			fmt.Sprintf("/deliveries/%s", delivery.ID), "delivery")
+	}
+	return dispute, nil
+}
+
+func (s *deliveryDisputeService) GetDisputes(status string) ([]models.DeliveryDispute, error) {
+	return s.disputeRepo.FindAll(status)
+}
+
+// GetDisputeDetail menampilkan sengketa bersama bukti serah terima, timeline pengiriman,
+// dan laporan kondisi muatan sebagai bahan pertimbangan admin.
+func (s *deliveryDisputeService) GetDisputeDetail(disputeID uuid.UUID) (*dto.DeliveryDisputeDetailResponse, error) {
+	dispute, err := s.disputeRepo.FindByID(disputeID)
+	if err != nil {
+		return nil, errors.New("dispute not found")
+	}
+	events, err := s.deliveryRepo.FindEventsByDeliveryID(dispute.DeliveryID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load delivery timeline: %w", err)
+	}
+
+	response := &dto.DeliveryDisputeDetailResponse{Dispute: dispute, Timeline: events}
+	if dispute.Delivery != nil {
+		response.Proof = dispute.Delivery.Proof
+		condition, err := loadConditionReport(s.conditionRepo, dispute.Delivery)
+		if err != nil {
+			return nil, err
+		}
+		response.Condition = condition
+	}
+	return response, nil
+}
+
+// ResolveDispute mencatat keputusan admin. Status resolved/closed membuka kembali pelepasan dana.
+func (s *deliveryDisputeService) ResolveDispute(disputeID, adminID uuid.UUID, input dto.ResolveDeliveryDisputeRequest) (*models.DeliveryDispute, error) {
+	dispute, err := s.disputeRepo.FindByID(disputeID)
+	if err != nil {
+		return nil, errors.New("dispute not found")
+	}
+	if !dispute.IsOpen() {
+		return nil, errors.New("invalid state: dispute has already been closed")
+	}
+
+	dispute.Status = input.Status
+	dispute.Resolution = &input.Resolution
+	if !dispute.IsOpen() {
+		now := time.Now()
+		dispute.ResolvedBy = &adminID
+		dispute.ResolvedAt = &now
+	}
+	if err := s.disputeRepo.Update(dispute); err != nil {
+		return nil, fmt.Errorf("failed to update dispute: %w", err)
+	}
+
+	if dispute.Delivery != nil {
+		message := fmt.Sprintf("Sengketa pengiriman \"%s\" diperbarui menjadi %s: %s", dispute.Delivery.ItemDescription, dispute.Status, input.Resolution)
+		link := fmt.Sprintf("/deliveries/%s", dispute.DeliveryID)
+		s.notifService.CreateNotification(dispute.Delivery.FarmerID, "Pembaruan Sengketa Pengiriman", message, link, "delivery")
+		if dispute.Delivery.DriverID != nil {
+			s.notifService.CreateNotification(*dispute.Delivery.DriverID, "Pembaruan Sengketa Pengiriman", message, link, "delivery")
+		}
+	}
+	return dispute, nil
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/pkg/tracking"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"gorm.io/gorm"
+)
+
+// Kebijakan biaya pengiriman gagal, dihitung dari total tagihan jasa pengiriman.
+const (
+	redeliveryFeeRate = 0.5 // Biaya kirim ulang
+	returnFeeRate     = 0.5 // Biaya retur ke lokasi asal
+	// Kompensasi driver saat petani membatalkan; sisa dana escrow dikembalikan ke petani
+	cancelCompensationBeforePickup = 0.25
+	cancelCompensationAfterPickup  = 0.5
+	failureFeeDueWindow            = 72 * time.Hour
+)
+
+type DeliveryFailureService interface {
+	ReportFailure(deliveryID string, driverID uuid.UUID, input dto.ReportDeliveryFailureInput) (*models.DeliveryFailure, error)
+	ResolveFailure(deliveryID string, farmerID uuid.UUID, input dto.ResolveDeliveryFailureRequest) (*dto.DeliveryFailureResolutionResponse, error)
+	GetFailures(deliveryID string, user *models.User) ([]models.DeliveryFailure, error)
+}
+
+type deliveryFailureService struct {
+	deliveryRepo    repositories.DeliveryRepository
+	failureRepo     repositories.DeliveryFailureRepository
+	invoiceRepo     repositories.InvoiceRepository
+	transactionRepo repositories.TransactionRepository
+	payoutRepo      repositories.PayoutRepository
+	notifService    NotificationService
+	hub             *tracking.Hub
+	db              *gorm.DB
+}
+
+func NewDeliveryFailureService(
+	deliveryRepo repositories.DeliveryRepository,
+	failureRepo repositories.DeliveryFailureRepository,
+	invoiceRepo repositories.InvoiceRepository,
+	transactionRepo repositories.TransactionRepository,
+	payoutRepo repositories.PayoutRepository,
+	notifService NotificationService,
+	hub *tracking.Hub,
+	db *gorm.DB,
+) DeliveryFailureService {
+	return &deliveryFailureService{
+		deliveryRepo:    deliveryRepo,
+		failureRepo:     failureRepo,
+		invoiceRepo:     invoiceRepo,
+		transactionRepo: transactionRepo,
+		payoutRepo:      payoutRepo,
+		notifService:    notifService,
+		hub:             hub,
+		db:              db,
+	}
+}
+
+// ReportFailure dipakai driver untuk melaporkan pengiriman yang gagal beserta alasan
+// dan foto bukti. Pengiriman menjadi "failed" sampai petani memutuskan tindak lanjutnya.
+func (s *deliveryFailureService) ReportFailure(deliveryID string, driverID uuid.UUID, input dto.ReportDeliveryFailureInput) (*models.DeliveryFailure, error) {
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	if delivery.DriverID == nil || *delivery.DriverID != driverID {
+		return nil, errors.New("forbidden: you are not assigned to this delivery")
+	}
+	if !delivery.CanTransitionTo(models.DeliveryStatusFailed) {
+		return nil, fmt.Errorf("invalid status transition from %s to %s", delivery.Status, models.DeliveryStatusFailed)
+	}
+
+	failure := &models.DeliveryFailure{
+		DeliveryID:       delivery.ID,
+		ReportedByID:     driverID,
+		Reason:           input.Reason,
+		Notes:            input.Notes,
+		PhotoURL:         input.PhotoURL,
+		Lat:              input.Lat,
+		Lng:              input.Lng,
+		FailedFromStatus: delivery.Status,
+	}
+	notes := fmt.Sprintf("%s: %s", input.Reason, input.Notes)
+	liveEvents := &deliveryEventBatch{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.failureRepo.Create(tx, failure); err != nil {
+			return fmt.Errorf("failed to save failure report: %w", err)
+		}
+		return transitionDelivery(tx, s.deliveryRepo, liveEvents, delivery, deliveryTransition{
+			To:        models.DeliveryStatusFailed,
+			EventType: models.DeliveryEventFailed,
+			ActorID:   &driverID,
+			ActorRole: "driver",
+			Notes:     &notes,
+			Lat:       input.Lat,
+			Lng:       input.Lng,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	liveEvents.publish(s.hub)
+
+	s.notifService.CreateNotification(delivery.FarmerID, "Pengiriman Gagal",
+		fmt.Sprintf("Pengiriman \"%s\" gagal (%s). Pilih kirim ulang, retur, atau batalkan pengiriman.", delivery.ItemDescription, input.Reason),
+		fmt.Sprintf("/deliveries/%s", delivery.ID), "delivery")
+	return failure, nil
+}
+
+// ResolveFailure mencatat keputusan petani atas laporan kegagalan terakhir:
+//   - redeliver: driver mencoba lagi; dikenai biaya kirim ulang
+//   - return_to_origin: muatan dibawa kembali ke lokasi pickup; dikenai biaya retur
+//   - cancel: pengiriman dibatalkan; dana escrow dibagi antara kompensasi driver dan refund petani
+//
+// Biaya tambahan dan kompensasi tidak berlaku jika kegagalan disebabkan driver.
+func (s *deliveryFailureService) ResolveFailure(deliveryID string, farmerID uuid.UUID, input dto.ResolveDeliveryFailureRequest) (*dto.DeliveryFailureResolutionResponse, error) {
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	if delivery.FarmerID != farmerID {
+		return nil, errors.New("forbidden: you do not own this delivery")
+	}
+	if delivery.Status != models.DeliveryStatusFailed {
+		return nil, fmt.Errorf("invalid state: delivery with status %s has no failure to resolve", delivery.Status)
+	}
+
+	// Tagihan jasa utama menjadi dasar biaya tambahan dan pembagian dana
+	serviceInvoice, err := s.invoiceRepo.FindByDeliveryID(deliveryID)
+	if err != nil {
+		serviceInvoice = nil
+	}
+	baseAmount := defaultDeliveryFare
+	if serviceInvoice != nil {
+		baseAmount = serviceInvoice.TotalAmount
+	} else if delivery.QuotedPrice != nil {
+		baseAmount = *delivery.QuotedPrice
+	}
+
+	var failure *models.DeliveryFailure
+	var feeInvoice *models.Invoice
+	liveEvents := &deliveryEventBatch{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Kunci pengiriman dan laporan kegagalan, lalu periksa ulang agar keputusan
+		// yang dikirim bersamaan tidak membuat tagihan biaya ganda
+		if err := lockDeliveryStatus(tx, s.deliveryRepo, delivery); err != nil {
+			return err
+		}
+		if delivery.Status != models.DeliveryStatusFailed {
+			return fmt.Errorf("invalid state: delivery with status %s has no failure to resolve", delivery.Status)
+		}
+		var err error
+		failure, err = s.failureRepo.FindOpenByDeliveryIDForUpdate(tx, delivery.ID)
+		if err != nil {
+			return errors.New("open failure report not found")
+		}
+
+		transition := deliveryTransition{ActorID: &farmerID, ActorRole: "farmer", Notes: input.Notes}
+		var feeRate float64
+		var feePurpose string
+		switch input.Resolution {
+		case models.FailureResolutionRedeliver:
+			transition.To = models.DeliveryStatusPickupPending
+			if failure.GoodsPickedUp() {
+				transition.To = models.DeliveryStatusInTransit
+			}
+			transition.EventType = models.DeliveryEventRedeliveryScheduled
+			feeRate, feePurpose = redeliveryFeeRate, models.InvoicePurposeRedeliveryFee
+		case models.FailureResolutionReturnToOrigin:
+			if !failure.GoodsPickedUp() {
+				return errors.New("invalid resolution: goods were never picked up, nothing to return")
+			}
+			transition.To = models.DeliveryStatusReturning
+			transition.EventType = models.DeliveryEventReturnStarted
+			feeRate, feePurpose = returnFeeRate, models.InvoicePurposeReturnFee
+		case models.FailureResolutionCancel:
+			transition.To = models.DeliveryStatusCancelled
+			transition.EventType = models.DeliveryEventCancelled
+		}
+		if failure.IsDriverFault() {
+			feeRate = 0
+		}
+
+		if err := transitionDelivery(tx, s.deliveryRepo, liveEvents, delivery, transition); err != nil {
+			return err
+		}
+
+		if feeRate > 0 {
+			fee := roundTo(baseAmount*feeRate, 0)
+			platformFee := roundTo(fee*0.05, 0)
+			feeInvoice = &models.Invoice{
+				DeliveryID:  &delivery.ID,
+				Purpose:     feePurpose,
+				FarmerID:    farmerID,
+				Amount:      fee - platformFee,
+				PlatformFee: platformFee,
+				TotalAmount: fee,
+				Status:      "pending",
+				DueDate:     time.Now().Add(failureFeeDueWindow),
+			}
+			if err := s.invoiceRepo.Create(tx, feeInvoice); err != nil {
+				return fmt.Errorf("failed to create fee invoice: %w", err)
+			}
+			failure.FeeAmount = fee
+			failure.FeeInvoiceID = &feeInvoice.ID
+		}
+
+		if input.Resolution == models.FailureResolutionCancel && serviceInvoice != nil && serviceInvoice.Status == "paid" {
+			if err := s.settleCancelledEscrow(tx, liveEvents, delivery, failure, serviceInvoice, farmerID); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		resolution := input.Resolution
+		failure.Resolution = &resolution
+		failure.ResolutionNotes = input.Notes
+		failure.ResolvedByID = &farmerID
+		failure.ResolvedAt = &now
+		return s.failureRepo.Update(tx, failure)
+	})
+	if err != nil {
+		return nil, err
+	}
+	liveEvents.publish(s.hub)
+
+	if delivery.DriverID != nil {
+		s.notifService.CreateNotification(*delivery.DriverID, "Keputusan Pengiriman Gagal",
+			fmt.Sprintf("Petani memilih %s untuk pengiriman \"%s\".", input.Resolution, delivery.ItemDescription),
+			fmt.Sprintf("/deliveries/%s", delivery.ID), "delivery")
+	}
+	return &dto.DeliveryFailureResolutionResponse{Failure: failure, Delivery: delivery, FeeInvoice: feeInvoice}, nil
+}
+
+// settleCancelledEscrow membagi dana escrow pengiriman yang dibatalkan, termasuk biaya
+// tambahan yang sudah lunas: driver menerima kompensasi sesuai tahap kegagalan,
+// sisanya dikembalikan ke petani.
+func (s *deliveryFailureService) settleCancelledEscrow(tx *gorm.DB, liveEvents *deliveryEventBatch, delivery *models.Delivery, failure *models.DeliveryFailure, invoice *models.Invoice, farmerID uuid.UUID) error {
+	transaction, err := s.transactionRepo.FindByInvoiceID(invoice.ID.String())
+	if err != nil {
+		return errors.New("paid transaction not found for this delivery")
+	}
+	if err := lockUnreleasedDelivery(tx, s.deliveryRepo, delivery); err != nil {
+		return err
+	}
+
+	// Biaya kirim ulang/retur yang sudah lunas ikut ditahan escrow dan dibagi dengan aturan yang sama
+	invoices, err := s.invoiceRepo.FindAllByDeliveryID(delivery.ID.String())
+	if err != nil {
+		return fmt.Errorf("failed to load delivery invoices: %w", err)
+	}
+	driverShare, paidTotal := invoice.Amount, invoice.TotalAmount
+	for _, surcharge := range invoices {
+		if surcharge.Purpose == models.InvoicePurposeService || surcharge.Status != "paid" {
+			continue
+		}
+		driverShare += surcharge.Amount
+		paidTotal += surcharge.TotalAmount
+	}
+
+	rate := cancelCompensationBeforePickup
+	if failure.GoodsPickedUp() {
+		rate = cancelCompensationAfterPickup
+	}
+	if failure.IsDriverFault() {
+		rate = 0
+	}
+	compensation := roundTo(driverShare*rate, 0)
+	refund := roundTo(paidTotal*(1-rate), 0)
+
+	if compensation > 0 && delivery.DriverID != nil {
+		if err := s.payoutRepo.Create(tx, &models.Payout{
+			TransactionID: transaction.ID,
+			PayeeID:       *delivery.DriverID,
+			PayeeType:     "driver",
+			Amount:        compensation,
+		}); err != nil {
+			return fmt.Errorf("failed to create driver compensation: %w", err)
+		}
+		failure.DriverCompensation = compensation
+	}
+	if refund > 0 {
+		if err := s.payoutRepo.Create(tx, &models.Payout{
+			TransactionID: transaction.ID,
+			PayeeID:       farmerID,
+			PayeeType:     "farmer",
+			Amount:        refund,
+		}); err != nil {
+			return fmt.Errorf("failed to create farmer refund: %w", err)
+		}
+		failure.RefundAmount = refund
+	}
+
+	notes := fmt.Sprintf("Kompensasi driver %.0f, refund petani %.0f", compensation, refund)
+	return recordDeliveryEvent(tx, s.deliveryRepo, liveEvents, delivery, delivery.Status, deliveryTransition{
+		EventType: models.DeliveryEventPaymentReleased,
+		ActorID:   &farmerID,
+		ActorRole: "farmer",
+		Notes:     &notes,
+	})
+}
+
+// GetFailures menampilkan riwayat kegagalan kepada petani, driver, atau admin.
+func (s *deliveryFailureService) GetFailures(deliveryID string, user *models.User) ([]models.DeliveryFailure, error) {
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	isDriver := delivery.DriverID != nil && *delivery.DriverID == user.ID
+	if delivery.FarmerID != user.ID && !isDriver && user.Role != "admin" {
+		return nil, errors.New("forbidden: you are not involved in this delivery")
+	}
+	return s.failureRepo.FindAllByDeliveryID(delivery.ID)
+}
@@ -0,0 +1,450 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/pkg/tracking"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"github.com/whsasmita/AgroLink_API/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	deliveryOTPTTL            = 10 * time.Minute
+	deliveryOTPMaxAttempts    = 5
+	deliveryOTPMaxSends       = 5
+	deliveryOTPResendCooldown = time.Minute
+)
+
+type DeliveryProofService interface {
+	RequestOTP(deliveryID string, driverID uuid.UUID) (*dto.OTPRequestResponse, error)
+	SubmitProof(deliveryID string, driverID uuid.UUID, input dto.SubmitDeliveryProofInput) (*models.DeliveryProof, error)
+	RequestStopOTP(deliveryID, stopID string, driverID uuid.UUID) (*dto.OTPRequestResponse, error)
+	SubmitStopProof(deliveryID, stopID string, driverID uuid.UUID, input dto.SubmitDeliveryProofInput) (*models.DeliveryProof, error)
+}
+
+type deliveryProofService struct {
+	deliveryRepo repositories.DeliveryRepository
+	proofRepo    repositories.DeliveryProofRepository
+	orderRepo    repositories.OrderRepository
+	notifService NotificationService
+	emailService EmailService
+	hub          *tracking.Hub
+	db           *gorm.DB
+}
+
+func NewDeliveryProofService(
+	deliveryRepo repositories.DeliveryRepository,
+	proofRepo repositories.DeliveryProofRepository,
+	orderRepo repositories.OrderRepository,
+	notifService NotificationService,
+	emailService EmailService,
+	hub *tracking.Hub,
+	db *gorm.DB,
+) DeliveryProofService {
+	return &deliveryProofService{
+		deliveryRepo: deliveryRepo,
+		proofRepo:    proofRepo,
+		orderRepo:    orderRepo,
+		notifService: notifService,
+		emailService: emailService,
+		hub:          hub,
+		db:           db,
+	}
+}
+
+// loadAssignedDelivery memastikan delivery ada, milik driver, dan siap diselesaikan.
+func (s *deliveryProofService) loadAssignedDelivery(deliveryID string, driverID uuid.UUID) (*models.Delivery, error) {
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	if delivery.DriverID == nil || *delivery.DriverID != driverID {
+		return nil, errors.New("forbidden: you are not assigned to this delivery")
+	}
+	if !delivery.CanTransitionTo(models.DeliveryStatusDelivered) {
+		return nil, fmt.Errorf("invalid status: delivery in status %s cannot be handed over", delivery.Status)
+	}
+	return delivery, nil
+}
+
+// handoverTarget adalah pihak penerima satu serah terima: tujuan akhir atau satu titik antar.
+type handoverTarget struct {
+	proof          *models.DeliveryProof
+	label          string // deskripsi muatan pada pesan OTP
+	recipientName  *string
+	recipientEmail *string
+	buyerOrder     *models.Order // pesanan e-commerce yang diantar; pembelinya tujuan cadangan kode
+}
+
+// RequestOTP membuat kode serah terima 6 digit dan mengirimkannya ke penerima.
+// Jika email penerima tidak tersedia, kode dikirim ke akun pembeli pesanan; kode
+// tidak pernah dikirim ke petani karena petani bukan pihak penerima barang.
+func (s *deliveryProofService) RequestOTP(deliveryID string, driverID uuid.UUID) (*dto.OTPRequestResponse, error) {
+	delivery, err := s.loadAssignedDelivery(deliveryID, driverID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.IsConsolidated {
+		return nil, errors.New("invalid status: consolidated deliveries are handed over per stop")
+	}
+
+	proof := delivery.Proof
+	if proof == nil {
+		proof = &models.DeliveryProof{DeliveryID: delivery.ID, DriverID: driverID}
+	}
+	return s.issueOTP(delivery, handoverTarget{
+		proof:          proof,
+		label:          delivery.ItemDescription,
+		recipientName:  delivery.RecipientName,
+		recipientEmail: delivery.RecipientEmail,
+		buyerOrder:     deliveryBuyerOrder(delivery.Orders),
+	})
+}
+
+// RequestStopOTP mengirim kode serah terima untuk satu titik antar.
+func (s *deliveryProofService) RequestStopOTP(deliveryID, stopID string, driverID uuid.UUID) (*dto.OTPRequestResponse, error) {
+	delivery, stop, err := s.loadPendingStop(deliveryID, stopID, driverID)
+	if err != nil {
+		return nil, err
+	}
+
+	proof := stop.Proof
+	if proof == nil {
+		proof = &models.DeliveryProof{DeliveryID: delivery.ID, StopID: &stop.ID, DriverID: driverID}
+	}
+	label := stop.Address
+	if stop.ItemDescription != nil {
+		label = *stop.ItemDescription
+	}
+	var buyerOrder *models.Order
+	if stop.OrderID != nil {
+		if order, err := s.orderRepo.FindByID(*stop.OrderID); err == nil {
+			buyerOrder = order
+		}
+	}
+	return s.issueOTP(delivery, handoverTarget{
+		proof:          proof,
+		label:          label,
+		recipientName:  stop.RecipientName,
+		recipientEmail: stop.RecipientEmail,
+		buyerOrder:     buyerOrder,
+	})
+}
+
+// deliveryBuyerOrder mengembalikan pesanan yang diantar bila semuanya milik satu pembeli.
+func deliveryBuyerOrder(orders []models.Order) *models.Order {
+	if len(orders) == 0 {
+		return nil
+	}
+	for _, order := range orders[1:] {
+		if order.UserID != orders[0].UserID {
+			return nil
+		}
+	}
+	return &orders[0]
+}
+
+// issueOTP mengirim kode baru ke penerima. Pengiriman ulang dibatasi jeda dan jumlah,
+// dan percobaan salah tetap terakumulasi agar kode tidak bisa ditebak dengan kirim ulang.
+func (s *deliveryProofService) issueOTP(delivery *models.Delivery, target handoverTarget) (*dto.OTPRequestResponse, error) {
+	proof := target.proof
+	if proof.OTPAttempts >= deliveryOTPMaxAttempts {
+		return nil, errors.New("invalid otp: too many wrong attempts, report the delivery as failed")
+	}
+	if proof.OTPSendCount >= deliveryOTPMaxSends {
+		return nil, errors.New("invalid otp: handover code resend limit reached, report the delivery as failed")
+	}
+	if proof.OTPSentAt != nil {
+		if wait := time.Until(proof.OTPSentAt.Add(deliveryOTPResendCooldown)); wait > 0 {
+			return nil, fmt.Errorf("invalid otp: please wait %d seconds before requesting a new code", int(wait.Seconds())+1)
+		}
+	}
+	hasEmail := target.recipientEmail != nil && *target.recipientEmail != ""
+	if !hasEmail && target.buyerOrder == nil {
+		return nil, errors.New("invalid recipient: no recipient email or buyer account to receive the handover code")
+	}
+
+	code, err := generateOTP()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate otp: %w", err)
+	}
+	hash, err := utils.HashPassword(code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to secure otp: %w", err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(deliveryOTPTTL)
+	proof.OTPHash = hash
+	proof.OTPExpiresAt = &expiresAt
+	proof.OTPSentAt = &now
+	proof.OTPSendCount++
+
+	message := fmt.Sprintf("Kode serah terima pengiriman \"%s\" adalah %s. Berikan kode ini kepada driver hanya setelah barang Anda terima. Berlaku %d menit.",
+		target.label, code, int(deliveryOTPTTL.Minutes()))
+	sentTo := "buyer"
+	if hasEmail {
+		recipientName := ""
+		if target.recipientName != nil {
+			recipientName = *target.recipientName
+		}
+		if err := s.emailService.SendEmail(*target.recipientEmail, recipientName, "Kode Serah Terima AgroLink", message); err == nil {
+			sentTo = maskEmail(*target.recipientEmail)
+		} else if target.buyerOrder == nil {
+			return nil, fmt.Errorf("failed to send handover code: %w", err)
+		} else {
+			log.Printf("WARN: failed to email delivery otp for %s, falling back to buyer account: %v", delivery.ID, err)
+		}
+	}
+	proof.OTPSentTo = &sentTo
+
+	if err := s.proofRepo.Save(nil, proof); err != nil {
+		return nil, fmt.Errorf("failed to store otp: %w", err)
+	}
+
+	link := fmt.Sprintf("/deliveries/%s", delivery.ID)
+	if sentTo == "buyer" {
+		s.notifService.CreateNotification(target.buyerOrder.UserID, "Kode Serah Terima Pengiriman", message,
+			fmt.Sprintf("/orders/%s", target.buyerOrder.ID), "order")
+	}
+	s.notifService.CreateNotification(delivery.FarmerID, "Driver Tiba di Tujuan",
+		fmt.Sprintf("Driver meminta kode serah terima untuk \"%s\". Kode telah dikirim ke penerima.", target.label),
+		link, "delivery")
+
+	return &dto.OTPRequestResponse{SentTo: sentTo, ExpiresAt: expiresAt}, nil
+}
+
+// verifyOTP mengunci bukti serah terima, memeriksa kode, dan mencatat percobaan yang gagal
+// di transaksi yang sama. Jika cocok, bukti dilengkapi dengan data penerima dan kode
+// tidak bisa dipakai lagi. rejected berisi alasan penolakan kode; transaksi tetap boleh
+// di-commit agar percobaan yang salah tersimpan.
+func (s *deliveryProofService) verifyOTP(tx *gorm.DB, proofID uuid.UUID, input dto.SubmitDeliveryProofInput) (proof *models.DeliveryProof, rejected error, err error) {
+	proof, err = s.proofRepo.FindByIDForUpdate(tx, proofID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock proof of delivery: %w", err)
+	}
+	if proof.OTPAttempts >= deliveryOTPMaxAttempts {
+		return nil, errors.New("invalid otp: too many attempts, report the delivery as failed"), nil
+	}
+	if proof.OTPHash == "" {
+		return nil, errors.New("invalid otp: request a handover code first"), nil
+	}
+	if proof.OTPExpiresAt == nil || time.Now().After(*proof.OTPExpiresAt) {
+		return nil, errors.New("invalid otp: code has expired, request a new one"), nil
+	}
+	if !utils.CheckPasswordHash(strings.TrimSpace(input.OTP), proof.OTPHash) {
+		proof.OTPAttempts++
+		if proof.OTPAttempts >= deliveryOTPMaxAttempts {
+			proof.OTPHash = "" // Kode hangus setelah batas percobaan tercapai
+		}
+		if err := s.proofRepo.UpdateOTPAttempts(tx, proof); err != nil {
+			return nil, nil, fmt.Errorf("failed to record otp attempt: %w", err)
+		}
+		return nil, errors.New("invalid otp: code does not match"), nil
+	}
+
+	now := time.Now()
+	recipientName := strings.TrimSpace(input.RecipientName)
+	proof.RecipientName = &recipientName
+	proof.PhotoURL = &input.PhotoURL
+	proof.SignatureURL = &input.SignatureURL
+	proof.Lat = input.Lat
+	proof.Lng = input.Lng
+	proof.VerifiedAt = &now
+	proof.OTPHash = "" // Kode hanya sekali pakai
+	if err := s.proofRepo.Save(tx, proof); err != nil {
+		return nil, nil, fmt.Errorf("failed to save proof of delivery: %w", err)
+	}
+	return proof, nil, nil
+}
+
+// SubmitProof memverifikasi OTP, menyimpan foto & tanda tangan penerima,
+// lalu menandai pengiriman sebagai 'delivered'.
+func (s *deliveryProofService) SubmitProof(deliveryID string, driverID uuid.UUID, input dto.SubmitDeliveryProofInput) (*models.DeliveryProof, error) {
+	delivery, err := s.loadAssignedDelivery(deliveryID, driverID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.IsConsolidated {
+		return nil, errors.New("invalid status: consolidated deliveries are handed over per stop")
+	}
+	if delivery.Proof == nil {
+		return nil, errors.New("invalid otp: request a handover code first")
+	}
+
+	var proof *models.DeliveryProof
+	var rejected error
+	var recipientName string
+	liveEvents := &deliveryEventBatch{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		proof, rejected, err = s.verifyOTP(tx, delivery.Proof.ID, input)
+		if err != nil || rejected != nil {
+			return err // Percobaan yang salah tetap di-commit
+		}
+		recipientName = *proof.RecipientName
+		notes := fmt.Sprintf("Diterima oleh %s", recipientName)
+		return transitionDelivery(tx, s.deliveryRepo, liveEvents, delivery, deliveryTransition{
+			To:        models.DeliveryStatusDelivered,
+			EventType: models.DeliveryEventDelivered,
+			ActorID:   &driverID,
+			ActorRole: "driver",
+			Notes:     &notes,
+			Lat:       input.Lat,
+			Lng:       input.Lng,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rejected != nil {
+		return nil, rejected
+	}
+	liveEvents.publish(s.hub)
+
+	s.notifService.CreateNotification(delivery.FarmerID, "Barang Telah Diterima",
+		fmt.Sprintf("Pengiriman \"%s\" telah diterima oleh %s. Periksa bukti serah terima sebelum melepas pembayaran.", delivery.ItemDescription, recipientName),
+		fmt.Sprintf("/deliveries/%s", delivery.ID), "delivery")
+	return proof, nil
+}
+
+// loadPendingStop memastikan titik antar milik pengiriman driver dan belum diserahterimakan.
+func (s *deliveryProofService) loadPendingStop(deliveryID, stopID string, driverID uuid.UUID) (*models.Delivery, *models.DeliveryStop, error) {
+	delivery, err := s.loadAssignedDelivery(deliveryID, driverID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range delivery.Stops {
+		stop := &delivery.Stops[i]
+		if stop.ID.String() != stopID {
+			continue
+		}
+		if stop.Status == models.DeliveryStopStatusDelivered {
+			return nil, nil, errors.New("invalid status: this stop has already been handed over")
+		}
+		return delivery, stop, nil
+	}
+	return nil, nil, errors.New("delivery stop not found")
+}
+
+// SubmitStopProof menyelesaikan serah terima satu titik antar. Pesanan e-commerce pada
+// titik tersebut ikut selesai; pengiriman gabungan menjadi 'delivered' setelah
+// titik antar terakhir diterima.
+func (s *deliveryProofService) SubmitStopProof(deliveryID, stopID string, driverID uuid.UUID, input dto.SubmitDeliveryProofInput) (*models.DeliveryProof, error) {
+	delivery, stop, err := s.loadPendingStop(deliveryID, stopID, driverID)
+	if err != nil {
+		return nil, err
+	}
+	if stop.Proof == nil {
+		return nil, errors.New("invalid otp: request a handover code first")
+	}
+
+	var order *models.Order
+	if stop.OrderID != nil {
+		if found, err := s.orderRepo.FindByID(*stop.OrderID); err == nil && found.DeliveryID != nil && *found.DeliveryID == delivery.ID {
+			order = found
+		}
+	}
+
+	now := time.Now()
+	stop.Status = models.DeliveryStopStatusDelivered
+	stop.DeliveredAt = &now
+	allDelivered := len(delivery.PendingStops()) == 0
+
+	var proof *models.DeliveryProof
+	var rejected error
+	var recipientName string
+	liveEvents := &deliveryEventBatch{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		proof, rejected, err = s.verifyOTP(tx, stop.Proof.ID, input)
+		if err != nil || rejected != nil {
+			return err // Percobaan yang salah tetap di-commit
+		}
+		recipientName = *proof.RecipientName
+		notes := fmt.Sprintf("Titik antar %d diterima oleh %s", stop.Sequence, recipientName)
+		if err := s.deliveryRepo.UpdateStop(tx, stop); err != nil {
+			return fmt.Errorf("failed to update delivery stop: %w", err)
+		}
+		if order != nil && (order.Status == models.OrderStatusPaid || order.Status == models.OrderStatusShipped) {
+			if order.ShippedAt == nil {
+				order.ShippedAt = &now
+			}
+			order.Status = models.OrderStatusCompleted
+			order.CompletedAt = &now
+			if err := s.orderRepo.Update(tx, order); err != nil {
+				return fmt.Errorf("failed to complete order: %w", err)
+			}
+		}
+		if err := recordDeliveryEvent(tx, s.deliveryRepo, liveEvents, delivery, delivery.Status, deliveryTransition{
+			EventType: models.DeliveryEventStopDelivered,
+			ActorID:   &driverID,
+			ActorRole: "driver",
+			Notes:     &notes,
+			Lat:       input.Lat,
+			Lng:       input.Lng,
+		}); err != nil {
+			return err
+		}
+		if !delivery.IsConsolidated || !allDelivered {
+			return nil
+		}
+		finalNotes := "Semua titik antar telah diterima"
+		return transitionDelivery(tx, s.deliveryRepo, liveEvents, delivery, deliveryTransition{
+			To:        models.DeliveryStatusDelivered,
+			EventType: models.DeliveryEventDelivered,
+			ActorID:   &driverID,
+			ActorRole: "driver",
+			Notes:     &finalNotes,
+			Lat:       input.Lat,
+			Lng:       input.Lng,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rejected != nil {
+		return nil, rejected
+	}
+	liveEvents.publish(s.hub)
+
+	link := fmt.Sprintf("/deliveries/%s", delivery.ID)
+	message := fmt.Sprintf("Titik antar %d (%s) telah diterima oleh %s.", stop.Sequence, stop.Address, recipientName)
+	if delivery.IsConsolidated && allDelivered {
+		message += " Semua titik antar selesai; periksa bukti serah terima sebelum melepas pembayaran."
+	}
+	s.notifService.CreateNotification(delivery.FarmerID, "Barang Telah Diterima", message, link, "delivery")
+	if order != nil {
+		s.notifService.CreateNotification(order.UserID, "Pesanan Diterima",
+			fmt.Sprintf("Pesanan %s telah diterima oleh %s.", order.InvoiceNumber, recipientName),
+			fmt.Sprintf("/orders/%s", order.ID), "order")
+	}
+	return proof, nil
+}
+
+func generateOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// maskEmail menyamarkan email penerima, mis. "budi@mail.com" -> "b***@mail.com".
+func maskEmail(email string) string {
+	at := strings.Index(email, "@")
+	if at <= 1 {
+		return email
+	}
+	return email[:1] + "***" + email[at:]
+}
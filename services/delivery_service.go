@@ -1,28 +1,52 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/whsasmita/AgroLink_API/dto"
 	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/pkg/tracking"
 	"github.com/whsasmita/AgroLink_API/repositories"
 	"gorm.io/gorm"
 )
 
 type DeliveryService interface {
 	CreateDelivery(input dto.CreateDeliveryRequest, farmerID uuid.UUID) (*models.Delivery, error)
+	CreateLinkedDelivery(input dto.CreateDeliveryRequest, farmerID uuid.UUID, link func(tx *gorm.DB, delivery *models.Delivery) error) (*models.Delivery, error)
+	CreateConsolidatedDelivery(input dto.CreateConsolidatedDeliveryRequest, farmerID uuid.UUID) (*dto.ConsolidatedDeliveryResponse, error)
 	FindAvailableDrivers(deliveryID string, farmerID uuid.UUID, radius int) ([]dto.DriverRecommendationResponse, error)
-	SelectDriver(deliveryID, driverID, farmerID string) (*models.Contract, error)
+	SelectDriver(deliveryID, driverID, farmerID, quoteID string) (*models.Contract, error)
 	FindByID(deliveryID string) (*models.Delivery, error)
 	GetMyDeliveries(userID uuid.UUID, role string) ([]dto.MyDeliveryResponse, error)
+	GetDeliveryDetail(deliveryID string, userID uuid.UUID) (*dto.DeliveryDetailResponse, error)
+	UpdateStatusByDriver(deliveryID string, driverID uuid.UUID, input dto.DeliveryStatusUpdateRequest) (*models.Delivery, error)
+}
+
+// driverActions memetakan aksi driver ke status tujuan dan jenis event.
+var driverActions = map[string]struct {
+	status    string
+	eventType string
+}{
+	"pickup": {models.DeliveryStatusPickedUp, models.DeliveryEventPickedUp},
+	"depart": {models.DeliveryStatusInTransit, models.DeliveryEventDeparted},
+	"arrive": {models.DeliveryStatusArrived, models.DeliveryEventArrived},
+	"return": {models.DeliveryStatusReturned, models.DeliveryEventReturned},
 }
 
 type deliveryService struct {
 	deliveryRepo repositories.DeliveryRepository
 	driverRepo   repositories.DriverRepository
 	contractRepo repositories.ContractRepository
+	orderRepo    repositories.OrderRepository
+	pricing      PricingService
+	routing      RoutingProvider
+	matching     DriverMatchingService
+	hub          *tracking.Hub
 	db           *gorm.DB // Diperlukan untuk transaksi
 }
 
@@ -31,12 +55,22 @@ func NewDeliveryService(
 	deliveryRepo repositories.DeliveryRepository,
 	driverRepo repositories.DriverRepository,
 	contractRepo repositories.ContractRepository,
+	orderRepo repositories.OrderRepository,
+	pricing PricingService,
+	routing RoutingProvider,
+	matching DriverMatchingService,
+	hub *tracking.Hub,
 	db *gorm.DB,
 ) DeliveryService {
 	return &deliveryService{
 		deliveryRepo: deliveryRepo,
 		driverRepo:   driverRepo,
 		contractRepo: contractRepo,
+		orderRepo:    orderRepo,
+		pricing:      pricing,
+		routing:      routing,
+		matching:     matching,
+		hub:          hub,
 		db:           db,
 	}
 }
@@ -44,25 +78,136 @@ func NewDeliveryService(
 func (s *deliveryService) FindByID(deliveryID string) (*models.Delivery, error) {
 	return s.deliveryRepo.FindByID(deliveryID)
 }
+
 // CreateDelivery membuat permintaan pengiriman baru dari petani.
 func (s *deliveryService) CreateDelivery(input dto.CreateDeliveryRequest, farmerID uuid.UUID) (*models.Delivery, error) {
+	return s.CreateLinkedDelivery(input, farmerID, nil)
+}
+
+// CreateLinkedDelivery membuat delivery lalu menjalankan link (boleh nil) pada transaksi
+// yang sama, mis. untuk menautkan pesanan yang dikirim. Kegagalan link membatalkan delivery.
+func (s *deliveryService) CreateLinkedDelivery(input dto.CreateDeliveryRequest, farmerID uuid.UUID, link func(tx *gorm.DB, delivery *models.Delivery) error) (*models.Delivery, error) {
+	if (input.DestinationLat == nil) != (input.DestinationLng == nil) {
+		return nil, errors.New("invalid input: destination_lat and destination_lng must be provided together")
+	}
 	newDelivery := &models.Delivery{
 		FarmerID:           farmerID,
 		PickupAddress:      input.PickupAddress,
 		PickupLat:          input.PickupLat,
 		PickupLng:          input.PickupLng,
 		DestinationAddress: input.DestinationAddress,
+		DestinationLat:     input.DestinationLat,
+		DestinationLng:     input.DestinationLng,
+		RecipientName:      input.RecipientName,
+		RecipientPhone:     input.RecipientPhone,
+		RecipientEmail:     input.RecipientEmail,
 		ItemDescription:    input.ItemDescription,
 		ItemWeight:         input.ItemWeight,
-		Status:             "pending_driver",
+		Status:             models.DeliveryStatusPendingDriver,
+	}
+	if input.PickupDate != "" {
+		pickupDate, err := time.Parse("2006-01-02", input.PickupDate)
+		if err != nil {
+			return nil, errors.New("invalid pickup_date format, use YYYY-MM-DD")
+		}
+		newDelivery.PickupDate = &pickupDate
+	}
+	for i, stop := range input.Stops {
+		newDelivery.Stops = append(newDelivery.Stops, models.DeliveryStop{
+			Sequence:       i + 1,
+			Address:        stop.Address,
+			Lat:            stop.Lat,
+			Lng:            stop.Lng,
+			RecipientName:  stop.RecipientName,
+			RecipientPhone: stop.RecipientPhone,
+			Notes:          stop.Notes,
+		})
 	}
 
-	if err := s.deliveryRepo.Create(newDelivery); err != nil {
-		return nil, fmt.Errorf("failed to create delivery request: %w", err)
+	if err := s.saveNewDelivery(newDelivery, farmerID, link); err != nil {
+		return nil, err
 	}
 	return newDelivery, nil
 }
 
+// saveNewDelivery menghitung estimasi rute di luar transaksi, lalu menyimpan delivery,
+// mencatat event pembuatan, dan menjalankan link (bila ada) dalam satu transaksi.
+func (s *deliveryService) saveNewDelivery(newDelivery *models.Delivery, farmerID uuid.UUID, link func(tx *gorm.DB, delivery *models.Delivery) error) error {
+	// Hitung estimasi jarak & durasi; kegagalan routing tidak menggagalkan permintaan,
+	// tetapi driver baru bisa dipilih setelah rute berhasil dihitung (lihat ensureRouteEstimate).
+	if err := s.estimateRoute(newDelivery); err != nil {
+		log.Printf("WARN: failed to estimate route for new delivery: %v", err)
+	}
+
+	liveEvents := &deliveryEventBatch{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.deliveryRepo.Create(tx, newDelivery); err != nil {
+			return fmt.Errorf("failed to create delivery request: %w", err)
+		}
+		if err := recordDeliveryEvent(tx, s.deliveryRepo, liveEvents, newDelivery, "", deliveryTransition{
+			EventType: models.DeliveryEventCreated,
+			ActorID:   &farmerID,
+			ActorRole: "farmer",
+		}); err != nil {
+			return err
+		}
+		if link == nil {
+			return nil
+		}
+		return link(tx, newDelivery)
+	})
+	if err != nil {
+		return err
+	}
+	liveEvents.publish(s.hub)
+	return nil
+}
+
+// estimateRoute mengisi estimasi jarak & durasi delivery dari RoutingProvider. Tanpa
+// koordinat tujuan rute tidak lengkap sehingga tidak dihitung.
+func (s *deliveryService) estimateRoute(delivery *models.Delivery) error {
+	if delivery.DestinationLat == nil || delivery.DestinationLng == nil {
+		return errors.New("delivery has no destination coordinates")
+	}
+	estimate, err := s.routing.Route(deliveryRoutePoints(delivery))
+	if err != nil {
+		return err
+	}
+	delivery.EstimatedDistanceKm = &estimate.DistanceKm
+	delivery.EstimatedDurationMinutes = &estimate.DurationMinutes
+	delivery.RouteSource = &estimate.Source
+	return nil
+}
+
+// ensureRouteEstimate menghitung ulang rute delivery yang routing-nya gagal saat dibuat,
+// karena tarif per-km hanya boleh dihitung dari rute server. Delivery tanpa koordinat
+// tujuan tetap tanpa rute dan tidak bisa diberi penawaran harga.
+func (s *deliveryService) ensureRouteEstimate(delivery *models.Delivery) {
+	if delivery.RouteSource != nil {
+		return
+	}
+	if err := s.estimateRoute(delivery); err != nil {
+		log.Printf("WARN: delivery %s still has no route estimate: %v", delivery.ID, err)
+		return
+	}
+	if err := s.deliveryRepo.UpdateRouteEstimate(nil, delivery); err != nil {
+		log.Printf("WARN: failed to save route estimate for delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// deliveryRoutePoints menyusun urutan titik: pickup -> stop tambahan -> tujuan akhir.
+// Titik antar yang sudah diserahterimakan dilewati.
+func deliveryRoutePoints(d *models.Delivery) []dto.GeoPoint {
+	points := []dto.GeoPoint{{Lat: d.PickupLat, Lng: d.PickupLng}}
+	for _, stop := range d.PendingStops() {
+		points = append(points, dto.GeoPoint{Lat: stop.Lat, Lng: stop.Lng})
+	}
+	if d.DestinationLat != nil && d.DestinationLng != nil {
+		points = append(points, dto.GeoPoint{Lat: *d.DestinationLat, Lng: *d.DestinationLng})
+	}
+	return points
+}
+
 // FindAvailableDrivers adalah logika inti untuk mencari driver yang cocok.
 func (s *deliveryService) FindAvailableDrivers(deliveryID string, farmerID uuid.UUID, radius int) ([]dto.DriverRecommendationResponse, error) {
 	delivery, err := s.deliveryRepo.FindByID(deliveryID)
@@ -73,6 +218,7 @@ func (s *deliveryService) FindAvailableDrivers(deliveryID string, farmerID uuid.
 	if delivery.FarmerID != farmerID {
 		return nil, fmt.Errorf("forbidden: you do not own this delivery request")
 	}
+	s.ensureRouteEstimate(delivery)
 
 	// 1. Cari driver terdekat menggunakan Haversine
 	nearbyDrivers, err := s.driverRepo.FindNearby(delivery.PickupLat, delivery.PickupLng, radius)
@@ -80,25 +226,14 @@ func (s *deliveryService) FindAvailableDrivers(deliveryID string, farmerID uuid.
 		return nil, fmt.Errorf("failed to find nearby drivers: %w", err)
 	}
 
-	// 2. TODO: Filter lebih lanjut berdasarkan rute yang cocok (untuk V2)
-	// Untuk saat ini, kita tampilkan semua yang terdekat.
-
-	// 3. Transformasi ke DTO
-	var recommendations []dto.DriverRecommendationResponse
-	for _, driver := range nearbyDrivers {
-		recommendations = append(recommendations, dto.DriverRecommendationResponse{
-			DriverID:     driver.UserID,
-			DriverName:   driver.User.Name,
-			VehicleTypes: driver.VehicleTypes,
-			Rating:       driver.Rating,
-			Distance:     driver.Distance,
-		})
-	}
-	return recommendations, nil
+	// 2. Nilai & urutkan berdasarkan rute, jadwal, kapasitas, rating dan beban kerja
+	return s.matching.RankDrivers(delivery, nearbyDrivers, radius)
 }
 
-// SelectDriver memilih driver dan membuatkan kontrak untuk pengiriman.
-func (s *deliveryService) SelectDriver(deliveryID, driverID, farmerID string) (*models.Contract, error) {
+// SelectDriver memilih driver dan membuatkan kontrak untuk pengiriman. Harga dihitung ulang
+// dan hanya dikunci bila masih sama dengan penawaran (quoteID) yang dilihat petani.
+func (s *deliveryService) SelectDriver(deliveryID, driverID, farmerID, quoteID string) (*models.Contract, error) {
+	liveEvents := &deliveryEventBatch{}
 	tx := s.db.Begin()
 	if tx.Error != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", tx.Error)
@@ -119,14 +254,36 @@ func (s *deliveryService) SelectDriver(deliveryID, driverID, farmerID string) (*
 		tx.Rollback()
 		return nil, errors.New("forbidden: you do not own this delivery")
 	}
-	if delivery.Status != "pending_driver" {
+	if delivery.Status != models.DeliveryStatusPendingDriver {
 		tx.Rollback()
 		return nil, errors.New("this delivery is no longer waiting for a driver")
 	}
+	s.ensureRouteEstimate(delivery)
 
 	driverUUID, _ := uuid.Parse(driverID)
 	farmerUUID, _ := uuid.Parse(farmerID)
 
+	// Hitung & kunci harga dari skema harga driver yang dipilih
+	driver, err := s.driverRepo.GetDriverByID(driverID)
+	if err != nil {
+		tx.Rollback()
+		return nil, errors.New("driver not found")
+	}
+	quote, err := s.pricing.QuoteDelivery(&driver, delivery)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("cannot quote this driver: %w", err)
+	}
+	if quote.QuoteID != quoteID {
+		tx.Rollback()
+		return nil, errors.New("invalid quote: the driver's price has changed, please review the new quote")
+	}
+	quoteJSON, err := json.Marshal(quote)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to encode quote: %w", err)
+	}
+
 	// 2. Buat Kontrak baru dengan tipe 'delivery'
 	newContract := &models.Contract{
 		ContractType:   "delivery",
@@ -143,15 +300,28 @@ func (s *deliveryService) SelectDriver(deliveryID, driverID, farmerID string) (*
 	// 3. Update status Delivery
 	delivery.DriverID = &driverUUID
 	delivery.ContractID = &newContract.ID
-	delivery.Status = "pending_signature"
-	if err := s.deliveryRepo.Update(tx, delivery); err != nil { // Asumsi repo.Update menerima 'tx'
+	quotedAt := time.Now()
+	delivery.QuotedPrice = &quote.TotalPrice
+	delivery.QuoteDetails = quoteJSON
+	delivery.QuotedAt = &quotedAt
+	if err := transitionDelivery(tx, s.deliveryRepo, liveEvents, delivery, deliveryTransition{
+		To:        models.DeliveryStatusPendingSignature,
+		EventType: models.DeliveryEventDriverSelected,
+		ActorID:   &farmerUUID,
+		ActorRole: "farmer",
+	}); err != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("failed to update delivery status: %w", err)
+		return nil, err
+	}
+	if err := s.deliveryRepo.AssignDriver(tx, delivery); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to assign driver: %w", err)
 	}
 
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("transaction commit failed: %w", err)
 	}
+	liveEvents.publish(s.hub)
 
 	return newContract, nil
 }
@@ -176,3 +346,64 @@ func (s *deliveryService) GetMyDeliveries(userID uuid.UUID, role string) ([]dto.
 	return responseDTOs, nil
 }
 
+// GetDeliveryDetail menampilkan detail pengiriman dan timeline status.
+// Hanya petani pemilik, driver yang ditugaskan dan pembeli pesanan yang diantar
+// yang boleh melihatnya.
+func (s *deliveryService) GetDeliveryDetail(deliveryID string, userID uuid.UUID) (*dto.DeliveryDetailResponse, error) {
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	if !canWatchDelivery(delivery, &models.User{ID: userID}) {
+		return nil, errors.New("forbidden: you are not involved in this delivery")
+	}
+
+	events, err := s.deliveryRepo.FindEventsByDeliveryID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load delivery timeline: %w", err)
+	}
+
+	nextStatuses := delivery.NextStatuses()
+	if nextStatuses == nil {
+		nextStatuses = []string{}
+	}
+	return &dto.DeliveryDetailResponse{
+		Delivery:     delivery,
+		Timeline:     events,
+		NextStatuses: nextStatuses,
+	}, nil
+}
+
+// UpdateStatusByDriver menjalankan aksi lapangan driver (pickup, berangkat, tiba, gagal).
+func (s *deliveryService) UpdateStatusByDriver(deliveryID string, driverID uuid.UUID, input dto.DeliveryStatusUpdateRequest) (*models.Delivery, error) {
+	action, ok := driverActions[input.Action]
+	if !ok {
+		return nil, fmt.Errorf("invalid action: %s", input.Action)
+	}
+
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	if delivery.DriverID == nil || *delivery.DriverID != driverID {
+		return nil, errors.New("forbidden: you are not assigned to this delivery")
+	}
+
+	liveEvents := &deliveryEventBatch{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return transitionDelivery(tx, s.deliveryRepo, liveEvents, delivery, deliveryTransition{
+			To:        action.status,
+			EventType: action.eventType,
+			ActorID:   &driverID,
+			ActorRole: "driver",
+			Notes:     input.Notes,
+			Lat:       input.Lat,
+			Lng:       input.Lng,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	liveEvents.publish(s.hub)
+	return delivery, nil
+}
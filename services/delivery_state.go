@@ -0,0 +1,135 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/pkg/tracking"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"gorm.io/gorm"
+)
+
+// deliveryTransition menjelaskan satu perpindahan status beserta pelakunya.
+type deliveryTransition struct {
+	To        string
+	EventType string
+	ActorID   *uuid.UUID
+	ActorRole string
+	Notes     *string
+	Lat       *float64
+	Lng       *float64
+}
+
+// linkedOrderUpdates memetakan status pengiriman ke perubahan pada pesanan e-commerce
+// yang diantarnya, beserta status asal pesanan yang boleh diperbarui. Pengiriman yang
+// dibatalkan atau dikembalikan mengembalikan pesanan ke "paid" agar bisa dikirim ulang.
+func linkedOrderUpdates(deliveryStatus string) ([]string, map[string]interface{}, bool) {
+	switch deliveryStatus {
+	case models.DeliveryStatusPickedUp:
+		return []string{models.OrderStatusPaid},
+			map[string]interface{}{"status": models.OrderStatusShipped, "shipped_at": time.Now()}, true
+	case models.DeliveryStatusDelivered:
+		return []string{models.OrderStatusPaid, models.OrderStatusShipped},
+			map[string]interface{}{"status": models.OrderStatusCompleted, "completed_at": time.Now()}, true
+	case models.DeliveryStatusCancelled, models.DeliveryStatusReturned:
+		return []string{models.OrderStatusShipped},
+			map[string]interface{}{"status": models.OrderStatusPaid, "shipped_at": nil}, true
+	}
+	return nil, nil, false
+}
+
+// deliveryEventBatch menampung event live tracking yang tercatat di dalam transaksi.
+// Event baru dikirim ke hub setelah transaksi commit, sehingga pemantau tidak pernah
+// menerima event dari transaksi yang di-rollback.
+type deliveryEventBatch struct {
+	events []tracking.Event
+}
+
+// publish mengirim event yang terkumpul ke hub; panggil hanya setelah commit berhasil.
+func (b *deliveryEventBatch) publish(hub *tracking.Hub) {
+	for _, event := range b.events {
+		hub.Publish(event)
+	}
+	b.events = nil
+}
+
+// transitionDelivery mengunci baris delivery, memvalidasi perpindahan status terhadap
+// status yang terkunci, menyimpan kolom status saja, lalu mencatat event pada transaksi
+// yang sama. Dengan begitu aksi bersamaan (geofence, pickup, bukti, kegagalan) tidak
+// saling menimpa status. Event live tracking dikumpulkan di batch untuk dipublikasikan
+// setelah commit.
+func transitionDelivery(tx *gorm.DB, repo repositories.DeliveryRepository, batch *deliveryEventBatch, delivery *models.Delivery, t deliveryTransition) error {
+	if err := lockDeliveryStatus(tx, repo, delivery); err != nil {
+		return err
+	}
+	if !delivery.CanTransitionTo(t.To) {
+		return fmt.Errorf("invalid status transition from %s to %s", delivery.Status, t.To)
+	}
+
+	from := delivery.Status
+	delivery.Status = t.To
+	if err := repo.UpdateStatus(tx, delivery.ID, t.To); err != nil {
+		delivery.Status = from
+		return fmt.Errorf("failed to update delivery status: %w", err)
+	}
+	if fromStatuses, updates, ok := linkedOrderUpdates(t.To); ok {
+		if err := repo.UpdateLinkedOrders(tx, delivery.ID, fromStatuses, updates); err != nil {
+			return fmt.Errorf("failed to update linked orders: %w", err)
+		}
+	}
+	return recordDeliveryEvent(tx, repo, batch, delivery, from, t)
+}
+
+// lockUnreleasedDelivery mengunci baris delivery di dalam transaksi lalu memastikan dana
+// escrow-nya belum pernah dilepas, sehingga permintaan bersamaan tidak membuat payout ganda.
+// Status delivery disegarkan dari baris yang terkunci.
+func lockUnreleasedDelivery(tx *gorm.DB, repo repositories.DeliveryRepository, delivery *models.Delivery) error {
+	if err := lockDeliveryStatus(tx, repo, delivery); err != nil {
+		return err
+	}
+	released, err := repo.HasEvent(tx, delivery.ID, models.DeliveryEventPaymentReleased)
+	if err != nil {
+		return fmt.Errorf("failed to load delivery history: %w", err)
+	}
+	if released {
+		return fmt.Errorf("invalid state: payment for this delivery has already been released")
+	}
+	return nil
+}
+
+// lockDeliveryStatus mengunci baris delivery di dalam transaksi dan menyegarkan status
+// di memori dari baris yang terkunci.
+func lockDeliveryStatus(tx *gorm.DB, repo repositories.DeliveryRepository, delivery *models.Delivery) error {
+	locked, err := repo.FindByIDForUpdate(tx, delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to lock delivery: %w", err)
+	}
+	delivery.Status = locked.Status
+	return nil
+}
+
+// recordDeliveryEvent mencatat event tanpa mengubah status (mis. pembuatan atau pelepasan dana).
+func recordDeliveryEvent(tx *gorm.DB, repo repositories.DeliveryRepository, batch *deliveryEventBatch, delivery *models.Delivery, from string, t deliveryTransition) error {
+	event := &models.DeliveryEvent{
+		DeliveryID: delivery.ID,
+		EventType:  t.EventType,
+		FromStatus: from,
+		ToStatus:   delivery.Status,
+		ActorID:    t.ActorID,
+		ActorRole:  t.ActorRole,
+		Notes:      t.Notes,
+		Lat:        t.Lat,
+		Lng:        t.Lng,
+	}
+	if err := repo.CreateEvent(tx, event); err != nil {
+		return fmt.Errorf("failed to record delivery event: %w", err)
+	}
+	eventKind := tracking.EventStatus
+	if event.FromStatus == event.ToStatus {
+		eventKind = tracking.EventTimeline
+	}
+	batch.events = append(batch.events, tracking.Event{Type: eventKind, DeliveryID: delivery.ID, Data: event})
+	return nil
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/repositories"
+)
+
+// Bobot skor pencocokan driver (total maksimum 100).
+const (
+	matchWeightProximity    = 30.0
+	matchWeightRoute        = 25.0
+	matchWeightRating       = 20.0
+	matchWeightCapacity     = 15.0
+	matchWeightAvailability = 10.0
+	matchPenaltyBusy        = 20.0
+)
+
+// vehicleCapacityKg adalah kapasitas muatan standar per jenis kendaraan.
+var vehicleCapacityKg = map[string]float64{
+	"motor":       100,
+	"pickup":      1000,
+	"box":         2000,
+	"truk engkel": 4000,
+	"truk":        8000,
+}
+
+type DriverMatchingService interface {
+	RankDrivers(delivery *models.Delivery, drivers []models.Driver, radius int) ([]dto.DriverRecommendationResponse, error)
+}
+
+type driverMatchingService struct {
+	deliveryRepo repositories.DeliveryRepository
+	pricing      PricingService
+}
+
+func NewDriverMatchingService(deliveryRepo repositories.DeliveryRepository, pricing PricingService) DriverMatchingService {
+	return &driverMatchingService{deliveryRepo: deliveryRepo, pricing: pricing}
+}
+
+// RankDrivers memberi skor setiap kandidat driver lalu mengurutkannya dari yang paling cocok.
+// Driver yang semua kendaraannya tidak mampu mengangkut barang akan dikeluarkan.
+func (s *driverMatchingService) RankDrivers(delivery *models.Delivery, drivers []models.Driver, radius int) ([]dto.DriverRecommendationResponse, error) {
+	driverIDs := make([]uuid.UUID, 0, len(drivers))
+	for _, d := range drivers {
+		driverIDs = append(driverIDs, d.UserID)
+	}
+	activeCounts, err := s.deliveryRepo.CountActiveByDriverIDs(driverIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check driver workload: %w", err)
+	}
+
+	pickupDay := time.Now()
+	if delivery.PickupDate != nil {
+		pickupDay = *delivery.PickupDate
+	}
+
+	recommendations := make([]dto.DriverRecommendationResponse, 0, len(drivers))
+	for i := range drivers {
+		driver := drivers[i]
+
+		capacityFactor, fits := scoreCapacity(driver.VehicleTypes, delivery.ItemWeight)
+		if !fits {
+			continue
+		}
+
+		factors := []dto.MatchFactor{
+			scoreProximity(driver.Distance, radius),
+			scoreRoute(driver.DriverRoutes, delivery),
+			scoreAvailability(driver.DriverRoutes, pickupDay.Weekday()),
+			capacityFactor,
+			scoreRating(driver.Rating, driver.ReviewCount),
+		}
+		hasActive := activeCounts[driver.UserID] > 0
+		if hasActive {
+			factors = append(factors, dto.MatchFactor{
+				Factor: "workload",
+				Points: -matchPenaltyBusy,
+				Note:   fmt.Sprintf("Sedang menangani %d pengiriman aktif", activeCounts[driver.UserID]),
+			})
+		}
+
+		var score float64
+		for _, f := range factors {
+			score += f.Points
+		}
+
+		quote, err := s.pricing.QuoteDelivery(&driver, delivery)
+		if err != nil {
+			quote = nil // Driver tetap ditampilkan, hanya tanpa harga
+		}
+
+		recommendations = append(recommendations, dto.DriverRecommendationResponse{
+			DriverID:          driver.UserID,
+			DriverName:        driver.User.Name,
+			VehicleTypes:      driver.VehicleTypes,
+			Rating:            driver.Rating,
+			Distance:          driver.Distance,
+			Quote:             quote,
+			HasActiveDelivery: hasActive,
+			Score:             math.Round(math.Max(score, 0)*10) / 10,
+			ScoreBreakdown:    factors,
+		})
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].Distance < recommendations[j].Distance
+	})
+	return recommendations, nil
+}
+
+func scoreProximity(distanceKm float64, radius int) dto.MatchFactor {
+	points := matchWeightProximity
+	if radius > 0 {
+		points = matchWeightProximity * math.Max(0, 1-distanceKm/float64(radius))
+	}
+	return dto.MatchFactor{
+		Factor: "proximity",
+		Points: math.Round(points*10) / 10,
+		Note:   fmt.Sprintf("%.1f km dari lokasi pickup", distanceKm),
+	}
+}
+
+func scoreRoute(routes []models.DriverRoute, delivery *models.Delivery) dto.MatchFactor {
+	if len(routes) == 0 {
+		return dto.MatchFactor{Factor: "route", Points: 0, Note: "Driver belum mendaftarkan rute reguler"}
+	}
+
+	pickup := strings.ToLower(delivery.PickupAddress)
+	destination := strings.ToLower(delivery.DestinationAddress)
+	best := dto.MatchFactor{Factor: "route", Points: 0, Note: "Tidak ada rute reguler yang melewati pickup/tujuan"}
+	for _, r := range routes {
+		if !r.IsActive {
+			continue
+		}
+		originMatch := containsArea(pickup, r.Origin)
+		destMatch := containsArea(destination, r.Destination)
+		switch {
+		case originMatch && destMatch:
+			return dto.MatchFactor{Factor: "route", Points: matchWeightRoute, Note: fmt.Sprintf("Rute reguler %s - %s", r.Origin, r.Destination)}
+		case (originMatch || destMatch) && best.Points == 0:
+			best = dto.MatchFactor{Factor: "route", Points: matchWeightRoute * 0.4, Note: fmt.Sprintf("Rute reguler %s - %s cocok sebagian", r.Origin, r.Destination)}
+		}
+	}
+	return best
+}
+
+func containsArea(address, area string) bool {
+	area = strings.ToLower(strings.TrimSpace(area))
+	return area != "" && strings.Contains(address, area)
+}
+
+func scoreAvailability(routes []models.DriverRoute, day time.Weekday) dto.MatchFactor {
+	if len(routes) == 0 {
+		return dto.MatchFactor{Factor: "availability", Points: matchWeightAvailability / 2, Note: "Jadwal driver tidak diketahui"}
+	}
+	for _, r := range routes {
+		if !r.IsActive {
+			continue
+		}
+		for _, d := range r.WeekdayList() {
+			if d == day {
+				return dto.MatchFactor{Factor: "availability", Points: matchWeightAvailability, Note: "Tersedia pada hari pickup"}
+			}
+		}
+	}
+	return dto.MatchFactor{Factor: "availability", Points: 0, Note: "Tidak beroperasi pada hari pickup"}
+}
+
+// scoreCapacity mengembalikan false jika tidak ada kendaraan yang sanggup membawa muatan.
+func scoreCapacity(vehicleTypesJSON string, weightKg float64) (dto.MatchFactor, bool) {
+	var vehicles []string
+	_ = json.Unmarshal([]byte(vehicleTypesJSON), &vehicles)
+
+	unknown := false
+	for _, v := range vehicles {
+		capacity, ok := vehicleCapacityKg[strings.ToLower(strings.TrimSpace(v))]
+		if !ok {
+			unknown = true
+			continue
+		}
+		if capacity >= weightKg {
+			return dto.MatchFactor{
+				Factor: "capacity",
+				Points: matchWeightCapacity,
+				Note:   fmt.Sprintf("%s mampu membawa %.0f kg (kapasitas %.0f kg)", v, weightKg, capacity),
+			}, true
+		}
+	}
+	if unknown || len(vehicles) == 0 {
+		return dto.MatchFactor{Factor: "capacity", Points: matchWeightCapacity / 3, Note: "Kapasitas kendaraan tidak diketahui"}, true
+	}
+	return dto.MatchFactor{}, false
+}
+
+func scoreRating(rating float64, reviewCount int) dto.MatchFactor {
+	if reviewCount == 0 && rating == 0 {
+		return dto.MatchFactor{Factor: "rating", Points: matchWeightRating / 2, Note: "Belum memiliki ulasan"}
+	}
+	return dto.MatchFactor{
+		Factor: "rating",
+		Points: math.Round(rating/5*matchWeightRating*10) / 10,
+		Note:   fmt.Sprintf("Rating %.1f dari %d ulasan", rating, reviewCount),
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/repositories"
+)
+
+type DriverRouteService interface {
+	GetMyRoutes(driverID uuid.UUID) ([]dto.DriverRouteResponse, error)
+	CreateRoute(driverID uuid.UUID, input dto.DriverRouteInput) (*dto.DriverRouteResponse, error)
+	UpdateRoute(routeID, driverID uuid.UUID, input dto.DriverRouteInput) (*dto.DriverRouteResponse, error)
+	DeleteRoute(routeID, driverID uuid.UUID) error
+	SearchDrivers(origin, destination, date string) ([]dto.DriverRouteSearchResult, error)
+}
+
+type driverRouteService struct {
+	routeRepo repositories.DriverRouteRepository
+}
+
+func NewDriverRouteService(routeRepo repositories.DriverRouteRepository) DriverRouteService {
+	return &driverRouteService{routeRepo: routeRepo}
+}
+
+func toDriverRouteResponse(route models.DriverRoute) dto.DriverRouteResponse {
+	days := route.WeekdayList()
+	weekdays := make([]int, 0, len(days))
+	for _, d := range days {
+		weekdays = append(weekdays, int(d))
+	}
+	return dto.DriverRouteResponse{
+		ID:            route.ID,
+		Origin:        route.Origin,
+		Destination:   route.Destination,
+		Weekdays:      weekdays,
+		DaysAvailable: models.WeekdayNames(days),
+		StartTime:     route.StartTime,
+		EndTime:       route.EndTime,
+		Notes:         route.Notes,
+		IsActive:      route.IsActive,
+	}
+}
+
+// applyRouteInput memvalidasi input lalu menyalinnya ke model.
+func applyRouteInput(route *models.DriverRoute, input dto.DriverRouteInput) error {
+	if input.StartTime != "" {
+		if _, err := time.Parse("15:04", input.StartTime); err != nil {
+			return errors.New("invalid start_time format, use HH:MM")
+		}
+	}
+	if input.EndTime != "" {
+		if _, err := time.Parse("15:04", input.EndTime); err != nil {
+			return errors.New("invalid end_time format, use HH:MM")
+		}
+	}
+	if input.StartTime != "" && input.EndTime != "" && input.EndTime <= input.StartTime {
+		return errors.New("end_time must be later than start_time")
+	}
+
+	days := make([]time.Weekday, 0, len(input.Weekdays))
+	for _, d := range input.Weekdays {
+		days = append(days, time.Weekday(d))
+	}
+
+	route.Origin = strings.TrimSpace(input.Origin)
+	route.Destination = strings.TrimSpace(input.Destination)
+	route.Weekdays = models.FormatWeekdays(days)
+	route.DaysAvailable = models.WeekdayNames(route.WeekdayList())
+	route.StartTime = input.StartTime
+	route.EndTime = input.EndTime
+	route.Notes = input.Notes
+	if input.IsActive != nil {
+		route.IsActive = *input.IsActive
+	}
+	return nil
+}
+
+func (s *driverRouteService) GetMyRoutes(driverID uuid.UUID) ([]dto.DriverRouteResponse, error) {
+	routes, err := s.routeRepo.FindAllByDriverID(driverID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]dto.DriverRouteResponse, 0, len(routes))
+	for _, r := range routes {
+		responses = append(responses, toDriverRouteResponse(r))
+	}
+	return responses, nil
+}
+
+func (s *driverRouteService) CreateRoute(driverID uuid.UUID, input dto.DriverRouteInput) (*dto.DriverRouteResponse, error) {
+	route := &models.DriverRoute{DriverID: driverID, IsActive: true}
+	if err := applyRouteInput(route, input); err != nil {
+		return nil, err
+	}
+	if err := s.routeRepo.Create(route); err != nil {
+		return nil, fmt.Errorf("failed to create route: %w", err)
+	}
+	// GORM mengabaikan nilai false pada kolom ber-default saat insert
+	if !route.IsActive {
+		if err := s.routeRepo.Update(route); err != nil {
+			return nil, fmt.Errorf("failed to create route: %w", err)
+		}
+	}
+	response := toDriverRouteResponse(*route)
+	return &response, nil
+}
+
+func (s *driverRouteService) UpdateRoute(routeID, driverID uuid.UUID, input dto.DriverRouteInput) (*dto.DriverRouteResponse, error) {
+	route, err := s.routeRepo.FindByID(routeID)
+	if err != nil {
+		return nil, errors.New("route not found")
+	}
+	if route.DriverID != driverID {
+		return nil, errors.New("forbidden: you do not own this route")
+	}
+	if err := applyRouteInput(route, input); err != nil {
+		return nil, err
+	}
+	if err := s.routeRepo.Update(route); err != nil {
+		return nil, fmt.Errorf("failed to update route: %w", err)
+	}
+	response := toDriverRouteResponse(*route)
+	return &response, nil
+}
+
+func (s *driverRouteService) DeleteRoute(routeID, driverID uuid.UUID) error {
+	route, err := s.routeRepo.FindByID(routeID)
+	if err != nil {
+		return errors.New("route not found")
+	}
+	if route.DriverID != driverID {
+		return errors.New("forbidden: you do not own this route")
+	}
+	return s.routeRepo.Delete(routeID)
+}
+
+// SearchDrivers mencari driver dengan rute reguler yang cocok. Jika date diisi
+// (format YYYY-MM-DD), hanya rute yang beroperasi pada hari tersebut yang ditampilkan.
+func (s *driverRouteService) SearchDrivers(origin, destination, date string) ([]dto.DriverRouteSearchResult, error) {
+	var weekday *int
+	if date != "" {
+		parsed, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, errors.New("invalid date format, use YYYY-MM-DD")
+		}
+		day := int(parsed.Weekday())
+		weekday = &day
+	}
+
+	routes, err := s.routeRepo.Search(strings.TrimSpace(origin), strings.TrimSpace(destination), weekday)
+	if err != nil {
+		return nil, err
+	}
+
+	// Kelompokkan per driver dengan tetap menjaga urutan hasil
+	results := make([]dto.DriverRouteSearchResult, 0)
+	indexByDriver := make(map[uuid.UUID]int)
+	for _, r := range routes {
+		idx, ok := indexByDriver[r.DriverID]
+		if !ok {
+			result := dto.DriverRouteSearchResult{DriverID: r.DriverID}
+			if r.Driver != nil {
+				result.DriverName = r.Driver.User.Name
+				result.VehicleTypes = r.Driver.VehicleTypes
+				result.Rating = r.Driver.Rating
+			}
+			results = append(results, result)
+			idx = len(results) - 1
+			indexByDriver[r.DriverID] = idx
+		}
+		results[idx].Routes = append(results[idx].Routes, toDriverRouteResponse(r))
+	}
+	return results, nil
+}
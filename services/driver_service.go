@@ -2,13 +2,25 @@
 package services
 
 import (
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
 	"github.com/whsasmita/AgroLink_API/repositories"
 )
 
+// driverHeartbeatInterval adalah interval heartbeat yang disarankan ke aplikasi driver,
+// cukup rapat agar driver tidak kedaluwarsa sebelum DriverPresenceTTL.
+const driverHeartbeatInterval = 60 * time.Second
+
 type DriverService interface {
 	GetDrivers(sortBy, order string, limit, offset int) ([]dto.DriverResponse, int64, error)
 	GetDriverProfile(id string) (dto.DriverResponse, error)
+	SetOnline(driverID uuid.UUID, input dto.DriverPresenceRequest) (*dto.DriverPresenceResponse, error)
+	SetOffline(driverID uuid.UUID) (*dto.DriverPresenceResponse, error)
+	Heartbeat(driverID uuid.UUID, input dto.DriverPresenceRequest) (*dto.DriverPresenceResponse, error)
+	GetPresence(driverID uuid.UUID) (*dto.DriverPresenceResponse, error)
 }
 
 type driverService struct {
@@ -39,6 +51,8 @@ func (s *driverService) GetDrivers(sortBy, order string, limit, offset int) ([]d
 			Name:             driver.User.Name,
 			ProfilePicture:   driver.User.ProfilePicture,
 			PhoneNumber:      driver.User.PhoneNumber,
+			IsOnline:         isDriverAvailable(driver),
+			LastSeenAt:       driver.LastSeenAt,
 		})
 	}
 	return driverResponses, total, nil
@@ -62,7 +76,58 @@ func (s *driverService) GetDriverProfile(id string) (dto.DriverResponse, error)
 		Name:             driver.User.Name,
 		ProfilePicture:   driver.User.ProfilePicture,
 		PhoneNumber:      driver.User.PhoneNumber,
+		IsOnline:         isDriverAvailable(driver),
+		LastSeenAt:       driver.LastSeenAt,
 	}
 
 	return response, nil
 }
+
+// isDriverAvailable bernilai true jika driver online dan heartbeat-nya belum kedaluwarsa.
+func isDriverAvailable(driver models.Driver) bool {
+	return driver.IsOnline && driver.LastSeenAt != nil &&
+		time.Since(*driver.LastSeenAt) <= repositories.DriverPresenceTTL
+}
+
+// SetOnline menandai driver siap menerima pengiriman pada posisi saat ini.
+func (s *driverService) SetOnline(driverID uuid.UUID, input dto.DriverPresenceRequest) (*dto.DriverPresenceResponse, error) {
+	if err := s.repo.UpdatePresence(driverID, true, input.Lat, input.Lng); err != nil {
+		return nil, err
+	}
+	return s.GetPresence(driverID)
+}
+
+// SetOffline mengeluarkan driver dari pencarian driver terdekat.
+func (s *driverService) SetOffline(driverID uuid.UUID) (*dto.DriverPresenceResponse, error) {
+	if err := s.repo.UpdatePresence(driverID, false, nil, nil); err != nil {
+		return nil, err
+	}
+	return s.GetPresence(driverID)
+}
+
+// Heartbeat memperbarui posisi driver tanpa mengubah status online/offline-nya.
+func (s *driverService) Heartbeat(driverID uuid.UUID, input dto.DriverPresenceRequest) (*dto.DriverPresenceResponse, error) {
+	driver, err := s.repo.GetDriverByID(driverID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdatePresence(driverID, driver.IsOnline, input.Lat, input.Lng); err != nil {
+		return nil, err
+	}
+	return s.GetPresence(driverID)
+}
+
+func (s *driverService) GetPresence(driverID uuid.UUID) (*dto.DriverPresenceResponse, error) {
+	driver, err := s.repo.GetDriverByID(driverID.String())
+	if err != nil {
+		return nil, err
+	}
+	return &dto.DriverPresenceResponse{
+		IsOnline:                 driver.IsOnline,
+		IsStale:                  driver.IsOnline && !isDriverAvailable(driver),
+		LastSeenAt:               driver.LastSeenAt,
+		CurrentLat:               driver.CurrentLat,
+		CurrentLng:               driver.CurrentLng,
+		HeartbeatIntervalSeconds: int(driverHeartbeatInterval.Seconds()),
+	}, nil
+}
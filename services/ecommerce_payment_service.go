@@ -2,9 +2,12 @@ package services
 
 import (
 	"crypto/sha512"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"os"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/midtrans/midtrans-go"
@@ -17,15 +20,30 @@ import (
 )
 type ECommercePaymentService interface {
 	InitiatePayment(tx *gorm.DB, userID uuid.UUID, orders []models.Order, grandTotal float64) (*dto.PaymentInitiationResponse, error)
+	InitiatePaymentFor(tx *gorm.DB, userID uuid.UUID, orders []models.Order, amount float64, purpose string) (*models.ECommercePayment, *dto.PaymentInitiationResponse, error)
+	SetPreOrderHandler(handler PreOrderPaymentHandler)
 	HandleWebhook(notificationPayload map[string]interface{}) error
+	GetRefunds(status string) ([]dto.ECommerceRefundResponse, error)
+	CompleteRefund(paymentID uuid.UUID) error
+}
+
+// PreOrderPaymentHandler menerima hasil pembayaran uang muka/pelunasan pre-order.
+// Didaftarkan lewat SetPreOrderHandler karena PreOrderService sendiri membutuhkan
+// ECommercePaymentService untuk membuat pembayaran.
+type PreOrderPaymentHandler interface {
+	OnPreOrderPaymentSettled(tx *gorm.DB, payment *models.ECommercePayment) error
+	OnPreOrderPaymentFailed(tx *gorm.DB, payment *models.ECommercePayment) error
 }
 
 type eCommercePaymentService struct {
 	paymentRepo repositories.ECommercePaymentRepository
 	orderRepo   repositories.OrderRepository
 	userRepo    repositories.UserRepository
-	productRepo repositories.ProductRepository
-	db          *gorm.DB
+	reservationService StockReservationService
+	voucherService     VoucherService
+	notifService       NotificationService
+	preOrderHandler    PreOrderPaymentHandler
+	db                 *gorm.DB
 }
 
 // NewECommercePaymentService adalah constructor untuk service pembayaran e-commerce.
@@ -33,37 +51,56 @@ func NewECommercePaymentService(
 	paymentRepo repositories.ECommercePaymentRepository,
 	orderRepo repositories.OrderRepository,
 	userRepo repositories.UserRepository,
-	productRepo repositories.ProductRepository,
+	reservationService StockReservationService,
+	voucherService VoucherService,
+	notifService NotificationService,
 	db *gorm.DB,
 ) ECommercePaymentService {
 	return &eCommercePaymentService{
-		paymentRepo: paymentRepo,
-		orderRepo:   orderRepo,
-		userRepo:    userRepo,
-		productRepo: productRepo,
-		db:          db,
+		paymentRepo:        paymentRepo,
+		orderRepo:          orderRepo,
+		userRepo:           userRepo,
+		reservationService: reservationService,
+		voucherService:     voucherService,
+		notifService:       notifService,
+		db:                 db,
 	}
 }
 
+func (s *eCommercePaymentService) SetPreOrderHandler(handler PreOrderPaymentHandler) {
+	s.preOrderHandler = handler
+}
+
 // InitiatePayment dipanggil DARI DALAM transaksi CheckoutService.
 // Tugasnya adalah membuat record Payment dan mendapatkan Snap Token.
 func (s *eCommercePaymentService) InitiatePayment(tx *gorm.DB, userID uuid.UUID, orders []models.Order, grandTotal float64) (*dto.PaymentInitiationResponse, error) {
+	_, response, err := s.InitiatePaymentFor(tx, userID, orders, grandTotal, models.PaymentPurposeCheckout)
+	return response, err
+}
+
+// InitiatePaymentFor membuat pembayaran dengan tujuan tertentu, mis. uang muka pre-order
+// yang nominalnya tidak sama dengan total order.
+func (s *eCommercePaymentService) InitiatePaymentFor(tx *gorm.DB, userID uuid.UUID, orders []models.Order, grandTotal float64, purpose string) (*models.ECommercePayment, *dto.PaymentInitiationResponse, error) {
+	// Midtrans hanya menerima nominal rupiah penuh; simpan nominal yang benar-benar ditagihkan
+	grandTotal = math.Round(grandTotal)
+
 	// 1. Buat record pembayaran induk
 	payment := &models.ECommercePayment{
 		ID:         uuid.New(),
 		UserID:     userID,
 		GrandTotal: grandTotal,
 		Status:     "pending",
+		Purpose:    purpose,
 		Orders:     orders, 
 	}
 	if err := s.paymentRepo.Create(tx, payment); err != nil {
-		return nil, fmt.Errorf("failed to create payment record: %w", err)
+		return nil, nil, fmt.Errorf("failed to create payment record: %w", err)
 	}
 
 	// 2. Ambil detail pelanggan
 	user, err := s.userRepo.FindByID(userID.String())
 	if err != nil {
-		return nil, fmt.Errorf("customer data not found: %w", err)
+		return nil, nil, fmt.Errorf("customer data not found: %w", err)
 	}
 
 	// 3. Buat request ke Midtrans Snap
@@ -77,24 +114,30 @@ func (s *eCommercePaymentService) InitiatePayment(tx *gorm.DB, userID uuid.UUID,
 			Email: user.Email,
 			Phone: *user.PhoneNumber,
 		},
+		// Batas waktu bayar; reservasi stok dilepas tak lama setelahnya
+		Expiry: &snap.ExpiryDetails{
+			Unit:     "minute",
+			Duration: models.PaymentExpiryMinutes,
+		},
 	}
 
 	snapResponse, midtransErr := config.SnapClient.CreateTransaction(snapReq)
 	if midtransErr != nil {
-		return nil, fmt.Errorf("failed to create midtrans snap token: %s", midtransErr.Message)
+		return nil, nil, fmt.Errorf("failed to create midtrans snap token: %s", midtransErr.Message)
 	}
 
 	// 4. Simpan SnapToken ke record pembayaran
 	payment.SnapToken = snapResponse.Token
 	if err := s.paymentRepo.Update(tx, payment); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// 5. Kembalikan DTO respons
-	return &dto.PaymentInitiationResponse{
+	return payment, &dto.PaymentInitiationResponse{
 		SnapToken:   snapResponse.Token,
 		RedirectURL: snapResponse.RedirectURL,
 		OrderID:     payment.ID.String(),
+		Amount:      grandTotal,
 	}, nil
 }
 
@@ -134,48 +177,131 @@ func (s *eCommercePaymentService) HandleWebhook(notificationPayload map[string]i
 	if calculatedHash != signatureKey {
 		return fmt.Errorf("invalid midtrans signature for e-commerce payment %s", paymentID)
 	}
+	// Pembayaran pre-order tidak melunasi order secara langsung; status pre-order,
+	// order, dan stoknya diatur oleh PreOrderService.
+	if payment.Purpose == models.PaymentPurposePreOrderDeposit || payment.Purpose == models.PaymentPurposePreOrderBalance {
+		return s.handlePreOrderWebhook(payment, transactionStatus, fraudStatus)
+	}
+
 	// Fungsi internal untuk memproses pembayaran yang sukses
+	var lateRefund float64
 	finalizeSuccess := func() error {
-		return s.db.Transaction(func(tx *gorm.DB) error {
-			// 1. Update status ECommercePayment menjadi 'paid'
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// 1. Kunci pembayaran dan periksa ulang statusnya agar webhook ganda tidak diproses dua kali
+			locked, err := s.paymentRepo.FindByIDForUpdate(tx, paymentID)
+			if err != nil {
+				return err
+			}
+			if locked.Status == "paid" {
+				return nil
+			}
+
+			// 2. Pisahkan order yang masih menunggu pembayaran dari order yang sudah dibatalkan
+			// (mis. reservasinya kedaluwarsa sebelum pembayaran masuk)
+			orders, err := s.orderRepo.FindOrdersByPaymentID(tx, payment.ID)
+			if err != nil {
+				return fmt.Errorf("failed to load orders for payment %s: %w", paymentID, err)
+			}
+			payable := make([]models.Order, 0, len(orders))
+			var refund float64
+			refundReason := "Pembayaran diterima setelah pesanan dibatalkan"
+			for _, order := range orders {
+				switch order.Status {
+				case models.OrderStatusPending:
+					payable = append(payable, order)
+				case models.OrderStatusCancelled:
+					refund += order.TotalAmount
+				}
+			}
+
+			// 3. Update status ECommercePayment menjadi 'paid'
 			if err := s.paymentRepo.UpdateStatus(tx, paymentID, "paid"); err != nil {
 				return err
 			}
-			
-			// 2. Update status SEMUA Order yang terhubung menjadi 'paid'
+
+			// 4. Kurangi stok produk sesuai reservasi pesanan; stok order yang batal sudah dilepas.
+			// Order yang stoknya sudah terjual ke pembeli lain dibatalkan dan dananya dikembalikan.
+			oversold, err := s.reservationService.CommitForOrders(tx, payable)
+			if err != nil {
+				return err
+			}
+			if len(oversold) > 0 {
+				oversoldIDs := make([]uuid.UUID, 0, len(oversold))
+				for _, order := range oversold {
+					oversoldIDs = append(oversoldIDs, order.ID)
+					refund += order.TotalAmount
+				}
+				if err := s.reservationService.ReleaseForOrders(tx, oversoldIDs, models.ReservationReleaseOversold); err != nil {
+					return err
+				}
+				if err := s.orderRepo.CancelPendingByIDs(tx, oversoldIDs); err != nil {
+					return err
+				}
+				refundReason = "Stok pesanan sudah habis saat pembayaran diterima"
+			}
+
+			// 5. Update status Order yang masih pending menjadi 'paid'
 			if err := s.orderRepo.UpdateStatusByPaymentID(tx, payment.ID, "paid"); err != nil {
 				log.Printf("WARN: E-commerce orders status update failed for payment %s: %v", paymentID, err)
 			}
 
-			// 3. [BARU] Kurangi Stok Produk
+			// 6. Dana order yang batal dikembalikan lewat admin
+			if refund > 0 {
+				locked.Status = "paid"
+				locked.QueueRefund(refund, refundReason)
+				if err := s.paymentRepo.Update(tx, locked); err != nil {
+					return err
+				}
+				lateRefund = locked.RefundAmount
+			}
+
+			// 7. Tandai voucher terpakai & catat biaya promo platform. Bila semua order
+			// sudah batal, pemakaian voucher dilepas.
+			if len(payable) == len(oversold) {
+				return s.voucherService.OnPaymentFailed(tx, payment)
+			}
+			return s.voucherService.OnPaymentSettled(tx, payment) // Commit
+		})
+		if err != nil {
+			return err
+		}
+		if lateRefund > 0 {
+			s.notifService.CreateNotification(payment.UserID, "Pembayaran Akan Dikembalikan",
+				fmt.Sprintf("Sebagian atau seluruh pesanan Anda dibatalkan karena sudah kedaluwarsa atau stoknya habis. Dana sebesar Rp%.0f akan dikembalikan.", lateRefund),
+				"/orders", "order")
+		}
+		return nil
+	}
+
+	// Fungsi internal untuk pembayaran yang gagal/kedaluwarsa: batalkan order dan lepas stoknya
+	finalizeFailure := func() error {
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			locked, err := s.paymentRepo.FindByIDForUpdate(tx, paymentID)
+			if err != nil {
+				return err
+			}
+			if locked.Status != "pending" {
+				return nil
+			}
+			if err := s.paymentRepo.UpdateStatus(tx, paymentID, "failed"); err != nil {
+				return err
+			}
 			orders, err := s.orderRepo.FindOrdersByPaymentID(tx, payment.ID)
 			if err != nil {
-				log.Printf("WARN: Gagal mengambil order untuk mengurangi stok: %v", err)
-				return nil // Jangan gagalkan transaksi utama
+				return err
 			}
-
+			orderIDs := make([]uuid.UUID, 0, len(orders))
 			for _, order := range orders {
-				for _, item := range order.Items {
-					product, err := s.productRepo.FindByIDForUpdate(tx, item.ProductID)
-					if err != nil {
-						log.Printf("WARN: Gagal menemukan produk %s untuk mengurangi stok: %v", item.ProductID, err)
-						continue
-					}
-					
-					// Kurangi stok total DAN stok yang direservasi
-					product.Stock -= item.Quantity
-					product.ReservedStock -= item.Quantity
-					
-					if product.Stock < 0 { product.Stock = 0 }
-					if product.ReservedStock < 0 { product.ReservedStock = 0 }
-
-					if err := s.productRepo.UpdateStock(tx, product); err != nil {
-						log.Printf("WARN: Gagal mengupdate stok produk %s: %v", item.ProductID, err)
-					}
-				}
+				orderIDs = append(orderIDs, order.ID)
 			}
-
-			return nil // Commit
+			if err := s.reservationService.ReleaseForOrders(tx, orderIDs, models.ReservationReleasePaymentFailed); err != nil {
+				return err
+			}
+			// Kuota voucher dikembalikan agar bisa dipakai lagi
+			if err := s.voucherService.OnPaymentFailed(tx, payment); err != nil {
+				return err
+			}
+			return s.orderRepo.CancelPendingByIDs(tx, orderIDs)
 		})
 	}
 
@@ -185,10 +311,110 @@ func (s *eCommercePaymentService) HandleWebhook(notificationPayload map[string]i
 		if fraudStatus == "accept" || fraudStatus == "" {
 			return finalizeSuccess()
 		}
-		return s.paymentRepo.UpdateStatus(nil, paymentID, "failed") // Gunakan tx jika ingin transaksional
+		return finalizeFailure()
 	case "expire", "cancel", "deny":
-		return s.paymentRepo.UpdateStatus(nil, paymentID, "failed")
+		return finalizeFailure()
 	default:
 		return nil // Abaikan status lain seperti "pending"
 	}
-}
\ No newline at end of file
+}
+
+// handlePreOrderWebhook memperbarui status pembayaran pre-order dan meneruskannya ke
+// PreOrderService dalam satu transaksi.
+func (s *eCommercePaymentService) handlePreOrderWebhook(payment *models.ECommercePayment, transactionStatus, fraudStatus string) error {
+	if s.preOrderHandler == nil {
+		return fmt.Errorf("pre-order payment handler is not configured for payment %s", payment.ID)
+	}
+
+	settled := false
+	switch transactionStatus {
+	case "capture", "settlement":
+		settled = fraudStatus == "accept" || fraudStatus == ""
+	case "expire", "cancel", "deny":
+	default:
+		return nil // Abaikan status lain seperti "pending"
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// Kunci pembayaran dan periksa ulang statusnya agar webhook ganda tidak menambah
+		// dana pre-order dua kali
+		locked, err := s.paymentRepo.FindByIDForUpdate(tx, payment.ID.String())
+		if err != nil {
+			return err
+		}
+		if settled {
+			if locked.Status == "paid" {
+				return nil
+			}
+			if err := s.paymentRepo.UpdateStatus(tx, locked.ID.String(), "paid"); err != nil {
+				return err
+			}
+			locked.Status = "paid"
+			return s.preOrderHandler.OnPreOrderPaymentSettled(tx, locked)
+		}
+		if locked.Status != "pending" {
+			return nil
+		}
+		if err := s.paymentRepo.UpdateStatus(tx, locked.ID.String(), "failed"); err != nil {
+			return err
+		}
+		locked.Status = "failed"
+		return s.preOrderHandler.OnPreOrderPaymentFailed(tx, locked)
+	})
+}
+
+// GetRefunds menampilkan pengembalian dana pembayaran e-commerce untuk admin.
+func (s *eCommercePaymentService) GetRefunds(status string) ([]dto.ECommerceRefundResponse, error) {
+	if status == "" {
+		status = models.PaymentRefundPending
+	}
+	payments, err := s.paymentRepo.FindByRefundStatus(status)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]dto.ECommerceRefundResponse, 0, len(payments))
+	for _, payment := range payments {
+		response := dto.ECommerceRefundResponse{
+			PaymentID:    payment.ID,
+			UserID:       payment.UserID,
+			GrandTotal:   payment.GrandTotal,
+			RefundAmount: payment.RefundAmount,
+			RefundStatus: payment.RefundStatus,
+			RefundReason: payment.RefundReason,
+			RefundedAt:   payment.RefundedAt,
+			UpdatedAt:    payment.UpdatedAt,
+		}
+		if payment.User != nil {
+			response.BuyerName = payment.User.Name
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// CompleteRefund dipakai admin setelah dana benar-benar ditransfer ke pembeli.
+func (s *eCommercePaymentService) CompleteRefund(paymentID uuid.UUID) error {
+	var payment *models.ECommercePayment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		payment, err = s.paymentRepo.FindByIDForUpdate(tx, paymentID.String())
+		if err != nil {
+			return errors.New("payment not found")
+		}
+		if payment.RefundStatus == nil || *payment.RefundStatus != models.PaymentRefundPending {
+			return errors.New("invalid state: payment has no pending refund")
+		}
+		status := models.PaymentRefundCompleted
+		now := time.Now()
+		payment.RefundStatus = &status
+		payment.RefundedAt = &now
+		return s.paymentRepo.Update(tx, payment)
+	})
+	if err != nil {
+		return err
+	}
+	s.notifService.CreateNotification(payment.UserID, "Dana Pembayaran Dikembalikan",
+		fmt.Sprintf("Pengembalian dana sebesar Rp%.0f untuk pesanan Anda sudah ditransfer.", payment.RefundAmount),
+		"/orders", "order")
+	return nil
+}
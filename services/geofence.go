@@ -0,0 +1,173 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/utils"
+	"gorm.io/gorm"
+)
+
+// Parameter geofence & deteksi keterlambatan.
+const (
+	pickupGeofenceKm      = 0.2 // radius dianggap tiba di lokasi pickup
+	destinationGeofenceKm = 0.2 // radius dianggap tiba di tujuan
+	geofenceExitKm        = 0.3 // histeresis agar GPS yang goyah tidak memicu "keluar"
+	stationaryRadiusKm    = 0.1
+	stationaryAlertAfter  = 20 * time.Minute
+	delayAlertCooldown    = 30 * time.Minute
+)
+
+// processGeofences menafsirkan satu titik GPS: memicu event geofence yang
+// memajukan state machine. Tiba di pickup memindahkan status ke at_pickup;
+// meninggalkan pickup memajukan ke in_transit, atau mengembalikan ke pickup_pending
+// bila barang belum dikonfirmasi. events adalah riwayat event delivery dan akan
+// ditambah bila ada event baru.
+func (s *trackingService) processGeofences(delivery *models.Delivery, point *models.LocationTrack, events *[]models.DeliveryEvent) {
+	pickupKm := utils.HaversineKm(point.Lat, point.Lng, delivery.PickupLat, delivery.PickupLng)
+	destinationKm := deliveryDestinationKm(delivery, point)
+	at := point.Timestamp.Format("15:04")
+
+	switch {
+	case pickupKm <= pickupGeofenceKm && delivery.Status == models.DeliveryStatusPickupPending && !isAtPickup(*events):
+		s.emitGeofenceEvent(delivery, point, events, models.DeliveryEventArrivedAtPickup, models.DeliveryStatusAtPickup,
+			fmt.Sprintf("Driver tiba di lokasi penjemputan pukul %s", at))
+
+	case pickupKm > geofenceExitKm && isAtPickup(*events):
+		// Hanya barang yang sudah dikonfirmasi pickup yang dianggap mulai diantar
+		next := ""
+		message := fmt.Sprintf("Driver meninggalkan lokasi penjemputan pukul %s", at)
+		switch delivery.Status {
+		case models.DeliveryStatusPickedUp:
+			next = models.DeliveryStatusInTransit
+		case models.DeliveryStatusAtPickup:
+			next = models.DeliveryStatusPickupPending
+			message += " tanpa konfirmasi pickup"
+		}
+		s.emitGeofenceEvent(delivery, point, events, models.DeliveryEventDepartedPickup, next, message)
+
+	case destinationKm >= 0 && destinationKm <= destinationGeofenceKm &&
+		(delivery.Status == models.DeliveryStatusInTransit || delivery.Status == models.DeliveryStatusOutForDelivery):
+		s.emitGeofenceEvent(delivery, point, events, models.DeliveryEventArrivedAtDestination, models.DeliveryStatusArrived,
+			fmt.Sprintf("Driver tiba di tujuan pukul %s", at))
+	}
+}
+
+// deliveryDestinationKm mengembalikan jarak titik ke tujuan akhir, atau -1 bila tujuan tidak berkoordinat.
+func deliveryDestinationKm(delivery *models.Delivery, point *models.LocationTrack) float64 {
+	if delivery.DestinationLat == nil || delivery.DestinationLng == nil {
+		return -1
+	}
+	return utils.HaversineKm(point.Lat, point.Lng, *delivery.DestinationLat, *delivery.DestinationLng)
+}
+
+// checkStationary memicu peringatan keterlambatan jika driver tidak bergerak
+// selama stationaryAlertAfter di luar area pickup/tujuan. Dipanggil sekali per
+// unggahan dengan titik terbaru agar jendela waktu hanya di-query satu kali.
+func (s *trackingService) checkStationary(delivery *models.Delivery, point *models.LocationTrack, events *[]models.DeliveryEvent) {
+	switch delivery.Status {
+	case models.DeliveryStatusPickupPending, models.DeliveryStatusPickedUp,
+		models.DeliveryStatusInTransit, models.DeliveryStatusOutForDelivery:
+	default:
+		return
+	}
+	destinationKm := deliveryDestinationKm(delivery, point)
+	if utils.HaversineKm(point.Lat, point.Lng, delivery.PickupLat, delivery.PickupLng) <= pickupGeofenceKm ||
+		(destinationKm >= 0 && destinationKm <= destinationGeofenceKm) {
+		return
+	}
+	if last := lastDeliveryEvent(*events, models.DeliveryEventDelayAlert); last != nil &&
+		point.Timestamp.Sub(last.CreatedAt) < delayAlertCooldown {
+		return
+	}
+
+	window, err := s.trackRepo.FindBetween(delivery.ID, point.Timestamp.Add(-stationaryAlertAfter), point.Timestamp)
+	if err != nil || len(window) < 2 {
+		return
+	}
+	// Butuh data yang menutupi hampir seluruh jendela waktu, bukan hanya beberapa titik terakhir
+	if point.Timestamp.Sub(window[0].Timestamp) < stationaryAlertAfter*9/10 {
+		return
+	}
+	for _, p := range window {
+		if utils.HaversineKm(point.Lat, point.Lng, p.Lat, p.Lng) > stationaryRadiusKm {
+			return
+		}
+	}
+
+	minutes := int(point.Timestamp.Sub(window[0].Timestamp).Minutes())
+	s.emitGeofenceEvent(delivery, point, events, models.DeliveryEventDelayAlert, "",
+		fmt.Sprintf("Driver tidak bergerak selama %d menit, pengiriman kemungkinan terlambat", minutes))
+}
+
+// emitGeofenceEvent mencatat event (dan memajukan status bila nextStatus diisi),
+// lalu memberi tahu petani.
+func (s *trackingService) emitGeofenceEvent(delivery *models.Delivery, point *models.LocationTrack, events *[]models.DeliveryEvent, eventType, nextStatus, message string) {
+	lat, lng := point.Lat, point.Lng
+	transition := deliveryTransition{
+		To:        nextStatus,
+		EventType: eventType,
+		ActorRole: "system",
+		Notes:     &message,
+		Lat:       &lat,
+		Lng:       &lng,
+	}
+
+	var from string
+	liveEvents := &deliveryEventBatch{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Status diputuskan dari baris yang terkunci, bukan dari data saat lokasi diterima
+		if err := lockDeliveryStatus(tx, s.deliveryRepo, delivery); err != nil {
+			return err
+		}
+		from = delivery.Status
+		if nextStatus != "" && delivery.CanTransitionTo(nextStatus) {
+			return transitionDelivery(tx, s.deliveryRepo, liveEvents, delivery, transition)
+		}
+		return recordDeliveryEvent(tx, s.deliveryRepo, liveEvents, delivery, from, transition)
+	})
+	if err != nil {
+		log.Printf("WARN: failed to record %s for delivery %s: %v", eventType, delivery.ID, err)
+		return
+	}
+	liveEvents.publish(s.hub)
+
+	*events = append(*events, models.DeliveryEvent{
+		DeliveryID: delivery.ID,
+		EventType:  eventType,
+		FromStatus: from,
+		ToStatus:   delivery.Status,
+		CreatedAt:  point.Timestamp,
+	})
+
+	notifType := "delivery"
+	if eventType == models.DeliveryEventDelayAlert {
+		notifType = "warning"
+	}
+	s.notifService.CreateNotification(delivery.FarmerID, "Update Pengiriman: "+delivery.ItemDescription, message,
+		fmt.Sprintf("/deliveries/%s", delivery.ID), notifType)
+}
+
+// isAtPickup bernilai true bila event tiba di pickup terakhir belum diikuti event meninggalkan pickup.
+func isAtPickup(events []models.DeliveryEvent) bool {
+	return lastDeliveryEventIndex(events, models.DeliveryEventArrivedAtPickup) >
+		lastDeliveryEventIndex(events, models.DeliveryEventDepartedPickup)
+}
+
+func lastDeliveryEvent(events []models.DeliveryEvent, eventType string) *models.DeliveryEvent {
+	if i := lastDeliveryEventIndex(events, eventType); i >= 0 {
+		return &events[i]
+	}
+	return nil
+}
+
+func lastDeliveryEventIndex(events []models.DeliveryEvent, eventType string) int {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].EventType == eventType {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"gorm.io/gorm"
+)
+
+// StockChange menjelaskan satu perubahan stok varian beserta alasannya.
+type StockChange struct {
+	Type          string
+	StockDelta    float64
+	ReservedDelta float64
+	OrderID       *uuid.UUID
+	ActorID       *uuid.UUID
+	Note          string
+}
+
+// InventoryService adalah satu-satunya jalur untuk mengubah Stock/ReservedStock varian.
+// Setiap perubahan disimpan bersama satu baris ledger inventaris dalam transaksi yang sama.
+type InventoryService interface {
+	ApplyStockChange(tx *gorm.DB, variant *models.ProductVariant, change StockChange) error
+	RecordMovement(productID, variantID uuid.UUID, input dto.StockMovementRequest, farmerID uuid.UUID) (*dto.StockMovementResponse, error)
+	GetProductMovements(productID uuid.UUID, query dto.StockMovementQuery, farmerID uuid.UUID) (*dto.ProductStockHistoryResponse, error)
+}
+
+type inventoryService struct {
+	movementRepo repositories.InventoryMovementRepository
+	productRepo  repositories.ProductRepository
+	variantRepo  repositories.ProductVariantRepository
+	orderRepo    repositories.OrderRepository
+	db           *gorm.DB
+}
+
+func NewInventoryService(
+	movementRepo repositories.InventoryMovementRepository,
+	productRepo repositories.ProductRepository,
+	variantRepo repositories.ProductVariantRepository,
+	orderRepo repositories.OrderRepository,
+	db *gorm.DB,
+) InventoryService {
+	return &inventoryService{
+		movementRepo: movementRepo,
+		productRepo:  productRepo,
+		variantRepo:  variantRepo,
+		orderRepo:    orderRepo,
+		db:           db,
+	}
+}
+
+// ApplyStockChange menerapkan delta ke varian (yang sudah dikunci pemanggil) dan mencatatnya
+// di ledger. Perubahan yang membuat stok atau stok reservasi negatif ditolak dengan error
+// agar ketidaksesuaian stok tidak tertutupi dan penjumlahan ledger selalu sama dengan stok
+// tersimpan.
+func (s *inventoryService) ApplyStockChange(tx *gorm.DB, variant *models.ProductVariant, change StockChange) error {
+	_, err := s.applyStockChange(tx, variant, change)
+	return err
+}
+
+// applyStockChange mengembalikan baris ledger yang dibuat, atau nil jika tidak ada perubahan.
+func (s *inventoryService) applyStockChange(tx *gorm.DB, variant *models.ProductVariant, change StockChange) (*models.InventoryMovement, error) {
+	stockBefore, reservedBefore := variant.Stock, variant.ReservedStock
+	stockAfter := models.RoundQuantity(stockBefore + change.StockDelta)
+	reservedAfter := models.RoundQuantity(reservedBefore + change.ReservedDelta)
+	if stockAfter < 0 {
+		return nil, fmt.Errorf("invalid stock: variant %s has only %g %s in stock", variant.Name, stockBefore, variant.Unit)
+	}
+	if reservedAfter < 0 {
+		return nil, fmt.Errorf("invalid stock: variant %s has only %g %s reserved", variant.Name, reservedBefore, variant.Unit)
+	}
+	variant.Stock = stockAfter
+	variant.ReservedStock = reservedAfter
+
+	stockDelta := models.RoundQuantity(variant.Stock - stockBefore)
+	reservedDelta := models.RoundQuantity(variant.ReservedStock - reservedBefore)
+	if stockDelta == 0 && reservedDelta == 0 {
+		return nil, nil
+	}
+	if err := s.variantRepo.UpdateStock(tx, variant); err != nil {
+		return nil, err
+	}
+
+	movement := &models.InventoryMovement{
+		ProductID:     variant.ProductID,
+		VariantID:     variant.ID,
+		Type:          change.Type,
+		StockDelta:    stockDelta,
+		ReservedDelta: reservedDelta,
+		StockAfter:    variant.Stock,
+		ReservedAfter: variant.ReservedStock,
+		OrderID:       change.OrderID,
+		ActorID:       change.ActorID,
+	}
+	if note := strings.TrimSpace(change.Note); note != "" {
+		movement.Note = &note
+	}
+	if err := s.movementRepo.Create(tx, movement); err != nil {
+		return nil, fmt.Errorf("failed to record inventory movement: %w", err)
+	}
+	return movement, nil
+}
+
+// RecordMovement dipakai petani untuk mencatat stok masuk, koreksi manual, atau retur pesanan.
+func (s *inventoryService) RecordMovement(productID, variantID uuid.UUID, input dto.StockMovementRequest, farmerID uuid.UUID) (*dto.StockMovementResponse, error) {
+	if err := s.ensureProductOwner(productID, farmerID); err != nil {
+		return nil, err
+	}
+
+	quantity := models.RoundQuantity(input.Quantity)
+	switch {
+	case quantity == 0:
+		return nil, errors.New("invalid quantity: must not be zero")
+	case input.Type != models.MovementAdjustment && quantity < 0:
+		return nil, fmt.Errorf("invalid quantity: %s quantity must be positive", input.Type)
+	case input.Type == models.MovementReturn && input.OrderID == nil:
+		return nil, errors.New("invalid return: order_id is required")
+	}
+
+	var movement *dto.StockMovementResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		variant, err := s.variantRepo.FindByIDForUpdate(tx, variantID)
+		if err != nil || variant.ProductID != productID {
+			return errors.New("variant not found")
+		}
+		if input.Type == models.MovementReturn {
+			if err := s.validateReturn(*input.OrderID, variant.ID, quantity, farmerID); err != nil {
+				return err
+			}
+		}
+		if variant.Stock+quantity < variant.ReservedStock {
+			return fmt.Errorf("invalid stock: cannot be lower than reserved quantity %g %s", variant.ReservedStock, variant.Unit)
+		}
+
+		actorID := farmerID
+		change := StockChange{
+			Type:       input.Type,
+			StockDelta: quantity,
+			OrderID:    input.OrderID,
+			ActorID:    &actorID,
+			Note:       input.Note,
+		}
+		recorded, err := s.applyStockChange(tx, variant, change)
+		if err != nil {
+			return err
+		}
+		response := toStockMovementResponse(*recorded)
+		response.VariantName = variant.Name
+		response.Unit = variant.Unit
+		movement = &response
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movement, nil
+}
+
+// validateReturn memastikan barang yang diretur memang berasal dari pesanan petani ini.
+func (s *inventoryService) validateReturn(orderID, variantID uuid.UUID, quantity float64, farmerID uuid.UUID) error {
+	order, err := s.orderRepo.FindByID(orderID)
+	if err != nil {
+		return errors.New("order not found")
+	}
+	if order.FarmerID != farmerID {
+		return errors.New("forbidden: order does not belong to you")
+	}
+	if order.Status == models.OrderStatusPending || order.Status == models.OrderStatusCancelled {
+		return errors.New("invalid return: order was never paid")
+	}
+	for _, item := range order.Items {
+		if item.VariantID != nil && *item.VariantID == variantID {
+			if quantity > item.Quantity {
+				return fmt.Errorf("invalid return: order only contains %g %s", item.Quantity, item.Unit)
+			}
+			return nil
+		}
+	}
+	return errors.New("invalid return: variant is not part of the order")
+}
+
+// GetProductMovements menampilkan riwayat pergerakan stok produk milik petani,
+// beserta saldo per varian yang dihitung ulang dari ledger.
+func (s *inventoryService) GetProductMovements(productID uuid.UUID, query dto.StockMovementQuery, farmerID uuid.UUID) (*dto.ProductStockHistoryResponse, error) {
+	if err := s.ensureProductOwner(productID, farmerID); err != nil {
+		return nil, err
+	}
+
+	variants, err := s.variantRepo.FindAllByProductID(nil, productID)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := s.movementRepo.SumByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	movements, total, err := s.movementRepo.FindByProduct(productID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger := make(map[uuid.UUID]repositories.InventoryBalance, len(balances))
+	for _, b := range balances {
+		ledger[b.VariantID] = b
+	}
+	variantsByID := make(map[uuid.UUID]models.ProductVariant, len(variants))
+	response := &dto.ProductStockHistoryResponse{Balances: make([]dto.VariantStockBalance, 0, len(variants))}
+	for _, v := range variants {
+		variantsByID[v.ID] = v
+		b := ledger[v.ID]
+		ledgerStock := models.RoundQuantity(b.StockTotal)
+		ledgerReserved := models.RoundQuantity(b.ReservedTotal)
+		response.Balances = append(response.Balances, dto.VariantStockBalance{
+			VariantID:           v.ID,
+			VariantName:         v.Name,
+			Unit:                v.Unit,
+			Stock:               v.Stock,
+			ReservedStock:       v.ReservedStock,
+			LedgerStock:         ledgerStock,
+			LedgerReservedStock: ledgerReserved,
+			InSync:              ledgerStock == models.RoundQuantity(v.Stock) && ledgerReserved == models.RoundQuantity(v.ReservedStock),
+		})
+	}
+
+	rows := make([]dto.StockMovementResponse, 0, len(movements))
+	for _, m := range movements {
+		row := toStockMovementResponse(m)
+		if v, ok := variantsByID[m.VariantID]; ok {
+			row.VariantName = v.Name
+			row.Unit = v.Unit
+		}
+		rows = append(rows, row)
+	}
+	response.Movements = dto.PaginationResponse{
+		Data:       rows,
+		Total:      total,
+		Page:       query.Page,
+		Limit:      query.Limit,
+		TotalPages: int(math.Ceil(float64(total) / float64(query.Limit))),
+	}
+	return response, nil
+}
+
+func toStockMovementResponse(m models.InventoryMovement) dto.StockMovementResponse {
+	return dto.StockMovementResponse{
+		ID:            m.ID,
+		VariantID:     m.VariantID,
+		Type:          m.Type,
+		StockDelta:    m.StockDelta,
+		ReservedDelta: m.ReservedDelta,
+		StockAfter:    m.StockAfter,
+		ReservedAfter: m.ReservedAfter,
+		OrderID:       m.OrderID,
+		ActorID:       m.ActorID,
+		Note:          m.Note,
+		CreatedAt:     m.CreatedAt,
+	}
+}
+
+func (s *inventoryService) ensureProductOwner(productID, farmerID uuid.UUID) error {
+	_, err := findOwnedProduct(s.productRepo, productID, farmerID)
+	return err
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"gorm.io/gorm"
+)
+
+type OrderService interface {
+	GetMyOrders(userID uuid.UUID) ([]models.Order, error)
+	GetIncomingOrders(farmerID uuid.UUID, status string) ([]dto.IncomingOrderResponse, error)
+	GetOrderDetail(orderID uuid.UUID, user *models.User) (*dto.OrderDetailResponse, error)
+	ShipOrder(orderID, farmerID uuid.UUID, input dto.ShipOrderRequest) (*models.Order, error)
+	ConfirmReceived(orderID, buyerID uuid.UUID) (*models.Order, error)
+}
+
+type orderService struct {
+	orderRepo       repositories.OrderRepository
+	deliveryRepo    repositories.DeliveryRepository
+	deliveryService DeliveryService
+	notifService    NotificationService
+}
+
+func NewOrderService(
+	orderRepo repositories.OrderRepository,
+	deliveryRepo repositories.DeliveryRepository,
+	deliveryService DeliveryService,
+	notifService NotificationService,
+) OrderService {
+	return &orderService{
+		orderRepo:       orderRepo,
+		deliveryRepo:    deliveryRepo,
+		deliveryService: deliveryService,
+		notifService:    notifService,
+	}
+}
+
+func (s *orderService) GetMyOrders(userID uuid.UUID) ([]models.Order, error) {
+	return s.orderRepo.FindAllByUserID(userID)
+}
+
+func (s *orderService) GetIncomingOrders(farmerID uuid.UUID, status string) ([]dto.IncomingOrderResponse, error) {
+	orders, err := s.orderRepo.FindAllByFarmerID(farmerID, status)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]dto.IncomingOrderResponse, 0, len(orders))
+	for _, order := range orders {
+		response = append(response, dto.IncomingOrderResponse{Order: order, SellerAmount: order.SellerAmount()})
+	}
+	return response, nil
+}
+
+// GetOrderDetail menampilkan pesanan kepada pembeli, petani penjual, atau admin.
+func (s *orderService) GetOrderDetail(orderID uuid.UUID, user *models.User) (*dto.OrderDetailResponse, error) {
+	order, err := s.orderRepo.FindByID(orderID)
+	if err != nil {
+		return nil, errors.New("order not found")
+	}
+	if order.UserID != user.ID && order.FarmerID != user.ID && user.Role != "admin" {
+		return nil, errors.New("forbidden: you are not involved in this order")
+	}
+
+	response := &dto.OrderDetailResponse{Order: order, DeliveryTimeline: []models.DeliveryEvent{}}
+	if order.FarmerID == user.ID || user.Role == "admin" {
+		sellerAmount := order.SellerAmount()
+		response.SellerAmount = &sellerAmount
+	}
+	if order.DeliveryID != nil {
+		events, err := s.deliveryRepo.FindEventsByDeliveryID(order.DeliveryID.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load delivery timeline: %w", err)
+		}
+		response.DeliveryTimeline = events
+	}
+	return response, nil
+}
+
+// ShipOrder memenuhi pesanan yang sudah dibayar. Metode "courier" membuat Delivery
+// yang terhubung; status pesanan lalu mengikuti event pengiriman (dijemput -> shipped,
+// diterima -> completed). Antar sendiri dan ambil di tempat langsung menjadi shipped.
+func (s *orderService) ShipOrder(orderID, farmerID uuid.UUID, input dto.ShipOrderRequest) (*models.Order, error) {
+	order, err := s.orderRepo.FindByID(orderID)
+	if err != nil {
+		return nil, errors.New("order not found")
+	}
+	if order.FarmerID != farmerID {
+		return nil, errors.New("forbidden: you do not own this order")
+	}
+	if !order.CanBeShipped() {
+		return nil, fmt.Errorf("invalid order state: order with status %s cannot be shipped", order.Status)
+	}
+
+	method := input.Method
+	var title, message string
+	switch method {
+	case models.FulfillmentCourier:
+		title = "Pesanan Sedang Disiapkan"
+		message = fmt.Sprintf("Pesanan %s akan diantar oleh kurir. Anda dapat memantau pengirimannya dari detail pesanan.", order.InvoiceNumber)
+	case models.FulfillmentSelfDelivery, models.FulfillmentPickup:
+		now := time.Now()
+		order.Status = models.OrderStatusShipped
+		order.ShippedAt = &now
+		title = "Pesanan Dikirim"
+		message = fmt.Sprintf("Pesanan %s sedang diantar langsung oleh petani. Konfirmasi setelah pesanan diterima.", order.InvoiceNumber)
+		if method == models.FulfillmentPickup {
+			title = "Pesanan Siap Diambil"
+			message = fmt.Sprintf("Pesanan %s siap diambil di lokasi petani. Konfirmasi setelah pesanan diterima.", order.InvoiceNumber)
+		}
+	}
+	order.FulfillmentMethod = &method
+
+	if method == models.FulfillmentCourier {
+		// Delivery dan penautan pesanan disimpan dalam satu transaksi agar tidak ada delivery yatim
+		request, err := orderDeliveryRequest(order, input)
+		if err != nil {
+			return nil, err
+		}
+		delivery, err := s.deliveryService.CreateLinkedDelivery(request, order.FarmerID, func(tx *gorm.DB, delivery *models.Delivery) error {
+			locked, err := s.orderRepo.FindByIDForUpdate(tx, order.ID)
+			if err != nil {
+				return errors.New("order not found")
+			}
+			if !locked.CanBeShipped() {
+				return fmt.Errorf("invalid order state: order with status %s cannot be shipped", locked.Status)
+			}
+			order.DeliveryID = &delivery.ID
+			if err := s.orderRepo.Update(tx, order); err != nil {
+				return fmt.Errorf("failed to update order: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		order.Delivery = delivery
+	} else if err := s.orderRepo.Update(nil, order); err != nil {
+		return nil, fmt.Errorf("failed to update order: %w", err)
+	}
+	s.notifService.CreateNotification(order.UserID, title, message, fmt.Sprintf("/orders/%s", order.ID), "order")
+	return order, nil
+}
+
+// orderDeliveryRequest menyusun permintaan Delivery dari data pesanan. Penerima
+// diisi dengan data pembeli agar OTP serah terima dikirim kepadanya.
+func orderDeliveryRequest(order *models.Order, input dto.ShipOrderRequest) (dto.CreateDeliveryRequest, error) {
+	if input.PickupAddress == "" || input.PickupLat == nil || input.PickupLng == nil {
+		return dto.CreateDeliveryRequest{}, errors.New("invalid input: pickup_address, pickup_lat and pickup_lng are required for courier delivery")
+	}
+	// Koordinat tujuan default diambil dari alamat pengiriman pesanan
+	if input.DestinationLat == nil || input.DestinationLng == nil {
+		input.DestinationLat, input.DestinationLng = order.ShippingLat, order.ShippingLng
+	}
+	if input.DestinationLat == nil || input.DestinationLng == nil {
+		return dto.CreateDeliveryRequest{}, errors.New("invalid input: destination_lat and destination_lng are required for courier delivery")
+	}
+	if input.ItemWeight <= 0 {
+		return dto.CreateDeliveryRequest{}, errors.New("invalid input: item_weight is required for courier delivery")
+	}
+	destination := input.DestinationAddress
+	if destination == "" && order.ShippingAddress != nil {
+		destination = *order.ShippingAddress
+	}
+	if destination == "" {
+		return dto.CreateDeliveryRequest{}, errors.New("invalid input: destination_address is required because the order has no shipping address")
+	}
+
+	buyer := order.User
+	recipientName, recipientPhone := &buyer.Name, buyer.PhoneNumber
+	if order.ShippingRecipient != nil {
+		recipientName, recipientPhone = order.ShippingRecipient, order.ShippingPhone
+	}
+	return dto.CreateDeliveryRequest{
+		PickupAddress:      input.PickupAddress,
+		PickupLat:          *input.PickupLat,
+		PickupLng:          *input.PickupLng,
+		DestinationAddress: destination,
+		DestinationLat:     input.DestinationLat,
+		DestinationLng:     input.DestinationLng,
+		ItemDescription:    orderItemSummary(order),
+		ItemWeight:         input.ItemWeight,
+		PickupDate:         input.PickupDate,
+		RecipientName:      recipientName,
+		RecipientPhone:     recipientPhone,
+		RecipientEmail:     &buyer.Email,
+	}, nil
+}
+
+// orderItemSummary menyusun deskripsi muatan dari isi pesanan, mis. "Pesanan ORD-1: 2.5 kg Tomat".
+func orderItemSummary(order *models.Order) string {
+	items := make([]string, 0, len(order.Items))
+	for _, item := range order.Items {
+		line := fmt.Sprintf("%gx %s", item.Quantity, item.Product.Title)
+		if item.Unit != "" {
+			line = fmt.Sprintf("%g %s %s", item.Quantity, item.Unit, item.Product.Title)
+		}
+		items = append(items, line)
+	}
+	return fmt.Sprintf("Pesanan %s: %s", order.InvoiceNumber, strings.Join(items, ", "))
+}
+
+// ConfirmReceived dipakai pembeli untuk menyelesaikan pesanan yang diantar sendiri
+// atau diambil di tempat. Pesanan via kurir selesai lewat bukti serah terima driver.
+func (s *orderService) ConfirmReceived(orderID, buyerID uuid.UUID) (*models.Order, error) {
+	order, err := s.orderRepo.FindByID(orderID)
+	if err != nil {
+		return nil, errors.New("order not found")
+	}
+	if order.UserID != buyerID {
+		return nil, errors.New("forbidden: you are not the buyer of this order")
+	}
+	if order.Status != models.OrderStatusShipped {
+		return nil, fmt.Errorf("invalid order state: order with status %s cannot be confirmed", order.Status)
+	}
+	if order.FulfillmentMethod != nil && *order.FulfillmentMethod == models.FulfillmentCourier {
+		return nil, errors.New("invalid order state: courier orders are completed by the delivery handover")
+	}
+
+	now := time.Now()
+	order.Status = models.OrderStatusCompleted
+	order.CompletedAt = &now
+	if err := s.orderRepo.Update(nil, order); err != nil {
+		return nil, fmt.Errorf("failed to update order: %w", err)
+	}
+
+	message := fmt.Sprintf("Pembeli telah mengonfirmasi penerimaan pesanan %s.", order.InvoiceNumber)
+	s.notifService.CreateNotification(order.FarmerID, "Pesanan Selesai", message, fmt.Sprintf("/orders/%s", order.ID), "order")
+	return order, nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/whsasmita/AgroLink_API/config"
 	"github.com/whsasmita/AgroLink_API/dto"
 	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/pkg/tracking"
 	"github.com/whsasmita/AgroLink_API/repositories"
 	"gorm.io/gorm"
 )
@@ -31,6 +32,8 @@ type paymentService struct {
 	projectRepo     repositories.ProjectRepository
 	userRepo        repositories.UserRepository
 	deliveryRepo repositories.DeliveryRepository
+	disputeRepo     repositories.DeliveryDisputeRepository
+	hub             *tracking.Hub
 	db              *gorm.DB
 }
 
@@ -42,6 +45,8 @@ func NewPaymentService(
 	projectRepo repositories.ProjectRepository,
 	userRepo repositories.UserRepository,
 	deliveryRepo repositories.DeliveryRepository,
+	disputeRepo repositories.DeliveryDisputeRepository,
+	hub *tracking.Hub,
 	db *gorm.DB,
 ) PaymentService {
 	return &paymentService{
@@ -52,6 +57,8 @@ func NewPaymentService(
 		projectRepo:     projectRepo,
 		userRepo:        userRepo,
 		deliveryRepo: deliveryRepo,
+		disputeRepo:     disputeRepo,
+		hub:             hub,
 		db:              db,
 	}
 }
@@ -177,24 +184,36 @@ func (s *paymentService) HandleWebhookNotification(notificationPayload map[strin
         return nil
     }
 
+    if invoice.DeliveryID != nil && invoice.Purpose != models.InvoicePurposeService {
+        // Biaya tambahan (kirim ulang/retur) tidak mengubah status pengiriman
+        log.Printf("INFO: %s invoice %s settled for delivery %s", invoice.Purpose, invoice.ID.String(), invoice.DeliveryID.String())
+        return nil
+    }
+
     if invoice.DeliveryID != nil {
-        // Ambil delivery & set status ke 'in_transit'
+        // Pembayaran lunas: driver boleh berangkat menjemput barang
         delivery, derr := s.deliveryRepo.FindByID(invoice.DeliveryID.String())
         if derr != nil {
             log.Printf("WARN: delivery load failed for delivery %s: %v", invoice.DeliveryID.String(), derr)
             return nil // jangan gagalkan webhook
         }
-        // Pastikan transisi sesuai enum kamu
-        if delivery.Status == "pending_payment" || delivery.Status == "pending_signature" || delivery.Status == "pending_driver" {
-            delivery.Status = "in_transit"
-        } else {
-            // fallback aman: tetap set in_transit setelah paid
-            delivery.Status = "in_transit"
+        if delivery.Status != models.DeliveryStatusPendingPayment {
+            log.Printf("WARN: delivery %s is %s, skipped payment_settled transition", delivery.ID.String(), delivery.Status)
+            return nil
         }
-        if err := s.deliveryRepo.Update(nil, delivery); err != nil {
-            // Jika Update(nil, ...) tidak diterima, ganti ke UpdateStatus seperti di bawah (bagian repo)
+        liveEvents := &deliveryEventBatch{}
+        err := s.db.Transaction(func(tx *gorm.DB) error {
+            return transitionDelivery(tx, s.deliveryRepo, liveEvents, delivery, deliveryTransition{
+                To:        models.DeliveryStatusPickupPending,
+                EventType: models.DeliveryEventPaymentSettled,
+                ActorRole: "system",
+            })
+        })
+        if err != nil {
             log.Printf("WARN: delivery update failed for delivery %s: %v", delivery.ID.String(), err)
+            return nil
         }
+        liveEvents.publish(s.hub)
         return nil
     }
 
@@ -298,6 +317,7 @@ func (s *paymentService) ReleaseProjectPayment(projectID string, farmerID uuid.U
 }
 
 func (s *paymentService) ReleaseDeliveryPayment(deliveryID string, farmerID uuid.UUID) error {
+	liveEvents := &deliveryEventBatch{}
 	tx := s.db.Begin()
 	if tx.Error != nil {
 		return fmt.Errorf("failed to begin transaction: %w", tx.Error)
@@ -335,6 +355,41 @@ func (s *paymentService) ReleaseDeliveryPayment(deliveryID string, farmerID uuid
 		return fmt.Errorf("delivery data not found")
 	}
 
+	// Kunci delivery dan cek ulang penanda pelepasan dana di dalam transaksi
+	if err := lockUnreleasedDelivery(tx, s.deliveryRepo, delivery); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Dana ditahan selama masih ada sengketa yang belum diputuskan admin
+	hasDispute, err := s.disputeRepo.HasOpenDispute(delivery.ID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to check delivery disputes: %w", err)
+	}
+	if hasDispute {
+		tx.Rollback()
+		return fmt.Errorf("payment is on hold while a dispute for this delivery is open")
+	}
+
+	// Biaya tambahan (kirim ulang/retur) harus lunas dan ikut dibayarkan ke driver
+	invoices, err := s.invoiceRepo.FindAllByDeliveryID(deliveryID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to load delivery invoices: %w", err)
+	}
+	driverAmount := invoice.Amount // Gaji driver adalah jumlah pokok
+	for _, surcharge := range invoices {
+		if surcharge.Purpose == models.InvoicePurposeService {
+			continue
+		}
+		if surcharge.Status != "paid" {
+			tx.Rollback()
+			return fmt.Errorf("payment is on hold until the %s invoice for this delivery is paid", surcharge.Purpose)
+		}
+		driverAmount += surcharge.Amount
+	}
+
 	// 3. Buat Payout untuk Driver
 	// Pastikan driver sudah terpilih di data delivery
 	if delivery.DriverID == nil {
@@ -345,22 +400,41 @@ func (s *paymentService) ReleaseDeliveryPayment(deliveryID string, farmerID uuid
 		TransactionID: transaction.ID,
 		PayeeID:      *delivery.DriverID, // Menggunakan WorkerID sebagai field generik
 		PayeeType: "driver",
-		Amount:        invoice.Amount,     // Gaji driver adalah jumlah pokok
+		Amount:        driverAmount,
 	}
 	if err := s.payoutRepo.Create(tx, &payout); err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// 4. Update status Delivery menjadi 'delivered'
-	delivery.Status = "delivered"
-	if err := s.deliveryRepo.Update(tx, delivery); err != nil {
+	// 4. Petani mengonfirmasi penerimaan: tandai 'delivered' bila driver belum melakukannya.
+	// Muatan yang sudah diretur ke petani tetap berstatus 'returned'.
+	if delivery.Status != models.DeliveryStatusDelivered && delivery.Status != models.DeliveryStatusReturned {
+		if err := transitionDelivery(tx, s.deliveryRepo, liveEvents, delivery, deliveryTransition{
+			To:        models.DeliveryStatusDelivered,
+			EventType: models.DeliveryEventDelivered,
+			ActorID:   &farmerID,
+			ActorRole: "farmer",
+		}); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("delivery cannot be completed yet: %w", err)
+		}
+	}
+	if err := recordDeliveryEvent(tx, s.deliveryRepo, liveEvents, delivery, delivery.Status, deliveryTransition{
+		EventType: models.DeliveryEventPaymentReleased,
+		ActorID:   &farmerID,
+		ActorRole: "farmer",
+	}); err != nil {
 		tx.Rollback()
 		return err
 	}
 	
 	// 5. Commit transaksi jika semua berhasil
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	liveEvents.publish(s.hub)
+	return nil
 }
 
 
@@ -0,0 +1,944 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"gorm.io/gorm"
+)
+
+// Jumlah pre-order maksimal yang dibatalkan dalam satu putaran job.
+const preOrderExpiryBatchSize = 100
+
+// PreOrderService mengelola penjualan hasil panen sebelum dipanen: kampanye pre-order
+// petani, uang muka dan pelunasan lewat ECommercePaymentService, alokasi hasil panen,
+// serta pengembalian dana otomatis bila panen kurang dari yang dipesan.
+type PreOrderService interface {
+	PreOrderPaymentHandler
+	CreateCampaign(productID uuid.UUID, input dto.PreOrderCampaignRequest, farmerID uuid.UUID) (*dto.PreOrderCampaignResponse, error)
+	GetCampaigns(productID, farmerID uuid.UUID) ([]dto.PreOrderCampaignResponse, error)
+	GetOpenCampaigns(productID uuid.UUID) ([]dto.PreOrderCampaignResponse, error)
+	GetCampaignPreOrders(productID, campaignID, farmerID uuid.UUID) ([]dto.PreOrderResponse, error)
+	CancelCampaign(productID, campaignID, farmerID uuid.UUID) error
+	HarvestCampaign(productID, campaignID uuid.UUID, input dto.HarvestRequest, farmerID uuid.UUID) (*dto.PreOrderHarvestResponse, error)
+	PlacePreOrder(userID uuid.UUID, input dto.CreatePreOrderRequest) (*dto.PreOrderResponse, error)
+	GetMyPreOrders(userID uuid.UUID) ([]dto.PreOrderResponse, error)
+	PayBalance(preOrderID, userID uuid.UUID) (*dto.PreOrderResponse, error)
+	GetRefunds(status string) ([]dto.PreOrderResponse, error)
+	CompleteRefund(preOrderID uuid.UUID) error
+	ExpireUnpaid() (int, error)
+	RunExpiryJob(interval time.Duration)
+}
+
+type preOrderService struct {
+	campaignRepo   repositories.PreOrderCampaignRepository
+	preOrderRepo   repositories.PreOrderRepository
+	productRepo    repositories.ProductRepository
+	variantRepo    repositories.ProductVariantRepository
+	orderRepo      repositories.OrderRepository
+	addressRepo    repositories.AddressRepository
+	paymentRepo    repositories.ECommercePaymentRepository
+	paymentService ECommercePaymentService
+	inventory      InventoryService
+	notifService   NotificationService
+	db             *gorm.DB
+}
+
+// NewPreOrderService juga mendaftarkan dirinya sebagai penerima webhook pembayaran pre-order.
+func NewPreOrderService(
+	campaignRepo repositories.PreOrderCampaignRepository,
+	preOrderRepo repositories.PreOrderRepository,
+	productRepo repositories.ProductRepository,
+	variantRepo repositories.ProductVariantRepository,
+	orderRepo repositories.OrderRepository,
+	addressRepo repositories.AddressRepository,
+	paymentRepo repositories.ECommercePaymentRepository,
+	paymentService ECommercePaymentService,
+	inventory InventoryService,
+	notifService NotificationService,
+	db *gorm.DB,
+) PreOrderService {
+	s := &preOrderService{
+		campaignRepo:   campaignRepo,
+		preOrderRepo:   preOrderRepo,
+		productRepo:    productRepo,
+		variantRepo:    variantRepo,
+		orderRepo:      orderRepo,
+		addressRepo:    addressRepo,
+		paymentRepo:    paymentRepo,
+		paymentService: paymentService,
+		inventory:      inventory,
+		notifService:   notifService,
+		db:             db,
+	}
+	paymentService.SetPreOrderHandler(s)
+	return s
+}
+
+// preOrderNotice adalah notifikasi ke pembeli yang baru dikirim setelah transaksi commit.
+type preOrderNotice struct {
+	userID  uuid.UUID
+	title   string
+	message string
+}
+
+func (s *preOrderService) sendNotices(notices []preOrderNotice) {
+	for _, n := range notices {
+		s.notifService.CreateNotification(n.userID, n.title, n.message, "/pre-orders", "order")
+	}
+}
+
+// CreateCampaign membuka pre-order untuk satu varian. Pre-order diterima sampai hari
+// sebelum jendela panen dimulai.
+func (s *preOrderService) CreateCampaign(productID uuid.UUID, input dto.PreOrderCampaignRequest, farmerID uuid.UUID) (*dto.PreOrderCampaignResponse, error) {
+	product, err := findOwnedProduct(s.productRepo, productID, farmerID)
+	if err != nil {
+		return nil, err
+	}
+	variant, err := variantOfProduct(product, input.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	harvestStart, harvestEnd, err := parseHarvestWindow(input.HarvestStart, input.HarvestEnd)
+	if err != nil {
+		return nil, err
+	}
+	quota := models.RoundQuantity(input.Quota)
+	if quota < variant.MinOrderQty {
+		return nil, fmt.Errorf("invalid quota: must be at least the minimum order of %g %s", variant.MinOrderQty, variant.Unit)
+	}
+
+	price := variant.Price
+	if input.Price != nil {
+		price = roundTo(*input.Price, 2)
+	}
+	campaign := &models.PreOrderCampaign{
+		ProductID:      productID,
+		VariantID:      variant.ID,
+		FarmerID:       farmerID,
+		Price:          price,
+		Quota:          quota,
+		DepositPercent: input.DepositPercent,
+		HarvestStart:   harvestStart,
+		HarvestEnd:     harvestEnd,
+		Status:         models.PreOrderCampaignOpen,
+		Notes:          input.Notes,
+	}
+	if err := s.campaignRepo.Create(nil, campaign); err != nil {
+		return nil, fmt.Errorf("failed to create pre-order campaign: %w", err)
+	}
+	response := toPreOrderCampaignResponse(*campaign, variant)
+	return &response, nil
+}
+
+func (s *preOrderService) GetCampaigns(productID, farmerID uuid.UUID) ([]dto.PreOrderCampaignResponse, error) {
+	if _, err := findOwnedProduct(s.productRepo, productID, farmerID); err != nil {
+		return nil, err
+	}
+	campaigns, err := s.campaignRepo.FindAllByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	return toPreOrderCampaignResponses(campaigns), nil
+}
+
+// GetOpenCampaigns menampilkan kampanye yang masih bisa dipesan pembeli.
+func (s *preOrderService) GetOpenCampaigns(productID uuid.UUID) ([]dto.PreOrderCampaignResponse, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, errors.New("product not found")
+	}
+	campaigns, err := s.campaignRepo.FindOpenByProductID(productID, today())
+	if err != nil {
+		return nil, err
+	}
+	return toPreOrderCampaignResponses(campaigns), nil
+}
+
+func (s *preOrderService) GetCampaignPreOrders(productID, campaignID, farmerID uuid.UUID) ([]dto.PreOrderResponse, error) {
+	if _, err := findOwnedProduct(s.productRepo, productID, farmerID); err != nil {
+		return nil, err
+	}
+	campaign, err := s.campaignRepo.FindByID(campaignID)
+	if err != nil || campaign.ProductID != productID {
+		return nil, errors.New("pre-order campaign not found")
+	}
+	preOrders, err := s.preOrderRepo.FindAllByCampaignID(campaignID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]dto.PreOrderResponse, 0, len(preOrders))
+	for _, preOrder := range preOrders {
+		responses = append(responses, toPreOrderResponse(preOrder))
+	}
+	return responses, nil
+}
+
+// CancelCampaign membatalkan kampanye sebelum panen (mis. gagal panen total).
+// Semua pre-order dibatalkan dan dana yang sudah dibayar dikembalikan penuh.
+func (s *preOrderService) CancelCampaign(productID, campaignID, farmerID uuid.UUID) error {
+	if _, err := findOwnedProduct(s.productRepo, productID, farmerID); err != nil {
+		return err
+	}
+
+	var notices []preOrderNotice
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		campaign, err := s.openCampaign(tx, productID, campaignID)
+		if err != nil {
+			return err
+		}
+		preOrders, err := s.preOrderRepo.FindActiveByCampaignIDForUpdate(tx, campaignID)
+		if err != nil {
+			return err
+		}
+		for i := range preOrders {
+			preOrder := &preOrders[i]
+			if err := s.cancelBeforeHarvest(tx, campaign, preOrder, "Kampanye pre-order dibatalkan petani"); err != nil {
+				return err
+			}
+			notices = append(notices, preOrderNotice{preOrder.UserID, "Pre-Order Dibatalkan",
+				cancellationMessage(preOrder, "dibatalkan oleh petani")})
+		}
+		campaign.Status = models.PreOrderCampaignCancelled
+		return s.campaignRepo.Update(tx, campaign)
+	})
+	if err != nil {
+		return err
+	}
+	s.sendNotices(notices)
+	return nil
+}
+
+// HarvestCampaign memasukkan hasil panen ke stok lalu mengalokasikannya ke pre-order
+// berdasarkan urutan pemesanan. Pre-order yang tidak kebagian penuh disesuaikan totalnya
+// dan kelebihan bayarnya otomatis diantrekan untuk dikembalikan. Pre-order yang sudah
+// lunas langsung menjadi pesanan berbayar; sisanya menunggu pelunasan dengan stok ditahan.
+func (s *preOrderService) HarvestCampaign(productID, campaignID uuid.UUID, input dto.HarvestRequest, farmerID uuid.UUID) (*dto.PreOrderHarvestResponse, error) {
+	if _, err := findOwnedProduct(s.productRepo, productID, farmerID); err != nil {
+		return nil, err
+	}
+
+	var response dto.PreOrderHarvestResponse
+	var notices []preOrderNotice
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		campaign, err := s.openCampaign(tx, productID, campaignID)
+		if err != nil {
+			return err
+		}
+		variant, err := s.variantRepo.FindByIDForUpdate(tx, campaign.VariantID)
+		if err != nil {
+			return errors.New("variant not found")
+		}
+
+		harvested := models.RoundQuantity(input.ActualQuantity)
+		change := StockChange{
+			Type:       models.MovementRestock,
+			StockDelta: harvested,
+			ActorID:    &farmerID,
+			Note:       "Panen pre-order " + campaign.HarvestStart.Format("2006-01-02"),
+		}
+		if err := s.inventory.ApplyStockChange(tx, variant, change); err != nil {
+			return err
+		}
+
+		preOrders, err := s.preOrderRepo.FindActiveByCampaignIDForUpdate(tx, campaignID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		dueAt := now.AddDate(0, 0, models.PreOrderBalanceDueDays)
+		remaining := harvested
+		var requested, allocated, refundTotal float64
+		response.PreOrders = make([]dto.PreOrderResponse, 0, len(preOrders))
+
+		for i := range preOrders {
+			preOrder := &preOrders[i]
+			refundBefore := preOrder.RefundAmount
+
+			// Uang muka yang belum dibayar saat panen tidak ikut mendapat alokasi
+			if preOrder.Status == models.PreOrderStatusAwaitingDeposit {
+				if err := s.cancelBeforeHarvest(tx, campaign, preOrder, "Uang muka belum dibayar saat panen"); err != nil {
+					return err
+				}
+				continue
+			}
+
+			requested += preOrder.Quantity
+			quantity := allocateHarvest(preOrder.Quantity, remaining, variant.QuantityStep)
+			if quantity <= 0 {
+				if err := s.cancelBeforeHarvest(tx, campaign, preOrder, "Hasil panen tidak mencukupi"); err != nil {
+					return err
+				}
+				notices = append(notices, preOrderNotice{preOrder.UserID, "Pre-Order Dibatalkan",
+					cancellationMessage(preOrder, "tidak kebagian hasil panen")})
+				refundTotal += preOrder.RefundAmount - refundBefore
+				response.PreOrders = append(response.PreOrders, toPreOrderResponse(*preOrder))
+				continue
+			}
+			remaining = models.RoundQuantity(remaining - quantity)
+			allocated += quantity
+			preOrder.AllocatedQuantity = &quantity
+
+			if quantity < preOrder.Quantity {
+				if err := s.applyShortfall(tx, preOrder, quantity, variant.Unit); err != nil {
+					return err
+				}
+			}
+
+			orderID := preOrder.OrderID
+			if preOrder.OutstandingBalance() == 0 {
+				// Sudah lunas di muka: stok hasil panen langsung terjual
+				if err := s.completeSale(tx, preOrder, variant, false); err != nil {
+					return err
+				}
+				notices = append(notices, preOrderNotice{preOrder.UserID, "Pre-Order Siap Dikirim",
+					fmt.Sprintf("Panen sudah selesai. %g %s pesanan Anda sedang disiapkan petani.%s",
+						quantity, variant.Unit, refundNote(preOrder.RefundAmount-refundBefore))})
+			} else {
+				reserve := StockChange{
+					Type:          models.MovementReservation,
+					ReservedDelta: quantity,
+					OrderID:       &orderID,
+					Note:          "Ditahan untuk pelunasan pre-order",
+				}
+				if err := s.inventory.ApplyStockChange(tx, variant, reserve); err != nil {
+					return err
+				}
+				preOrder.Status = models.PreOrderStatusAwaitingBalance
+				preOrder.BalanceDueAt = &dueAt
+				notices = append(notices, preOrderNotice{preOrder.UserID, "Panen Selesai: Lunasi Pre-Order",
+					fmt.Sprintf("%g %s pesanan Anda siap. Lunasi Rp%.0f paling lambat %s agar pesanan tidak dibatalkan.%s",
+						quantity, variant.Unit, preOrder.OutstandingBalance(), dueAt.Format("02-01-2006 15:04"),
+						refundNote(preOrder.RefundAmount-refundBefore))})
+			}
+			if err := s.preOrderRepo.Update(tx, preOrder); err != nil {
+				return err
+			}
+			refundTotal += preOrder.RefundAmount - refundBefore
+			response.PreOrders = append(response.PreOrders, toPreOrderResponse(*preOrder))
+		}
+
+		campaign.Status = models.PreOrderCampaignHarvested
+		campaign.ActualQuantity = &harvested
+		campaign.HarvestedAt = &now
+		if err := s.campaignRepo.Update(tx, campaign); err != nil {
+			return err
+		}
+
+		response.Campaign = toPreOrderCampaignResponse(*campaign, variant)
+		response.HarvestedQuantity = harvested
+		response.AllocatedQuantity = models.RoundQuantity(allocated)
+		response.SurplusQuantity = remaining
+		response.ShortfallQuantity = models.RoundQuantity(math.Max(0, requested-allocated))
+		response.RefundTotal = roundTo(refundTotal, 2)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.sendNotices(notices)
+	return &response, nil
+}
+
+// applyShortfall menyesuaikan pre-order dan item pesanannya ke kuantitas teralokasi, lalu
+// mengantrekan kelebihan pembayaran untuk dikembalikan.
+func (s *preOrderService) applyShortfall(tx *gorm.DB, preOrder *models.PreOrder, quantity float64, unit string) error {
+	order, err := s.orderRepo.FindByIDForUpdate(tx, preOrder.OrderID)
+	if err != nil {
+		return fmt.Errorf("order for pre-order %s not found", preOrder.ID)
+	}
+	newTotal := roundTo(quantity*preOrder.UnitPrice, 2)
+	for i := range order.Items {
+		order.Items[i].Quantity = quantity
+		order.Items[i].SubTotal = newTotal
+		if err := s.orderRepo.UpdateItem(tx, &order.Items[i]); err != nil {
+			return err
+		}
+	}
+	order.SubTotal = newTotal
+	order.RecalculateTotal()
+	if err := s.orderRepo.Update(tx, order); err != nil {
+		return err
+	}
+
+	preOrder.TotalAmount = newTotal
+	overpaid := roundTo(preOrder.PaidAmount-preOrder.RefundAmount-newTotal, 0)
+	preOrder.QueueRefund(overpaid, fmt.Sprintf("Hasil panen kurang: teralokasi %g dari %g %s", quantity, preOrder.Quantity, unit))
+	return nil
+}
+
+// completeSale menandai pre-order lunas: stok terjual dicatat di ledger dan pesanan
+// menjadi paid sehingga bisa dikirim lewat alur pesanan biasa.
+func (s *preOrderService) completeSale(tx *gorm.DB, preOrder *models.PreOrder, variant *models.ProductVariant, reserved bool) error {
+	quantity := *preOrder.AllocatedQuantity
+	orderID := preOrder.OrderID
+	change := StockChange{
+		Type:       models.MovementSale,
+		StockDelta: -quantity,
+		OrderID:    &orderID,
+		Note:       "Pre-order lunas",
+	}
+	if reserved {
+		change.ReservedDelta = -quantity
+	}
+	if err := s.inventory.ApplyStockChange(tx, variant, change); err != nil {
+		return err
+	}
+
+	order, err := s.orderRepo.FindByIDForUpdate(tx, preOrder.OrderID)
+	if err != nil {
+		return fmt.Errorf("order for pre-order %s not found", preOrder.ID)
+	}
+	order.Status = models.OrderStatusPaid
+	if err := s.orderRepo.Update(tx, order); err != nil {
+		return err
+	}
+	preOrder.Status = models.PreOrderStatusCompleted
+	preOrder.BalanceDueAt = nil
+	return nil
+}
+
+// cancelBeforeHarvest membatalkan pre-order yang belum mendapat stok: kuota dikembalikan
+// ke kampanye (disimpan pemanggil), pesanan dibatalkan, dan dana yang sudah dibayar dikembalikan.
+func (s *preOrderService) cancelBeforeHarvest(tx *gorm.DB, campaign *models.PreOrderCampaign, preOrder *models.PreOrder, reason string) error {
+	campaign.ReservedQuota = math.Max(0, models.RoundQuantity(campaign.ReservedQuota-preOrder.Quantity))
+	if err := s.orderRepo.CancelPendingByIDs(tx, []uuid.UUID{preOrder.OrderID}); err != nil {
+		return err
+	}
+	preOrder.QueueRefund(roundTo(preOrder.PaidAmount-preOrder.RefundAmount, 2), reason)
+	preOrder.Status = models.PreOrderStatusCancelled
+	return s.preOrderRepo.Update(tx, preOrder)
+}
+
+// PlacePreOrder memesan dari kampanye yang masih buka. Kuota langsung dipegang; jika
+// kampanye mensyaratkan uang muka, pembayaran Midtrans dibuat dan kuota dilepas lagi
+// bila uang muka gagal dibayar.
+func (s *preOrderService) PlacePreOrder(userID uuid.UUID, input dto.CreatePreOrderRequest) (*dto.PreOrderResponse, error) {
+	var response dto.PreOrderResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		address, err := resolveShippingAddress(tx, s.addressRepo, userID, input.AddressID)
+		if err != nil {
+			return err
+		}
+		campaign, err := s.campaignRepo.FindByIDForUpdate(tx, input.CampaignID)
+		if err != nil {
+			return errors.New("pre-order campaign not found")
+		}
+		if campaign.Status != models.PreOrderCampaignOpen || !today().Before(campaign.HarvestStart) {
+			return errors.New("invalid state: pre-order campaign is closed")
+		}
+		if campaign.FarmerID == userID {
+			return errors.New("invalid state: you cannot pre-order your own product")
+		}
+		variant, err := s.variantRepo.FindByID(campaign.VariantID)
+		if err != nil {
+			return errors.New("variant not found")
+		}
+		product, err := s.productRepo.FindByID(campaign.ProductID)
+		if err != nil {
+			return errors.New("product not found")
+		}
+
+		quantity := models.RoundQuantity(input.Quantity)
+		if err := variant.ValidateQuantity(quantity); err != nil {
+			return err
+		}
+		if quantity > campaign.RemainingQuota() {
+			return fmt.Errorf("invalid quantity: only %g %s left for pre-order", campaign.RemainingQuota(), variant.Unit)
+		}
+
+		total := roundTo(quantity*campaign.Price, 2)
+		// Uang muka dibayar lewat Midtrans yang hanya menerima rupiah penuh
+		deposit := roundTo(total*float64(campaign.DepositPercent)/100, 0)
+		order := models.Order{
+			UserID:        userID,
+			FarmerID:      campaign.FarmerID,
+			InvoiceNumber: fmt.Sprintf("PO-%d", time.Now().UnixNano()),
+			SubTotal:      total,
+			TotalAmount:   total,
+		}
+		order.ApplyShippingAddress(address)
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		orderItem := models.OrderItem{
+			ID:              uuid.New(),
+			OrderID:         order.ID,
+			ProductID:       campaign.ProductID,
+			VariantID:       &variant.ID,
+			VariantName:     variant.Name,
+			Unit:            variant.Unit,
+			Quantity:        quantity,
+			PriceAtPurchase: campaign.Price,
+			NameAtPurchase:  product.Title,
+			SubTotal:        total,
+		}
+		if err := tx.Create(&orderItem).Error; err != nil {
+			return err
+		}
+		order.Items = []models.OrderItem{orderItem}
+
+		preOrder := &models.PreOrder{
+			CampaignID:    campaign.ID,
+			OrderID:       order.ID,
+			UserID:        userID,
+			Quantity:      quantity,
+			UnitPrice:     campaign.Price,
+			TotalAmount:   total,
+			DepositAmount: deposit,
+			Status:        models.PreOrderStatusReserved,
+		}
+		var paymentResponse *dto.PaymentInitiationResponse
+		if deposit > 0 {
+			payment, initiation, err := s.paymentService.InitiatePaymentFor(tx, userID, []models.Order{order}, deposit, models.PaymentPurposePreOrderDeposit)
+			if err != nil {
+				return err
+			}
+			preOrder.Status = models.PreOrderStatusAwaitingDeposit
+			preOrder.DepositPaymentID = &payment.ID
+			paymentResponse = initiation
+		}
+		if err := s.preOrderRepo.Create(tx, preOrder); err != nil {
+			return fmt.Errorf("failed to create pre-order: %w", err)
+		}
+
+		campaign.ReservedQuota = models.RoundQuantity(campaign.ReservedQuota + quantity)
+		if err := s.campaignRepo.Update(tx, campaign); err != nil {
+			return err
+		}
+
+		preOrder.Campaign = campaign
+		campaign.Product = product
+		campaign.Variant = variant
+		response = toPreOrderResponse(*preOrder)
+		response.Payment = paymentResponse
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (s *preOrderService) GetMyPreOrders(userID uuid.UUID) ([]dto.PreOrderResponse, error) {
+	preOrders, err := s.preOrderRepo.FindAllByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]dto.PreOrderResponse, 0, len(preOrders))
+	for _, preOrder := range preOrders {
+		responses = append(responses, toPreOrderResponse(preOrder))
+	}
+	return responses, nil
+}
+
+// PayBalance membuat pembayaran pelunasan setelah panen. Pembayaran baru hanya dibuat
+// bila pembayaran pelunasan sebelumnya sudah gagal atau kedaluwarsa.
+func (s *preOrderService) PayBalance(preOrderID, userID uuid.UUID) (*dto.PreOrderResponse, error) {
+	var response dto.PreOrderResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		preOrder, err := s.preOrderRepo.FindByIDForUpdate(tx, preOrderID)
+		if err != nil || preOrder.UserID != userID {
+			return errors.New("pre-order not found")
+		}
+		if preOrder.Status != models.PreOrderStatusAwaitingBalance {
+			return fmt.Errorf("invalid state: pre-order is %s", preOrder.Status)
+		}
+		if preOrder.BalanceDueAt != nil && time.Now().After(*preOrder.BalanceDueAt) {
+			return errors.New("invalid state: balance payment deadline has passed")
+		}
+		if preOrder.BalancePaymentID != nil {
+			previous, err := s.paymentRepo.FindByID(preOrder.BalancePaymentID.String())
+			if err == nil && previous.Status == "pending" &&
+				time.Since(previous.CreatedAt) < models.PaymentExpiryMinutes*time.Minute {
+				return errors.New("invalid state: a balance payment is already in progress")
+			}
+		}
+
+		order, err := s.orderRepo.FindByIDForUpdate(tx, preOrder.OrderID)
+		if err != nil {
+			return fmt.Errorf("order for pre-order %s not found", preOrder.ID)
+		}
+		payment, initiation, err := s.paymentService.InitiatePaymentFor(tx, userID, []models.Order{*order}, preOrder.OutstandingBalance(), models.PaymentPurposePreOrderBalance)
+		if err != nil {
+			return err
+		}
+		preOrder.BalancePaymentID = &payment.ID
+		if err := s.preOrderRepo.Update(tx, preOrder); err != nil {
+			return err
+		}
+		response = toPreOrderResponse(*preOrder)
+		response.Payment = initiation
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// OnPreOrderPaymentSettled dipanggil dari webhook di dalam transaksinya. Pembayaran yang
+// masuk setelah pre-order dibatalkan langsung diantrekan untuk dikembalikan.
+func (s *preOrderService) OnPreOrderPaymentSettled(tx *gorm.DB, payment *models.ECommercePayment) error {
+	_, preOrder, err := s.lockByPayment(tx, payment.ID)
+	if err != nil {
+		return err
+	}
+	preOrder.PaidAmount = roundTo(preOrder.PaidAmount+payment.GrandTotal, 2)
+
+	switch {
+	case payment.Purpose == models.PaymentPurposePreOrderDeposit && preOrder.Status == models.PreOrderStatusAwaitingDeposit:
+		preOrder.Status = models.PreOrderStatusReserved
+	case payment.Purpose == models.PaymentPurposePreOrderBalance && preOrder.Status == models.PreOrderStatusAwaitingBalance:
+		if preOrder.OutstandingBalance() == 0 {
+			variantID := orderVariantID(tx, s.orderRepo, preOrder)
+			if variantID == nil {
+				return fmt.Errorf("variant for pre-order %s not found", preOrder.ID)
+			}
+			variant, err := s.variantRepo.FindByIDForUpdate(tx, *variantID)
+			if err != nil {
+				return fmt.Errorf("variant for pre-order %s not found", preOrder.ID)
+			}
+			if err := s.completeSale(tx, preOrder, variant, true); err != nil {
+				return err
+			}
+		}
+	default:
+		log.Printf("WARN: Pembayaran %s masuk saat pre-order %s berstatus %s, dana dikembalikan", payment.ID, preOrder.ID, preOrder.Status)
+		preOrder.QueueRefund(payment.GrandTotal, "Pembayaran diterima setelah pre-order dibatalkan")
+	}
+	return s.preOrderRepo.Update(tx, preOrder)
+}
+
+// OnPreOrderPaymentFailed membatalkan pre-order yang uang mukanya gagal dibayar. Pelunasan
+// yang gagal tidak membatalkan apa pun; pembeli masih bisa mencoba lagi sampai batas waktu.
+func (s *preOrderService) OnPreOrderPaymentFailed(tx *gorm.DB, payment *models.ECommercePayment) error {
+	if payment.Purpose != models.PaymentPurposePreOrderDeposit {
+		return nil
+	}
+	campaign, preOrder, err := s.lockByPayment(tx, payment.ID)
+	if err != nil {
+		return err
+	}
+	if preOrder.Status != models.PreOrderStatusAwaitingDeposit {
+		return nil
+	}
+	if err := s.cancelBeforeHarvest(tx, campaign, preOrder, "Pembayaran uang muka gagal"); err != nil {
+		return err
+	}
+	return s.campaignRepo.Update(tx, campaign)
+}
+
+func (s *preOrderService) lockByPayment(tx *gorm.DB, paymentID uuid.UUID) (*models.PreOrderCampaign, *models.PreOrder, error) {
+	found, err := s.preOrderRepo.FindByPaymentID(tx, paymentID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pre-order for payment %s not found", paymentID)
+	}
+	return s.lockPreOrder(tx, found)
+}
+
+// lockPreOrder mengunci kampanye lebih dulu lalu pre-order-nya, urutan yang sama dengan
+// HarvestCampaign dan CancelCampaign agar tidak saling menunggu (deadlock).
+func (s *preOrderService) lockPreOrder(tx *gorm.DB, found *models.PreOrder) (*models.PreOrderCampaign, *models.PreOrder, error) {
+	campaign, err := s.campaignRepo.FindByIDForUpdate(tx, found.CampaignID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pre-order campaign %s not found", found.CampaignID)
+	}
+	preOrder, err := s.preOrderRepo.FindByIDForUpdate(tx, found.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pre-order %s not found", found.ID)
+	}
+	return campaign, preOrder, nil
+}
+
+func (s *preOrderService) GetRefunds(status string) ([]dto.PreOrderResponse, error) {
+	if status == "" {
+		status = models.PreOrderRefundPending
+	}
+	preOrders, err := s.preOrderRepo.FindByRefundStatus(status)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]dto.PreOrderResponse, 0, len(preOrders))
+	for _, preOrder := range preOrders {
+		responses = append(responses, toPreOrderResponse(preOrder))
+	}
+	return responses, nil
+}
+
+// CompleteRefund dipakai admin setelah dana benar-benar ditransfer ke pembeli. Hanya
+// selisih yang belum ditransfer yang dicairkan; pengembalian sebelumnya tidak dihitung lagi.
+func (s *preOrderService) CompleteRefund(preOrderID uuid.UUID) error {
+	var preOrder *models.PreOrder
+	var amount float64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		preOrder, err = s.preOrderRepo.FindByIDForUpdate(tx, preOrderID)
+		if err != nil {
+			return errors.New("pre-order not found")
+		}
+		amount = preOrder.PendingRefund()
+		if preOrder.RefundStatus == nil || *preOrder.RefundStatus != models.PreOrderRefundPending || amount <= 0 {
+			return errors.New("invalid state: pre-order has no pending refund")
+		}
+		status := models.PreOrderRefundCompleted
+		now := time.Now()
+		preOrder.RefundedAmount = preOrder.RefundAmount
+		preOrder.RefundStatus = &status
+		preOrder.RefundedAt = &now
+		return s.preOrderRepo.Update(tx, preOrder)
+	})
+	if err != nil {
+		return err
+	}
+	s.notifService.CreateNotification(preOrder.UserID, "Dana Pre-Order Dikembalikan",
+		fmt.Sprintf("Pengembalian dana sebesar Rp%.0f untuk pre-order Anda sudah ditransfer.", amount),
+		"/pre-orders", "order")
+	return nil
+}
+
+// ExpireUnpaid membatalkan pre-order yang uang mukanya tidak dibayar sampai pembayaran
+// kedaluwarsa, dan pre-order yang tidak dilunasi sampai batas waktu pelunasan. Uang muka
+// pre-order yang tidak dilunasi hangus, stok yang ditahan dikembalikan ke stok jual.
+func (s *preOrderService) ExpireUnpaid() (int, error) {
+	staleIDs, err := s.preOrderRepo.FindStaleDepositIDs(time.Now().Add(-models.ReservationTTL), preOrderExpiryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	overdueIDs, err := s.preOrderRepo.FindOverdueBalanceIDs(time.Now(), preOrderExpiryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range append(staleIDs, overdueIDs...) {
+		var notice *preOrderNotice
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			found, err := s.preOrderRepo.FindByID(tx, id)
+			if err != nil {
+				return err
+			}
+			campaign, preOrder, err := s.lockPreOrder(tx, found)
+			if err != nil {
+				return err
+			}
+
+			switch preOrder.Status {
+			case models.PreOrderStatusAwaitingDeposit:
+				if err := s.cancelBeforeHarvest(tx, campaign, preOrder, "Uang muka tidak dibayar"); err != nil {
+					return err
+				}
+				return s.campaignRepo.Update(tx, campaign)
+			case models.PreOrderStatusAwaitingBalance:
+				if preOrder.BalanceDueAt == nil || time.Now().Before(*preOrder.BalanceDueAt) {
+					return nil
+				}
+				return s.cancelOverdueBalance(tx, preOrder, &notice)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("WARN: Gagal membatalkan pre-order %s yang tidak dibayar: %v", id, err)
+			continue
+		}
+		if notice != nil {
+			s.sendNotices([]preOrderNotice{*notice})
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// cancelOverdueBalance melepas stok yang ditahan untuk pre-order yang tidak dilunasi.
+func (s *preOrderService) cancelOverdueBalance(tx *gorm.DB, preOrder *models.PreOrder, notice **preOrderNotice) error {
+	variantID := orderVariantID(tx, s.orderRepo, preOrder)
+	if variantID != nil && preOrder.AllocatedQuantity != nil {
+		variant, err := s.variantRepo.FindByIDForUpdate(tx, *variantID)
+		if err == nil {
+			orderID := preOrder.OrderID
+			change := StockChange{
+				Type:          models.MovementRelease,
+				ReservedDelta: -*preOrder.AllocatedQuantity,
+				OrderID:       &orderID,
+				Note:          "Pelunasan pre-order melewati batas waktu",
+			}
+			if err := s.inventory.ApplyStockChange(tx, variant, change); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	if err := s.orderRepo.CancelPendingByIDs(tx, []uuid.UUID{preOrder.OrderID}); err != nil {
+		return err
+	}
+	preOrder.Status = models.PreOrderStatusCancelled
+	preOrder.BalanceDueAt = nil
+	*notice = &preOrderNotice{preOrder.UserID, "Pre-Order Dibatalkan",
+		"Pre-order Anda dibatalkan karena tidak dilunasi sampai batas waktu. Uang muka tidak dapat dikembalikan."}
+	return s.preOrderRepo.Update(tx, preOrder)
+}
+
+// RunExpiryJob menjalankan ExpireUnpaid secara berkala. Dipanggil sebagai goroutine dari main.
+func (s *preOrderService) RunExpiryJob(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		expired, err := s.ExpireUnpaid()
+		if err != nil {
+			log.Printf("WARN: Job pembatalan pre-order gagal: %v", err)
+		} else if expired > 0 {
+			log.Printf("⌛ Cancelled %d unpaid pre-orders", expired)
+		}
+		<-ticker.C
+	}
+}
+
+// openCampaign mengunci kampanye milik produk yang belum dipanen/dibatalkan.
+func (s *preOrderService) openCampaign(tx *gorm.DB, productID, campaignID uuid.UUID) (*models.PreOrderCampaign, error) {
+	campaign, err := s.campaignRepo.FindByIDForUpdate(tx, campaignID)
+	if err != nil || campaign.ProductID != productID {
+		return nil, errors.New("pre-order campaign not found")
+	}
+	if campaign.Status != models.PreOrderCampaignOpen {
+		return nil, fmt.Errorf("invalid state: pre-order campaign is already %s", campaign.Status)
+	}
+	return campaign, nil
+}
+
+// orderVariantID mengambil varian dari item pesanan pre-order.
+func orderVariantID(tx *gorm.DB, orderRepo repositories.OrderRepository, preOrder *models.PreOrder) *uuid.UUID {
+	order, err := orderRepo.FindByIDForUpdate(tx, preOrder.OrderID)
+	if err != nil || len(order.Items) == 0 {
+		return nil
+	}
+	return order.Items[0].VariantID
+}
+
+// allocateHarvest membagi sisa panen ke satu pre-order, dibulatkan ke bawah sesuai
+// kelipatan jual varian.
+func allocateHarvest(requested, remaining, step float64) float64 {
+	quantity := math.Min(requested, remaining)
+	if step > 0 {
+		quantity = math.Floor(quantity/step+1e-9) * step
+	}
+	return models.RoundQuantity(quantity)
+}
+
+// parseHarvestWindow memastikan jendela panen belum dimulai dan tanggal akhirnya tidak
+// mendahului tanggal mulai.
+func parseHarvestWindow(start, end string) (time.Time, time.Time, error) {
+	harvestStart, err := time.ParseInLocation("2006-01-02", start, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid harvest_start: use YYYY-MM-DD")
+	}
+	harvestEnd, err := time.ParseInLocation("2006-01-02", end, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid harvest_end: use YYYY-MM-DD")
+	}
+	if !today().Before(harvestStart) {
+		return time.Time{}, time.Time{}, errors.New("invalid harvest_start: must be after today")
+	}
+	if harvestEnd.Before(harvestStart) {
+		return time.Time{}, time.Time{}, errors.New("invalid harvest_end: must not be before harvest_start")
+	}
+	return harvestStart, harvestEnd, nil
+}
+
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+}
+
+func cancellationMessage(preOrder *models.PreOrder, cause string) string {
+	return "Pre-order Anda " + cause + "." + refundNote(preOrder.PendingRefund())
+}
+
+func refundNote(amount float64) string {
+	if amount <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" Dana sebesar Rp%.0f akan dikembalikan ke Anda.", amount)
+}
+
+func toPreOrderCampaignResponses(campaigns []models.PreOrderCampaign) []dto.PreOrderCampaignResponse {
+	responses := make([]dto.PreOrderCampaignResponse, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		responses = append(responses, toPreOrderCampaignResponse(campaign, campaign.Variant))
+	}
+	return responses
+}
+
+func toPreOrderCampaignResponse(campaign models.PreOrderCampaign, variant *models.ProductVariant) dto.PreOrderCampaignResponse {
+	response := dto.PreOrderCampaignResponse{
+		ID:             campaign.ID,
+		ProductID:      campaign.ProductID,
+		VariantID:      campaign.VariantID,
+		Price:          campaign.Price,
+		Quota:          campaign.Quota,
+		ReservedQuota:  campaign.ReservedQuota,
+		RemainingQuota: campaign.RemainingQuota(),
+		DepositPercent: campaign.DepositPercent,
+		HarvestStart:   campaign.HarvestStart.Format("2006-01-02"),
+		HarvestEnd:     campaign.HarvestEnd.Format("2006-01-02"),
+		Status:         campaign.Status,
+		ActualQuantity: campaign.ActualQuantity,
+		HarvestedAt:    campaign.HarvestedAt,
+		Notes:          campaign.Notes,
+		CreatedAt:      campaign.CreatedAt,
+	}
+	if variant != nil {
+		response.VariantName = variant.Name
+		response.Unit = variant.Unit
+	}
+	return response
+}
+
+func toPreOrderResponse(preOrder models.PreOrder) dto.PreOrderResponse {
+	response := dto.PreOrderResponse{
+		ID:                 preOrder.ID,
+		CampaignID:         preOrder.CampaignID,
+		OrderID:            preOrder.OrderID,
+		Quantity:           preOrder.Quantity,
+		AllocatedQuantity:  preOrder.AllocatedQuantity,
+		UnitPrice:          preOrder.UnitPrice,
+		TotalAmount:        preOrder.TotalAmount,
+		DepositAmount:      preOrder.DepositAmount,
+		PaidAmount:         preOrder.PaidAmount,
+		OutstandingBalance: preOrder.OutstandingBalance(),
+		Status:             preOrder.Status,
+		BalanceDueAt:       preOrder.BalanceDueAt,
+		RefundAmount:       preOrder.RefundAmount,
+		RefundedAmount:     preOrder.RefundedAmount,
+		PendingRefund:      preOrder.PendingRefund(),
+		RefundStatus:       preOrder.RefundStatus,
+		RefundReason:       preOrder.RefundReason,
+		RefundedAt:         preOrder.RefundedAt,
+		CreatedAt:          preOrder.CreatedAt,
+	}
+	if preOrder.Status == models.PreOrderStatusCancelled {
+		response.OutstandingBalance = 0
+	}
+	if preOrder.User != nil {
+		response.BuyerName = preOrder.User.Name
+	}
+	if c := preOrder.Campaign; c != nil {
+		response.ProductID = c.ProductID
+		response.HarvestStart = c.HarvestStart.Format("2006-01-02")
+		response.HarvestEnd = c.HarvestEnd.Format("2006-01-02")
+		if c.Product != nil {
+			response.ProductTitle = c.Product.Title
+		}
+		if c.Variant != nil {
+			response.VariantName = c.Variant.Name
+			response.Unit = c.Variant.Unit
+		}
+	}
+	return response
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"gorm.io/gorm"
+)
+
+// PriceLine adalah satu baris belanja yang harganya perlu dihitung.
+type PriceLine struct {
+	VariantID uuid.UUID
+	BasePrice float64
+	Quantity  float64
+}
+
+// PriceQuote adalah harga satuan yang berlaku untuk satu baris belanja beserta tier-nya.
+type PriceQuote struct {
+	UnitPrice float64
+	TierID    *uuid.UUID
+}
+
+// PriceTierService mengelola harga grosir bertingkat per varian dan menghitung harga
+// yang berlaku untuk pembeli berdasarkan kuantitas dan grup pembelinya.
+type PriceTierService interface {
+	GetVariantTiers(productID, variantID, farmerID uuid.UUID) ([]dto.PriceTierResponse, error)
+	ReplaceVariantTiers(productID, variantID uuid.UUID, input dto.PriceTierSetRequest, farmerID uuid.UUID) ([]dto.PriceTierResponse, error)
+	QuotePrices(tx *gorm.DB, userID uuid.UUID, lines []PriceLine) (map[uuid.UUID]PriceQuote, error)
+}
+
+type priceTierService struct {
+	tierRepo    repositories.PriceTierRepository
+	groupRepo   repositories.BuyerGroupRepository
+	productRepo repositories.ProductRepository
+	db          *gorm.DB
+}
+
+func NewPriceTierService(
+	tierRepo repositories.PriceTierRepository,
+	groupRepo repositories.BuyerGroupRepository,
+	productRepo repositories.ProductRepository,
+	db *gorm.DB,
+) PriceTierService {
+	return &priceTierService{
+		tierRepo:    tierRepo,
+		groupRepo:   groupRepo,
+		productRepo: productRepo,
+		db:          db,
+	}
+}
+
+func (s *priceTierService) GetVariantTiers(productID, variantID, farmerID uuid.UUID) ([]dto.PriceTierResponse, error) {
+	product, err := findOwnedProduct(s.productRepo, productID, farmerID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := variantOfProduct(product, variantID); err != nil {
+		return nil, err
+	}
+	tiers, err := s.tierRepo.FindByVariantID(variantID)
+	if err != nil {
+		return nil, err
+	}
+	return toPriceTierResponses(tiers), nil
+}
+
+// ReplaceVariantTiers mengganti seluruh tier harga satu varian. Ambang kuantitas harus
+// unik per grup agar harga yang berlaku selalu jelas.
+func (s *priceTierService) ReplaceVariantTiers(productID, variantID uuid.UUID, input dto.PriceTierSetRequest, farmerID uuid.UUID) ([]dto.PriceTierResponse, error) {
+	product, err := findOwnedProduct(s.productRepo, productID, farmerID)
+	if err != nil {
+		return nil, err
+	}
+	variant, err := variantOfProduct(product, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(input.Tiers))
+	tiers := make([]models.PriceTier, 0, len(input.Tiers))
+	for _, t := range input.Tiers {
+		minQuantity := models.RoundQuantity(t.MinQuantity)
+		groupKey := ""
+		if t.BuyerGroupID != nil {
+			if _, err := s.groupRepo.FindByID(*t.BuyerGroupID); err != nil {
+				return nil, fmt.Errorf("buyer group %s not found", *t.BuyerGroupID)
+			}
+			groupKey = t.BuyerGroupID.String()
+		}
+		key := fmt.Sprintf("%s|%g", groupKey, minQuantity)
+		if seen[key] {
+			return nil, fmt.Errorf("invalid tiers: duplicate min_quantity %g %s for the same buyer group", minQuantity, variant.Unit)
+		}
+		seen[key] = true
+		tiers = append(tiers, models.PriceTier{
+			ProductID:    productID,
+			VariantID:    variantID,
+			BuyerGroupID: t.BuyerGroupID,
+			MinQuantity:  minQuantity,
+			Price:        roundTo(t.Price, 2),
+		})
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.tierRepo.ReplaceForVariant(tx, variantID, tiers)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to save price tiers: %w", err)
+	}
+	return s.GetVariantTiers(productID, variantID, farmerID)
+}
+
+// QuotePrices menghitung harga satuan setiap baris untuk pembeli: harga termurah antara
+// harga dasar varian, tier umum, dan tier grup pembeli yang ambang kuantitasnya terpenuhi.
+func (s *priceTierService) QuotePrices(tx *gorm.DB, userID uuid.UUID, lines []PriceLine) (map[uuid.UUID]PriceQuote, error) {
+	groupID, err := s.groupRepo.FindActiveGroupIDByUserID(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	variantIDs := make([]uuid.UUID, 0, len(lines))
+	for _, line := range lines {
+		variantIDs = append(variantIDs, line.VariantID)
+	}
+	tiers, err := s.tierRepo.FindApplicable(tx, variantIDs, groupID)
+	if err != nil {
+		return nil, err
+	}
+	byVariant := make(map[uuid.UUID][]models.PriceTier)
+	for _, tier := range tiers {
+		byVariant[tier.VariantID] = append(byVariant[tier.VariantID], tier)
+	}
+
+	quotes := make(map[uuid.UUID]PriceQuote, len(lines))
+	for _, line := range lines {
+		price, tier := models.BestPrice(line.BasePrice, line.Quantity, byVariant[line.VariantID])
+		quote := PriceQuote{UnitPrice: price}
+		if tier != nil {
+			quote.TierID = &tier.ID
+		}
+		quotes[line.VariantID] = quote
+	}
+	return quotes, nil
+}
+
+// quoteUnitPrice adalah jalan pintas untuk satu baris belanja.
+func quoteUnitPrice(pricing PriceTierService, tx *gorm.DB, userID uuid.UUID, variant *models.ProductVariant, quantity float64) (PriceQuote, error) {
+	quotes, err := pricing.QuotePrices(tx, userID, []PriceLine{{VariantID: variant.ID, BasePrice: variant.Price, Quantity: quantity}})
+	if err != nil {
+		return PriceQuote{}, err
+	}
+	quote, ok := quotes[variant.ID]
+	if !ok {
+		return PriceQuote{}, errors.New("failed to quote price")
+	}
+	return quote, nil
+}
+
+func toPriceTierResponses(tiers []models.PriceTier) []dto.PriceTierResponse {
+	responses := make([]dto.PriceTierResponse, 0, len(tiers))
+	for _, tier := range tiers {
+		response := dto.PriceTierResponse{
+			ID:           tier.ID,
+			BuyerGroupID: tier.BuyerGroupID,
+			MinQuantity:  tier.MinQuantity,
+			Price:        tier.Price,
+		}
+		if tier.BuyerGroup != nil {
+			response.BuyerGroupName = tier.BuyerGroup.Name
+		}
+		responses = append(responses, response)
+	}
+	return responses
+}
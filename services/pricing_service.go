@@ -0,0 +1,133 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+)
+
+// defaultDeliveryFare dipakai untuk pengiriman lama yang belum memiliki harga terkunci.
+const defaultDeliveryFare = 150000.0
+
+type PricingService interface {
+	ParsePricingScheme(raw string) (*dto.DriverPricingScheme, error)
+	QuoteDelivery(driver *models.Driver, delivery *models.Delivery) (*dto.DeliveryQuote, error)
+}
+
+type pricingService struct{}
+
+func NewPricingService() PricingService {
+	return &pricingService{}
+}
+
+// ParsePricingScheme mengurai dan memvalidasi JSON skema harga milik driver.
+func (s *pricingService) ParsePricingScheme(raw string) (*dto.DriverPricingScheme, error) {
+	if strings.TrimSpace(raw) == "" || raw == "null" {
+		return nil, errors.New("pricing scheme is empty")
+	}
+
+	var scheme dto.DriverPricingScheme
+	if err := json.Unmarshal([]byte(raw), &scheme); err != nil {
+		return nil, fmt.Errorf("invalid pricing scheme format: %w", err)
+	}
+
+	if scheme.BaseFare < 0 || scheme.PerKm < 0 || scheme.PerKg < 0 || scheme.MinimumFare < 0 {
+		return nil, errors.New("pricing scheme values cannot be negative")
+	}
+	for vehicle, surcharge := range scheme.VehicleSurcharges {
+		if surcharge < 0 {
+			return nil, fmt.Errorf("surcharge for vehicle %s cannot be negative", vehicle)
+		}
+	}
+	if scheme.BaseFare == 0 && scheme.PerKm == 0 && scheme.PerKg == 0 && scheme.MinimumFare == 0 {
+		return nil, errors.New("pricing scheme does not define any fare")
+	}
+
+	return &scheme, nil
+}
+
+// QuoteDelivery menghitung harga pengiriman berdasarkan skema harga driver,
+// jarak tempuh, dan berat barang. Jika driver memiliki beberapa jenis kendaraan,
+// kendaraan termurah yang sanggup membawa muatan yang dipakai.
+func (s *pricingService) QuoteDelivery(driver *models.Driver, delivery *models.Delivery) (*dto.DeliveryQuote, error) {
+	scheme, err := s.ParsePricingScheme(driver.PricingScheme)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tarif per-km hanya dihitung dari rute server, bukan estimasi jarak dari klien
+	if delivery.RouteSource == nil || delivery.EstimatedDistanceKm == nil {
+		return nil, errors.New("invalid quote: delivery has no route estimate yet, destination coordinates are required")
+	}
+	distanceKm := *delivery.EstimatedDistanceKm
+
+	var vehicles []string
+	if driver.VehicleTypes != "" {
+		_ = json.Unmarshal([]byte(driver.VehicleTypes), &vehicles)
+	}
+	if len(vehicles) == 0 {
+		vehicles = []string{""}
+	}
+
+	var best *dto.DeliveryQuote
+	for _, vehicle := range vehicles {
+		if !vehicleCanCarry(vehicle, delivery.ItemWeight) {
+			continue
+		}
+		quote := calculateQuote(scheme, vehicle, distanceKm, delivery.ItemWeight)
+		if best == nil || quote.TotalPrice < best.TotalPrice {
+			best = quote
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no vehicle of this driver can carry %.0f kg", delivery.ItemWeight)
+	}
+	best.QuoteID = quoteFingerprint(driver, delivery, best)
+	return best, nil
+}
+
+// quoteFingerprint menghasilkan ID penawaran dari driver, pengiriman, dan rincian harganya.
+// ID berubah bila driver mengubah skema harga atau jarak/berat pengiriman berubah.
+func quoteFingerprint(driver *models.Driver, delivery *models.Delivery, quote *dto.DeliveryQuote) string {
+	details, _ := json.Marshal(quote)
+	sum := sha256.Sum256([]byte(driver.UserID.String() + "|" + delivery.ID.String() + "|" + string(details)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// vehicleCanCarry memeriksa kapasitas kendaraan terhadap berat muatan. Jenis kendaraan
+// yang kapasitasnya tidak diketahui tetap boleh dipakai, sama seperti pada pencocokan driver.
+func vehicleCanCarry(vehicle string, weightKg float64) bool {
+	capacity, ok := vehicleCapacityKg[strings.ToLower(strings.TrimSpace(vehicle))]
+	return !ok || capacity >= weightKg
+}
+
+func calculateQuote(scheme *dto.DriverPricingScheme, vehicle string, distanceKm, weightKg float64) *dto.DeliveryQuote {
+	quote := &dto.DeliveryQuote{
+		VehicleType:      vehicle,
+		DistanceKm:       distanceKm,
+		WeightKg:         weightKg,
+		BaseFare:         scheme.BaseFare,
+		DistanceFare:     roundRupiah(scheme.PerKm * distanceKm),
+		WeightFare:       roundRupiah(scheme.PerKg * weightKg),
+		VehicleSurcharge: scheme.VehicleSurcharges[vehicle],
+		MinimumFare:      scheme.MinimumFare,
+	}
+
+	total := quote.BaseFare + quote.DistanceFare + quote.WeightFare + quote.VehicleSurcharge
+	if total < scheme.MinimumFare {
+		total = scheme.MinimumFare
+	}
+	quote.TotalPrice = roundRupiah(total)
+	return quote
+}
+
+func roundRupiah(amount float64) float64 {
+	return math.Round(amount)
+}
@@ -3,6 +3,9 @@ package services
 import (
 	"encoding/json" // Tambahkan import ini
 	"errors"
+	"fmt"
+	"math"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/whsasmita/AgroLink_API/dto"
@@ -14,20 +17,34 @@ import (
 
 type ProductService interface {
 	CreateProduct(input dto.CreateProductInput, farmerID uuid.UUID) (*dto.ProductResponse, error)
-	GetAllProducts() ([]dto.ProductResponse, error)
+	SearchProducts(filter dto.ProductSearchRequest) (*dto.ProductSearchResponse, error)
 	GetMyProducts(farmerID uuid.UUID) ([]dto.ProductResponse, error)
 	GetProductByID(productID uuid.UUID) (*dto.ProductResponse, error)
 	UpdateProduct(productID uuid.UUID, input dto.UpdateProductInput, farmerID uuid.UUID) (*dto.ProductResponse, error)
 	DeleteProduct(productID uuid.UUID, farmerID uuid.UUID) error
+	AddVariant(productID uuid.UUID, input dto.ProductVariantInput, farmerID uuid.UUID) (*models.ProductVariant, error)
+	UpdateVariant(productID, variantID uuid.UUID, input dto.ProductVariantInput, farmerID uuid.UUID) (*models.ProductVariant, error)
+	DeleteVariant(productID, variantID uuid.UUID, farmerID uuid.UUID) error
 }
 
 type productService struct {
-	productRepo repositories.ProductRepository
-	db          *gorm.DB
+	productRepo  repositories.ProductRepository
+	variantRepo  repositories.ProductVariantRepository
+	categoryRepo repositories.CategoryRepository
+	inventory    InventoryService
+	db           *gorm.DB
 }
 
-func NewProductService(repo repositories.ProductRepository, db *gorm.DB) ProductService {
-	return &productService{productRepo: repo, db: db}
+func NewProductService(repo repositories.ProductRepository, variantRepo repositories.ProductVariantRepository, categoryRepo repositories.CategoryRepository, inventory InventoryService, db *gorm.DB) ProductService {
+	return &productService{productRepo: repo, variantRepo: variantRepo, categoryRepo: categoryRepo, inventory: inventory, db: db}
+}
+
+func productCategorySummary(product models.Product) *dto.CategorySummary {
+	if product.Category == nil {
+		return nil
+	}
+	summary := toCategorySummary(*product.Category)
+	return &summary
 }
 
 // Fungsi helper untuk transformasi dari Model ke DTO
@@ -36,23 +53,29 @@ func toPublicProductResponse(product models.Product) dto.ProductResponse {
 	if product.ImageURLs != nil {
 		json.Unmarshal(product.ImageURLs, &imageURLs)
 	}
-	availableStock := product.Stock - product.ReservedStock
-	if availableStock < 0 {
-		availableStock = 0
+	variants := make([]dto.ProductVariantResponse, 0, len(product.Variants))
+	for _, v := range product.Variants {
+		variants = append(variants, toVariantResponse(v, false)) // Hanya tampilkan stok tersedia
 	}
 
 	return dto.ProductResponse{
-		ID:             product.ID,
-		FarmerID:       product.FarmerID,
-		FarmerName:     product.Farmer.User.Name,
-		Title:          product.Title,
-		Description:    product.Description,
-		Rating:         product.Rating,
-		Price:          product.Price,
-		AvailableStock: &availableStock, // Hanya tampilkan stok tersedia
-		Category:       product.Category,
-		Location:       product.Location,
-		ImageURLs:      imageURLs,
+		ID:          product.ID,
+		FarmerID:    product.FarmerID,
+		FarmerName:  product.Farmer.User.Name,
+		Title:       product.Title,
+		Description: product.Description,
+		Rating:      product.Rating,
+		Price:       product.Price,
+		CategoryID:  product.CategoryID,
+		Category:    productCategorySummary(product),
+		Location:    product.Location,
+		Latitude:    product.Latitude,
+		Longitude:   product.Longitude,
+		ImageURLs:   imageURLs,
+		Variants:    variants,
+
+		Availability:     productAvailability(product),
+		UpcomingHarvests: upcomingHarvests(product),
 	}
 }
 
@@ -62,25 +85,92 @@ func toFarmerProductResponse(product models.Product) dto.ProductResponse {
 	if product.ImageURLs != nil {
 		json.Unmarshal(product.ImageURLs, &imageURLs)
 	}
-	availableStock := product.Stock - product.ReservedStock
-	if availableStock < 0 {
-		availableStock = 0
+	variants := make([]dto.ProductVariantResponse, 0, len(product.Variants))
+	for _, v := range product.Variants {
+		variants = append(variants, toVariantResponse(v, true)) // Tampilkan stok total & reservasi
 	}
 
 	return dto.ProductResponse{
-		ID:             product.ID,
-		FarmerID:       product.FarmerID,
-		FarmerName:     product.Farmer.User.Name,
-		Title:          product.Title,
-		Description:    product.Description,
-		Price:          product.Price,
-		AvailableStock: &availableStock,
-		Stock:          &product.Stock,         // Tampilkan stok total
-		ReservedStock:  &product.ReservedStock, // Tampilkan stok direservasi
-		Category:       product.Category,
-		Location:       product.Location,
-		ImageURLs:      imageURLs,
+		ID:          product.ID,
+		FarmerID:    product.FarmerID,
+		FarmerName:  product.Farmer.User.Name,
+		Title:       product.Title,
+		Description: product.Description,
+		Price:       product.Price,
+		CategoryID:  product.CategoryID,
+		Category:    productCategorySummary(product),
+		Location:    product.Location,
+		Latitude:    product.Latitude,
+		Longitude:   product.Longitude,
+		ImageURLs:   imageURLs,
+		Variants:    variants,
+
+		Availability:     productAvailability(product),
+		UpcomingHarvests: upcomingHarvests(product),
+	}
+}
+
+func toVariantResponse(variant models.ProductVariant, forOwner bool) dto.ProductVariantResponse {
+	response := dto.ProductVariantResponse{
+		ID:             variant.ID,
+		Name:           variant.Name,
+		SKU:            variant.SKU,
+		Unit:           variant.Unit,
+		Price:          variant.Price,
+		AvailableStock: variant.AvailableStock(),
+		MinOrderQty:    variant.MinOrderQty,
+		QuantityStep:   variant.QuantityStep,
+	}
+	if forOwner {
+		response.Stock = &variant.Stock
+		response.ReservedStock = &variant.ReservedStock
+		response.LowStockThreshold = variant.LowStockThreshold
+		response.PriceTiers = toPriceTierResponses(variant.PriceTiers)
+		return response
+	}
+	// Tier grup pembeli (mis. grosir terverifikasi) tidak dipublikasikan
+	publicTiers := make([]models.PriceTier, 0, len(variant.PriceTiers))
+	for _, tier := range variant.PriceTiers {
+		if tier.BuyerGroupID == nil {
+			publicTiers = append(publicTiers, tier)
+		}
+	}
+	response.PriceTiers = toPriceTierResponses(publicTiers)
+	return response
+}
+
+// productAvailability menentukan status ketersediaan produk untuk halaman produk.
+// RestockSchedules diharapkan sudah di-preload hanya berisi jadwal mendatang.
+func productAvailability(product models.Product) string {
+	for _, v := range product.Variants {
+		if v.AvailableStock() > 0 {
+			return dto.AvailabilityInStock
+		}
+	}
+	if len(product.RestockSchedules) > 0 {
+		return dto.AvailabilityComingSoon
 	}
+	return dto.AvailabilityOutOfStock
+}
+
+func upcomingHarvests(product models.Product) []dto.RestockScheduleSummary {
+	summaries := make([]dto.RestockScheduleSummary, 0, len(product.RestockSchedules))
+	for _, schedule := range product.RestockSchedules {
+		summary := dto.RestockScheduleSummary{
+			VariantID:        schedule.VariantID,
+			ExpectedQuantity: schedule.ExpectedQuantity,
+			ExpectedDate:     schedule.ExpectedDate.Format("2006-01-02"),
+		}
+		for _, v := range product.Variants {
+			if v.ID == schedule.VariantID {
+				summary.VariantName = v.Name
+				summary.Unit = v.Unit
+				break
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
 }
 
 func (s *productService) GetMyProducts(farmerID uuid.UUID) ([]dto.ProductResponse, error) {
@@ -103,19 +193,57 @@ func (s *productService) CreateProduct(input dto.CreateProductInput, farmerID uu
 		return nil, err
 	}
 
+	if _, err := s.categoryRepo.FindByID(input.CategoryID); err != nil {
+		return nil, errors.New("category not found")
+	}
+
+	// Varian dibuat bersama produk; harga produk = harga varian termurah.
+	// Stok awal dicatat ke ledger setelah varian tersimpan.
+	variants := make([]models.ProductVariant, 0, len(input.Variants))
+	initialStock := make([]float64, 0, len(input.Variants))
+	seenSKU := make(map[string]bool)
+	for _, v := range input.Variants {
+		variant := models.ProductVariant{}
+		if err := s.applyVariantInput(&variant, v); err != nil {
+			return nil, err
+		}
+		if variant.SKU != nil {
+			if seenSKU[*variant.SKU] {
+				return nil, fmt.Errorf("invalid sku: %s is used more than once", *variant.SKU)
+			}
+			seenSKU[*variant.SKU] = true
+		}
+		initialStock = append(initialStock, variant.Stock)
+		variant.Stock = 0
+		variants = append(variants, variant)
+	}
+
 	product := models.Product{
 		ID:          uuid.New(),
 		FarmerID:    farmerID,
 		Title:       input.Title,
 		Description: input.Description,
-		Price:       input.Price,
-		Stock:       input.Stock,
-		Category:    &input.Category,
+		Price:       lowestVariantPrice(variants),
+		CategoryID:  &input.CategoryID,
 		Location:    &input.Location,
+		Latitude:    input.Latitude,
+		Longitude:   input.Longitude,
 		ImageURLs:   datatypes.JSON(imageURLsJSON),
+		Variants:    variants,
 	}
 
-	if err := s.productRepo.Create(&product); err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.productRepo.Create(tx, &product); err != nil {
+			return err
+		}
+		for i := range product.Variants {
+			if err := s.recordInitialStock(tx, &product.Variants[i], initialStock[i], farmerID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 	createdProduct, _ := s.productRepo.FindByID(product.ID)
@@ -123,16 +251,49 @@ func (s *productService) CreateProduct(input dto.CreateProductInput, farmerID uu
 	return &response, nil
 }
 
-func (s *productService) GetAllProducts() ([]dto.ProductResponse, error) {
-	products, err := s.productRepo.FindAll()
+// SearchProducts menjalankan pencarian katalog publik beserta facet kategori.
+func (s *productService) SearchProducts(filter dto.ProductSearchRequest) (*dto.ProductSearchResponse, error) {
+	if filter.Category != "" {
+		category, err := s.categoryRepo.FindBySlug(filter.Category)
+		if err != nil {
+			return nil, errors.New("category not found")
+		}
+		categories, err := s.categoryRepo.FindAll()
+		if err != nil {
+			return nil, err
+		}
+		filter.CategoryIDs = descendantCategoryIDs(categories, category.ID)
+	}
+
+	products, total, err := s.productRepo.Search(filter)
 	if err != nil {
 		return nil, err
 	}
-	var responses []dto.ProductResponse
+	facets, err := s.productRepo.CategoryFacets(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.ProductResponse, 0, len(products))
 	for _, p := range products {
-		responses = append(responses, toPublicProductResponse(p))
+		response := toPublicProductResponse(p)
+		if p.DistanceKm != nil {
+			distance := roundTo(*p.DistanceKm, 2)
+			response.DistanceKm = &distance
+		}
+		responses = append(responses, response)
 	}
-	return responses, nil
+
+	return &dto.ProductSearchResponse{
+		PaginationResponse: dto.PaginationResponse{
+			Data:       responses,
+			Total:      total,
+			Page:       filter.Page,
+			Limit:      filter.Limit,
+			TotalPages: int(math.Ceil(float64(total) / float64(filter.Limit))),
+		},
+		Facets: facets,
+	}, nil
 }
 
 func (s *productService) GetProductByID(productID uuid.UUID) (*dto.ProductResponse, error) {
@@ -166,19 +327,19 @@ func (s *productService) UpdateProduct(productID uuid.UUID, input dto.UpdateProd
 		if input.Description != "" {
 			product.Description = input.Description
 		}
-		if input.Price > 0 {
-			product.Price = input.Price
-		}
-		// Pengecekan 'Stock' bisa >= 0 jika Anda ingin mengizinkan stok menjadi 0
-		if input.Stock >= 0 {
-			product.Stock = input.Stock
-		}
-		if input.Category != "" {
-			product.Category = &input.Category
+		if input.CategoryID != nil {
+			if _, err := s.categoryRepo.FindByID(*input.CategoryID); err != nil {
+				return errors.New("category not found")
+			}
+			product.CategoryID = input.CategoryID
 		}
 		if input.Location != "" {
 			product.Location = &input.Location
 		}
+		if input.Latitude != nil && input.Longitude != nil {
+			product.Latitude = input.Latitude
+			product.Longitude = input.Longitude
+		}
 		// Cek jika array ImageURLs di-provide (bisa juga array kosong untuk menghapus semua gambar)
 		if input.ImageURLs != nil {
 			imageURLsJSON, err := json.Marshal(input.ImageURLs)
@@ -201,7 +362,10 @@ func (s *productService) UpdateProduct(productID uuid.UUID, input dto.UpdateProd
 		return nil, err
 	}
 
-	// 5. Kembalikan data yang sudah diperbarui dengan format DTO yang benar
+	// 5. Kembalikan data yang sudah diperbarui (beserta relasi petani & kategori)
+	if reloaded, err := s.productRepo.FindByID(updatedProduct.ID); err == nil {
+		updatedProduct = reloaded
+	}
 	response := toFarmerProductResponse(*updatedProduct)
 	return &response, nil
 }
@@ -216,3 +380,171 @@ func (s *productService) DeleteProduct(productID uuid.UUID, farmerID uuid.UUID)
 	}
 	return s.productRepo.Delete(productID)
 }
+
+// AddVariant menambah satuan jual baru pada produk milik petani.
+func (s *productService) AddVariant(productID uuid.UUID, input dto.ProductVariantInput, farmerID uuid.UUID) (*models.ProductVariant, error) {
+	if _, err := s.findOwnedProduct(productID, farmerID); err != nil {
+		return nil, err
+	}
+
+	variant := &models.ProductVariant{ProductID: productID}
+	if err := s.applyVariantInput(variant, input); err != nil {
+		return nil, err
+	}
+	initialStock := variant.Stock
+	variant.Stock = 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.variantRepo.Create(tx, variant); err != nil {
+			return fmt.Errorf("failed to create variant: %w", err)
+		}
+		if err := s.recordInitialStock(tx, variant, initialStock, farmerID); err != nil {
+			return err
+		}
+		return s.variantRepo.SyncProductPrice(tx, productID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+// UpdateVariant mengubah varian. Stok tidak boleh diturunkan di bawah jumlah yang
+// sedang direservasi keranjang atau pesanan yang belum dibayar.
+func (s *productService) UpdateVariant(productID, variantID uuid.UUID, input dto.ProductVariantInput, farmerID uuid.UUID) (*models.ProductVariant, error) {
+	if _, err := s.findOwnedProduct(productID, farmerID); err != nil {
+		return nil, err
+	}
+
+	var updated *models.ProductVariant
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		variant, err := s.variantRepo.FindByIDForUpdate(tx, variantID)
+		if err != nil || variant.ProductID != productID {
+			return errors.New("variant not found")
+		}
+		previousStock := variant.Stock
+		if err := s.applyVariantInput(variant, input); err != nil {
+			return err
+		}
+		if variant.Stock < variant.ReservedStock {
+			return fmt.Errorf("invalid stock: cannot be lower than reserved quantity %g %s", variant.ReservedStock, variant.Unit)
+		}
+
+		// Perubahan stok tidak ditulis langsung, melainkan lewat ledger inventaris
+		stockDelta := models.RoundQuantity(variant.Stock - previousStock)
+		variant.Stock = previousStock
+		if err := s.variantRepo.Update(tx, variant); err != nil {
+			return fmt.Errorf("failed to update variant: %w", err)
+		}
+		movementType := models.MovementAdjustment
+		if stockDelta > 0 {
+			movementType = models.MovementRestock
+		}
+		change := StockChange{Type: movementType, StockDelta: stockDelta, ActorID: &farmerID, Note: "Ubah stok lewat edit varian"}
+		if err := s.inventory.ApplyStockChange(tx, variant, change); err != nil {
+			return err
+		}
+		updated = variant
+		return s.variantRepo.SyncProductPrice(tx, productID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteVariant menghapus varian yang tidak sedang direservasi. Produk harus tetap
+// memiliki minimal satu varian.
+func (s *productService) DeleteVariant(productID, variantID uuid.UUID, farmerID uuid.UUID) error {
+	if _, err := s.findOwnedProduct(productID, farmerID); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		variant, err := s.variantRepo.FindByIDForUpdate(tx, variantID)
+		if err != nil || variant.ProductID != productID {
+			return errors.New("variant not found")
+		}
+		variants, err := s.variantRepo.FindAllByProductID(tx, productID)
+		if err != nil {
+			return err
+		}
+		if len(variants) <= 1 {
+			return errors.New("invalid state: a product must keep at least one variant")
+		}
+		if variant.ReservedStock > 0 {
+			return errors.New("invalid state: variant is reserved in carts or unpaid orders")
+		}
+		if err := s.variantRepo.Delete(tx, variant); err != nil {
+			return err
+		}
+		return s.variantRepo.SyncProductPrice(tx, productID)
+	})
+}
+
+// recordInitialStock mencatat stok awal varian baru sebagai restock di ledger.
+func (s *productService) recordInitialStock(tx *gorm.DB, variant *models.ProductVariant, quantity float64, farmerID uuid.UUID) error {
+	change := StockChange{Type: models.MovementRestock, StockDelta: quantity, ActorID: &farmerID, Note: "Stok awal"}
+	return s.inventory.ApplyStockChange(tx, variant, change)
+}
+
+func (s *productService) findOwnedProduct(productID, farmerID uuid.UUID) (*models.Product, error) {
+	return findOwnedProduct(s.productRepo, productID, farmerID)
+}
+
+// findOwnedProduct mengambil produk dan memastikan petani yang meminta adalah pemiliknya.
+func findOwnedProduct(productRepo repositories.ProductRepository, productID, farmerID uuid.UUID) (*models.Product, error) {
+	product, err := productRepo.FindByID(productID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+	if product.FarmerID != farmerID {
+		return nil, errors.New("forbidden: you are not the owner of this product")
+	}
+	return product, nil
+}
+
+// applyVariantInput mengisi field varian beserta default minimum order & kelipatan,
+// serta memastikan SKU belum dipakai varian lain.
+func (s *productService) applyVariantInput(variant *models.ProductVariant, input dto.ProductVariantInput) error {
+	variant.Name = strings.TrimSpace(input.Name)
+	variant.Unit = strings.TrimSpace(input.Unit)
+	variant.Price = input.Price
+	variant.Stock = models.RoundQuantity(input.Stock)
+	variant.SortOrder = input.SortOrder
+	variant.LowStockThreshold = nil
+	if input.LowStockThreshold != nil {
+		threshold := models.RoundQuantity(*input.LowStockThreshold)
+		variant.LowStockThreshold = &threshold
+	}
+	variant.MinOrderQty, variant.QuantityStep = 1, 1
+	if input.QuantityStep != nil {
+		variant.QuantityStep = models.RoundQuantity(*input.QuantityStep)
+		variant.MinOrderQty = variant.QuantityStep
+	}
+	if input.MinOrderQty != nil {
+		variant.MinOrderQty = models.RoundQuantity(*input.MinOrderQty)
+	}
+	if variant.QuantityStep <= 0 || variant.MinOrderQty <= 0 {
+		return errors.New("invalid variant: min_order_qty and quantity_step must be at least 0.001")
+	}
+
+	variant.SKU = nil
+	if input.SKU != nil && strings.TrimSpace(*input.SKU) != "" {
+		sku := strings.TrimSpace(*input.SKU)
+		if existing, err := s.variantRepo.FindBySKU(sku); err == nil && existing.ID != variant.ID {
+			return fmt.Errorf("invalid sku: %s is already used", sku)
+		}
+		variant.SKU = &sku
+	}
+	return nil
+}
+
+func lowestVariantPrice(variants []models.ProductVariant) float64 {
+	var lowest float64
+	for i, v := range variants {
+		if i == 0 || v.Price < lowest {
+			lowest = v.Price
+		}
+	}
+	return lowest
+}
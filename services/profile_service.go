@@ -154,6 +154,10 @@ func (s *profileService) UpdateRoleDetails(userID string, userRole string, input
 		if err := json.Unmarshal(input.Details, &details); err != nil {
 			return nil, fmt.Errorf("invalid expedition details format: %w", err)
 		}
+		// Skema harga harus bisa dibaca oleh PricingService
+		if _, err := NewPricingService().ParsePricingScheme(string(details.PricingScheme)); err != nil {
+			return nil, fmt.Errorf("invalid pricing scheme: %w", err)
+		}
 		pricingJSON, _ := json.Marshal(details.PricingScheme)
 		vehiclesJSON, _ := json.Marshal(details.VehicleTypes)
 
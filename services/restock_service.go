@@ -0,0 +1,340 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"gorm.io/gorm"
+)
+
+// Jumlah langganan back-in-stock maksimal yang diproses dalam satu putaran job.
+const backInStockBatchSize = 200
+
+// RestockService mengelola jadwal panen petani, langganan back-in-stock pembeli,
+// dan job notifikasi stok menipis / stok kembali tersedia.
+type RestockService interface {
+	GetSchedules(productID, farmerID uuid.UUID) ([]dto.RestockScheduleResponse, error)
+	CreateSchedule(productID uuid.UUID, input dto.RestockScheduleRequest, farmerID uuid.UUID) (*dto.RestockScheduleResponse, error)
+	UpdateSchedule(productID, scheduleID uuid.UUID, input dto.RestockScheduleRequest, farmerID uuid.UUID) (*dto.RestockScheduleResponse, error)
+	CancelSchedule(productID, scheduleID, farmerID uuid.UUID) error
+	MarkHarvested(productID, scheduleID uuid.UUID, input dto.HarvestRequest, farmerID uuid.UUID) (*dto.RestockScheduleResponse, error)
+	Subscribe(productID, userID uuid.UUID) (*dto.RestockSubscriptionResponse, error)
+	Unsubscribe(productID, userID uuid.UUID) error
+	ProcessStockAlerts() error
+	RunAlertJob(interval time.Duration)
+}
+
+type restockService struct {
+	scheduleRepo     repositories.RestockScheduleRepository
+	subscriptionRepo repositories.BackInStockSubscriptionRepository
+	productRepo      repositories.ProductRepository
+	variantRepo      repositories.ProductVariantRepository
+	inventory        InventoryService
+	notifService     NotificationService
+	db               *gorm.DB
+}
+
+func NewRestockService(
+	scheduleRepo repositories.RestockScheduleRepository,
+	subscriptionRepo repositories.BackInStockSubscriptionRepository,
+	productRepo repositories.ProductRepository,
+	variantRepo repositories.ProductVariantRepository,
+	inventory InventoryService,
+	notifService NotificationService,
+	db *gorm.DB,
+) RestockService {
+	return &restockService{
+		scheduleRepo:     scheduleRepo,
+		subscriptionRepo: subscriptionRepo,
+		productRepo:      productRepo,
+		variantRepo:      variantRepo,
+		inventory:        inventory,
+		notifService:     notifService,
+		db:               db,
+	}
+}
+
+func (s *restockService) GetSchedules(productID, farmerID uuid.UUID) ([]dto.RestockScheduleResponse, error) {
+	if _, err := findOwnedProduct(s.productRepo, productID, farmerID); err != nil {
+		return nil, err
+	}
+	schedules, err := s.scheduleRepo.FindAllByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]dto.RestockScheduleResponse, 0, len(schedules))
+	for _, schedule := range schedules {
+		responses = append(responses, toRestockScheduleResponse(schedule, schedule.Variant))
+	}
+	return responses, nil
+}
+
+// CreateSchedule mengumumkan perkiraan panen. Selama jadwal masih planned dan tanggalnya
+// belum lewat, produk yang habis tampil sebagai "segera hadir".
+func (s *restockService) CreateSchedule(productID uuid.UUID, input dto.RestockScheduleRequest, farmerID uuid.UUID) (*dto.RestockScheduleResponse, error) {
+	product, err := findOwnedProduct(s.productRepo, productID, farmerID)
+	if err != nil {
+		return nil, err
+	}
+	variant, err := variantOfProduct(product, input.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	expectedDate, err := parseHarvestDate(input.ExpectedDate)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &models.RestockSchedule{
+		ProductID:        productID,
+		VariantID:        variant.ID,
+		ExpectedQuantity: models.RoundQuantity(input.ExpectedQuantity),
+		ExpectedDate:     expectedDate,
+		Status:           models.RestockStatusPlanned,
+		Notes:            input.Notes,
+	}
+	if err := s.scheduleRepo.Create(nil, schedule); err != nil {
+		return nil, fmt.Errorf("failed to create restock schedule: %w", err)
+	}
+	response := toRestockScheduleResponse(*schedule, variant)
+	return &response, nil
+}
+
+func (s *restockService) UpdateSchedule(productID, scheduleID uuid.UUID, input dto.RestockScheduleRequest, farmerID uuid.UUID) (*dto.RestockScheduleResponse, error) {
+	product, err := findOwnedProduct(s.productRepo, productID, farmerID)
+	if err != nil {
+		return nil, err
+	}
+	variant, err := variantOfProduct(product, input.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	expectedDate, err := parseHarvestDate(input.ExpectedDate)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.plannedSchedule(nil, productID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	schedule.VariantID = variant.ID
+	schedule.ExpectedQuantity = models.RoundQuantity(input.ExpectedQuantity)
+	schedule.ExpectedDate = expectedDate
+	schedule.Notes = input.Notes
+	if err := s.scheduleRepo.Update(nil, schedule); err != nil {
+		return nil, fmt.Errorf("failed to update restock schedule: %w", err)
+	}
+	response := toRestockScheduleResponse(*schedule, variant)
+	return &response, nil
+}
+
+func (s *restockService) CancelSchedule(productID, scheduleID, farmerID uuid.UUID) error {
+	if _, err := findOwnedProduct(s.productRepo, productID, farmerID); err != nil {
+		return err
+	}
+	schedule, err := s.plannedSchedule(nil, productID, scheduleID)
+	if err != nil {
+		return err
+	}
+	schedule.Status = models.RestockStatusCancelled
+	return s.scheduleRepo.Update(nil, schedule)
+}
+
+// MarkHarvested menutup jadwal dan memasukkan hasil panen aktual ke stok lewat ledger.
+// Pelanggan back-in-stock diberi tahu oleh job notifikasi begitu stok tersedia.
+func (s *restockService) MarkHarvested(productID, scheduleID uuid.UUID, input dto.HarvestRequest, farmerID uuid.UUID) (*dto.RestockScheduleResponse, error) {
+	if _, err := findOwnedProduct(s.productRepo, productID, farmerID); err != nil {
+		return nil, err
+	}
+
+	var response dto.RestockScheduleResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		schedule, err := s.plannedSchedule(tx, productID, scheduleID)
+		if err != nil {
+			return err
+		}
+		variant, err := s.variantRepo.FindByIDForUpdate(tx, schedule.VariantID)
+		if err != nil {
+			return errors.New("variant not found")
+		}
+
+		quantity := models.RoundQuantity(input.ActualQuantity)
+		change := StockChange{
+			Type:       models.MovementRestock,
+			StockDelta: quantity,
+			ActorID:    &farmerID,
+			Note:       "Panen sesuai jadwal " + schedule.ExpectedDate.Format("2006-01-02"),
+		}
+		if err := s.inventory.ApplyStockChange(tx, variant, change); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		schedule.Status = models.RestockStatusHarvested
+		schedule.ActualQuantity = &quantity
+		schedule.HarvestedAt = &now
+		if err := s.scheduleRepo.Update(tx, schedule); err != nil {
+			return err
+		}
+		response = toRestockScheduleResponse(*schedule, variant)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// plannedSchedule mengambil jadwal milik produk yang masih bisa diubah.
+func (s *restockService) plannedSchedule(tx *gorm.DB, productID, scheduleID uuid.UUID) (*models.RestockSchedule, error) {
+	schedule, err := s.scheduleRepo.FindByIDForUpdate(tx, scheduleID)
+	if err != nil || schedule.ProductID != productID {
+		return nil, errors.New("restock schedule not found")
+	}
+	if schedule.Status != models.RestockStatusPlanned {
+		return nil, fmt.Errorf("invalid state: restock schedule is already %s", schedule.Status)
+	}
+	return schedule, nil
+}
+
+// Subscribe mendaftarkan pembeli untuk notifikasi saat produk yang habis kembali tersedia.
+// Berlangganan ulang setelah pernah diberi tahu akan mengaktifkan langganan kembali.
+func (s *restockService) Subscribe(productID, userID uuid.UUID) (*dto.RestockSubscriptionResponse, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+	if productAvailability(*product) == dto.AvailabilityInStock {
+		return nil, errors.New("invalid state: product is currently in stock")
+	}
+
+	subscription, err := s.subscriptionRepo.FindByUserAndProduct(userID, productID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		subscription = &models.BackInStockSubscription{UserID: userID, ProductID: productID}
+	}
+	subscription.NotifiedAt = nil
+	if err := s.subscriptionRepo.Save(subscription); err != nil {
+		return nil, fmt.Errorf("failed to save subscription: %w", err)
+	}
+	return &dto.RestockSubscriptionResponse{ProductID: productID, SubscribedAt: subscription.UpdatedAt}, nil
+}
+
+func (s *restockService) Unsubscribe(productID, userID uuid.UUID) error {
+	if err := s.subscriptionRepo.Delete(userID, productID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("subscription not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// ProcessStockAlerts memberi tahu petani tentang varian yang stoknya menipis dan pembeli
+// tentang produk yang kembali tersedia. Dijalankan berkala oleh RunAlertJob, di luar
+// transaksi perubahan stok sehingga notifikasi tidak terkirim untuk transaksi yang batal.
+func (s *restockService) ProcessStockAlerts() error {
+	if _, err := s.variantRepo.ClearRecoveredLowStockAlerts(); err != nil {
+		return fmt.Errorf("failed to reset low-stock alerts: %w", err)
+	}
+
+	lowStock, err := s.variantRepo.FindLowStockUnalerted()
+	if err != nil {
+		return fmt.Errorf("failed to load low-stock variants: %w", err)
+	}
+	now := time.Now()
+	alerted := make([]uuid.UUID, 0, len(lowStock))
+	for _, v := range lowStock {
+		if v.Product == nil {
+			continue
+		}
+		message := fmt.Sprintf("Stok %s (%s) tersisa %g %s, di bawah ambang %g %s. Segera tambah stok atau umumkan jadwal panen berikutnya.",
+			v.Product.Title, v.Name, v.AvailableStock(), v.Unit, *v.LowStockThreshold, v.Unit)
+		s.notifService.CreateNotification(v.Product.FarmerID, "Stok Menipis: "+v.Product.Title, message,
+			fmt.Sprintf("/products/%s", v.ProductID), "warning")
+		alerted = append(alerted, v.ID)
+	}
+	if err := s.variantRepo.MarkLowStockAlerted(alerted, now); err != nil {
+		return fmt.Errorf("failed to mark low-stock alerts: %w", err)
+	}
+
+	subscriptions, err := s.subscriptionRepo.FindRestockedPending(backInStockBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to load back-in-stock subscriptions: %w", err)
+	}
+	notified := make([]uuid.UUID, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		if sub.Product == nil {
+			continue
+		}
+		s.notifService.CreateNotification(sub.UserID, "Produk Tersedia Kembali",
+			fmt.Sprintf("%s sudah tersedia kembali. Pesan sekarang sebelum kehabisan lagi.", sub.Product.Title),
+			fmt.Sprintf("/products/%s", sub.ProductID), "product")
+		notified = append(notified, sub.ID)
+	}
+	return s.subscriptionRepo.MarkNotified(notified, now)
+}
+
+// RunAlertJob menjalankan ProcessStockAlerts secara berkala. Dipanggil sebagai goroutine dari main.
+func (s *restockService) RunAlertJob(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.ProcessStockAlerts(); err != nil {
+			log.Printf("WARN: Job notifikasi stok gagal: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+func variantOfProduct(product *models.Product, variantID uuid.UUID) (*models.ProductVariant, error) {
+	for i := range product.Variants {
+		if product.Variants[i].ID == variantID {
+			return &product.Variants[i], nil
+		}
+	}
+	return nil, errors.New("variant not found")
+}
+
+// parseHarvestDate menolak tanggal panen yang sudah lewat.
+func parseHarvestDate(value string) (time.Time, error) {
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("invalid expected_date: use YYYY-MM-DD")
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if date.Before(today) {
+		return time.Time{}, errors.New("invalid expected_date: must not be in the past")
+	}
+	return date, nil
+}
+
+func toRestockScheduleResponse(schedule models.RestockSchedule, variant *models.ProductVariant) dto.RestockScheduleResponse {
+	response := dto.RestockScheduleResponse{
+		ID:               schedule.ID,
+		ProductID:        schedule.ProductID,
+		VariantID:        schedule.VariantID,
+		ExpectedQuantity: schedule.ExpectedQuantity,
+		ExpectedDate:     schedule.ExpectedDate.Format("2006-01-02"),
+		Status:           schedule.Status,
+		ActualQuantity:   schedule.ActualQuantity,
+		HarvestedAt:      schedule.HarvestedAt,
+		Notes:            schedule.Notes,
+		CreatedAt:        schedule.CreatedAt,
+	}
+	if variant != nil {
+		response.VariantName = variant.Name
+		response.Unit = variant.Unit
+	}
+	return response
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+// Parameter perhitungan statistik rute.
+const (
+	movingSpeedKmh     = 3.0             // di bawah ini driver dianggap diam
+	stopRadiusKm       = 0.05            // titik dalam radius 50 m dianggap satu lokasi
+	stopMinDuration    = 3 * time.Minute // berhenti lebih singkat tidak dicatat
+	minSpeedSampleTime = 5 * time.Second // segmen lebih pendek terlalu berisik untuk kecepatan
+)
+
+// computeRouteStats menghitung jarak tempuh, waktu bergerak, titik berhenti
+// dan kecepatan maksimum dari titik GPS yang sudah terurut berdasarkan waktu.
+func computeRouteStats(track []models.LocationTrack) dto.RouteStats {
+	stats := dto.RouteStats{PointCount: len(track), Stops: []dto.RouteStop{}}
+	if len(track) == 0 {
+		return stats
+	}
+
+	startedAt, endedAt := track[0].Timestamp, track[len(track)-1].Timestamp
+	stats.StartedAt, stats.EndedAt = &startedAt, &endedAt
+	stats.DurationMinutes = roundTo(endedAt.Sub(startedAt).Minutes(), 1)
+
+	var moving time.Duration
+	var movingKm float64
+	for i := 1; i < len(track); i++ {
+		prev, cur := track[i-1], track[i]
+		km := utils.HaversineKm(prev.Lat, prev.Lng, cur.Lat, cur.Lng)
+		elapsed := cur.Timestamp.Sub(prev.Timestamp)
+		if elapsed <= 0 {
+			continue
+		}
+		speed := km / elapsed.Hours()
+		if speed > maxPlausibleSpeedKmh {
+			continue // lompatan GPS tidak dihitung sebagai jarak tempuh
+		}
+		stats.TotalDistanceKm += km
+		if speed >= movingSpeedKmh {
+			moving += elapsed
+			movingKm += km
+		}
+		if elapsed >= minSpeedSampleTime && speed > stats.MaxSpeedKmh {
+			stats.MaxSpeedKmh = speed
+		}
+	}
+
+	stats.TotalDistanceKm = roundTo(stats.TotalDistanceKm, 2)
+	stats.MovingMinutes = roundTo(moving.Minutes(), 1)
+	stats.MaxSpeedKmh = roundTo(stats.MaxSpeedKmh, 1)
+	if moving > 0 {
+		stats.AvgMovingSpeedKmh = roundTo(movingKm/moving.Hours(), 1)
+	}
+	stats.Stops = detectStops(track)
+	return stats
+}
+
+// detectStops mengelompokkan titik berurutan yang berada dalam radius kecil
+// selama minimal stopMinDuration menjadi satu titik berhenti.
+func detectStops(track []models.LocationTrack) []dto.RouteStop {
+	stops := []dto.RouteStop{}
+	for i := 0; i < len(track); {
+		anchor := track[i]
+		j := i + 1
+		for j < len(track) && utils.HaversineKm(anchor.Lat, anchor.Lng, track[j].Lat, track[j].Lng) <= stopRadiusKm {
+			j++
+		}
+		last := track[j-1]
+		if dwell := last.Timestamp.Sub(anchor.Timestamp); dwell >= stopMinDuration {
+			stops = append(stops, dto.RouteStop{
+				Lat:             anchor.Lat,
+				Lng:             anchor.Lng,
+				ArrivedAt:       anchor.Timestamp,
+				DepartedAt:      last.Timestamp,
+				DurationMinutes: roundTo(dwell.Minutes(), 1),
+			})
+		}
+		i = j
+	}
+	return stops
+}
+
+func roundTo(value float64, decimals int) float64 {
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
+
+// BuildRouteGeoJSON mengubah riwayat rute menjadi FeatureCollection:
+// satu LineString untuk jejak perjalanan dan satu Point untuk tiap titik berhenti.
+func BuildRouteGeoJSON(history *dto.RouteHistory) dto.GeoJSONFeatureCollection {
+	coordinates := make([][]float64, 0, len(history.Track))
+	timestamps := make([]string, 0, len(history.Track))
+	for _, p := range history.Track {
+		coordinates = append(coordinates, []float64{p.Lng, p.Lat}) // GeoJSON: [lng, lat]
+		timestamps = append(timestamps, p.Timestamp.UTC().Format(time.RFC3339))
+	}
+
+	features := []dto.GeoJSONFeature{{
+		Type:     "Feature",
+		Geometry: dto.GeoJSONGeometry{Type: "LineString", Coordinates: coordinates},
+		Properties: map[string]any{
+			"delivery_id": history.DeliveryID,
+			"stats":       history.Stats,
+			"timestamps":  timestamps,
+		},
+	}}
+	for i, stop := range history.Stats.Stops {
+		features = append(features, dto.GeoJSONFeature{
+			Type:     "Feature",
+			Geometry: dto.GeoJSONGeometry{Type: "Point", Coordinates: []float64{stop.Lng, stop.Lat}},
+			Properties: map[string]any{
+				"kind":             "stop",
+				"sequence":         i + 1,
+				"arrived_at":       stop.ArrivedAt,
+				"departed_at":      stop.DepartedAt,
+				"duration_minutes": stop.DurationMinutes,
+			},
+		})
+	}
+	return dto.GeoJSONFeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+type gpxFile struct {
+	XMLName  xml.Name      `xml:"gpx"`
+	Version  string        `xml:"version,attr"`
+	Creator  string        `xml:"creator,attr"`
+	Xmlns    string        `xml:"xmlns,attr"`
+	Metadata gpxMetadata   `xml:"metadata"`
+	Waypts   []gpxWaypoint `xml:"wpt"`
+	Track    gpxTrack      `xml:"trk"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name"`
+	Desc string `xml:"desc"`
+}
+
+type gpxWaypoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time,omitempty"`
+	Name string  `xml:"name,omitempty"`
+	Desc string  `xml:"desc,omitempty"`
+}
+
+type gpxTrack struct {
+	Name    string     `xml:"name"`
+	Segment gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxWaypoint `xml:"trkpt"`
+}
+
+// BuildRouteGPX mengubah riwayat rute menjadi dokumen GPX 1.1.
+func BuildRouteGPX(history *dto.RouteHistory) ([]byte, error) {
+	stats := history.Stats
+	doc := gpxFile{
+		Version: "1.1",
+		Creator: "AgroLink",
+		Xmlns:   "http://www.topografix.com/GPX/1/1",
+		Metadata: gpxMetadata{
+			Name: fmt.Sprintf("Pengiriman %s", history.DeliveryID),
+			Desc: fmt.Sprintf("Jarak %.2f km, bergerak %.1f menit, %d kali berhenti, kecepatan maks %.1f km/jam",
+				stats.TotalDistanceKm, stats.MovingMinutes, len(stats.Stops), stats.MaxSpeedKmh),
+		},
+		Track: gpxTrack{Name: history.DeliveryID.String()},
+	}
+	for i, stop := range stats.Stops {
+		doc.Waypts = append(doc.Waypts, gpxWaypoint{
+			Lat:  stop.Lat,
+			Lon:  stop.Lng,
+			Time: stop.ArrivedAt.UTC().Format(time.RFC3339),
+			Name: fmt.Sprintf("Berhenti %d", i+1),
+			Desc: fmt.Sprintf("%.1f menit", stop.DurationMinutes),
+		})
+	}
+	for _, p := range history.Track {
+		doc.Track.Segment.Points = append(doc.Track.Segment.Points, gpxWaypoint{
+			Lat:  p.Lat,
+			Lon:  p.Lng,
+			Time: p.Timestamp.UTC().Format(time.RFC3339),
+		})
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"math"
+
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+// maxTwoOptPasses membatasi jumlah putaran perbaikan 2-opt agar tetap ringan.
+const maxTwoOptPasses = 50
+
+// optimizeStopSequence mengurutkan titik antar mulai dari start dengan heuristik
+// nearest-neighbour, lalu memperbaikinya dengan 2-opt. Rute bersifat terbuka
+// (tidak kembali ke start). Mengembalikan indeks stops sesuai urutan kunjungan.
+func optimizeStopSequence(start dto.GeoPoint, stops []dto.GeoPoint) []int {
+	n := len(stops)
+	order := make([]int, 0, n)
+	visited := make([]bool, n)
+	current := start
+	for len(order) < n {
+		next, nextDist := -1, math.MaxFloat64
+		for i, stop := range stops {
+			if visited[i] {
+				continue
+			}
+			if d := geoDistanceKm(current, stop); d < nextDist {
+				next, nextDist = i, d
+			}
+		}
+		visited[next] = true
+		order = append(order, next)
+		current = stops[next]
+	}
+
+	// point(-1) adalah titik awal; posisi lain menunjuk ke urutan saat ini
+	point := func(pos int) dto.GeoPoint {
+		if pos < 0 {
+			return start
+		}
+		return stops[order[pos]]
+	}
+	for pass := 0; pass < maxTwoOptPasses; pass++ {
+		improved := false
+		for i := 0; i < n-1; i++ {
+			for j := i + 1; j < n; j++ {
+				// Membalik order[i..j] mengganti sisi (i-1,i) & (j,j+1) dengan (i-1,j) & (i,j+1)
+				before := geoDistanceKm(point(i-1), point(i))
+				after := geoDistanceKm(point(i-1), point(j))
+				if j+1 < n {
+					before += geoDistanceKm(point(j), point(j+1))
+					after += geoDistanceKm(point(i), point(j+1))
+				}
+				if after < before-1e-9 {
+					for l, r := i, j; l < r; l, r = l+1, r-1 {
+						order[l], order[r] = order[r], order[l]
+					}
+					improved = true
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return order
+}
+
+// pathDistanceKm menghitung panjang rute garis lurus melewati titik-titik secara berurutan.
+func pathDistanceKm(points []dto.GeoPoint) float64 {
+	var total float64
+	for i := 1; i < len(points); i++ {
+		total += geoDistanceKm(points[i-1], points[i])
+	}
+	return total
+}
+
+func geoDistanceKm(a, b dto.GeoPoint) float64 {
+	return utils.HaversineKm(a.Lat, a.Lng, b.Lat, b.Lng)
+}
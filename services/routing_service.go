@@ -0,0 +1,150 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/utils"
+)
+
+const (
+	// Faktor koreksi jalan: jarak jalan raya rata-rata lebih panjang dari garis lurus.
+	haversineRoadFactor = 1.3
+	// Kecepatan rata-rata kendaraan barang di jalan pedesaan (km/jam).
+	haversineAverageSpeedKmh = 40.0
+)
+
+// RoutingProvider menghitung jarak & durasi tempuh melalui urutan titik.
+type RoutingProvider interface {
+	Route(points []dto.GeoPoint) (*dto.RouteEstimate, error)
+}
+
+// NewRoutingProvider memilih provider berdasarkan environment.
+// Jika OSRM_BASE_URL diatur, OSRM dipakai dengan haversine sebagai cadangan.
+func NewRoutingProvider() RoutingProvider {
+	fallback := NewHaversineRoutingProvider()
+	baseURL := strings.TrimSpace(os.Getenv("OSRM_BASE_URL"))
+	if baseURL == "" {
+		return fallback
+	}
+	return &fallbackRoutingProvider{
+		primary:  NewOSRMRoutingProvider(baseURL),
+		fallback: fallback,
+	}
+}
+
+// =====================================================================
+// Haversine
+// =====================================================================
+
+type haversineRoutingProvider struct{}
+
+func NewHaversineRoutingProvider() RoutingProvider {
+	return &haversineRoutingProvider{}
+}
+
+func (p *haversineRoutingProvider) Route(points []dto.GeoPoint) (*dto.RouteEstimate, error) {
+	if len(points) < 2 {
+		return nil, errors.New("route needs at least two points")
+	}
+
+	var straightKm float64
+	for i := 1; i < len(points); i++ {
+		straightKm += utils.HaversineKm(points[i-1].Lat, points[i-1].Lng, points[i].Lat, points[i].Lng)
+	}
+	distanceKm := straightKm * haversineRoadFactor
+
+	return &dto.RouteEstimate{
+		DistanceKm:      math.Round(distanceKm*100) / 100,
+		DurationMinutes: int(math.Ceil(distanceKm / haversineAverageSpeedKmh * 60)),
+		Source:          "haversine",
+	}, nil
+}
+
+// =====================================================================
+// OSRM (self-hosted, API kompatibel dengan /route/v1)
+// =====================================================================
+
+type osrmRoutingProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewOSRMRoutingProvider(baseURL string) RoutingProvider {
+	return &osrmRoutingProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type osrmRouteResponse struct {
+	Code   string `json:"code"`
+	Routes []struct {
+		Distance float64 `json:"distance"` // meter
+		Duration float64 `json:"duration"` // detik
+	} `json:"routes"`
+}
+
+func (p *osrmRoutingProvider) Route(points []dto.GeoPoint) (*dto.RouteEstimate, error) {
+	if len(points) < 2 {
+		return nil, errors.New("route needs at least two points")
+	}
+
+	// OSRM memakai urutan lng,lat
+	coords := make([]string, 0, len(points))
+	for _, pt := range points {
+		coords = append(coords, fmt.Sprintf("%f,%f", pt.Lng, pt.Lat))
+	}
+	endpoint := fmt.Sprintf("%s/route/v1/driving/%s?overview=false", p.baseURL, strings.Join(coords, ";"))
+
+	resp, err := p.client.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("osrm request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("osrm returned status %d", resp.StatusCode)
+	}
+
+	var body osrmRouteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid osrm response: %w", err)
+	}
+	if body.Code != "Ok" || len(body.Routes) == 0 {
+		return nil, fmt.Errorf("osrm could not find a route (code: %s)", body.Code)
+	}
+
+	route := body.Routes[0]
+	return &dto.RouteEstimate{
+		DistanceKm:      math.Round(route.Distance/10) / 100,
+		DurationMinutes: int(math.Ceil(route.Duration / 60)),
+		Source:          "osrm",
+	}, nil
+}
+
+// =====================================================================
+// Fallback
+// =====================================================================
+
+type fallbackRoutingProvider struct {
+	primary  RoutingProvider
+	fallback RoutingProvider
+}
+
+func (p *fallbackRoutingProvider) Route(points []dto.GeoPoint) (*dto.RouteEstimate, error) {
+	estimate, err := p.primary.Route(points)
+	if err == nil {
+		return estimate, nil
+	}
+	log.Printf("WARN: primary routing provider failed, using fallback: %v", err)
+	return p.fallback.Route(points)
+}
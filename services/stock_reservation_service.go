@@ -0,0 +1,401 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"gorm.io/gorm"
+)
+
+// Jumlah order maksimal yang dilepas dalam satu putaran job.
+const reservationReleaseBatchSize = 100
+
+// StockReservationService mengelola stok yang ditahan pesanan e-commerce sejak checkout
+// hingga pembayaran sukses (commit) atau gagal/kedaluwarsa (release), serta melepas
+// tahanan stok keranjang yang sudah kedaluwarsa.
+type StockReservationService interface {
+	TrackOrder(tx *gorm.DB, order *models.Order) error
+	CommitForOrders(tx *gorm.DB, orders []models.Order) ([]models.Order, error)
+	ReleaseForOrders(tx *gorm.DB, orderIDs []uuid.UUID, reason string) error
+	ReleaseExpired() (int, error)
+	ReleaseExpiredCartHolds() (int, error)
+	RunReleaseJob(interval time.Duration)
+	GetStuckReservations() (*dto.StuckReservationReport, error)
+	ReleaseReservation(reservationID uuid.UUID) error
+}
+
+type stockReservationService struct {
+	reservationRepo repositories.StockReservationRepository
+	variantRepo     repositories.ProductVariantRepository
+	orderRepo       repositories.OrderRepository
+	cartRepo        repositories.CartRepository
+	inventory       InventoryService
+	vouchers        VoucherService
+	db              *gorm.DB
+}
+
+func NewStockReservationService(
+	reservationRepo repositories.StockReservationRepository,
+	variantRepo repositories.ProductVariantRepository,
+	orderRepo repositories.OrderRepository,
+	cartRepo repositories.CartRepository,
+	inventory InventoryService,
+	vouchers VoucherService,
+	db *gorm.DB,
+) StockReservationService {
+	return &stockReservationService{
+		reservationRepo: reservationRepo,
+		variantRepo:     variantRepo,
+		orderRepo:       orderRepo,
+		cartRepo:        cartRepo,
+		inventory:       inventory,
+		vouchers:        vouchers,
+		db:              db,
+	}
+}
+
+// TrackOrder mencatat reservasi untuk setiap item order yang baru dibuat. Stok varian
+// sudah ditahan sebelumnya (oleh keranjang atau direct checkout); di sini hanya dicatat
+// pemiliknya dan batas waktunya.
+func (s *stockReservationService) TrackOrder(tx *gorm.DB, order *models.Order) error {
+	expiresAt := time.Now().Add(models.ReservationTTL)
+	for _, item := range order.Items {
+		if item.VariantID == nil {
+			continue
+		}
+		reservation := &models.StockReservation{
+			OrderID:     order.ID,
+			OrderItemID: item.ID,
+			ProductID:   item.ProductID,
+			VariantID:   *item.VariantID,
+			Quantity:    item.Quantity,
+			Status:      models.ReservationStatusActive,
+			ExpiresAt:   expiresAt,
+		}
+		if err := s.reservationRepo.Create(tx, reservation); err != nil {
+			return fmt.Errorf("failed to track stock reservation: %w", err)
+		}
+	}
+	return nil
+}
+
+// errStockOversold menandai order yang stoknya sudah tidak cukup saat pembayaran di-commit.
+var errStockOversold = errors.New("invalid stock: stock was sold to another buyer")
+
+// CommitForOrders dipanggil saat pembayaran sukses: stok fisik dan stok yang direservasi
+// sama-sama dikurangi. Jika reservasi sudah terlanjur dilepas (webhook terlambat), hanya
+// stok fisik yang dikurangi karena stok reservasinya sudah dikembalikan. Order yang stoknya
+// sudah terjual ke pembeli lain tidak di-commit sama sekali dan dikembalikan sebagai
+// oversold agar pemanggil membatalkan dan me-refund-nya; transaksi pemanggil tetap berjalan.
+func (s *stockReservationService) CommitForOrders(tx *gorm.DB, orders []models.Order) ([]models.Order, error) {
+	orderIDs := make([]uuid.UUID, 0, len(orders))
+	for _, order := range orders {
+		orderIDs = append(orderIDs, order.ID)
+	}
+	reservations, err := s.reservationRepo.FindByOrderIDsForUpdate(tx, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	byItem := make(map[uuid.UUID]*models.StockReservation, len(reservations))
+	for i := range reservations {
+		byItem[reservations[i].OrderItemID] = &reservations[i]
+	}
+
+	var oversold []models.Order
+	for _, order := range orders {
+		// Setiap order di-commit dalam savepoint agar order yang oversold tidak mengurangi stok sebagian
+		err := tx.Transaction(func(otx *gorm.DB) error {
+			return s.commitOrder(otx, order, byItem)
+		})
+		if errors.Is(err, errStockOversold) {
+			log.Printf("WARN: Stok order %s sudah terjual ke pembeli lain, order dibatalkan: %v", order.ID, err)
+			oversold = append(oversold, order)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return oversold, nil
+}
+
+// commitOrder mengurangi stok semua item satu order dan menandai reservasinya committed.
+func (s *stockReservationService) commitOrder(tx *gorm.DB, order models.Order, byItem map[uuid.UUID]*models.StockReservation) error {
+	now := time.Now()
+	for _, item := range order.Items {
+		if item.VariantID == nil {
+			log.Printf("WARN: Item pesanan %s tidak memiliki varian, stok tidak dikurangi", item.ID)
+			continue
+		}
+		reservation, tracked := byItem[item.ID]
+		if tracked && reservation.Status == models.ReservationStatusCommitted {
+			continue
+		}
+		variant, err := s.variantRepo.FindByIDForUpdate(tx, *item.VariantID)
+		if err != nil {
+			log.Printf("WARN: Gagal menemukan varian %s untuk mengurangi stok: %v", *item.VariantID, err)
+			continue
+		}
+
+		orderID := order.ID
+		change := StockChange{
+			Type:          models.MovementSale,
+			StockDelta:    -item.Quantity,
+			ReservedDelta: -item.Quantity,
+			OrderID:       &orderID,
+		}
+		// Reservasi yang sudah dilepas hanya boleh memakai stok yang belum ditahan pembeli lain
+		enough := variant.Stock >= item.Quantity && variant.ReservedStock >= item.Quantity
+		if tracked && reservation.Status == models.ReservationStatusReleased {
+			change.ReservedDelta = 0
+			enough = variant.AvailableStock() >= item.Quantity
+		}
+		if !enough {
+			return fmt.Errorf("%w: variant %s", errStockOversold, variant.ID)
+		}
+		if err := s.inventory.ApplyStockChange(tx, variant, change); err != nil {
+			return fmt.Errorf("failed to commit stock for order %s: %w", order.ID, err)
+		}
+
+		if tracked {
+			reservation.Status = models.ReservationStatusCommitted
+			reservation.CommittedAt = &now
+			if err := s.reservationRepo.Update(tx, reservation); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReleaseForOrders mengembalikan semua reservasi aktif milik order-order tersebut ke stok tersedia.
+func (s *stockReservationService) ReleaseForOrders(tx *gorm.DB, orderIDs []uuid.UUID, reason string) error {
+	reservations, err := s.reservationRepo.FindByOrderIDsForUpdate(tx, orderIDs)
+	if err != nil {
+		return err
+	}
+	for i := range reservations {
+		if reservations[i].Status != models.ReservationStatusActive {
+			continue
+		}
+		if err := s.release(tx, &reservations[i], reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// release mengurangi ReservedStock varian dan menandai reservasi sebagai dilepas.
+func (s *stockReservationService) release(tx *gorm.DB, reservation *models.StockReservation, reason string) error {
+	variant, err := s.variantRepo.FindByIDForUpdate(tx, reservation.VariantID)
+	if err == nil {
+		change := StockChange{
+			Type:          models.MovementRelease,
+			ReservedDelta: -reservation.Quantity,
+			OrderID:       &reservation.OrderID,
+			Note:          "Reservasi dilepas: " + reason,
+		}
+		if err := s.inventory.ApplyStockChange(tx, variant, change); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	now := time.Now()
+	reservation.Status = models.ReservationStatusReleased
+	reservation.ReleasedAt = &now
+	reservation.ReleaseReason = &reason
+	return s.reservationRepo.Update(tx, reservation)
+}
+
+// ReleaseExpired melepas reservasi order pending yang sudah lewat batas waktu, membatalkan
+// order tersebut, dan mengembalikan kuota voucher yang dipakainya. Setiap order diproses dalam transaksinya sendiri agar
+// satu kegagalan tidak menahan order lain.
+func (s *stockReservationService) ReleaseExpired() (int, error) {
+	orderIDs, err := s.reservationRepo.FindExpiredOrderIDs(time.Now(), reservationReleaseBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, orderID := range orderIDs {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			ids := []uuid.UUID{orderID}
+			if err := s.ReleaseForOrders(tx, ids, models.ReservationReleaseExpired); err != nil {
+				return err
+			}
+			if err := s.vouchers.ReleaseForOrder(tx, orderID); err != nil {
+				return err
+			}
+			return s.orderRepo.CancelPendingByIDs(tx, ids)
+		})
+		if err != nil {
+			log.Printf("WARN: Gagal melepas reservasi kedaluwarsa untuk order %s: %v", orderID, err)
+			continue
+		}
+		released++
+	}
+	return released, nil
+}
+
+// ReleaseExpiredCartHolds melepas stok yang ditahan item keranjang setelah CartHoldTTL.
+// Item tetap ada di keranjang; stoknya ditahan ulang saat diubah atau di-checkout.
+func (s *stockReservationService) ReleaseExpiredCartHolds() (int, error) {
+	cartItems, err := s.cartRepo.FindExpiredHolds(time.Now(), reservationReleaseBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, item := range cartItems {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			variant, err := s.variantRepo.FindByIDForUpdate(tx, item.VariantID)
+			if err != nil {
+				return err
+			}
+			// Periksa ulang setelah varian dikunci: item bisa saja baru diubah atau di-checkout
+			cartItem, err := s.cartRepo.FindByIDWithTx(tx, item.ID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if cartItem.HeldQuantity <= 0 || cartItem.HoldExpiresAt == nil || time.Now().Before(*cartItem.HoldExpiresAt) {
+				return nil
+			}
+			if err := s.inventory.ApplyStockChange(tx, variant, StockChange{
+				Type:          models.MovementRelease,
+				ReservedDelta: -cartItem.HeldQuantity,
+				ActorID:       &cartItem.UserID,
+				Note:          "Tahanan keranjang kedaluwarsa",
+			}); err != nil {
+				return err
+			}
+			cartItem.HeldQuantity = 0
+			cartItem.HoldExpiresAt = nil
+			return s.cartRepo.Update(tx, cartItem)
+		})
+		if err != nil {
+			log.Printf("WARN: Gagal melepas tahanan keranjang %s: %v", item.ID, err)
+			continue
+		}
+		released++
+	}
+	return released, nil
+}
+
+// RunReleaseJob menjalankan ReleaseExpired dan ReleaseExpiredCartHolds secara berkala.
+// Dipanggil sebagai goroutine dari main.
+func (s *stockReservationService) RunReleaseJob(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		released, err := s.ReleaseExpired()
+		if err != nil {
+			log.Printf("WARN: Job pelepasan reservasi stok gagal: %v", err)
+		} else if released > 0 {
+			log.Printf("🔓 Released stock reservations for %d expired orders", released)
+		}
+		holds, err := s.ReleaseExpiredCartHolds()
+		if err != nil {
+			log.Printf("WARN: Job pelepasan tahanan keranjang gagal: %v", err)
+		} else if holds > 0 {
+			log.Printf("🔓 Released expired stock holds for %d cart items", holds)
+		}
+		<-ticker.C
+	}
+}
+
+// GetStuckReservations menyusun laporan admin untuk reservasi aktif yang seharusnya sudah selesai.
+func (s *stockReservationService) GetStuckReservations() (*dto.StuckReservationReport, error) {
+	now := time.Now()
+	reservations, err := s.reservationRepo.FindStuck(now)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dto.StuckReservationReport{
+		TotalReservations: len(reservations),
+		TotalByVariant:    make(map[string]float64),
+		Reservations:      make([]dto.StuckReservationResponse, 0, len(reservations)),
+	}
+	for _, r := range reservations {
+		row := dto.StuckReservationResponse{
+			ID:        r.ID,
+			OrderID:   r.OrderID,
+			ProductID: r.ProductID,
+			VariantID: r.VariantID,
+			Quantity:  r.Quantity,
+			Reason:    dto.StuckReasonExpired,
+			ExpiresAt: r.ExpiresAt,
+			CreatedAt: r.CreatedAt,
+		}
+		if r.Order != nil {
+			row.InvoiceNumber = r.Order.InvoiceNumber
+			row.OrderStatus = r.Order.Status
+			if r.Order.Status != models.OrderStatusPending {
+				row.Reason = dto.StuckReasonOrderNotPending
+			}
+		}
+		if r.Variant != nil {
+			row.VariantName = r.Variant.Name
+			row.Unit = r.Variant.Unit
+			if r.Variant.Product != nil {
+				row.ProductTitle = r.Variant.Product.Title
+			}
+		}
+		if now.After(r.ExpiresAt) {
+			row.OverdueMinutes = int(math.Floor(now.Sub(r.ExpiresAt).Minutes()))
+		}
+		report.TotalByVariant[r.VariantID.String()] = models.RoundQuantity(report.TotalByVariant[r.VariantID.String()] + r.Quantity)
+		report.Reservations = append(report.Reservations, row)
+	}
+	return report, nil
+}
+
+// ReleaseReservation dipakai admin untuk melepas satu reservasi macet secara manual.
+// Reservasi pesanan yang sudah dibayar tidak bisa dilepas. Untuk pesanan yang masih
+// pending, pesanan ikut dibatalkan beserta seluruh reservasi dan voucher-nya, sama
+// seperti pesanan kedaluwarsa.
+func (s *stockReservationService) ReleaseReservation(reservationID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		reservation, err := s.reservationRepo.FindByIDForUpdate(tx, reservationID)
+		if err != nil {
+			return errors.New("reservation not found")
+		}
+		if reservation.Status != models.ReservationStatusActive {
+			return fmt.Errorf("invalid state: reservation is already %s", reservation.Status)
+		}
+
+		order, err := s.orderRepo.FindByIDForUpdate(tx, reservation.OrderID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err != nil {
+			return s.release(tx, reservation, models.ReservationReleaseManual)
+		}
+		switch order.Status {
+		case models.OrderStatusPending:
+			ids := []uuid.UUID{order.ID}
+			if err := s.ReleaseForOrders(tx, ids, models.ReservationReleaseManual); err != nil {
+				return err
+			}
+			if err := s.vouchers.ReleaseForOrder(tx, order.ID); err != nil {
+				return err
+			}
+			return s.orderRepo.CancelPendingByIDs(tx, ids)
+		case models.OrderStatusPaid, models.OrderStatusShipped, models.OrderStatusCompleted:
+			return fmt.Errorf("invalid state: order %s is already %s, its stock must be committed instead of released", order.InvoiceNumber, order.Status)
+		}
+		return s.release(tx, reservation, models.ReservationReleaseManual)
+	})
+}
@@ -1,27 +1,61 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"sort"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/dto"
 	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/pkg/tracking"
 	"github.com/whsasmita/AgroLink_API/repositories"
+	"github.com/whsasmita/AgroLink_API/utils"
+	"gorm.io/gorm"
+)
+
+// Ambang penyaringan titik GPS pada unggahan batch.
+const (
+	maxPlausibleSpeedKmh = 150.0            // lebih cepat dari ini dianggap lompatan GPS
+	minSampleInterval    = 10 * time.Second // titik lebih rapat dari ini...
+	minSampleDistanceKm  = 0.02             // ...dan bergeser < 20 m akan dibuang
+	maxClockSkew         = 5 * time.Minute  // toleransi jam perangkat yang lebih cepat
 )
 
 type TrackingService interface {
 	UpdateLocation(deliveryID, driverID uuid.UUID, lat, lng float64) error
+	UploadBatch(deliveryID, driverID uuid.UUID, points []dto.LocationPointInput) (*dto.BatchLocationResult, error)
 	GetLatestLocation(deliveryID string, farmerID uuid.UUID) (*models.LocationTrack, error)
+	GetRouteHistory(deliveryID string, user *models.User) (*dto.RouteHistory, error)
+	Subscribe(deliveryID string, user *models.User) (*dto.TrackingSnapshot, <-chan tracking.Event, func(), error)
 }
 
 type trackingService struct {
 	trackRepo    repositories.LocationTrackRepository
 	deliveryRepo repositories.DeliveryRepository
+	routing      RoutingProvider
+	hub          *tracking.Hub
+	notifService NotificationService
+	db           *gorm.DB
 }
 
-func NewTrackingService(trackRepo repositories.LocationTrackRepository, deliveryRepo repositories.DeliveryRepository) TrackingService {
+func NewTrackingService(
+	trackRepo repositories.LocationTrackRepository,
+	deliveryRepo repositories.DeliveryRepository,
+	routing RoutingProvider,
+	hub *tracking.Hub,
+	notifService NotificationService,
+	db *gorm.DB,
+) TrackingService {
 	return &trackingService{
 		trackRepo:    trackRepo,
 		deliveryRepo: deliveryRepo,
+		routing:      routing,
+		hub:          hub,
+		notifService: notifService,
+		db:           db,
 	}
 }
 
@@ -35,8 +69,8 @@ func (s *trackingService) UpdateLocation(deliveryID, driverID uuid.UUID, lat, ln
 	if delivery.DriverID == nil || *delivery.DriverID != driverID {
 		return fmt.Errorf("forbidden: you are not assigned to this delivery")
 	}
-	if delivery.Status != "in_transit" {
-		return fmt.Errorf("location can only be updated for deliveries that are in transit")
+	if !delivery.IsTrackable() {
+		return fmt.Errorf("location can only be updated for deliveries that are in progress")
 	}
 
 	// 2. Buat catatan lokasi baru
@@ -45,7 +79,24 @@ func (s *trackingService) UpdateLocation(deliveryID, driverID uuid.UUID, lat, ln
 		Lat:        lat,
 		Lng:        lng,
 	}
-	return s.trackRepo.Create(newTrack)
+	if err := s.trackRepo.Create(newTrack); err != nil {
+		return err
+	}
+
+	// 3. Tafsirkan posisi terhadap geofence pickup/tujuan
+	if events, err := s.deliveryRepo.FindEventsByDeliveryID(deliveryID.String()); err != nil {
+		log.Printf("WARN: failed to load events for geofencing delivery %s: %v", deliveryID, err)
+	} else {
+		s.processGeofences(delivery, newTrack, &events)
+		s.checkStationary(delivery, newTrack, &events)
+	}
+
+	// 4. Dorong posisi & ETA terbaru ke pemantau live tracking
+	s.hub.Publish(tracking.Event{Type: tracking.EventLocation, DeliveryID: deliveryID, Data: newTrack})
+	if eta := s.estimateETA(delivery, newTrack); eta != nil {
+		s.hub.Publish(tracking.Event{Type: tracking.EventETA, DeliveryID: deliveryID, Data: eta})
+	}
+	return nil
 }
 
 // GetLatestLocation mengambil data lokasi terakhir dari sebuah pengiriman.
@@ -61,4 +112,200 @@ func (s *trackingService) GetLatestLocation(deliveryID string, farmerID uuid.UUI
 
 	// 2. Ambil data lokasi terakhir
 	return s.trackRepo.FindLatestByDeliveryID(deliveryID)
-}
\ No newline at end of file
+}
+
+// canWatchDelivery menentukan siapa saja yang boleh memantau pengiriman secara live:
+// petani pemilik, driver yang ditugaskan, dan pembeli pesanan yang diantar.
+func canWatchDelivery(delivery *models.Delivery, user *models.User) bool {
+	if delivery.FarmerID == user.ID {
+		return true
+	}
+	if delivery.DriverID != nil && *delivery.DriverID == user.ID {
+		return true
+	}
+	return delivery.HasOrderBuyer(user.ID)
+}
+
+// Subscribe memvalidasi akses lalu mendaftarkan pemantau live tracking.
+// Snapshot berisi status, lokasi terakhir dan ETA saat ini sebagai data awal.
+func (s *trackingService) Subscribe(deliveryID string, user *models.User) (*dto.TrackingSnapshot, <-chan tracking.Event, func(), error) {
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, nil, nil, errors.New("delivery not found")
+	}
+	if !canWatchDelivery(delivery, user) {
+		return nil, nil, nil, errors.New("forbidden: you are not allowed to track this delivery")
+	}
+
+	snapshot := &dto.TrackingSnapshot{Status: delivery.Status}
+	if latest, err := s.trackRepo.FindLatestByDeliveryID(deliveryID); err == nil {
+		snapshot.LatestLocation = latest
+		if delivery.IsTrackable() {
+			snapshot.ETA = s.estimateETA(delivery, latest)
+		}
+	}
+
+	events, unsubscribe := s.hub.Subscribe(delivery.ID)
+	return snapshot, events, unsubscribe, nil
+}
+
+// estimateETA menghitung sisa jarak dari posisi driver melewati titik yang belum
+// dikunjungi hingga tujuan akhir. Mengembalikan nil jika rute tidak bisa dihitung.
+func (s *trackingService) estimateETA(delivery *models.Delivery, position *models.LocationTrack) *dto.DeliveryETA {
+	points := []dto.GeoPoint{{Lat: position.Lat, Lng: position.Lng}}
+	if delivery.Status == models.DeliveryStatusPickupPending || delivery.Status == models.DeliveryStatusAtPickup {
+		points = append(points, dto.GeoPoint{Lat: delivery.PickupLat, Lng: delivery.PickupLng})
+	}
+	remaining := deliveryRoutePoints(delivery)[1:] // lewati titik pickup
+	points = append(points, remaining...)
+	if len(points) < 2 {
+		return nil
+	}
+
+	estimate, err := s.routing.Route(points)
+	if err != nil {
+		log.Printf("WARN: failed to estimate ETA for delivery %s: %v", delivery.ID, err)
+		return nil
+	}
+	return &dto.DeliveryETA{
+		RemainingKm: estimate.DistanceKm,
+		EtaMinutes:  estimate.DurationMinutes,
+		EtaAt:       time.Now().Add(time.Duration(estimate.DurationMinutes) * time.Minute),
+		Source:      estimate.Source,
+	}
+}
+
+// UploadBatch menyimpan titik yang direkam saat offline dengan mempertahankan
+// waktu perekaman perangkat. Titik duplikat, lompatan kecepatan yang mustahil,
+// dan titik yang terlalu rapat dibuang sebelum ditulis.
+func (s *trackingService) UploadBatch(deliveryID, driverID uuid.UUID, points []dto.LocationPointInput) (*dto.BatchLocationResult, error) {
+	delivery, err := s.deliveryRepo.FindByID(deliveryID.String())
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found")
+	}
+	if delivery.DriverID == nil || *delivery.DriverID != driverID {
+		return nil, fmt.Errorf("forbidden: you are not assigned to this delivery")
+	}
+	// Unggahan offline boleh tiba setelah pengiriman selesai
+	if !delivery.IsTrackable() && delivery.Status != models.DeliveryStatusDelivered && delivery.Status != models.DeliveryStatusFailed {
+		return nil, fmt.Errorf("invalid status: location can only be uploaded for deliveries that are in progress")
+	}
+
+	result := &dto.BatchLocationResult{Received: len(points)}
+
+	// 1. Buang titik dengan waktu di luar masa pengiriman, lalu urutkan
+	latestAllowed := time.Now().Add(maxClockSkew)
+	valid := make([]dto.LocationPointInput, 0, len(points))
+	for _, p := range points {
+		if p.Timestamp.Before(delivery.CreatedAt) || p.Timestamp.After(latestAllowed) {
+			result.InvalidTime++
+			continue
+		}
+		valid = append(valid, p)
+	}
+	if len(valid) == 0 {
+		return result, nil
+	}
+	sort.SliceStable(valid, func(i, j int) bool { return valid[i].Timestamp.Before(valid[j].Timestamp) })
+
+	// 2. Kumpulkan detik yang sudah tersimpan untuk deduplikasi
+	first, last := valid[0].Timestamp, valid[len(valid)-1].Timestamp
+	existing, err := s.trackRepo.FindTimestampsBetween(deliveryID, first.Add(-time.Second), last.Add(time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing locations: %w", err)
+	}
+	seen := make(map[int64]bool, len(existing)+len(valid))
+	for _, ts := range existing {
+		seen[ts.Unix()] = true
+	}
+
+	// 3. Saring berurutan terhadap titik terakhir yang diterima
+	var prev *models.LocationTrack
+	if before, err := s.trackRepo.FindLastBefore(deliveryID, first); err == nil {
+		prev = before
+	}
+	accepted := make([]models.LocationTrack, 0, len(valid))
+	for _, p := range valid {
+		if seen[p.Timestamp.Unix()] {
+			result.Duplicates++
+			continue
+		}
+		seen[p.Timestamp.Unix()] = true
+
+		if prev != nil {
+			distanceKm := utils.HaversineKm(prev.Lat, prev.Lng, p.Lat, p.Lng)
+			elapsed := p.Timestamp.Sub(prev.Timestamp)
+			if elapsed > 0 && distanceKm/elapsed.Hours() > maxPlausibleSpeedKmh {
+				result.Outliers++
+				continue
+			}
+			if elapsed < minSampleInterval && distanceKm < minSampleDistanceKm {
+				result.Downsampled++
+				continue
+			}
+		}
+
+		track := models.LocationTrack{
+			DeliveryID: deliveryID,
+			Lat:        p.Lat,
+			Lng:        p.Lng,
+			Accuracy:   p.Accuracy,
+			Source:     "batch",
+			Timestamp:  p.Timestamp,
+		}
+		accepted = append(accepted, track)
+		prev = &accepted[len(accepted)-1]
+	}
+
+	if err := s.trackRepo.CreateBatch(accepted); err != nil {
+		return nil, fmt.Errorf("failed to save locations: %w", err)
+	}
+	result.Accepted = len(accepted)
+
+	// 4. Tafsirkan geofence berurutan mengikuti perjalanan yang terekam
+	if len(accepted) > 0 && delivery.IsTrackable() {
+		if events, err := s.deliveryRepo.FindEventsByDeliveryID(deliveryID.String()); err != nil {
+			log.Printf("WARN: failed to load events for geofencing delivery %s: %v", deliveryID, err)
+		} else {
+			for i := range accepted {
+				s.processGeofences(delivery, &accepted[i], &events)
+			}
+			// Deteksi diam cukup sekali per batch, memakai titik terakhir yang diterima
+			s.checkStationary(delivery, &accepted[len(accepted)-1], &events)
+		}
+	}
+
+	// 5. Beri tahu pemantau jika batch memuat posisi terbaru
+	if len(accepted) > 0 {
+		newest := accepted[len(accepted)-1]
+		if latest, err := s.trackRepo.FindLatestByDeliveryID(deliveryID.String()); err == nil && latest.ID == newest.ID {
+			s.hub.Publish(tracking.Event{Type: tracking.EventLocation, DeliveryID: deliveryID, Data: &newest})
+			if delivery.IsTrackable() {
+				if eta := s.estimateETA(delivery, &newest); eta != nil {
+					s.hub.Publish(tracking.Event{Type: tracking.EventETA, DeliveryID: deliveryID, Data: eta})
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// GetRouteHistory mengambil seluruh jejak GPS pengiriman beserta statistiknya.
+// Admin juga boleh mengakses untuk keperluan sengketa dan penilaian driver.
+func (s *trackingService) GetRouteHistory(deliveryID string, user *models.User) (*dto.RouteHistory, error) {
+	delivery, err := s.deliveryRepo.FindByID(deliveryID)
+	if err != nil {
+		return nil, errors.New("delivery not found")
+	}
+	if user.Role != "admin" && !canWatchDelivery(delivery, user) {
+		return nil, errors.New("forbidden: you are not allowed to view this route")
+	}
+
+	track, err := s.trackRepo.FindAllByDeliveryID(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load route history: %w", err)
+	}
+	stats := computeRouteStats(track)
+	stats.PlannedDistanceKm = delivery.EstimatedDistanceKm
+	return &dto.RouteHistory{DeliveryID: delivery.ID, Stats: stats, Track: track}, nil
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whsasmita/AgroLink_API/config"
+	"github.com/whsasmita/AgroLink_API/dto"
+	"github.com/whsasmita/AgroLink_API/models"
+	"github.com/whsasmita/AgroLink_API/repositories"
+	"gorm.io/gorm"
+)
+
+// VoucherService mengelola voucher promo dan pemakaiannya saat checkout. Pemakaian
+// dipegang sejak checkout dan diselesaikan oleh webhook pembayaran e-commerce.
+type VoucherService interface {
+	GetVouchers(farmerID *uuid.UUID) ([]dto.VoucherResponse, error)
+	CreateVoucher(input dto.VoucherRequest, actorID uuid.UUID, farmerID *uuid.UUID) (*dto.VoucherResponse, error)
+	UpdateVoucher(voucherID uuid.UUID, input dto.VoucherRequest, farmerID *uuid.UUID) (*dto.VoucherResponse, error)
+	QuoteOrders(userID uuid.UUID, code string, orders []*models.Order) error
+	ApplyToOrders(tx *gorm.DB, userID uuid.UUID, code string, orders []*models.Order) (*models.VoucherRedemption, error)
+	RecordRedemption(tx *gorm.DB, redemption *models.VoucherRedemption, paymentID uuid.UUID) error
+	OnPaymentSettled(tx *gorm.DB, payment *models.ECommercePayment) error
+	OnPaymentFailed(tx *gorm.DB, payment *models.ECommercePayment) error
+	ReleaseForOrder(tx *gorm.DB, orderID uuid.UUID) error
+}
+
+type voucherService struct {
+	voucherRepo repositories.VoucherRepository
+	profitRepo  repositories.ProfitRepository
+	db          *gorm.DB
+}
+
+func NewVoucherService(voucherRepo repositories.VoucherRepository, profitRepo repositories.ProfitRepository, db *gorm.DB) VoucherService {
+	return &voucherService{voucherRepo: voucherRepo, profitRepo: profitRepo, db: db}
+}
+
+// ecommerceShippingFee adalah ongkos kirim tetap per pesanan petani yang ditagihkan saat
+// checkout, dibulatkan ke rupiah penuh. Nilai 0 (default) berarti ongkir tidak ditagihkan.
+func ecommerceShippingFee() float64 {
+	if config.AppConfig_ == nil {
+		return 0
+	}
+	return roundTo(config.AppConfig_.Platform.EcommerceShippingFee, 0)
+}
+
+// GetVouchers menampilkan semua voucher (admin) atau voucher toko milik petani.
+func (s *voucherService) GetVouchers(farmerID *uuid.UUID) ([]dto.VoucherResponse, error) {
+	vouchers, err := s.voucherRepo.FindAll(farmerID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]dto.VoucherResponse, 0, len(vouchers))
+	for i := range vouchers {
+		responses = append(responses, toVoucherResponse(&vouchers[i]))
+	}
+	return responses, nil
+}
+
+// CreateVoucher membuat voucher baru. farmerID diisi jika pembuatnya petani: voucher
+// menjadi voucher toko yang hanya berlaku untuk produknya dan ditanggung petani.
+func (s *voucherService) CreateVoucher(input dto.VoucherRequest, actorID uuid.UUID, farmerID *uuid.UUID) (*dto.VoucherResponse, error) {
+	code := models.NormalizeVoucherCode(input.Code)
+	if _, err := s.voucherRepo.FindByCode(code); err == nil {
+		return nil, errors.New("invalid code: voucher code already exists")
+	}
+
+	voucher := &models.Voucher{Code: code, PerUserLimit: 1, IsActive: true, CreatedBy: actorID}
+	if err := applyVoucherInput(voucher, input, farmerID); err != nil {
+		return nil, err
+	}
+	if err := s.voucherRepo.Create(voucher); err != nil {
+		return nil, fmt.Errorf("failed to create voucher: %w", err)
+	}
+	response := toVoucherResponse(voucher)
+	return &response, nil
+}
+
+// UpdateVoucher mengubah voucher. Petani hanya boleh mengubah voucher tokonya sendiri.
+// Kode voucher yang sudah dipakai tidak bisa diganti.
+func (s *voucherService) UpdateVoucher(voucherID uuid.UUID, input dto.VoucherRequest, farmerID *uuid.UUID) (*dto.VoucherResponse, error) {
+	voucher, err := s.voucherRepo.FindByID(voucherID)
+	if err != nil {
+		return nil, errors.New("voucher not found")
+	}
+	if farmerID != nil && (voucher.FarmerID == nil || *voucher.FarmerID != *farmerID) {
+		return nil, errors.New("forbidden: you do not own this voucher")
+	}
+
+	code := models.NormalizeVoucherCode(input.Code)
+	if code != voucher.Code {
+		if voucher.UsedCount > 0 {
+			return nil, errors.New("invalid code: code of a voucher that has been used cannot be changed")
+		}
+		if _, err := s.voucherRepo.FindByCode(code); err == nil {
+			return nil, errors.New("invalid code: voucher code already exists")
+		}
+		voucher.Code = code
+	}
+	if err := applyVoucherInput(voucher, input, farmerID); err != nil {
+		return nil, err
+	}
+	if err := s.voucherRepo.Update(nil, voucher); err != nil {
+		return nil, fmt.Errorf("failed to update voucher: %w", err)
+	}
+	response := toVoucherResponse(voucher)
+	return &response, nil
+}
+
+// applyVoucherInput memvalidasi dan menyalin input ke voucher.
+func applyVoucherInput(voucher *models.Voucher, input dto.VoucherRequest, farmerID *uuid.UUID) error {
+	switch input.DiscountType {
+	case models.VoucherTypePercentage:
+		if input.Value <= 0 || input.Value > 100 {
+			return errors.New("invalid value: percentage must be between 1 and 100")
+		}
+	case models.VoucherTypeFixed:
+		if input.Value <= 0 {
+			return errors.New("invalid value: fixed discount must be greater than 0")
+		}
+	case models.VoucherTypeFreeShipping:
+		if ecommerceShippingFee() <= 0 {
+			return errors.New("invalid voucher: shipping is not charged at checkout (ECOMMERCE_SHIPPING_FEE is 0), free shipping vouchers are unavailable")
+		}
+	}
+	if !input.EndsAt.After(input.StartsAt) {
+		return errors.New("invalid validity window: ends_at must be after starts_at")
+	}
+
+	fundedBy, scope := input.FundedBy, input.FarmerID
+	if farmerID != nil {
+		fundedBy, scope = models.VoucherFundedByFarmer, farmerID
+	}
+	if fundedBy == "" {
+		fundedBy = models.VoucherFundedByPlatform
+	}
+	if fundedBy == models.VoucherFundedByFarmer && scope == nil {
+		return errors.New("invalid voucher: farmer_id is required for farmer-funded vouchers")
+	}
+
+	voucher.Name = input.Name
+	voucher.Description = input.Description
+	voucher.DiscountType = input.DiscountType
+	voucher.Value = roundTo(input.Value, 2)
+	voucher.MaxDiscount = nil
+	if input.DiscountType == models.VoucherTypePercentage {
+		voucher.MaxDiscount = input.MaxDiscount
+	}
+	voucher.MinSpend = roundTo(input.MinSpend, 2)
+	voucher.FundedBy = fundedBy
+	voucher.FarmerID = scope
+	voucher.UsageLimit = input.UsageLimit
+	if input.PerUserLimit != nil {
+		voucher.PerUserLimit = *input.PerUserLimit
+	}
+	voucher.StartsAt = input.StartsAt
+	voucher.EndsAt = input.EndsAt
+	if input.IsActive != nil {
+		voucher.IsActive = *input.IsActive
+	}
+	return nil
+}
+
+// QuoteOrders menghitung potongan voucher pada draf pesanan tanpa memakai kuotanya,
+// untuk ringkasan checkout.
+func (s *voucherService) QuoteOrders(userID uuid.UUID, code string, orders []*models.Order) error {
+	voucher, err := s.voucherRepo.FindByCode(models.NormalizeVoucherCode(code))
+	if err != nil {
+		return errors.New("voucher not found")
+	}
+	if err := s.checkUserEligible(nil, voucher, userID); err != nil {
+		return err
+	}
+	_, _, err = allocateVoucherDiscount(voucher, orders)
+	return err
+}
+
+// ApplyToOrders mengunci voucher, memvalidasinya untuk pembeli, lalu membagi potongannya
+// ke pesanan-pesanan yang belum disimpan. Kuota voucher langsung dipegang; redemption
+// yang dikembalikan harus dicatat dengan RecordRedemption setelah pembayaran dibuat.
+func (s *voucherService) ApplyToOrders(tx *gorm.DB, userID uuid.UUID, code string, orders []*models.Order) (*models.VoucherRedemption, error) {
+	voucher, err := s.voucherRepo.FindByCodeForUpdate(tx, models.NormalizeVoucherCode(code))
+	if err != nil {
+		return nil, errors.New("voucher not found")
+	}
+	if err := s.checkUserEligible(tx, voucher, userID); err != nil {
+		return nil, err
+	}
+	platformFunded, farmerFunded, err := allocateVoucherDiscount(voucher, orders)
+	if err != nil {
+		return nil, err
+	}
+
+	voucher.UsedCount++
+	if err := s.voucherRepo.Update(tx, voucher); err != nil {
+		return nil, err
+	}
+	return &models.VoucherRedemption{
+		VoucherID:      voucher.ID,
+		UserID:         userID,
+		Status:         models.VoucherRedemptionReserved,
+		DiscountAmount: roundTo(platformFunded+farmerFunded, 2),
+		PlatformFunded: platformFunded,
+		FarmerFunded:   farmerFunded,
+	}, nil
+}
+
+func (s *voucherService) RecordRedemption(tx *gorm.DB, redemption *models.VoucherRedemption, paymentID uuid.UUID) error {
+	redemption.PaymentID = paymentID
+	return s.voucherRepo.CreateRedemption(tx, redemption)
+}
+
+// checkUserEligible memastikan voucher masih berlaku dan pembeli belum melewati batas pemakaiannya.
+func (s *voucherService) checkUserEligible(tx *gorm.DB, voucher *models.Voucher, userID uuid.UUID) error {
+	if err := voucher.CheckAvailable(time.Now()); err != nil {
+		return err
+	}
+	if voucher.PerUserLimit > 0 {
+		used, err := s.voucherRepo.CountActiveRedemptions(tx, voucher.ID, userID)
+		if err != nil {
+			return err
+		}
+		if used >= int64(voucher.PerUserLimit) {
+			return errors.New("invalid voucher: you have reached the usage limit for this voucher")
+		}
+	}
+	return nil
+}
+
+// allocateVoucherDiscount membagi potongan voucher ke pesanan yang memenuhi syarat secara
+// proporsional (subtotal untuk potongan barang, ongkir untuk gratis ongkir) dan mencatat
+// bagian yang ditanggung platform di setiap pesanan. Setiap bagian dibulatkan ke rupiah
+// penuh. Mengembalikan total potongan yang ditanggung platform dan petani.
+func allocateVoucherDiscount(voucher *models.Voucher, orders []*models.Order) (float64, float64, error) {
+	var eligible []*models.Order
+	var subTotal, shippingFee float64
+	for _, order := range orders {
+		if voucher.FarmerID != nil && order.FarmerID != *voucher.FarmerID {
+			continue
+		}
+		eligible = append(eligible, order)
+		subTotal += order.SubTotal
+		shippingFee += order.ShippingFee
+	}
+	if len(eligible) == 0 {
+		return 0, 0, errors.New("invalid voucher: voucher does not apply to the items in your order")
+	}
+	if subTotal < voucher.MinSpend {
+		return 0, 0, fmt.Errorf("invalid voucher: minimum spend of %.0f has not been reached", voucher.MinSpend)
+	}
+	if voucher.DiscountType == models.VoucherTypeFreeShipping && shippingFee <= 0 {
+		return 0, 0, errors.New("invalid voucher: shipping is not charged for this order")
+	}
+	discount := voucher.Discount(roundTo(subTotal, 2), roundTo(shippingFee, 2))
+	if discount <= 0 {
+		return 0, 0, errors.New("invalid voucher: voucher gives no discount for this order")
+	}
+
+	weight := func(order *models.Order) float64 { return order.SubTotal }
+	totalWeight := subTotal
+	if voucher.DiscountType == models.VoucherTypeFreeShipping {
+		weight = func(order *models.Order) float64 { return order.ShippingFee }
+		totalWeight = shippingFee
+	}
+
+	var platformFunded, farmerFunded float64
+	remaining := discount
+	for i, order := range eligible {
+		share := remaining // Pesanan terakhir menerima sisa pembulatan
+		if i < len(eligible)-1 {
+			share = roundTo(discount*weight(order)/totalWeight, 0)
+		}
+		remaining = roundTo(remaining-share, 0)
+
+		order.DiscountAmount = roundTo(order.DiscountAmount+share, 2)
+		if voucher.FundedBy == models.VoucherFundedByPlatform {
+			order.PlatformDiscount = roundTo(order.PlatformDiscount+share, 2)
+			platformFunded += share
+		} else {
+			farmerFunded += share
+		}
+		order.VoucherID = &voucher.ID
+		order.RecalculateTotal()
+	}
+	return roundTo(platformFunded, 2), roundTo(farmerFunded, 2), nil
+}
+
+// OnPaymentSettled menandai voucher terpakai. Potongan yang ditanggung platform dicatat
+// sebagai biaya promo (profit negatif) pada laporan profit e-commerce.
+func (s *voucherService) OnPaymentSettled(tx *gorm.DB, payment *models.ECommercePayment) error {
+	redemption, err := s.voucherRepo.FindRedemptionByPaymentIDForUpdate(tx, payment.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if redemption.Status != models.VoucherRedemptionReserved {
+		return nil
+	}
+
+	now := time.Now()
+	redemption.Status = models.VoucherRedemptionRedeemed
+	redemption.RedeemedAt = &now
+	if err := s.voucherRepo.UpdateRedemption(tx, redemption); err != nil {
+		return err
+	}
+	if redemption.PlatformFunded <= 0 {
+		return nil
+	}
+	return s.profitRepo.Create(tx, &models.PlatformProfit{
+		SourceType:         "ecommerce",
+		ECommercePaymentID: &payment.ID,
+		GrossProfit:        -redemption.PlatformFunded,
+		NetProfit:          -redemption.PlatformFunded,
+		ProfitDate:         now,
+	})
+}
+
+// OnPaymentFailed melepas pemakaian voucher agar kuotanya bisa dipakai lagi.
+func (s *voucherService) OnPaymentFailed(tx *gorm.DB, payment *models.ECommercePayment) error {
+	redemption, err := s.voucherRepo.FindRedemptionByPaymentIDForUpdate(tx, payment.ID)
+	return s.release(tx, redemption, err)
+}
+
+// ReleaseForOrder melepas pemakaian voucher dari pembayaran yang mencakup order yang
+// dibatalkan karena reservasinya kedaluwarsa sebelum webhook pembayaran datang.
+func (s *voucherService) ReleaseForOrder(tx *gorm.DB, orderID uuid.UUID) error {
+	redemption, err := s.voucherRepo.FindRedemptionByOrderIDForUpdate(tx, orderID)
+	return s.release(tx, redemption, err)
+}
+
+// release mengembalikan kuota voucher dari pemakaian yang masih dipegang; err adalah
+// hasil pencarian pemakaiannya.
+func (s *voucherService) release(tx *gorm.DB, redemption *models.VoucherRedemption, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if redemption.Status != models.VoucherRedemptionReserved {
+		return nil
+	}
+
+	now := time.Now()
+	redemption.Status = models.VoucherRedemptionReleased
+	redemption.ReleasedAt = &now
+	if err := s.voucherRepo.UpdateRedemption(tx, redemption); err != nil {
+		return err
+	}
+	voucher, err := s.voucherRepo.FindByIDForUpdate(tx, redemption.VoucherID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if voucher.UsedCount > 0 {
+		voucher.UsedCount--
+	}
+	return s.voucherRepo.Update(tx, voucher)
+}
+
+func toVoucherResponse(voucher *models.Voucher) dto.VoucherResponse {
+	return dto.VoucherResponse{
+		ID:           voucher.ID,
+		Code:         voucher.Code,
+		Name:         voucher.Name,
+		Description:  voucher.Description,
+		DiscountType: voucher.DiscountType,
+		Value:        voucher.Value,
+		MaxDiscount:  voucher.MaxDiscount,
+		MinSpend:     voucher.MinSpend,
+		FundedBy:     voucher.FundedBy,
+		FarmerID:     voucher.FarmerID,
+		UsageLimit:   voucher.UsageLimit,
+		PerUserLimit: voucher.PerUserLimit,
+		UsedCount:    voucher.UsedCount,
+		StartsAt:     voucher.StartsAt,
+		EndsAt:       voucher.EndsAt,
+		IsActive:     voucher.IsActive,
+		CreatedAt:    voucher.CreatedAt,
+	}
+}
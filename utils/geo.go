@@ -0,0 +1,18 @@
+package utils
+
+import "math"
+
+const earthRadiusKm = 6371.0
+
+// HaversineKm menghitung jarak garis lurus (km) antara dua koordinat.
+func HaversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLng := toRadians(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Slugify mengubah teks menjadi slug URL, mis. "Sayuran Daun" -> "sayuran-daun".
+func Slugify(s string) string {
+	var b strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			lastDash = false
+		case !lastDash:
+			b.WriteRune('-')
+			lastDash = true
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}